@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/auth/models"
+	"github.com/yzaimoglu/election/auth/utilities"
+	"gorm.io/gorm"
+)
+
+// Hash compared with the password of unknown users, so that they take as long as known users
+var dummyPasswordHash = utilities.HashPassword("election dummy password")
+
+// Get the logged login attempts, filtered by ?username=, ?userid=, ?ip= and ?success= and the newest first
+//
+//	GET /v1/login/attempts/?ip=10.0.0.7&success=false&limit=50
+func GetLoginAttempts(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Parse the limit
+	limit := 100
+	if limitQuery := c.Query("limit"); limitQuery != "" {
+		parsed, err := strconv.Atoi(limitQuery)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  "limit must be between 1 and 1000",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	// Filter the attempts
+	query := db.Model(&models.LoginAttempt{})
+	if username := c.Query("username"); username != "" {
+		query = query.Where("username = ?", username)
+	}
+	if userId := c.Query("userid"); userId != "" {
+		query = query.Where("user_id = ?", userId)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	if success := c.Query("success"); success != "" {
+		parsed, err := strconv.ParseBool(success)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  "success must be true or false",
+			})
+			return
+		}
+		query = query.Where("success = ?", parsed)
+	}
+
+	// Find the attempts
+	var attempts []models.LoginAttempt
+	if err := query.Order("created_at DESC").Limit(limit).Find(&attempts).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the attempts
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"attempts": attempts,
+	})
+}
+
+// Check if logins of the account or from the ip are locked, aborts the request with the time to wait if so
+//
+// Logins are allowed if the cache is unreachable, so that an outage of redis does not lock everybody out.
+func checkLoginLimits(c *gin.Context, db *gorm.DB, input models.LoginInput) bool {
+	retryAfter, err := models.LoginRetryAfter(input.Username, c.ClientIP())
+	if err != nil {
+		log.Println("error checking the login limits: " + err.Error())
+		return true
+	}
+	if retryAfter <= 0 {
+		return true
+	}
+
+	recordLoginAttempt(c, db, input, 0, models.LoginLocked)
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"status":     http.StatusTooManyRequests,
+		"error":      "too many failed logins, try again later",
+		"retryafter": seconds,
+	})
+	return false
+}
+
+// Count and log a failed login and abort the request, the error is the same for all reasons
+func failLogin(c *gin.Context, db *gorm.DB, input models.LoginInput, userId int64, reason string) {
+	if err := models.RegisterLoginFailure(input.Username, c.ClientIP()); err != nil {
+		log.Println("error counting a failed login: " + err.Error())
+	}
+	recordLoginAttempt(c, db, input, userId, reason)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"status": http.StatusUnauthorized,
+		"error":  "invalid login credentials",
+	})
+}
+
+// Log a login attempt in the database
+func recordLoginAttempt(c *gin.Context, db *gorm.DB, input models.LoginInput, userId int64, reason string) {
+	attempt := models.LoginAttempt{
+		UserId:    userId,
+		Username:  input.Username,
+		Email:     input.Email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Success:   reason == models.LoginSuccess,
+		Reason:    reason,
+		CreatedAt: utilities.GetCurrentTime(),
+	}
+	if err := db.Create(&attempt).Error; err != nil {
+		log.Println("error logging a login attempt: " + err.Error())
+	}
+}
@@ -1,11 +1,11 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"github.com/pquerna/otp/totp"
 	"github.com/yzaimoglu/election/auth/models"
 	"github.com/yzaimoglu/election/auth/utilities"
 	"gorm.io/gorm"
@@ -47,38 +47,90 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
+	// Check if the account or the ip is locked after too many failed logins
+	if !checkLoginLimits(c, db, input) {
+		return
+	}
+
 	// Object for the user to be logged in
 	var user models.User
 
-	// Check if user exists and return invalid username or email when not found
+	// Check if user exists, unknown users are checked against a dummy hash so that they cannot be told apart by the time
 	if err := db.Where("username = ? AND email = ?", input.Username, input.Email).First(&user).Error; err != nil {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status": http.StatusNotFound,
-			"error":  "no user with this username and email",
-		})
+		utilities.CheckPassword(dummyPasswordHash, input.PlainPassword)
+		failLogin(c, db, input, 0, models.LoginUnknownUser)
 		return
 	}
 
 	// Verify password
 	if !utilities.CheckPassword(user.HashedPassword, input.PlainPassword) {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"status": http.StatusUnauthorized,
-			"error":  "wrong password",
+		failLogin(c, db, input, user.Id, models.LoginWrongPassword)
+		return
+	}
+
+	// Users without a totp or a security key cannot log in, e.g. after an admin reset the totp
+	enrolled, err := hasSecondFactor(db, user)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
 		})
 		return
 	}
+	if !enrolled {
+		failLogin(c, db, input, user.Id, models.LoginNoSecondFactor)
+		return
+	}
+
+	// Try to verify totp, a security key or a recovery code if the device is lost can be used instead
+	response := gin.H{
+		"status":  http.StatusOK,
+		"message": "successfully logged in",
+	}
+	if input.RecoveryCode != "" {
+		remaining, ok := useRecoveryCode(db, user.Id, input.RecoveryCode)
+		if !ok {
+			failLogin(c, db, input, user.Id, models.LoginWrongRecoveryCode)
+			return
+		}
+		response["remainingrecoverycodes"] = remaining
+	} else if len(input.WebAuthn) > 0 {
+		if !verifyWebAuthnLogin(db, user, input) {
+			failLogin(c, db, input, user.Id, models.LoginWrongWebAuthn)
+			return
+		}
+	} else if !validateTOTP(input.TOTP, user.TOTP) {
+		failLogin(c, db, input, user.Id, models.LoginWrongOTP)
+		return
+	}
 
-	// Try to verify totp
-	if !totp.Validate(input.TOTP, string(utilities.FromBase64(user.TOTP))) {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"message": "could not verify the otp",
-			"status":  http.StatusUnauthorized,
+	// Only approved users can log in, the credentials are correct so this does not reveal anything
+	if user.Status != models.UserActive {
+		recordLoginAttempt(c, db, input, user.Id, models.LoginInactive)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  "the account has not been approved yet",
 		})
 		return
 	}
 
+	// Replace bcrypt hashes and hashes with old parameters now that the plain password is known
+	if utilities.PasswordNeedsRehash(user.HashedPassword) {
+		if hashedPassword := utilities.HashPassword(input.PlainPassword); hashedPassword != "" {
+			if err := db.Model(&user).Update("hashed_password", hashedPassword).Error; err != nil {
+				log.Println("error rehashing the password: " + err.Error())
+			}
+		}
+	}
+
+	// Forget the failed logins of the account and log the successful one
+	if err := models.ResetLoginFailures(input.Username); err != nil {
+		log.Println("error resetting the failed logins: " + err.Error())
+	}
+	recordLoginAttempt(c, db, input, user.Id, models.LoginSuccess)
+
 	// Generate Session
-	session := createSessionObject(user.Id)
+	session := createSessionObject(c, user.Id)
 
 	// Create Session in Database
 	if err := db.Create(&session).Error; err != nil {
@@ -93,7 +145,7 @@ func LoginHandler(c *gin.Context) {
 	c.SetCookie(
 		"session-token",
 		session.SessionToken,
-		60*60*24, // in seconds (86400) --> one day
+		int(models.SessionMaxLifetime().Seconds()), // the idle expiry is checked by the server
 		"",
 		utilities.GetEnv("AUTH_HOSTNAME", "localhost"),
 		false,
@@ -101,10 +153,7 @@ func LoginHandler(c *gin.Context) {
 	)
 
 	// Return StatusOK
-	c.JSON(http.StatusOK, gin.H{
-		"status":  http.StatusOK,
-		"message": "successfully logged in",
-	})
+	c.JSON(http.StatusOK, response)
 }
 
 // Handle the Login
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+	"github.com/yzaimoglu/election/auth/models"
+	"github.com/yzaimoglu/election/auth/utilities"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// TOTP secret of the test users
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+// Create an active user with the hashed password and the totp of the tests
+func newTestUser(t *testing.T, db *gorm.DB, username string, hashedPassword string) models.User {
+	t.Helper()
+	user := models.User{
+		Username:       username,
+		Email:          username + "@example.com",
+		FirstName:      "Test",
+		LastName:       "Kullanıcı",
+		HashedPassword: hashedPassword,
+		TOTP:           utilities.ToBase64([]byte(testTOTPSecret)),
+		Role:           models.RoleUser,
+		Status:         models.UserActive,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// Create the login body of a test user with the current totp, like the frontend without the fields of the other factors
+func newTestLogin(t *testing.T, user models.User, password string) gin.H {
+	t.Helper()
+	code, err := totp.GenerateCode(testTOTPSecret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gin.H{"username": user.Username, "email": user.Email, "plainpassword": password, "totp": code}
+}
+
+// Use cheap argon2 parameters, the defaults make the tests slow
+func setTestArgon2Params(t *testing.T, memory string) {
+	t.Helper()
+	t.Setenv("AUTH_ARGON2_MEMORY_KIB", memory)
+	t.Setenv("AUTH_ARGON2_ITERATIONS", "1")
+	t.Setenv("AUTH_ARGON2_PARALLELISM", "1")
+}
+
+func TestLoginRehashesThePassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDatabase(t)
+	newTestCache(t)
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("doğru at pili zımba"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setTestArgon2Params(t, "1024")
+	oldHash := utilities.HashPassword("doğru at pili zımba")
+	setTestArgon2Params(t, "2048")
+	currentHash := utilities.HashPassword("doğru at pili zımba")
+
+	tests := []struct {
+		name           string
+		hashedPassword string
+		password       string
+		status         int
+		rehashed       bool
+	}{
+		{"bcrypt hash", string(bcryptHash), "doğru at pili zımba", http.StatusOK, true},
+		{"argon2id hash with old parameters", oldHash, "doğru at pili zımba", http.StatusOK, true},
+		{"argon2id hash with the current parameters", currentHash, "doğru at pili zımba", http.StatusOK, false},
+		{"wrong password", string(bcryptHash), "yanlış şifre", http.StatusUnauthorized, false},
+	}
+	for index, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := newTestUser(t, db, "user"+string(rune('a'+index)), test.hashedPassword)
+			if status, response := performJSONRequest(t, LoginHandler, db, nil, newTestLogin(t, user, test.password)); status != test.status {
+				t.Fatalf("got the status %d with %s, want %d", status, response["error"], test.status)
+			}
+
+			var stored models.User
+			db.First(&stored, user.Id)
+			if rehashed := stored.HashedPassword != test.hashedPassword; rehashed != test.rehashed {
+				t.Fatalf("got the hash %q, want rehashed %t", stored.HashedPassword, test.rehashed)
+			}
+			if test.rehashed && (!strings.HasPrefix(stored.HashedPassword, "$argon2id$v=19$m=2048,t=1,p=1$") || !utilities.CheckPassword(stored.HashedPassword, test.password)) {
+				t.Fatalf("got the hash %q, want an argon2id hash of the password with the current parameters", stored.HashedPassword)
+			}
+		})
+	}
+}
+
+func TestLoginIsLockedAfterTooManyFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDatabase(t)
+	cache := newTestCache(t)
+	t.Setenv("AUTH_LOGIN_MAX_FAILURES", "3")
+	t.Setenv("AUTH_LOGIN_LOCKOUT_SECONDS", "600")
+	setTestArgon2Params(t, "1024")
+	user := newTestUser(t, db, "ahmet.yilmaz", utilities.HashPassword("doğru at pili zımba"))
+
+	// Each failure delays the next login, the correct password is rejected during the delay
+	for failure := 1; failure <= 3; failure++ {
+		if status, _ := performJSONRequest(t, LoginHandler, db, nil, newTestLogin(t, user, "yanlış şifre")); status != http.StatusUnauthorized {
+			t.Fatalf("failure %d: got the status %d, want %d", failure, status, http.StatusUnauthorized)
+		}
+		status, response := performJSONRequest(t, LoginHandler, db, nil, newTestLogin(t, user, "doğru at pili zımba"))
+		if status != http.StatusTooManyRequests {
+			t.Fatalf("login after failure %d: got the status %d, want %d", failure, status, http.StatusTooManyRequests)
+		}
+		var seconds int64
+		decodeField(t, response, "retryafter", &seconds)
+		if want := []int64{1, 2, 600}[failure-1]; seconds != want {
+			t.Fatalf("login after failure %d: got the retry after %d, want %d", failure, seconds, want)
+		}
+		cache.FastForward(time.Duration(seconds) * time.Second)
+	}
+
+	// The login works again after the lockout and the locked attempts are logged
+	if status, response := performJSONRequest(t, LoginHandler, db, nil, newTestLogin(t, user, "doğru at pili zımba")); status != http.StatusOK {
+		t.Fatalf("login after the lockout: got the status %d with %s", status, response["error"])
+	}
+	var locked int64
+	db.Model(&models.LoginAttempt{}).Where("reason = ?", models.LoginLocked).Count(&locked)
+	if locked != 3 {
+		t.Fatalf("got %d logged locked attempts, want 3", locked)
+	}
+}
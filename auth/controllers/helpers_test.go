@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/yzaimoglu/election/auth/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Create an in memory database with the tables of the server
+func newTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection would get its own database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.User{}, &models.Session{}, &models.TOTPVerification{}, &models.PasswordReset{},
+		&models.RecoveryCode{}, &models.LoginAttempt{}, &models.SigningKey{}, &models.ServiceClient{}, &models.Invite{},
+		&models.WebAuthnCredential{}, &models.WebAuthnCeremony{}, &models.OIDCClient{}, &models.AuthorizationCode{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// Start an in memory redis for the login limits
+func newTestCache(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	cache := miniredis.RunT(t)
+	t.Setenv("AUTH_CACHE_HOST", cache.Host())
+	t.Setenv("AUTH_CACHE_PORT", cache.Port())
+	models.SetupCache()
+	return cache
+}
+
+// Call a handler with a json body as the given user and decode the json response
+func performJSONRequest(t *testing.T, handler gin.HandlerFunc, db *gorm.DB, user *models.User, body interface{}) (int, map[string]json.RawMessage) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("db", db)
+	if user != nil {
+		c.Set("user", *user)
+	}
+	handler(c)
+
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not decode the response %q: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, response
+}
+
+// Decode a field of a json response
+func decodeField(t *testing.T, response map[string]json.RawMessage, field string, value interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(response[field], value); err != nil {
+		t.Fatalf("could not decode %s: %v", field, err)
+	}
+}
@@ -0,0 +1,534 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yzaimoglu/election/auth/models"
+	"github.com/yzaimoglu/election/auth/utilities"
+	"gorm.io/gorm"
+)
+
+// Returned if an invite cannot be accepted
+var errInvalidInvite = errors.New("the invite is invalid or has expired")
+
+// Get all invites, the newest first
+func GetInvites(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Find the invites
+	var invites []models.Invite
+	if err := db.Order("created_at DESC").Find(&invites).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the invites
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"invites": invites,
+	})
+}
+
+// Invite a user by email, the role, affiliation and region of the user are set by the admin
+func CreateInvite(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Initialize the create invite input
+	var input models.CreateInviteInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Check if there is a user with the specified email
+	if EmailExists(c, input.Email) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "email already exists",
+		})
+		return
+	}
+
+	// Create the invite and send the link
+	admin := c.MustGet("user").(models.User)
+	invite := models.Invite{
+		Email:       input.Email,
+		Role:        input.Role,
+		Affiliation: input.Affiliation,
+		Region:      input.Region,
+		CreatedBy:   admin.Id,
+	}
+	token, err := createInvite(db, &invite)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	inviteMail := newMail(invite.Email, "election/auth Tracker Invitation", frontendURL("/invite/"+token))
+	if err := sendMail(inviteMail); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the invite
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  http.StatusCreated,
+		"message": "the invite has been sent",
+		"invite":  invite,
+	})
+}
+
+// Revoke an invite which has not been accepted yet
+func DeleteInvite(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	id := c.Param("id")
+
+	// Delete the invite if it has not been accepted
+	result := db.Where("id = ? AND accepted_at = 0", id).Delete(&models.Invite{})
+	if result.Error != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  "there is no such open invite",
+		})
+		return
+	}
+
+	// Return Success JSON
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "the invite has been revoked",
+	})
+}
+
+// Get the invite of a token, so that the registration page can show the email, role and region
+func GetInviteByToken(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Find the open invite of the token
+	invite, err := findOpenInvite(db, c.Param("token"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the invite without the information of the admin
+	c.JSON(http.StatusOK, gin.H{
+		"email":       invite.Email,
+		"role":        invite.Role,
+		"affiliation": invite.Affiliation,
+		"region":      invite.Region,
+		"expiresat":   invite.ExpiresAt,
+	})
+}
+
+// Accept an invite by setting the name and password, the user enrols the totp with the returned verification code
+// and stays inactive until an admin approves the account
+func AcceptInvite(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Initialize the accept invite input
+	var input models.AcceptInviteInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Find the open invite of the token
+	invite, err := findOpenInvite(db, input.Token)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Check if a user has registered with the email in the meantime
+	if EmailExists(c, invite.Email) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "email already exists",
+		})
+		return
+	}
+
+	// Check the password against the password policy
+	if err := utilities.CheckPasswordPolicy(input.PlainPassword, invite.Email, input.FirstName, input.LastName); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Create the new user model, the totp is set once the enrolment is verified and automatically approved users are
+	// activated then as well
+	now := utilities.GetCurrentTime()
+	user := models.User{
+		Username:       uniqueUsername(c, input.FirstName, input.LastName),
+		FirstName:      input.FirstName,
+		LastName:       input.LastName,
+		Email:          invite.Email,
+		HashedPassword: utilities.HashPassword(input.PlainPassword),
+		CreatedAt:      now,
+		LastSeen:       -1,
+		Role:           invite.Role,
+		Affiliation:    invite.Affiliation,
+		Region:         invite.Region,
+		Status:         models.UserPending,
+		InvitedBy:      invite.CreatedBy,
+	}
+
+	// Create the totp verification object
+	totpVerification, err := newTOTPVerification(user.Username, user.Email)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Mark the invite as accepted and create the user, a second request with the same token does not match anymore
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Invite{}).Where("id = ? AND accepted_at = 0", invite.Id).Update("accepted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errInvalidInvite
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Invite{}).Where("id = ?", invite.Id).Update("user_id", user.Id).Error; err != nil {
+			return err
+		}
+		return tx.Create(&totpVerification).Error
+	})
+	if err == errInvalidInvite {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	} else if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the user and the code of the totp enrolment
+	message := "the account has been created, enrol the totp and wait for the approval of an admin"
+	if invite.AutoApprove {
+		message = "the account has been created, it is activated once the totp is enrolled"
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":           http.StatusCreated,
+		"message":          message,
+		"username":         user.Username,
+		"userstatus":       user.Status,
+		"totpverification": totpVerification.Code,
+	})
+}
+
+// Get the users who accepted an invite and wait for the approval of an admin
+func GetPendingUsers(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Find the pending users
+	var users []models.User
+	if err := db.Where("status = ?", models.UserPending).Order("created_at").Find(&users).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Users without sensitive information, with whether they have enrolled the totp
+	pending := make([]gin.H, 0, len(users))
+	for _, user := range users {
+		pending = append(pending, gin.H{
+			"id":           user.Id,
+			"username":     user.Username,
+			"firstname":    user.FirstName,
+			"lastname":     user.LastName,
+			"email":        user.Email,
+			"createdat":    user.CreatedAt,
+			"role":         user.Role,
+			"affiliation":  user.Affiliation,
+			"region":       user.Region,
+			"invitedby":    user.InvitedBy,
+			"totpenrolled": user.TOTP != "",
+		})
+	}
+
+	// Return the pending users
+	c.JSON(http.StatusOK, gin.H{
+		"status": http.StatusOK,
+		"users":  pending,
+	})
+}
+
+// Approve a pending user, the user can log in afterwards
+func ApproveUser(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	id := c.Param("id")
+
+	// Find the pending user and return 404 when not found
+	var user models.User
+	if err := db.Where("id = ? AND status = ?", id, models.UserPending).First(&user).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  "there is no such pending user",
+		})
+		return
+	}
+
+	// Only users with a totp or a security key can be approved, otherwise they could log in with the password only
+	enrolled, err := hasSecondFactor(db, user)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	if !enrolled {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"status": http.StatusConflict,
+			"error":  "the user has not enrolled the totp yet",
+		})
+		return
+	}
+
+	// Activate the user
+	admin := c.MustGet("user").(models.User)
+	if err := db.Model(&user).Updates(map[string]interface{}{
+		"status":      models.UserActive,
+		"approved_by": admin.Id,
+		"approved_at": utilities.GetCurrentTime(),
+	}).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Tell the user in the background
+	approvalMail := newMail(user.Email, "election/auth Tracker Account Approved", frontendURL("/login/"))
+	go func() {
+		if err := sendMail(approvalMail); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	// Return Success JSON
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "the user has been approved",
+		"userid":  user.Id,
+	})
+}
+
+// Reject a pending user, the account and its totp enrolment are deleted
+func RejectUser(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	id := c.Param("id")
+
+	// Find the pending user and return 404 when not found
+	var user models.User
+	if err := db.Where("id = ? AND status = ?", id, models.UserPending).First(&user).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  "there is no such pending user",
+		})
+		return
+	}
+
+	// Delete the user with its totp enrolment and recovery codes
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("username = ?", user.Username).Delete(&models.TOTPVerification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.Id).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return Success JSON
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "the user has been rejected",
+		"userid":  user.Id,
+	})
+}
+
+// Invite the first admin if there is no admin yet, the link is written to the log
+//
+// The email is set with AUTH_ADMIN_EMAIL, nothing is done if it is not set.
+func BootstrapAdminInvite(db *gorm.DB) {
+	email := utilities.GetEnv("AUTH_ADMIN_EMAIL", "")
+	if email == "" {
+		return
+	}
+	var admins int64
+	if err := db.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins).Error; err != nil {
+		log.Println("error counting the admins: " + err.Error())
+		return
+	}
+	if admins > 0 {
+		return
+	}
+
+	invite := models.Invite{
+		Email:       email,
+		Role:        models.RoleAdmin,
+		Affiliation: utilities.GetEnv("AUTH_ADMIN_AFFILIATION", "Yönetim"),
+		AutoApprove: true,
+	}
+	token, err := createInvite(db, &invite)
+	if err != nil {
+		log.Println("error inviting the first admin: " + err.Error())
+		return
+	}
+	log.Println("there is no admin yet, register the first one with " + frontendURL("/invite/"+token))
+}
+
+// Activate a pending user whose invite is approved automatically, called once the totp has been enrolled
+func activateAutoApprovedUser(tx *gorm.DB, user models.User) error {
+	if user.Status != models.UserPending {
+		return nil
+	}
+	var invites int64
+	if err := tx.Model(&models.Invite{}).Where("user_id = ? AND auto_approve = ?", user.Id, true).Count(&invites).Error; err != nil {
+		return err
+	}
+	if invites == 0 {
+		return nil
+	}
+	return tx.Model(&user).Updates(map[string]interface{}{
+		"status":      models.UserActive,
+		"approved_at": utilities.GetCurrentTime(),
+	}).Error
+}
+
+// Create an invite with a new token, the earlier open invites of the email are replaced
+func createInvite(db *gorm.DB, invite *models.Invite) (string, error) {
+	tokenBytes, err := utilities.GenerateRandomBytes(32)
+	if err != nil {
+		return "", err
+	}
+	token := utilities.ToBase64(tokenBytes)
+	invite.HashedToken = utilities.HashSHA512(token)
+	invite.CreatedAt = utilities.GetCurrentTime()
+	invite.ExpiresAt = invite.CreatedAt + models.InviteLifetime().Milliseconds()
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("email = ? AND accepted_at = 0", invite.Email).Delete(&models.Invite{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(invite).Error
+	})
+	return token, err
+}
+
+// Find the invite of a token which has neither been accepted nor expired
+func findOpenInvite(db *gorm.DB, token string) (models.Invite, error) {
+	var invite models.Invite
+	if err := db.Where("hashed_token = ? AND accepted_at = 0", utilities.HashSHA512(token)).First(&invite).Error; err != nil {
+		return invite, errInvalidInvite
+	}
+	if utilities.GetCurrentTime() >= invite.ExpiresAt {
+		return invite, errInvalidInvite
+	}
+	return invite, nil
+}
+
+// Create a username without turkish characters from the names, a number is appended if it already exists
+func uniqueUsername(c *gin.Context, firstName string, lastName string) string {
+	username := utilities.Slug(firstName) + "." + utilities.Slug(lastName)
+
+	i := 1
+	usernameWithoutNumbers := username
+
+	// Check if username already exists, if it does append append an integer
+	for UsernameExists(c, username) {
+		username = usernameWithoutNumbers + fmt.Sprint(i)
+		i++
+	}
+	return username
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	mail "github.com/xhit/go-simple-mail/v2"
+	"github.com/yzaimoglu/election/auth/models"
+	"github.com/yzaimoglu/election/auth/utilities"
+)
+
+// Create a mail from the authentication address with the smtp settings of the environment
+func newMail(to string, subject string, body string) models.Mail {
+	return models.Mail{
+		From:    "election/auth Tracker - Authentication <user@user>",
+		To:      to,
+		Subject: subject,
+		Body:    body,
+		Credentials: models.MailCredentials{
+			Username: utilities.GetEnv("SMTP_USER", "user@user"),
+			Password: utilities.GetEnv("SMTP_PASSWORD", "password"),
+		},
+		Server: models.MailServer{
+			Host:       utilities.GetEnv("SMTP_HOST", "smtp_host"),
+			Port:       587,
+			Encryption: mail.EncryptionSTARTTLS,
+		},
+	}
+}
+
+// Send a mail over the smtp server of the mail
+func sendMail(createMail models.Mail) error {
+	// Specify mailserver options
+	mailServer := mail.NewSMTPClient()
+	mailServer.Host = createMail.Server.Host
+	mailServer.Port = createMail.Server.Port
+	mailServer.Username = createMail.Credentials.Username
+	mailServer.Password = createMail.Credentials.Password
+	mailServer.Encryption = createMail.Server.Encryption
+
+	// Connect to mailserver
+	smtpClient, err := mailServer.Connect()
+	if err != nil {
+		return err
+	}
+
+	// Set email info
+	email := mail.NewMSG()
+	email.SetFrom(createMail.From)
+	email.AddTo(createMail.To)
+	email.SetSubject(createMail.Subject)
+	email.SetBody(mail.TextHTML, createMail.Body)
+
+	// Send mail
+	return email.Send(smtpClient)
+}
+
+// Get the url of a page of the frontend, set with AUTH_FRONTEND_URL
+func frontendURL(path string) string {
+	return utilities.GetEnv("AUTH_FRONTEND_URL", "https://localhost") + path
+}
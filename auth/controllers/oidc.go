@@ -0,0 +1,468 @@
+package controllers
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yzaimoglu/election/auth/models"
+	"github.com/yzaimoglu/election/auth/tokens"
+	"github.com/yzaimoglu/election/auth/utilities"
+	"gorm.io/gorm"
+)
+
+// Scopes of the OpenID Connect clients, the role and affiliation of the user are always part of the claims
+var oidcScopes = []string{"openid", "profile", "email"}
+
+// Return the OpenID Connect discovery document
+//
+//	GET /v1/oidc/.well-known/openid-configuration
+func GetOIDCConfiguration(c *gin.Context) {
+	issuer := models.OIDCIssuer()
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, gin.H{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{tokens.AlgorithmRSA},
+		"scopes_supported":                      oidcScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported": []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "azp", "preferred_username",
+			"name", "given_name", "family_name", "email", "role", "affiliation", "region"},
+	})
+}
+
+// Start the login of an OpenID Connect client, the user is sent to the login page of the frontend if there is no
+// session and back to the client with an authorization code afterwards
+//
+//	GET /v1/oidc/authorize?response_type=code&client_id=...&redirect_uri=...&scope=openid%20profile&state=...&code_challenge=...&code_challenge_method=S256
+//
+// The clients are internal tools, so there is no consent page. PKCE with S256 is required for every client.
+func Authorize(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	redirectURI := c.Query("redirect_uri")
+	state := c.Query("state")
+
+	// Errors with the client or the redirect uri are not sent to the redirect uri
+	var client models.OIDCClient
+	if err := db.Where("client_id = ?", c.Query("client_id")).First(&client).Error; err != nil || !client.AllowsRedirect(redirectURI) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the client or its redirect uri is unknown",
+		})
+		return
+	}
+
+	// Check the request
+	if c.Query("response_type") != "code" {
+		redirectOIDCError(c, redirectURI, state, "unsupported_response_type", "only the authorization code flow is supported")
+		return
+	}
+	scope := grantedOIDCScope(c.Query("scope"))
+	if !strings.Contains(" "+scope+" ", " openid ") {
+		redirectOIDCError(c, redirectURI, state, "invalid_scope", "the openid scope is required")
+		return
+	}
+	codeChallenge := c.Query("code_challenge")
+	if c.Query("code_challenge_method") != "S256" || len(codeChallenge) != 43 {
+		redirectOIDCError(c, redirectURI, state, "invalid_request", "pkce with the S256 method is required")
+		return
+	}
+
+	// Send the user to the login page if there is no session, the page returns to this url afterwards
+	sessionToken, err := c.Cookie("session-token")
+	if err != nil {
+		sessionToken = c.GetHeader("Authentication-Session-Token")
+	}
+	var user models.User
+	session, err := models.FindActiveSession(db, sessionToken)
+	if err == nil {
+		err = db.Where("id = ? AND status = ?", session.UserId, models.UserActive).First(&user).Error
+	}
+	if err != nil {
+		if c.Query("prompt") == "none" {
+			redirectOIDCError(c, redirectURI, state, "login_required", "the user is not logged in")
+			return
+		}
+		loginURL := utilities.GetEnv("AUTH_OIDC_LOGIN_URL", frontendURL("/login/"))
+		next := models.OIDCIssuer() + "/authorize?" + c.Request.URL.RawQuery
+		c.Redirect(http.StatusFound, loginURL+"?next="+url.QueryEscape(next))
+		return
+	}
+
+	// Create the authorization code, only its hash is stored and the expired codes are deleted on the way
+	if err := db.Where("expires_at <= ?", utilities.GetCurrentTime()).Delete(&models.AuthorizationCode{}).Error; err != nil {
+		log.Println("error deleting the expired authorization codes: " + err.Error())
+	}
+	codeBytes, err := utilities.GenerateRandomBytes(32)
+	if err != nil {
+		redirectOIDCError(c, redirectURI, state, "server_error", "the authorization code could not be created")
+		return
+	}
+	code := utilities.ToBase64(codeBytes)
+	authorizationCode := models.AuthorizationCode{
+		HashedCode:    utilities.HashSHA512(code),
+		ClientId:      client.ClientId,
+		UserId:        user.Id,
+		RedirectURI:   redirectURI,
+		Scope:         scope,
+		Nonce:         c.Query("nonce"),
+		CodeChallenge: codeChallenge,
+		AuthTime:      session.CreatedAt / 1000,
+		CreatedAt:     utilities.GetCurrentTime(),
+	}
+	authorizationCode.ExpiresAt = authorizationCode.CreatedAt + models.AuthorizationCodeLifetime.Milliseconds()
+	if err := db.Create(&authorizationCode).Error; err != nil {
+		redirectOIDCError(c, redirectURI, state, "server_error", "the authorization code could not be created")
+		return
+	}
+
+	// Send the user back to the client with the code
+	query := url.Values{}
+	query.Set("code", code)
+	if state != "" {
+		query.Set("state", state)
+	}
+	c.Redirect(http.StatusFound, appendQuery(redirectURI, query))
+}
+
+// Exchange an authorization code for an access token and an ID token
+//
+//	POST /v1/oidc/token grant_type=authorization_code&code=...&redirect_uri=...&code_verifier=...
+//
+// The errors follow the OAuth 2.0 format, so that the clients can read them.
+func OIDCToken(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	c.Header("Cache-Control", "no-store")
+
+	// Input required to exchange a code
+	var input models.OIDCTokenInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBind(&input); err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if clientId, clientSecret, ok := c.Request.BasicAuth(); ok {
+		input.ClientId, input.ClientSecret = clientId, clientSecret
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	// Authenticate the client, public clients are only authenticated by pkce
+	var client models.OIDCClient
+	if err := db.Where("client_id = ?", input.ClientId).First(&client).Error; err != nil || input.ClientId == "" {
+		utilities.CheckPassword(dummyPasswordHash, input.ClientSecret)
+		oauthError(c, http.StatusUnauthorized, "invalid_client", "invalid client credentials")
+		return
+	}
+	if !client.Public && !utilities.CheckPassword(client.HashedSecret, input.ClientSecret) {
+		oauthError(c, http.StatusUnauthorized, "invalid_client", "invalid client credentials")
+		return
+	}
+
+	// Find the code of the client and mark it as used, a second request with the same code does not match anymore
+	var code models.AuthorizationCode
+	err := db.Where("hashed_code = ? AND client_id = ? AND used_at = 0", utilities.HashSHA512(input.Code), client.ClientId).First(&code).Error
+	if err != nil || utilities.GetCurrentTime() >= code.ExpiresAt || code.RedirectURI != input.RedirectURI {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "the authorization code is invalid or has expired")
+		return
+	}
+	result := db.Model(&models.AuthorizationCode{}).Where("id = ? AND used_at = 0", code.Id).Update("used_at", utilities.GetCurrentTime())
+	if result.Error != nil || result.RowsAffected != 1 {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "the authorization code is invalid or has expired")
+		return
+	}
+
+	// Check the code verifier against the challenge of the authorization
+	verifierHash := sha256.Sum256([]byte(input.CodeVerifier))
+	if subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(verifierHash[:])), []byte(code.CodeChallenge)) != 1 {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "the code verifier does not match the code challenge")
+		return
+	}
+
+	// Find the user, users who are not active anymore get no tokens
+	var user models.User
+	if err := db.Where("id = ? AND status = ?", code.UserId, models.UserActive).First(&user).Error; err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "the user cannot log in")
+		return
+	}
+
+	// Sign the access token for the userinfo endpoint, its audience is the issuer so that the other services do not accept it
+	accessToken, accessClaims, err := models.IssueToken(db, tokens.Claims{
+		Audience:    models.OIDCIssuer(),
+		Subject:     strconv.FormatInt(user.Id, 10),
+		Scope:       code.Scope,
+		ClientId:    client.ClientId,
+		Role:        user.Role,
+		Affiliation: user.Affiliation,
+	})
+	if err != nil {
+		oauthError(c, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	// Sign the ID token
+	now := time.Now()
+	idToken, err := models.SignWithCurrentKey(db, tokens.AlgorithmRSA, models.IDTokenClaims{
+		Issuer:          models.OIDCIssuer(),
+		Audience:        client.ClientId,
+		ExpiresAt:       now.Add(models.TokenLifetime()).Unix(),
+		IssuedAt:        now.Unix(),
+		AuthTime:        code.AuthTime,
+		Nonce:           code.Nonce,
+		AuthorizedParty: client.ClientId,
+		UserInfo:        models.NewUserInfo(user, code.Scope),
+	})
+	if err != nil {
+		oauthError(c, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	// Return the tokens
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   accessClaims.ExpiresAt - accessClaims.IssuedAt,
+		"id_token":     idToken,
+		"scope":        code.Scope,
+	})
+}
+
+// Return the claims of the user of an access token from the OpenID Connect token endpoint
+//
+//	GET /v1/oidc/userinfo with Authorization: Bearer <access token>
+func GetUserInfo(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Verify the access token with the keys of the database
+	claims, err := tokens.Verify(tokens.BearerToken(c.GetHeader("Authorization")), models.TokenIssuer(), models.OIDCIssuer(), func(keyId string) ed25519.PublicKey {
+		var key models.SigningKey
+		if err := db.Where("key_id = ?", keyId).First(&key).Error; err != nil {
+			return nil
+		}
+		return key.Ed25519PublicKey()
+	})
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		oauthError(c, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+	if !claims.HasScope("openid") {
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+		oauthError(c, http.StatusForbidden, "insufficient_scope", "the access token was not issued for openid")
+		return
+	}
+
+	// Find the user of the token
+	var user models.User
+	if err := db.Where("id = ? AND status = ?", claims.Subject, models.UserActive).First(&user).Error; err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		oauthError(c, http.StatusUnauthorized, "invalid_token", "the user of the token cannot log in")
+		return
+	}
+
+	// Return the claims of the granted scopes
+	c.JSON(http.StatusOK, models.NewUserInfo(user, claims.Scope))
+}
+
+// Get all OpenID Connect clients
+func GetOIDCClients(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Find the clients
+	var clients []models.OIDCClient
+	if err := db.Order("created_at DESC").Find(&clients).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the clients
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"clients": clients,
+	})
+}
+
+// Create an OpenID Connect client, the secret of confidential clients is only returned once
+func CreateOIDCClient(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Input required to create a client
+	var input models.CreateOIDCClientInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Generate the id and the secret of the client
+	idBytes, err := utilities.GenerateRandomBytes(8)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	client := models.OIDCClient{
+		ClientId:     hex.EncodeToString(idBytes),
+		Name:         input.Name,
+		RedirectURIs: strings.Join(input.RedirectURIs, " "),
+		Public:       input.Public,
+		CreatedAt:    utilities.GetCurrentTime(),
+	}
+	response := gin.H{
+		"status":  http.StatusCreated,
+		"message": "the client has been created",
+	}
+	if !client.Public {
+		secretBytes, err := utilities.GenerateRandomBytes(32)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  err.Error(),
+			})
+			return
+		}
+		secret := utilities.ToBase64(secretBytes)
+		client.HashedSecret = utilities.HashPassword(secret)
+		response["message"] = "the client has been created, the secret is not shown again"
+		response["clientsecret"] = secret
+	}
+
+	// Create the client
+	if err := db.Create(&client).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the client
+	response["client"] = client
+	c.JSON(http.StatusCreated, response)
+}
+
+// Delete an OpenID Connect client, its open authorization codes are deleted with it
+func DeleteOIDCClient(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	id := c.Param("id")
+
+	// Find the client by its id or client id
+	var client models.OIDCClient
+	if err := db.Where("id = ? OR client_id = ?", id, id).First(&client).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  "there is no such client",
+		})
+		return
+	}
+
+	// Delete the client and its codes
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("client_id = ?", client.ClientId).Delete(&models.AuthorizationCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&client).Error
+	})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return Success JSON
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "the client has been deleted",
+	})
+}
+
+// Keep the supported scopes of a requested scope
+func grantedOIDCScope(requested string) string {
+	granted := []string{}
+	for _, scope := range strings.Fields(requested) {
+		for _, supported := range oidcScopes {
+			if scope == supported {
+				granted = append(granted, scope)
+				break
+			}
+		}
+	}
+	return strings.Join(granted, " ")
+}
+
+// Send the user back to the client with an error
+func redirectOIDCError(c *gin.Context, redirectURI string, state string, code string, description string) {
+	query := url.Values{}
+	query.Set("error", code)
+	query.Set("error_description", description)
+	if state != "" {
+		query.Set("state", state)
+	}
+	c.Redirect(http.StatusFound, appendQuery(redirectURI, query))
+}
+
+// Abort the request with an error in the OAuth 2.0 format
+func oauthError(c *gin.Context, status int, code string, description string) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+// Append query parameters to a url which may already have some
+func appendQuery(rawURL string, query url.Values) string {
+	if strings.Contains(rawURL, "?") {
+		return rawURL + "&" + query.Encode()
+	}
+	return rawURL + "?" + query.Encode()
+}
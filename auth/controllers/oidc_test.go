@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/auth/models"
+	"github.com/yzaimoglu/election/auth/utilities"
+	"gorm.io/gorm"
+)
+
+// Client and redirect uri of the tests
+const (
+	testOIDCClientId    = "wiki"
+	testOIDCRedirectURI = "https://wiki.localhost/callback"
+)
+
+// Call a handler with the session of the user and return the response
+func performOIDCRequest(handler gin.HandlerFunc, db *gorm.DB, request *http.Request, sessionToken string) *httptest.ResponseRecorder {
+	if sessionToken != "" {
+		request.AddCookie(&http.Cookie{Name: "session-token", Value: sessionToken})
+	}
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = request
+	c.Set("db", db)
+	handler(c)
+	return recorder
+}
+
+// Create a code verifier and its S256 challenge
+func newCodeVerifier(t *testing.T) (string, string) {
+	t.Helper()
+	verifierBytes, err := utilities.GenerateRandomBytes(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := base64.RawURLEncoding.EncodeToString(verifierBytes)
+	challenge := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(challenge[:])
+}
+
+func TestOIDCAuthorizationCodeWithPKCE(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("AUTH_OIDC_ISSUER", "https://localhost/v1/oidc")
+	db := newTestDatabase(t)
+
+	user := models.User{Username: "ayse", Email: "ayse@example.com", FirstName: "Ayşe", LastName: "Demir", Role: models.RoleUser, Status: models.UserActive}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := utilities.GetCurrentTime()
+	session := models.Session{UserId: user.Id, SessionToken: "session-1", CreatedAt: now, ExpiresAt: now + 60000, MaxExpiresAt: now + 60000}
+	if err := db.Create(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+	client := models.OIDCClient{ClientId: testOIDCClientId, Name: "Wiki", RedirectURIs: testOIDCRedirectURI, Public: true}
+	if err := db.Create(&client).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Get an authorization code for the challenge, the errors are sent to the redirect uri
+	authorize := func(t *testing.T, change func(query url.Values)) *httptest.ResponseRecorder {
+		t.Helper()
+		_, challenge := newCodeVerifier(t)
+		query := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {testOIDCClientId},
+			"redirect_uri":          {testOIDCRedirectURI},
+			"scope":                 {"openid profile"},
+			"state":                 {"state-1"},
+			"nonce":                 {"nonce-1"},
+			"code_challenge":        {challenge},
+			"code_challenge_method": {"S256"},
+		}
+		if change != nil {
+			change(query)
+		}
+		return performOIDCRequest(Authorize, db, httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil), session.SessionToken)
+	}
+	newCode := func(t *testing.T) (string, string) {
+		t.Helper()
+		verifier, challenge := newCodeVerifier(t)
+		recorder := authorize(t, func(query url.Values) { query.Set("code_challenge", challenge) })
+		location, err := url.Parse(recorder.Header().Get("Location"))
+		if recorder.Code != http.StatusFound || err != nil || location.Query().Get("code") == "" {
+			t.Fatalf("authorize: got the status %d and the location %q", recorder.Code, recorder.Header().Get("Location"))
+		}
+		if location.Query().Get("state") != "state-1" {
+			t.Fatalf("got the state %q, want state-1", location.Query().Get("state"))
+		}
+		return location.Query().Get("code"), verifier
+	}
+	exchange := func(t *testing.T, form url.Values) (int, map[string]string) {
+		t.Helper()
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		recorder := performOIDCRequest(OIDCToken, db, request, "")
+		var response map[string]string
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response
+	}
+	tokenForm := func(code string, verifier string) url.Values {
+		return url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {testOIDCRedirectURI},
+			"code_verifier": {verifier},
+			"client_id":     {testOIDCClientId},
+		}
+	}
+
+	t.Run("valid exchange", func(t *testing.T) {
+		code, verifier := newCode(t)
+		status, response := exchange(t, tokenForm(code, verifier))
+		if status != http.StatusOK || response["access_token"] == "" || response["id_token"] == "" {
+			t.Fatalf("got the status %d with %v", status, response)
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(strings.Split(response["id_token"], ".")[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var claims models.IDTokenClaims
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			t.Fatal(err)
+		}
+		if claims.Audience != testOIDCClientId || claims.Nonce != "nonce-1" || claims.PreferredUsername != "ayse" || claims.Email != "" {
+			t.Fatalf("got the id token claims %+v", claims)
+		}
+	})
+
+	t.Run("reused code", func(t *testing.T) {
+		code, verifier := newCode(t)
+		if status, _ := exchange(t, tokenForm(code, verifier)); status != http.StatusOK {
+			t.Fatalf("first exchange: got the status %d", status)
+		}
+		if status, response := exchange(t, tokenForm(code, verifier)); status != http.StatusBadRequest || response["error"] != "invalid_grant" {
+			t.Fatalf("second exchange: got the status %d with %v", status, response)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T, code string, form url.Values)
+	}{
+		{"wrong code verifier", func(t *testing.T, code string, form url.Values) {
+			verifier, _ := newCodeVerifier(t)
+			form.Set("code_verifier", verifier)
+		}},
+		{"mismatched redirect uri", func(t *testing.T, code string, form url.Values) {
+			form.Set("redirect_uri", "https://wiki.localhost/other")
+		}},
+		{"expired code", func(t *testing.T, code string, form url.Values) {
+			db.Model(&models.AuthorizationCode{}).Where("hashed_code = ?", utilities.HashSHA512(code)).Update("expires_at", utilities.GetCurrentTime()-1)
+		}},
+		{"code of another client", func(t *testing.T, code string, form url.Values) {
+			other := models.OIDCClient{ClientId: "grafana-" + t.Name(), Name: "Grafana", RedirectURIs: testOIDCRedirectURI, Public: true}
+			if err := db.Create(&other).Error; err != nil {
+				t.Fatal(err)
+			}
+			form.Set("client_id", other.ClientId)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, verifier := newCode(t)
+			form := tokenForm(code, verifier)
+			test.change(t, code, form)
+			if status, response := exchange(t, form); status != http.StatusBadRequest || response["error"] != "invalid_grant" {
+				t.Fatalf("got the status %d with %v, want invalid_grant", status, response)
+			}
+		})
+	}
+
+	t.Run("plain code challenge", func(t *testing.T) {
+		recorder := authorize(t, func(query url.Values) { query.Set("code_challenge_method", "plain") })
+		location, _ := url.Parse(recorder.Header().Get("Location"))
+		if recorder.Code != http.StatusFound || location.Query().Get("error") != "invalid_request" || location.Query().Get("code") != "" {
+			t.Fatalf("got the status %d and the location %q", recorder.Code, recorder.Header().Get("Location"))
+		}
+	})
+
+	t.Run("unregistered redirect uri", func(t *testing.T) {
+		recorder := authorize(t, func(query url.Values) { query.Set("redirect_uri", "https://attacker.example/callback") })
+		if recorder.Code != http.StatusBadRequest || recorder.Header().Get("Location") != "" {
+			t.Fatalf("got the status %d and the location %q", recorder.Code, recorder.Header().Get("Location"))
+		}
+	})
+}
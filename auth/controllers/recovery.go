@@ -0,0 +1,386 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yzaimoglu/election/auth/models"
+	"github.com/yzaimoglu/election/auth/utilities"
+	"gorm.io/gorm"
+)
+
+// Number of recovery codes created at the totp enrolment
+const recoveryCodeCount = 10
+
+// Send a password reset link to the email, the response is the same whether a user has the email or not
+func ForgotPassword(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Initialize the forgot password input
+	var input models.ForgotPasswordInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Same response for known and unknown emails so that the emails of the users cannot be guessed
+	response := gin.H{
+		"status":  http.StatusOK,
+		"message": "if there is a user with this email a password reset link has been sent",
+	}
+
+	// Find the User and return when not found
+	var user models.User
+	if err := db.Where("email = ?", input.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// Create the reset token, only its hash is stored
+	tokenBytes, err := utilities.GenerateRandomBytes(32)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	token := utilities.ToBase64(tokenBytes)
+	minutes, err := strconv.ParseInt(utilities.GetEnv("AUTH_PASSWORD_RESET_MINUTES", "60"), 10, 64)
+	if err != nil || minutes < 1 {
+		minutes = 60
+	}
+	reset := models.PasswordReset{
+		UserId:      user.Id,
+		HashedToken: utilities.HashSHA512(token),
+		CreatedAt:   utilities.GetCurrentTime(),
+	}
+	reset.ExpiresAt = reset.CreatedAt + minutes*60*1000
+
+	// Invalidate the earlier resets of the user and create the new one
+	if err := db.Where("user_id = ? AND used_at = 0", user.Id).Delete(&models.PasswordReset{}).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	if err := db.Create(&reset).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Send the mail in the background so that the response time does not reveal the user
+	resetMail := newMail(user.Email, "election/auth Tracker Password Reset", frontendURL("/password/reset/"+token))
+	go func() {
+		if err := sendMail(resetMail); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Set a new password with a reset token, the token can only be used once and all sessions of the user are ended
+func ResetPassword(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Initialize the reset password input
+	var input models.ResetPasswordInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Find the unused reset of the token and check if it has expired
+	var reset models.PasswordReset
+	if err := db.Where("hashed_token = ? AND used_at = 0", utilities.HashSHA512(input.Token)).First(&reset).Error; err != nil || utilities.GetCurrentTime() >= reset.ExpiresAt {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the reset link is invalid or has expired",
+		})
+		return
+	}
+
+	// Check the new password against the password policy before the token is used
+	var user models.User
+	if err := db.Where("id = ?", reset.UserId).First(&user).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the reset link is invalid or has expired",
+		})
+		return
+	}
+	if err := utilities.CheckPasswordPolicy(input.NewPassword, user.Username, user.Email, user.FirstName, user.LastName); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Mark the reset as used, a second request with the same token does not match anymore
+	result := db.Model(&models.PasswordReset{}).Where("id = ? AND used_at = 0", reset.Id).Update("used_at", utilities.GetCurrentTime())
+	if result.Error != nil || result.RowsAffected != 1 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the reset link is invalid or has expired",
+		})
+		return
+	}
+
+	// Set the new password and end all sessions of the user
+	if err := db.Model(&models.User{}).Where("id = ?", reset.UserId).Update("hashed_password", utilities.HashPassword(input.NewPassword)).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	db.Where("user_id = ?", reset.UserId).Delete(&models.Session{})
+
+	// Return StatusOK
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "the password has been reset",
+	})
+}
+
+// Get the number of unused recovery codes of the logged in user
+func GetRecoveryCodes(c *gin.Context) {
+	// Get the database connection and the user from the context
+	db := c.MustGet("db").(*gorm.DB)
+	user := c.MustGet("user").(models.User)
+
+	// Count the unused codes
+	var remaining int64
+	if err := db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at = 0", user.Id).Count(&remaining).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the number of unused codes
+	c.JSON(http.StatusOK, gin.H{
+		"status":    http.StatusOK,
+		"remaining": remaining,
+	})
+}
+
+// Replace the recovery codes of the logged in user, requires a valid totp, security key or current recovery code
+func RegenerateRecoveryCodes(c *gin.Context) {
+	// Get the database connection and the user from the context
+	db := c.MustGet("db").(*gorm.DB)
+	user := c.MustGet("user").(models.User)
+
+	// Initialize the regenerate input
+	var input models.RegenerateRecoveryCodesInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Try to verify totp, a security key or a current recovery code can be used instead like at the login
+	verified, message := false, "could not verify the otp"
+	if input.RecoveryCode != "" {
+		_, verified = useRecoveryCode(db, user.Id, input.RecoveryCode)
+		message = "could not verify the recovery code"
+	} else if len(input.WebAuthn) > 0 {
+		verified = verifyWebAuthnLogin(db, user, models.LoginInput{WebAuthn: input.WebAuthn, WebAuthnCeremony: input.WebAuthnCeremony})
+		message = "could not verify the security key"
+	} else {
+		verified = validateTOTP(input.TOTP, user.TOTP)
+	}
+	if !verified {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": message,
+			"status":  http.StatusUnauthorized,
+		})
+		return
+	}
+
+	// Create the new codes
+	codes, err := createRecoveryCodes(db, user.Id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the codes, they are only shown once
+	c.JSON(http.StatusOK, gin.H{
+		"status":        http.StatusOK,
+		"recoverycodes": codes,
+	})
+}
+
+// Reset the totp of a user so that it has to be enrolled again, used by admins if a user lost the device
+//
+// The totp, the recovery codes and the sessions of the user are removed and a new enrolment link is sent
+// through the existing totp verification.
+func ResetUserTOTP(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	id := c.Param("id")
+
+	// Initialize the User object
+	var user models.User
+
+	// Find the User and return 404 when not found
+	if err := db.Where("id = ? OR username = ? OR email = ?", id, id, id).First(&user).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Create the totp verification object
+	totpVerification, err := newTOTPVerification(user.Username, user.Email)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Replace the earlier verifications, remove the totp, the recovery codes and the sessions
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("username = ?", user.Username).Delete(&models.TOTPVerification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&totpVerification).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("totp", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.Id).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.Id).Delete(&models.Session{}).Error
+	})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Send the mail with the link to the totp enrolment once the reset is stored
+	resetMail := newMail(user.Email, "election/auth Tracker Authentication Reset", frontendURL("/totp/"+totpVerification.Code))
+	if err := sendMail(resetMail); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  "the totp has been reset but the enrolment link could not be sent: " + err.Error(),
+		})
+		return
+	}
+
+	// Return StatusOK
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "the totp of the user has been reset and a new enrolment link has been sent",
+	})
+}
+
+// Replace the recovery codes of a user with new ones, the plain codes are returned and only their hashes are stored
+func createRecoveryCodes(db *gorm.DB, userId int64) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	recoveryCodes := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		code, err := utilities.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		recoveryCodes = append(recoveryCodes, models.RecoveryCode{
+			UserId:     userId,
+			HashedCode: utilities.HashSHA512(utilities.NormalizeRecoveryCode(code)),
+			CreatedAt:  utilities.GetCurrentTime(),
+		})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&recoveryCodes).Error
+	})
+	return codes, err
+}
+
+// Use a recovery code of a user, returns the number of remaining codes and false if the code is invalid or used
+func useRecoveryCode(db *gorm.DB, userId int64, code string) (int64, bool) {
+	// Mark the code as used, only one request can use it
+	result := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND hashed_code = ? AND used_at = 0", userId, utilities.HashSHA512(utilities.NormalizeRecoveryCode(code))).
+		Update("used_at", utilities.GetCurrentTime())
+	if result.Error != nil || result.RowsAffected != 1 {
+		return 0, false
+	}
+
+	// Count the remaining codes
+	var remaining int64
+	db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at = 0", userId).Count(&remaining)
+	return remaining, true
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+	"github.com/yzaimoglu/election/auth/models"
+	"github.com/yzaimoglu/election/auth/utilities"
+)
+
+func TestResetPasswordIsSingleUse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDatabase(t)
+	setTestArgon2Params(t, "1024")
+	user := newTestUser(t, db, "ahmet.yilmaz", utilities.HashPassword("eski şifre 1234"))
+	now := utilities.GetCurrentTime()
+	if err := db.Create(&models.Session{UserId: user.Id, SessionToken: "session-1", CreatedAt: now, ExpiresAt: now + 60000}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Store a reset like the forgot password mail, only the hash of the token is known to the database
+	newReset := func(t *testing.T, token string, expiresAt int64) {
+		t.Helper()
+		reset := models.PasswordReset{UserId: user.Id, HashedToken: utilities.HashSHA512(token), CreatedAt: now, ExpiresAt: expiresAt}
+		if err := db.Create(&reset).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	newReset(t, "token-1", now+60000)
+	newReset(t, "token-expired", now-1)
+
+	tests := []struct {
+		name     string
+		token    string
+		password string
+		status   int
+	}{
+		{"unknown token", "token-unknown", "doğru at pili zımba", http.StatusBadRequest},
+		{"expired token", "token-expired", "doğru at pili zımba", http.StatusBadRequest},
+		{"password against the policy", "token-1", "kısa", http.StatusBadRequest},
+		{"valid token after a rejected password", "token-1", "doğru at pili zımba", http.StatusOK},
+		{"used token", "token-1", "başka bir uzun şifre", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if status, response := performJSONRequest(t, ResetPassword, db, nil, models.ResetPasswordInput{Token: test.token, NewPassword: test.password}); status != test.status {
+				t.Fatalf("got the status %d with %s, want %d", status, response["error"], test.status)
+			}
+		})
+	}
+
+	// The password of the valid reset is set and the sessions are ended
+	var stored models.User
+	db.First(&stored, user.Id)
+	if !utilities.CheckPassword(stored.HashedPassword, "doğru at pili zımba") {
+		t.Fatal("the password has not been reset")
+	}
+	var sessions int64
+	db.Model(&models.Session{}).Where("user_id = ?", user.Id).Count(&sessions)
+	if sessions != 0 {
+		t.Fatalf("got %d sessions after the reset, want 0", sessions)
+	}
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDatabase(t)
+	newTestCache(t)
+	setTestArgon2Params(t, "1024")
+	user := newTestUser(t, db, "ahmet.yilmaz", utilities.HashPassword("doğru at pili zımba"))
+	other := newTestUser(t, db, "mehmet.kaya", utilities.HashPassword("doğru at pili zımba"))
+
+	codes, err := createRecoveryCodes(db, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	// The case, the spaces and the dashes of a code are ignored
+	if remaining, ok := useRecoveryCode(db, user.Id, " "+strings.ToUpper(strings.Replace(codes[0], "-", " ", 1))); !ok || remaining != recoveryCodeCount-1 {
+		t.Fatalf("got %d remaining codes and %t, want %d and true", remaining, ok, recoveryCodeCount-1)
+	}
+	if _, ok := useRecoveryCode(db, user.Id, codes[0]); ok {
+		t.Fatal("a used code has been accepted again")
+	}
+	if _, ok := useRecoveryCode(db, other.Id, codes[1]); ok {
+		t.Fatal("the code of another user has been accepted")
+	}
+
+	// A login with a recovery code uses it up
+	login := gin.H{"username": user.Username, "email": user.Email, "plainpassword": "doğru at pili zımba", "recoverycode": codes[1]}
+	status, response := performJSONRequest(t, LoginHandler, db, nil, login)
+	if status != http.StatusOK {
+		t.Fatalf("login with a recovery code: got the status %d with %s", status, response["error"])
+	}
+	var remaining int64
+	decodeField(t, response, "remainingrecoverycodes", &remaining)
+	if remaining != recoveryCodeCount-2 {
+		t.Fatalf("got %d remaining codes after the login, want %d", remaining, recoveryCodeCount-2)
+	}
+	if status, _ := performJSONRequest(t, LoginHandler, db, nil, login); status != http.StatusUnauthorized {
+		t.Fatalf("second login with the same recovery code: got the status %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	// New codes replace all old ones
+	if _, err := createRecoveryCodes(db, user.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := useRecoveryCode(db, user.Id, codes[2]); ok {
+		t.Fatal("an old code has been accepted after the codes were replaced")
+	}
+}
+
+func TestRegenerateRecoveryCodesAcceptsEveryFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDatabase(t)
+	user := newTestUser(t, db, "ahmet.yilmaz", utilities.HashPassword("doğru at pili zımba"))
+	codes, err := createRecoveryCodes(db, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totp.GenerateCode(testTOTPSecret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		body   gin.H
+		status int
+	}{
+		{"no factor", gin.H{}, http.StatusBadRequest},
+		{"security key without its ceremony", gin.H{"webauthn": gin.H{"id": "key"}}, http.StatusBadRequest},
+		{"wrong totp", gin.H{"totp": "000000"}, http.StatusUnauthorized},
+		{"unknown recovery code", gin.H{"recoverycode": "aaaa-bbbb"}, http.StatusUnauthorized},
+		{"security key of an unknown ceremony", gin.H{"webauthn": gin.H{"id": "key"}, "webauthnceremony": "unknown"}, http.StatusUnauthorized},
+		{"current recovery code", gin.H{"recoverycode": codes[0]}, http.StatusOK},
+		{"recovery code replaced by the regeneration", gin.H{"recoverycode": codes[1]}, http.StatusUnauthorized},
+		{"totp", gin.H{"totp": code}, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, response := performJSONRequest(t, RegenerateRecoveryCodes, db, &user, test.body)
+			if status != test.status {
+				t.Fatalf("got the status %d with %s, want %d", status, response["message"], test.status)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			var regenerated []string
+			decodeField(t, response, "recoverycodes", &regenerated)
+			if len(regenerated) != recoveryCodeCount {
+				t.Fatalf("got %d codes, want %d", len(regenerated), recoveryCodeCount)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yzaimoglu/election/auth/models"
@@ -9,17 +10,24 @@ import (
 	"gorm.io/gorm"
 )
 
-// Get the active sessions of the user
+// Get the active sessions of the user, only for the user itself and admins
 func GetSessionsOfUser(c *gin.Context) {
 	// Get the database connection from the context
 	db := c.MustGet("db").(*gorm.DB)
 	id := c.Param("id")
 
+	// Check if the logged in user may see the sessions
+	userId, ok := managedUserId(c, id)
+	if !ok {
+		return
+	}
+
 	// Create the user object
 	var sessions []models.Session
 
-	// Find all sessions
-	if err := db.Where("user_id = ?", id).Find(&sessions).Error; err != nil {
+	// Find all sessions which have not expired
+	now := utilities.GetCurrentTime()
+	if err := db.Where("user_id = ? AND expires_at > ? AND (max_expires_at = 0 OR max_expires_at > ?)", userId, now, now).Order("last_active_at DESC").Find(&sessions).Error; err != nil {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 			"message": "no active sessions found",
 			"status":  http.StatusNotFound,
@@ -27,85 +35,117 @@ func GetSessionsOfUser(c *gin.Context) {
 		return
 	}
 
+	// Hide the tokens and mark the session of the request
+	current := c.MustGet("session").(models.Session)
+	for index := range sessions {
+		sessions[index].SessionToken = ""
+	}
+
 	// Return userid and sessions
 	c.JSON(http.StatusOK, gin.H{
-		"userid":   id,
-		"sessions": sessions,
+		"userid":         userId,
+		"currentsession": current.Id,
+		"sessions":       sessions,
 	})
 }
 
-// Return Session with user of provided session id or token
-func GetSessionById(c *gin.Context) {
+// Return Session with user
+func GetSessionByCookie(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	sessionToken, err := c.Cookie("session-token")
+	if err != nil {
+		sessionToken = c.GetHeader("Authentication-Session-Token")
+	}
+
+	// Find the session and return the session with its user
+	returnSession(c, db, sessionToken)
+}
+
+// Revoke a single session by its id, only for the owner of the session and admins
+func RevokeSession(c *gin.Context) {
 	// Get the database connection from the context
 	db := c.MustGet("db").(*gorm.DB)
-	sessionToken := c.Param("session")
+	id := c.Param("id")
 
-	// Create the session object
+	// Find the session and return 404 when not found
 	var session models.Session
-
-	// Check the database for the session token
-	if err := db.Where("id = ? OR session_token = ?", sessionToken, sessionToken).First(&session).Error; err != nil {
+	if err := db.Where("id = ?", id).First(&session).Error; err != nil {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"message": "you are not logged in",
+			"message": "there is no such session",
 			"status":  http.StatusNotFound,
 		})
 		return
 	}
 
-	// Check if session has expired if so delete session
-	if utilities.GetCurrentTime() >= session.ExpiresAt {
-		var message string
-		var status int
-		if err := db.Delete(&session); err.Error != nil {
-			message = "internal server error"
-			status = http.StatusInternalServerError
-		} else {
-			message = "the session has expired"
-			status = http.StatusNotFound
-		}
+	// Check if the logged in user may revoke the session
+	if _, ok := managedUserId(c, strconv.FormatInt(session.UserId, 10)); !ok {
+		return
+	}
+
+	// Delete Session from Database
+	if err := db.Delete(&session).Error; err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"message": message,
-			"status":  status,
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Initialize the User object
-	var user models.User
+	// Return Success JSON
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "the session has been revoked",
+		"status":    http.StatusOK,
+		"sessionid": session.Id,
+	})
+}
 
-	// Find the User and return 404 including error when not found
-	if err :=
-		db.Where("id = ?", session.UserId).
-			First(&user).Error; err != nil {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status": http.StatusNotFound,
-			"error":  err.Error(),
+// Revoke all sessions of a user to log out everywhere, only for the user itself and admins
+func RevokeSessionsOfUser(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	id := c.Param("id")
+
+	// Check if the logged in user may revoke the sessions
+	userId, ok := managedUserId(c, id)
+	if !ok {
+		return
+	}
+
+	// Delete the sessions from the database
+	result := db.Where("user_id = ?", userId).Delete(&models.Session{})
+	if result.Error != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  result.Error.Error(),
 		})
 		return
 	}
 
-	// User without sensitive information
-	userInformation := models.UserInformation{
-		Id:          user.Id,
-		Username:    user.Username,
-		FirstName:   user.FirstName,
-		LastName:    user.LastName,
-		Email:       user.Email,
-		CreatedAt:   user.CreatedAt,
-		LastSeen:    user.LastSeen,
-		Role:        user.Role,
-		Affiliation: user.Affiliation,
+	// Expire the cookie if the user logged out itself
+	if c.MustGet("user").(models.User).Id == userId {
+		c.SetCookie(
+			"session-token",
+			"expired",
+			1,
+			"/",
+			utilities.GetEnv("AUTH_HOSTNAME", "localhost"),
+			true,
+			false,
+		)
 	}
 
-	// Return session and user
+	// Return Success JSON
 	c.JSON(http.StatusOK, gin.H{
-		"session": session,
-		"user":    userInformation,
+		"message":      "all sessions of the user have been revoked",
+		"status":       http.StatusOK,
+		"userid":       userId,
+		"revokedcount": result.RowsAffected,
 	})
 }
 
-// Return Session with user
-func GetSessionByCookie(c *gin.Context) {
+// Check if a session exists
+func SessionExists(c *gin.Context) bool {
 	// Get the database connection from the context
 	db := c.MustGet("db").(*gorm.DB)
 	sessionToken, err := c.Cookie("session-token")
@@ -113,39 +153,25 @@ func GetSessionByCookie(c *gin.Context) {
 		sessionToken = c.GetHeader("Authentication-Session-Token")
 	}
 
-	// Create the session object
-	var session models.Session
-
-	// Check the database for the session token
-	if err := db.Where("session_token = ?", sessionToken).First(&session).Error; err != nil {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"message": "you are not logged in",
-			"status":  http.StatusNotFound,
-		})
-		return
-	}
-
-	// Check if Tokens are identical
-	if sessionToken != session.SessionToken {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"message": "unauthorized",
-			"status":  http.StatusUnauthorized,
-		})
-		return
-	}
+	// Check the database for an active session of the token
+	_, err = models.FindActiveSession(db, sessionToken)
+	return err == nil
+}
 
-	// Check if session has expired if so delete session
-	if utilities.GetCurrentTime() >= session.ExpiresAt {
-		var message string
-		var status int
-		if err := db.Delete(&session); err.Error != nil {
-			message = "internal server error"
-			status = http.StatusInternalServerError
-		} else {
-			message = "you are not logged in"
-			status = http.StatusNotFound
+// Find the active session of a token and return it with its user
+func returnSession(c *gin.Context, db *gorm.DB, sessionToken string) {
+	// Check the database for the session token, expired sessions are deleted
+	session, err := models.FindActiveSession(db, sessionToken)
+	if err != nil {
+		message, status := "you are not logged in", http.StatusNotFound
+		switch err {
+		case models.ErrSessionExpired:
+			message = "the session has expired"
+		case gorm.ErrRecordNotFound:
+		default:
+			message, status = "internal server error", http.StatusInternalServerError
 		}
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+		c.AbortWithStatusJSON(status, gin.H{
 			"message": message,
 			"status":  status,
 		})
@@ -167,17 +193,7 @@ func GetSessionByCookie(c *gin.Context) {
 	}
 
 	// User without sensitive information
-	userInformation := models.UserInformation{
-		Id:          user.Id,
-		Username:    user.Username,
-		FirstName:   user.FirstName,
-		LastName:    user.LastName,
-		Email:       user.Email,
-		CreatedAt:   user.CreatedAt,
-		LastSeen:    user.LastSeen,
-		Role:        user.Role,
-		Affiliation: user.Affiliation,
-	}
+	userInformation := newUserInformation(user)
 
 	// Return session and user
 	c.JSON(http.StatusOK, gin.H{
@@ -186,44 +202,42 @@ func GetSessionByCookie(c *gin.Context) {
 	})
 }
 
-// Check if a session exists
-func SessionExists(c *gin.Context) bool {
-	// Get the database connection from the context
-	db := c.MustGet("db").(*gorm.DB)
-	sessionToken, err := c.Cookie("session-token")
+// Parse the id of a managed user, aborts the request unless it is the logged in user or an admin
+func managedUserId(c *gin.Context, id string) (int64, bool) {
+	userId, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
-		sessionToken = c.GetHeader("Authentication-Session-Token")
-	}
-
-	// Create the session object
-	var session models.Session
-
-	// Check the database for the session token
-	if err := db.Where("session_token = ?", sessionToken).First(&session).Error; err != nil {
-		return false
-	}
-
-	// Check if Tokens are identical
-	if sessionToken != session.SessionToken {
-		return false
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the user id must be a number",
+		})
+		return 0, false
 	}
-
-	// Check if session has expired if so delete session
-	if utilities.GetCurrentTime() >= session.ExpiresAt {
-		db.Delete(&session)
-		return false
+	user := c.MustGet("user").(models.User)
+	if user.Id != userId && user.Role != models.RoleAdmin {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  "you are not allowed to do this",
+		})
+		return 0, false
 	}
-
-	return true
+	return userId, true
 }
 
-// Create a session object
-func createSessionObject(userId int64) models.Session {
+// Create a session object with the device of the request
+func createSessionObject(c *gin.Context, userId int64) models.Session {
 	sessionToken, _ := utilities.GenerateRandomBytes(256)
 	var session models.Session
 	session.UserId = userId
 	session.CreatedAt = utilities.GetCurrentTime()
-	session.ExpiresAt = session.CreatedAt + (60 * 60 * 24 * 1000)
+	session.LastActiveAt = session.CreatedAt
+	session.ExpiresAt = session.CreatedAt + models.SessionIdleTimeout().Milliseconds()
+	session.MaxExpiresAt = session.CreatedAt + models.SessionMaxLifetime().Milliseconds()
+	if session.ExpiresAt > session.MaxExpiresAt {
+		session.ExpiresAt = session.MaxExpiresAt
+	}
 	session.SessionToken = utilities.ToBase64(sessionToken)
+	session.IP = c.ClientIP()
+	session.UserAgent = c.Request.UserAgent()
+	session.Device = utilities.DeviceName(session.UserAgent)
 	return session
 }
@@ -0,0 +1,343 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yzaimoglu/election/auth/models"
+	"github.com/yzaimoglu/election/auth/tokens"
+	"github.com/yzaimoglu/election/auth/utilities"
+	"gorm.io/gorm"
+)
+
+// Return the public keys the access tokens are signed with
+//
+//	GET /v1/.well-known/jwks.json
+func GetJWKS(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Make sure there are keys for the access and the ID tokens before publishing them
+	for _, algorithm := range []string{tokens.Algorithm, tokens.AlgorithmRSA} {
+		if _, err := models.CurrentSigningKey(db, algorithm); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  err.Error(),
+			})
+			return
+		}
+	}
+
+	// Find the keys which have not retired
+	keySet, err := models.PublishedSigningKeys(db)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the keys, they may be cached for a few minutes
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keySet)
+}
+
+// Issue a short-lived access token, either for a client with its credentials or for the user of a session
+//
+//	POST /v1/token/ {"grant_type": "client_credentials", "client_id": "...", "client_secret": "...", "scope": "parliament:write"}
+//	POST /v1/token/ {"grant_type": "session"} with the session cookie
+//
+// The client credentials can also be sent with http basic authentication and the input as a form.
+func IssueToken(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Input required to get a token
+	var input models.TokenInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBind(&input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+	if clientId, clientSecret, ok := c.Request.BasicAuth(); ok {
+		input.ClientId, input.ClientSecret = clientId, clientSecret
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Create the claims of the client or of the user
+	var claims tokens.Claims
+	var ok bool
+	if input.GrantType == "client_credentials" {
+		claims, ok = clientClaims(c, db, input)
+	} else {
+		claims, ok = sessionClaims(c, db)
+	}
+	if !ok {
+		return
+	}
+
+	// Sign the token
+	token, claims, err := models.IssueToken(db, claims)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the token, it must not be cached
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   claims.ExpiresAt - claims.IssuedAt,
+		"scope":        claims.Scope,
+	})
+}
+
+// Rotate the signing keys of the access and the ID tokens, the old keys stay published until their tokens have expired
+func RotateSigningKey(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Create the new keys
+	keys := make([]models.SigningKey, 0, 2)
+	for _, algorithm := range []string{tokens.Algorithm, tokens.AlgorithmRSA} {
+		key, err := models.RotateSigningKey(db, algorithm)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  err.Error(),
+			})
+			return
+		}
+		keys = append(keys, key)
+	}
+
+	// Return the new keys
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  http.StatusCreated,
+		"message": "the signing keys have been rotated",
+		"keys":    keys,
+	})
+}
+
+// Get all service clients
+func GetServiceClients(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Find the clients
+	var clients []models.ServiceClient
+	if err := db.Order("created_at DESC").Find(&clients).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the clients
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"clients": clients,
+	})
+}
+
+// Create a service client, the secret is only returned once
+func CreateServiceClient(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Input required to create a client
+	var input models.CreateServiceClientInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Generate the id and the secret of the client
+	idBytes, err := utilities.GenerateRandomBytes(8)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	secretBytes, err := utilities.GenerateRandomBytes(32)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	secret := utilities.ToBase64(secretBytes)
+
+	// Create the client
+	client := models.ServiceClient{
+		ClientId:     hex.EncodeToString(idBytes),
+		Name:         input.Name,
+		HashedSecret: utilities.HashPassword(secret),
+		Scope:        strings.Join(strings.Fields(input.Scope), " "),
+		CreatedAt:    utilities.GetCurrentTime(),
+	}
+	if err := db.Create(&client).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the client with its secret
+	c.JSON(http.StatusCreated, gin.H{
+		"status":       http.StatusCreated,
+		"message":      "the client has been created, the secret is not shown again",
+		"client":       client,
+		"clientsecret": secret,
+	})
+}
+
+// Delete a service client, its tokens stay valid until they expire
+func DeleteServiceClient(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	id := c.Param("id")
+
+	// Delete the client by its id or client id
+	result := db.Where("id = ? OR client_id = ?", id, id).Delete(&models.ServiceClient{})
+	if result.Error != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  "there is no such client",
+		})
+		return
+	}
+
+	// Return Success JSON
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "the client has been deleted",
+	})
+}
+
+// Check the credentials of a client and create its claims, the requested scopes must be granted to the client
+func clientClaims(c *gin.Context, db *gorm.DB, input models.TokenInput) (tokens.Claims, bool) {
+	var claims tokens.Claims
+
+	// Find the client and check its secret, unknown clients are checked against a dummy hash
+	var client models.ServiceClient
+	if err := db.Where("client_id = ?", input.ClientId).First(&client).Error; err != nil || input.ClientId == "" {
+		utilities.CheckPassword(dummyPasswordHash, input.ClientSecret)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  "invalid client credentials",
+		})
+		return claims, false
+	}
+	if !utilities.CheckPassword(client.HashedSecret, input.ClientSecret) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  "invalid client credentials",
+		})
+		return claims, false
+	}
+
+	// All scopes of the client are granted if none are requested
+	granted := tokens.Claims{Scope: client.Scope}
+	scope := strings.Fields(input.Scope)
+	if len(scope) == 0 {
+		scope = strings.Fields(client.Scope)
+	}
+	for _, requested := range scope {
+		if !granted.HasScope(requested) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"status": http.StatusForbidden,
+				"error":  "the client is not allowed to request the scope " + requested,
+			})
+			return claims, false
+		}
+	}
+
+	// Remember when the client was used last
+	if err := db.Model(&client).Update("last_used_at", utilities.GetCurrentTime()).Error; err != nil {
+		log.Println("error updating the last use of a client: " + err.Error())
+	}
+
+	claims.Subject = client.ClientId
+	claims.ClientId = client.ClientId
+	claims.Scope = strings.Join(scope, " ")
+	return claims, true
+}
+
+// Create the claims of the user of the session of the request
+func sessionClaims(c *gin.Context, db *gorm.DB) (tokens.Claims, bool) {
+	var claims tokens.Claims
+	sessionToken, err := c.Cookie("session-token")
+	if err != nil {
+		sessionToken = c.GetHeader("Authentication-Session-Token")
+	}
+
+	// Find the session and its user, only active users get tokens
+	var user models.User
+	session, err := models.FindActiveSession(db, sessionToken)
+	if err == nil {
+		err = db.Where("id = ? AND status = ?", session.UserId, models.UserActive).First(&user).Error
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  "you are not logged in",
+		})
+		return claims, false
+	}
+
+	claims.Subject = strconv.FormatInt(user.Id, 10)
+	claims.Role = user.Role
+	claims.Affiliation = user.Affiliation
+	return claims, true
+}
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"image"
 	"image/png"
+	"log"
 	"net/http"
-	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 	"github.com/yzaimoglu/election/auth/models"
@@ -16,13 +18,12 @@ import (
 	"gorm.io/gorm"
 )
 
-// Verify the totp
+// Verify the totp of an enrolment, the verification can only be completed once and expires
 func VerifyTOTP(c *gin.Context) {
 	// Get the database connection from the context
 	db := c.MustGet("db").(*gorm.DB)
 
 	// Create the verificationcode model
-	var verification models.TOTPVerification
 	var verificationInput models.TOTPVerificationInput
 
 	// Bind the input from the request body to the Input object
@@ -44,17 +45,19 @@ func VerifyTOTP(c *gin.Context) {
 		return
 	}
 
-	// Check the database for the verification code
-	if err := db.Where("code = ?", verificationInput.Code).First(&verification).Error; err != nil {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"message": "could not find such a code in the database",
-			"status":  http.StatusNotFound,
-		})
+	// Check the database for a pending verification of the code
+	verification, ok := findPendingVerification(c, db, verificationInput.Code)
+	if !ok {
 		return
 	}
 
-	// Try to verify totp
-	if !totp.Validate(verificationInput.TOTP, string(utilities.FromBase64(verification.Secret))) {
+	// Try to verify totp, the verification is deleted after too many wrong codes
+	if !validateTOTP(verificationInput.TOTP, verification.Secret) {
+		if verification.Attempts+1 >= models.MaxEnrolmentAttempts {
+			db.Delete(&verification)
+		} else {
+			db.Model(&verification).Update("attempts", gorm.Expr("attempts + 1"))
+		}
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"message": "could not verify the totp",
 			"status":  http.StatusUnauthorized,
@@ -74,78 +77,178 @@ func VerifyTOTP(c *gin.Context) {
 		return
 	}
 
-	// Create an updated user object
-	var updatedUser models.User = user
-	updatedUser.TOTP = verification.Secret
-
-	// Save the Updated User to the Database
-	db.Model(&user).Updates(updatedUser)
-
-	// Delete the verification code from the database
-	if err := db.Where("code = ?", verification.Code).Delete(&verification); err.Error != nil {
+	// Complete the verification and move the secret to the user, a second request with the same code does not match anymore
+	// and the first admin is activated
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TOTPVerification{}).Where("id = ? AND completed_at = 0", verification.Id).
+			Updates(map[string]interface{}{"completed_at": utilities.GetCurrentTime(), "secret": "", "image": ""})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&user).Update("totp", verification.Secret).Error; err != nil {
+			return err
+		}
+		return activateAutoApprovedUser(tx, user)
+	})
+	if err == gorm.ErrRecordNotFound {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status": http.StatusNotFound,
-			"error":  err.Error,
+			"message": "could not find such a code in the database",
+			"status":  http.StatusNotFound,
+		})
+		return
+	} else if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Return verified totp
+	// Create the recovery codes for a lost device
+	recoveryCodes, err := createRecoveryCodes(db, user.Id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return verified totp with the recovery codes, they are only shown once
 	c.JSON(http.StatusOK, gin.H{
-		"status":  http.StatusOK,
-		"message": "user totp has been successfully verified",
+		"status":        http.StatusOK,
+		"message":       "user totp has been successfully verified",
+		"recoverycodes": recoveryCodes,
 	})
 }
 
-// Get the image
+// Get the qr code of a pending enrolment as png, it is sent from memory and must not be cached
 func GetImage(c *gin.Context) {
 	// Get the database connection from the context
 	db := c.MustGet("db").(*gorm.DB)
-	// Get the verification code from the url
-	verificationCode := c.Param("verification")
 
-	// Create the verificationcode model
-	var verification models.TOTPVerification
+	// Check the database for a pending verification of the code
+	verification, ok := findPendingVerification(c, db, c.Param("verification"))
+	if !ok {
+		return
+	}
+
+	// Return the image
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", utilities.FromBase64(verification.Image))
+}
+
+// Get the status of an enrolment, so that the frontend knows if the setup has been completed
+func GetEnrolmentStatus(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
 
 	// Check the database for the verification code
-	if err := db.Where("code = ?", verificationCode).First(&verification).Error; err != nil {
+	var verification models.TOTPVerification
+	if err := db.Where("code = ?", c.Param("verification")).First(&verification).Error; err != nil {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"message": "there is no such image",
+			"message": "could not find such a code in the database",
 			"status":  http.StatusNotFound,
 		})
 		return
 	}
 
-	// Create the file
-	file, err := os.Create(verificationCode + ".png")
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"message": err.Error(),
-			"status":  http.StatusNotFound,
+	// Return the status
+	c.JSON(http.StatusOK, gin.H{
+		"status":       http.StatusOK,
+		"enrolment":    verification.Status(utilities.GetCurrentTime()),
+		"expiresat":    verification.ExpiresAt,
+		"attemptsleft": models.MaxEnrolmentAttempts - verification.Attempts,
+	})
+}
+
+// Get the second factors of the logged in user and whether an enrolment is pending
+func GetTOTPStatus(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	user := c.MustGet("user").(models.User)
+
+	// Find the pending enrolment of the user
+	response := gin.H{
+		"status":           http.StatusOK,
+		"enrolled":         user.TOTP != "",
+		"pendingenrolment": false,
+	}
+	var verification models.TOTPVerification
+	err := db.Where("username = ? AND completed_at = 0 AND expires_at > ?", user.Username, utilities.GetCurrentTime()).
+		Order("created_at DESC").First(&verification).Error
+	if err == nil {
+		response["pendingenrolment"] = true
+		response["enrolmentexpiresat"] = verification.ExpiresAt
+	}
+
+	// Count the recovery codes and security keys
+	var recoveryCodes, securityKeys int64
+	if err := db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at = 0", user.Id).Count(&recoveryCodes).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
 		})
 		return
 	}
-	// Write to the file
-	if _, err := file.Write(utilities.FromBase64(verification.Image)); err != nil {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"message": err.Error(),
-			"status":  http.StatusNotFound,
+	if err := db.Model(&models.WebAuthnCredential{}).Where("user_id = ?", user.Id).Count(&securityKeys).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
 		})
 		return
 	}
-	file.Close()
+	response["remainingrecoverycodes"] = recoveryCodes
+	response["securitykeys"] = securityKeys
 
-	// Return the file
-	c.File(verificationCode + ".png")
+	// Return the status
+	c.JSON(http.StatusOK, response)
+}
 
-	// Remove the file
-	if err := os.Remove(verificationCode + ".png"); err != nil {
+// Find the verification of a code which has neither been completed nor expired, aborts the request if there is none
+//
+// Verifications which expired a week ago are deleted on the way, until then their status can still be asked for.
+func findPendingVerification(c *gin.Context, db *gorm.DB, code string) (models.TOTPVerification, bool) {
+	var verification models.TOTPVerification
+	now := utilities.GetCurrentTime()
+	if err := db.Where("expires_at <= ?", now-(7*24*time.Hour).Milliseconds()).Delete(&models.TOTPVerification{}).Error; err != nil {
+		log.Println("error deleting the expired totp verifications: " + err.Error())
+	}
+	if err := db.Where("code = ? AND completed_at = 0 AND expires_at > ?", code, now).First(&verification).Error; err != nil {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"message": err.Error(),
+			"message": "the enrolment link is invalid or has expired",
 			"status":  http.StatusNotFound,
 		})
-		return
+		return verification, false
 	}
+	return verification, true
+}
+
+// Create the totp verification of the enrolment of a user, the secret is set to the user once a code of it is verified
+func newTOTPVerification(username string, email string) (models.TOTPVerification, error) {
+	var verification models.TOTPVerification
+	key, err := CreateTOTP(email)
+	if err != nil {
+		return verification, err
+	}
+	imageBytes, err := GetImageBytes(key)
+	if err != nil {
+		return verification, err
+	}
+	bytes, err := utilities.GenerateRandomBytes(50)
+	if err != nil {
+		return verification, err
+	}
+	verification.Username = username
+	verification.Code = utilities.ToBase64(bytes) + uuid.New().String()
+	verification.Secret = utilities.ToBase64([]byte(key.Secret()))
+	verification.Image = utilities.ToBase64(imageBytes)
+	verification.CreatedAt = utilities.GetCurrentTime()
+	verification.ExpiresAt = verification.CreatedAt + models.TOTPEnrolmentLifetime().Milliseconds()
+	return verification, nil
 }
 
 // Create TOTP
@@ -175,3 +278,25 @@ func GetImageStruct(key *otp.Key) (image.Image, error) {
 	}
 	return img, nil
 }
+
+// Check a totp code against a base64 secret, an empty secret never passes
+//
+// The code of an empty secret is valid for the totp library, so a user whose totp was reset would pass with it.
+func validateTOTP(code string, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	return totp.Validate(code, string(utilities.FromBase64(secret)))
+}
+
+// Check if the user has enrolled a totp or registered a security key
+func hasSecondFactor(db *gorm.DB, user models.User) (bool, error) {
+	if user.TOTP != "" {
+		return true, nil
+	}
+	var credentials int64
+	if err := db.Model(&models.WebAuthnCredential{}).Where("user_id = ?", user.Id).Count(&credentials).Error; err != nil {
+		return false, err
+	}
+	return credentials > 0, nil
+}
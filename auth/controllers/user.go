@@ -1,15 +1,14 @@
 package controllers
 
 import (
-	"encoding/json"
-	"fmt"
+	"bytes"
+	"encoding/csv"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"github.com/google/uuid"
-	mail "github.com/xhit/go-simple-mail/v2"
 	"github.com/yzaimoglu/election/auth/models"
 	"github.com/yzaimoglu/election/auth/utilities"
 	"gorm.io/gorm"
@@ -87,198 +86,29 @@ func GetUser(c *gin.Context) {
 	}
 
 	// User without sensitive information
-	userInformation := models.UserInformation{
-		Id:          user.Id,
-		Username:    user.Username,
-		FirstName:   user.FirstName,
-		LastName:    user.LastName,
-		Email:       user.Email,
-		CreatedAt:   user.CreatedAt,
-		LastSeen:    user.LastSeen,
-		Role:        user.Role,
-		Affiliation: user.Affiliation,
-	}
+	userInformation := newUserInformation(user)
 
 	// Return the User
 	c.JSON(http.StatusOK, userInformation)
 }
 
-// Create a new user
-func CreateUser(c *gin.Context) {
-	// Get the database connection from the context
-	db := c.MustGet("db").(*gorm.DB)
-
-	// Initialize the CreateAnimecloUser Model
-	var createUser models.CreateUser
-
-	// Get the input from the Request Body and Decode into CreateUser Object
-	if err := json.NewDecoder(c.Request.Body).Decode(&createUser); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
-		return
-	}
-
-	// Validate the input
-	validator := validator.New()
-	if err := validator.Struct(createUser); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
-		return
-	}
-
-	// Check if there is a user with the specified email
-	if EmailExists(c, createUser.Email) {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-			"status": http.StatusBadRequest,
-			"error":  "email already exists",
-		})
-		return
-	}
-
-	// Create username and get rid of turkish characters from username
-	username := strings.ToLower(strings.ReplaceAll(createUser.FirstName, " ", "-") + "." + strings.ReplaceAll(createUser.LastName, " ", "-"))
-	username = strings.ReplaceAll(username, "ş", "s")
-	username = strings.ReplaceAll(username, "ğ", "g")
-	username = strings.ReplaceAll(username, "ç", "c")
-	username = strings.ReplaceAll(username, "ı", "i")
-	username = strings.ReplaceAll(username, "ö", "o")
-	username = strings.ReplaceAll(username, "ü", "u")
-
-	i := 1
-	usernameWithoutNumbers := username
-
-	// Check if username already exists, if it does append append an integer
-	for UsernameExists(c, username) {
-		username = usernameWithoutNumbers + fmt.Sprint(i)
-		i++
-	}
-
-	// Create TOTP
-	totp, err := CreateTOTP(createUser.Email)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status": http.StatusInternalServerError,
-			"error":  err.Error(),
-		})
-		return
-	}
-
-	// Create the new user model
-	user := models.User{
-		Username:       username,
-		FirstName:      createUser.FirstName,
-		LastName:       createUser.LastName,
-		Email:          createUser.Email,
-		Role:           "Kullanıcı",
-		Affiliation:    createUser.Affiliation,
-		CreatedAt:      utilities.GetCurrentTime(),
-		LastSeen:       -1,
-		HashedPassword: utilities.HashPassword(createUser.PlainPassword),
-		TOTP:           utilities.ToBase64([]byte(totp.Secret())),
-	}
-
-	// Create the totp verification object
-	imageBytes, err := GetImageBytes(totp)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status": http.StatusInternalServerError,
-			"error":  err.Error(),
-		})
-		return
-	}
-	bytes, err := utilities.GenerateRandomBytes(50)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status": http.StatusInternalServerError,
-			"error":  err.Error(),
-		})
-		return
-	}
-	totpVerification := models.TOTPVerification{
-		Username: username,
-		Code:     utilities.ToBase64(bytes) + uuid.New().String(),
-		Secret:   utilities.ToBase64([]byte(totp.Secret())),
-		Image:    utilities.ToBase64(imageBytes),
-	}
-
-	// Create Mail info
-	// TODO: Will be done in the emailer later on
-	createMail := models.Mail{
-		From:    "election/auth Tracker - Authentication <user@user>",
-		To:      createUser.Email,
-		Subject: "election/auth Tracker Authentication Requirement",
-		Body:    "https:///localhost/totp/" + totpVerification.Code,
-		Credentials: models.MailCredentials{
-			Username: utilities.GetEnv("SMTP_USER", "user@user"),
-			Password: utilities.GetEnv("SMTP_PASSWORD", "password"),
-		},
-		Server: models.MailServer{
-			Host:       utilities.GetEnv("SMTP_HOST", "smtp_host"),
-			Port:       587,
-			Encryption: mail.EncryptionSTARTTLS,
-		},
-	}
-
-	// Specify mailserver options
-	mailServer := mail.NewSMTPClient()
-	mailServer.Host = createMail.Server.Host
-	mailServer.Port = createMail.Server.Port
-	mailServer.Username = createMail.Credentials.Username
-	mailServer.Password = createMail.Credentials.Password
-	mailServer.Encryption = createMail.Server.Encryption
-
-	// Connect to mailserver
-	smtpClient, err := mailServer.Connect()
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status": http.StatusInternalServerError,
-			"error":  err.Error(),
-		})
-		return
-	}
-
-	// Set email info
-	email := mail.NewMSG()
-	email.SetFrom(createMail.From)
-	email.AddTo(createMail.To)
-	email.SetSubject(createMail.Subject)
-	email.SetBody(mail.TextHTML, createMail.Body)
-
-	// Send mail
-	if err := email.Send(smtpClient); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status": http.StatusInternalServerError,
-			"error":  err.Error(),
-		})
-		return
-	}
-
-	// Create the TOTP Verification in the Database
-	db.Select("Id", "Username", "Code", "Secret", "Image").Create(&totpVerification)
-
-	// Create the User in the Database
-	db.Select("Id", "Username", "FirstName", "LastName", "Email", "HashedPassword", "CreatedAt", "LastSeen",
-		"Role", "Affiliation", "TOTP").Create(&user)
-
-	// Return the newly created user
-	c.JSON(http.StatusOK, user)
-}
-
 // Update the email of a user
 func UpdateUserEmail(c *gin.Context) {
 	// Get the database connection from the context
 	db := c.MustGet("db").(*gorm.DB)
 	id := c.Param("id")
 
+	// Check if the logged in user may update the user
+	userId, ok := managedUserId(c, id)
+	if !ok {
+		return
+	}
+
 	// Initialize the User object
 	var user models.User
 
 	// Find the User and return 404 when not found
-	if err := db.Where("id = ? OR username = ? OR email = ?", id, id, id).First(&user).Error; err != nil {
+	if err := db.Where("id = ?", userId).First(&user).Error; err != nil {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 			"status": http.StatusNotFound,
 			"error":  err.Error(),
@@ -337,17 +167,23 @@ func UpdateUserEmail(c *gin.Context) {
 	c.JSON(http.StatusOK, input)
 }
 
-// Update the email of a user
+// Update the password of a user
 func UpdateUserPassword(c *gin.Context) {
 	// Get the database connection from the context
 	db := c.MustGet("db").(*gorm.DB)
 	id := c.Param("id")
 
+	// Check if the logged in user may update the user
+	userId, ok := managedUserId(c, id)
+	if !ok {
+		return
+	}
+
 	// Initialize the User object
 	var user models.User
 
 	// Find the User and return 404 when not found
-	if err := db.Where("id = ? OR username = ? OR email = ?", id, id, id).First(&user).Error; err != nil {
+	if err := db.Where("id = ?", userId).First(&user).Error; err != nil {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 			"status": http.StatusNotFound,
 			"error":  err.Error(),
@@ -395,12 +231,34 @@ func UpdateUserPassword(c *gin.Context) {
 		return
 	}
 
+	// Check the new password against the password policy
+	if err := utilities.CheckPasswordPolicy(input.NewPassword, user.Username, user.Email, user.FirstName, user.LastName); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
 	// Create an updated user object
 	var updatedUser models.User = user
 	updatedUser.HashedPassword = utilities.HashPassword(input.NewPassword)
 
-	// Save the Updated User to the Database
-	db.Model(&user).Updates(updatedUser)
+	// Save the Updated User to the Database and end the other sessions of the user, the session of the request is kept
+	current := c.MustGet("session").(models.Session)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(updatedUser).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND id <> ?", user.Id, current.Id).Delete(&models.Session{}).Error
+	})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
 
 	// Return the updated fields of the user object
 	c.JSON(http.StatusOK, input)
@@ -412,11 +270,17 @@ func UpdateUserAffiliation(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	id := c.Param("id")
 
+	// Check if the logged in user may update the user
+	userId, ok := managedUserId(c, id)
+	if !ok {
+		return
+	}
+
 	// Initialize the User object
 	var user models.User
 
 	// Find the User and return 404 when not found
-	if err := db.Where("id = ? OR username = ? OR email = ?", id, id, id).First(&user).Error; err != nil {
+	if err := db.Where("id = ?", userId).First(&user).Error; err != nil {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 			"status": http.StatusNotFound,
 			"error":  err.Error(),
@@ -466,7 +330,7 @@ func UpdateUserAffiliation(c *gin.Context) {
 	c.JSON(http.StatusOK, input)
 }
 
-// Update the role of a user
+// Update the role of a user, only for admins
 func UpdateUserRole(c *gin.Context) {
 	// Get the database connection from the context
 	db := c.MustGet("db").(*gorm.DB)
@@ -506,6 +370,11 @@ func UpdateUserRole(c *gin.Context) {
 		return
 	}
 
+	// Check that the admin does not lock itself out
+	if !checkSelfDemotion(c, []int64{user.Id}, input.Role) {
+		return
+	}
+
 	// Check if input is the same as the role of the user
 	if user.Role == input.Role {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
@@ -532,11 +401,17 @@ func UpdateUserLastseen(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	id := c.Param("id")
 
+	// Check if the logged in user may update the user
+	userId, ok := managedUserId(c, id)
+	if !ok {
+		return
+	}
+
 	// Initialize the User object
 	var user models.User
 
 	// Find the User and return 404 when not found
-	if err := db.Where("id = ? OR username = ? OR email = ?", id, id, id).First(&user).Error; err != nil {
+	if err := db.Where("id = ?", userId).First(&user).Error; err != nil {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 			"status": http.StatusNotFound,
 			"error":  err.Error(),
@@ -577,31 +452,388 @@ func UpdateUserLastseen(c *gin.Context) {
 	c.JSON(http.StatusOK, input)
 }
 
-// Deletes the user
-func DeleteUser(c *gin.Context) {
+// Search the users for admins, filtered by ?q= (name, username or email), ?role=, ?affiliation=, ?region=, ?status=,
+// ?lastseenbefore= and ?lastseenafter= (milliseconds), paged with ?limit= and ?offset=
+//
+//	GET /v1/users/?q=yilmaz&region=İstanbul&lastseenbefore=1672531200000&limit=50
+func GetUsers(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Parse the limit and the offset
+	limit, offset := 100, 0
+	if limitQuery := c.Query("limit"); limitQuery != "" {
+		parsed, err := strconv.Atoi(limitQuery)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  "limit must be between 1 and 1000",
+			})
+			return
+		}
+		limit = parsed
+	}
+	if offsetQuery := c.Query("offset"); offsetQuery != "" {
+		parsed, err := strconv.Atoi(offsetQuery)
+		if err != nil || parsed < 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  "offset must be a positive number",
+			})
+			return
+		}
+		offset = parsed
+	}
+
+	// Filter the users and count all matches for the paging
+	query, ok := filterUsers(c, db)
+	if !ok {
+		return
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Find the users of the page
+	var users []models.User
+	if err := query.Order("last_name, first_name, id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Users without sensitive information
+	userInformation := make([]models.UserInformation, 0, len(users))
+	for _, user := range users {
+		userInformation = append(userInformation, newUserInformation(user))
+	}
+
+	// Return the users
+	c.JSON(http.StatusOK, gin.H{
+		"status": http.StatusOK,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+		"users":  userInformation,
+	})
+}
+
+// Export the users as csv for admins, filtered like GetUsers but without paging
+//
+//	GET /v1/users/export/?status=active&affiliation=AKP
+func ExportUsers(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Filter and find the users
+	query, ok := filterUsers(c, db)
+	if !ok {
+		return
+	}
+	var users []models.User
+	if err := query.Order("last_name, first_name, id").Find(&users).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Write the users without sensitive information as csv
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write([]string{"id", "username", "firstname", "lastname", "email", "role", "affiliation", "region", "status", "createdat", "lastseen"})
+	for _, user := range users {
+		writer.Write([]string{
+			strconv.FormatInt(user.Id, 10),
+			csvField(user.Username),
+			csvField(user.FirstName),
+			csvField(user.LastName),
+			csvField(user.Email),
+			csvField(user.Role),
+			csvField(user.Affiliation),
+			csvField(user.Region),
+			user.Status,
+			strconv.FormatInt(user.CreatedAt, 10),
+			strconv.FormatInt(user.LastSeen, 10),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the csv as a download
+	c.Header("Content-Disposition", "attachment; filename=\"users.csv\"")
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buffer.Bytes())
+}
+
+// Assign a role to many users at once, admins cannot take the admin role from themselves
+func BulkAssignRole(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Initialize the bulk role input
+	var input models.BulkRoleInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Check that the admin does not lock itself out
+	if !checkSelfDemotion(c, input.UserIds, input.Role) {
+		return
+	}
+
+	// Find the users of the ids and update their roles
+	var users []models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id IN ?", input.UserIds).Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+		return tx.Model(&models.User{}).Where("id IN ?", input.UserIds).Update("role", input.Role).Error
+	})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Collect the ids which were updated and which were not found
+	found := make(map[int64]bool, len(users))
+	updated := make([]int64, 0, len(users))
+	for _, user := range users {
+		found[user.Id] = true
+		updated = append(updated, user.Id)
+	}
+	notFound := make([]int64, 0)
+	for _, userId := range input.UserIds {
+		if !found[userId] {
+			notFound = append(notFound, userId)
+			found[userId] = true
+		}
+	}
+
+	// Return the updated users
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"message":  "the role has been assigned",
+		"role":     input.Role,
+		"userids":  updated,
+		"notfound": notFound,
+	})
+}
+
+// Deactivate a user instead of deleting it, the user is logged out everywhere and cannot log in until it is reactivated
+//
+// Access tokens which were issued before stay valid until they expire.
+func DeactivateUser(c *gin.Context) {
 	// Get the database connection from the context
 	db := c.MustGet("db").(*gorm.DB)
 	id := c.Param("id")
 
-	// Initialize the User object
+	// Find the active user and return 404 when not found, pending users are rejected instead
 	var user models.User
-
-	// Find the User and return 404 when not found
-	if err := db.Where("id = ? OR username = ? OR email = ?", id, id, id).First(&user).Error; err != nil {
+	if err := db.Where("id = ? AND status = ?", id, models.UserActive).First(&user).Error; err != nil {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 			"status": http.StatusNotFound,
+			"error":  "there is no such active user",
+		})
+		return
+	}
+
+	// Check that the admin does not lock itself out
+	admin := c.MustGet("user").(models.User)
+	if user.Id == admin.Id {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "you cannot deactivate yourself",
+		})
+		return
+	}
+
+	// Deactivate the user and delete its sessions
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"status":         models.UserDeactivated,
+			"deactivated_by": admin.Id,
+			"deactivated_at": utilities.GetCurrentTime(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.Id).Delete(&models.Session{}).Error
+	})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
 			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Delete the user and return
-	if err := db.Delete(&user); err.Error != nil {
+	// Return Success JSON
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "the user has been deactivated",
+		"userid":  user.Id,
+	})
+}
+
+// Reactivate a deactivated user, the user can log in again afterwards
+func ReactivateUser(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	id := c.Param("id")
+
+	// Find the deactivated user and return 404 when not found
+	var user models.User
+	if err := db.Where("id = ? AND status = ?", id, models.UserDeactivated).First(&user).Error; err != nil {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 			"status": http.StatusNotFound,
-			"error":  err.Error,
+			"error":  "there is no such deactivated user",
+		})
+		return
+	}
+
+	// Activate the user
+	if err := db.Model(&user).Updates(map[string]interface{}{
+		"status":         models.UserActive,
+		"deactivated_by": 0,
+		"deactivated_at": 0,
+	}).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
 		})
 		return
 	}
-	c.JSON(http.StatusOK, user)
+
+	// Return Success JSON
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "the user has been reactivated",
+		"userid":  user.Id,
+	})
+}
+
+// Check that an admin does not take the admin role from itself, aborts the request if one of the users is the logged in admin
+func checkSelfDemotion(c *gin.Context, userIds []int64, role string) bool {
+	if role == models.RoleAdmin {
+		return true
+	}
+	admin := c.MustGet("user").(models.User)
+	for _, userId := range userIds {
+		if userId == admin.Id {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  "you cannot take the admin role from yourself",
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// Filter the users by the query parameters of the request, aborts the request if one is invalid
+func filterUsers(c *gin.Context, db *gorm.DB) (*gorm.DB, bool) {
+	query := db.Model(&models.User{})
+
+	// Every word of the search has to be found, so that a full name matches the first and the last name
+	// The escape character is set explicitly, not every database escapes with a backslash by default
+	escaper := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	for _, word := range strings.Fields(c.Query("q")) {
+		pattern := "%" + escaper.Replace(word) + "%"
+		query = query.Where("username LIKE ? ESCAPE '!' OR email LIKE ? ESCAPE '!' OR first_name LIKE ? ESCAPE '!' OR last_name LIKE ? ESCAPE '!'",
+			pattern, pattern, pattern, pattern)
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if affiliation := c.Query("affiliation"); affiliation != "" {
+		query = query.Where("affiliation = ?", affiliation)
+	}
+	if region := c.Query("region"); region != "" {
+		query = query.Where("region = ?", region)
+	}
+	if status := c.Query("status"); status != "" {
+		if status != models.UserPending && status != models.UserActive && status != models.UserDeactivated {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  "status must be pending, active or deactivated",
+			})
+			return nil, false
+		}
+		query = query.Where("status = ?", status)
+	}
+	for parameter, condition := range map[string]string{"lastseenbefore": "last_seen < ?", "lastseenafter": "last_seen >= ?"} {
+		if value := c.Query(parameter); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"status": http.StatusBadRequest,
+					"error":  parameter + " must be a number",
+				})
+				return nil, false
+			}
+			query = query.Where(condition, parsed)
+		}
+	}
+	return query.Session(&gorm.Session{}), true
+}
+
+// Get the user without sensitive information
+func newUserInformation(user models.User) models.UserInformation {
+	return models.UserInformation{
+		Id:          user.Id,
+		Username:    user.Username,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Email:       user.Email,
+		CreatedAt:   user.CreatedAt,
+		LastSeen:    user.LastSeen,
+		Role:        user.Role,
+		Affiliation: user.Affiliation,
+		Status:      user.Status,
+		Region:      user.Region,
+	}
+}
+
+// Escape a csv field which a spreadsheet would run as a formula
+func csvField(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
 }
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/auth/models"
+	"github.com/yzaimoglu/election/auth/utilities"
+)
+
+func TestUpdateUserPasswordEndsTheOtherSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDatabase(t)
+	setTestArgon2Params(t, "1024")
+	user := newTestUser(t, db, "ahmet.yilmaz", utilities.HashPassword("eski şifre 1234"))
+	other := newTestUser(t, db, "mehmet.kaya", utilities.HashPassword("eski şifre 1234"))
+
+	// The user is logged in on two devices, the other user on one
+	now := utilities.GetCurrentTime()
+	sessions := []models.Session{
+		{UserId: user.Id, SessionToken: "session-1", CreatedAt: now, ExpiresAt: now + 60000},
+		{UserId: user.Id, SessionToken: "session-2", CreatedAt: now, ExpiresAt: now + 60000},
+		{UserId: other.Id, SessionToken: "session-3", CreatedAt: now, ExpiresAt: now + 60000},
+	}
+	if err := db.Create(&sessions).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Change the password from the first session
+	data, err := json.Marshal(models.UpdatePasswordInput{OldPassword: "eski şifre 1234", NewPassword: "doğru at pili zımba"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: strconv.FormatInt(user.Id, 10)}}
+	c.Set("db", db)
+	c.Set("user", user)
+	c.Set("session", sessions[0])
+	UpdateUserPassword(c)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got the status %d with %s, want %d", recorder.Code, recorder.Body.String(), http.StatusOK)
+	}
+
+	// Only the session of the change and the sessions of the other user are left
+	var left []models.Session
+	db.Order("id").Find(&left)
+	if len(left) != 2 || left[0].Id != sessions[0].Id || left[1].Id != sessions[2].Id {
+		t.Fatalf("got the sessions %+v after the change, want the first and the third", left)
+	}
+	var stored models.User
+	db.First(&stored, user.Id)
+	if !utilities.CheckPassword(stored.HashedPassword, "doğru at pili zımba") {
+		t.Fatal("the password has not been changed")
+	}
+}
+
+func TestFilterUsersSearchesEveryWord(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDatabase(t)
+	for _, user := range []models.User{
+		{Username: "ahmet.yilmaz", Email: "ahmet@example.com", FirstName: "Ahmet", LastName: "Yılmaz", Role: models.RoleUser},
+		{Username: "ahmet.kaya", Email: "kaya@example.com", FirstName: "Ahmet", LastName: "Kaya", Role: models.RoleAdmin},
+		{Username: "ayse.yilmaz", Email: "ayse@example.com", FirstName: "Ayşe", LastName: "Yılmaz", Role: models.RoleUser},
+		{Username: "percent", Email: "percent@example.com", FirstName: "100%", LastName: "Kaya", Role: models.RoleUser},
+	} {
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query     string
+		usernames []string
+	}{
+		{"q=Ahmet", []string{"ahmet.yilmaz", "ahmet.kaya"}},
+		{"q=Ahmet+Yılmaz", []string{"ahmet.yilmaz"}},
+		{"q=++Yılmaz+Ahmet+", []string{"ahmet.yilmaz"}},
+		{"q=Ahmet&role=Y%C3%B6netici", []string{"ahmet.kaya"}},
+		{"q=Yılmaz&role=Y%C3%B6netici", []string{}},
+		{"q=0%25", []string{"percent"}},
+		{"q=_", []string{}},
+		{"q=ayse.yilmaz", []string{"ayse.yilmaz"}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/?"+test.query, nil)
+			query, ok := filterUsers(c, db)
+			if !ok {
+				t.Fatal("the filters have been rejected")
+			}
+			usernames := []string{}
+			if err := query.Order("id").Pluck("username", &usernames).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(usernames, test.usernames) {
+				t.Fatalf("got the users %v, want %v", usernames, test.usernames)
+			}
+		})
+	}
+}
@@ -0,0 +1,492 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/yzaimoglu/election/auth/models"
+	"github.com/yzaimoglu/election/auth/utilities"
+	"gorm.io/gorm"
+)
+
+// Time between the begin and the finish request of a webauthn ceremony
+const ceremonyLifetime = 5 * time.Minute
+
+// Returned if a webauthn ceremony is unknown, of another user or has expired
+var errInvalidCeremony = errors.New("the security key request is invalid or has expired")
+
+// User of the webauthn library with the credentials from the database
+type webAuthnUser struct {
+	user        models.User
+	credentials []webauthn.Credential
+}
+
+func (user webAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatInt(user.user.Id, 10))
+}
+
+func (user webAuthnUser) WebAuthnName() string {
+	return user.user.Username
+}
+
+func (user webAuthnUser) WebAuthnDisplayName() string {
+	return user.user.FirstName + " " + user.user.LastName
+}
+
+func (user webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (user webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return user.credentials
+}
+
+// Begin the registration of a security key for the logged in user, the options are passed to navigator.credentials.create
+func BeginWebAuthnRegistration(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	user := c.MustGet("user").(models.User)
+
+	// Load the credentials of the user, they are excluded so that a key is not registered twice
+	webAuthn, waUser, err := loadWebAuthnUser(db, user)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(waUser.credentials))
+	for _, credential := range waUser.credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	// Create the options and remember the challenge
+	options, sessionData, err := webAuthn.BeginRegistration(waUser, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	ceremony, err := saveCeremony(db, user.Id, models.CeremonyRegistration, sessionData)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the options
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"ceremony": ceremony,
+		"options":  options,
+	})
+}
+
+// Finish the registration of a security key with the response of navigator.credentials.create
+func FinishWebAuthnRegistration(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	user := c.MustGet("user").(models.User)
+
+	// Initialize the registration input
+	var input models.WebAuthnRegistrationInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Find the ceremony, it can only be finished once
+	sessionData, err := takeCeremony(db, user.Id, models.CeremonyRegistration, input.Ceremony)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Verify the attestation of the key
+	webAuthn, waUser, err := loadWebAuthnUser(db, user)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(input.Credential))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the security key response is malformed",
+		})
+		return
+	}
+	credential, err := webAuthn.CreateCredential(waUser, sessionData, parsed)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the security key could not be verified",
+		})
+		return
+	}
+
+	// Store the credential
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+	storedCredential := models.WebAuthnCredential{
+		UserId:          user.Id,
+		Name:            input.Name,
+		CredentialId:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:       base64.RawURLEncoding.EncodeToString(credential.PublicKey),
+		AttestationType: credential.AttestationType,
+		AAGUID:          hex.EncodeToString(credential.Authenticator.AAGUID),
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      strings.Join(transports, ","),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       utilities.GetCurrentTime(),
+	}
+	if err := db.Create(&storedCredential).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the credential
+	c.JSON(http.StatusCreated, gin.H{
+		"status":     http.StatusCreated,
+		"message":    "the security key has been registered",
+		"credential": storedCredential,
+	})
+}
+
+// Get the security keys of the logged in user
+func GetWebAuthnCredentials(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	user := c.MustGet("user").(models.User)
+
+	// Find the credentials
+	var credentials []models.WebAuthnCredential
+	if err := db.Where("user_id = ?", user.Id).Order("created_at").Find(&credentials).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the credentials
+	c.JSON(http.StatusOK, gin.H{
+		"status":      http.StatusOK,
+		"credentials": credentials,
+	})
+}
+
+// Remove a security key of the logged in user, the last second factor of a user cannot be removed
+func DeleteWebAuthnCredential(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	user := c.MustGet("user").(models.User)
+	id := c.Param("id")
+
+	// Find the credential of the user
+	var credential models.WebAuthnCredential
+	if err := db.Where("id = ? AND user_id = ?", id, user.Id).First(&credential).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  "there is no such security key",
+		})
+		return
+	}
+
+	// Keep the last key of users without totp
+	var count int64
+	if err := db.Model(&models.WebAuthnCredential{}).Where("user_id = ?", user.Id).Count(&count).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+	if count <= 1 && user.TOTP == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the last second factor cannot be removed",
+		})
+		return
+	}
+
+	// Delete the credential
+	if err := db.Delete(&credential).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return Success JSON
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "the security key has been removed",
+	})
+}
+
+// Begin a login with a security key, the options are passed to navigator.credentials.get and the assertion is sent to
+// the login with the ceremony instead of the totp
+//
+// Unknown users and users without keys get options of the same form, so that they cannot be told apart.
+func BeginWebAuthnLogin(c *gin.Context) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Initialize the webauthn login input
+	var input models.WebAuthnLoginInput
+
+	// Bind the input from the request body to the Input object
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(input); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Create the options for the keys of the user and remember the challenge
+	var user models.User
+	var options *protocol.CredentialAssertion
+	var ceremony string
+	err := db.Where("username = ? AND email = ?", input.Username, input.Email).First(&user).Error
+	if err == nil {
+		var webAuthn *webauthn.WebAuthn
+		var waUser webAuthnUser
+		webAuthn, waUser, err = loadWebAuthnUser(db, user)
+		if err == nil && len(waUser.credentials) > 0 {
+			var sessionData *webauthn.SessionData
+			options, sessionData, err = webAuthn.BeginLogin(waUser)
+			if err == nil {
+				ceremony, err = saveCeremony(db, user.Id, models.CeremonyLogin, sessionData)
+			}
+		}
+	}
+	if options == nil || err != nil {
+		options, ceremony, err = dummyLoginOptions()
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the options
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"ceremony": ceremony,
+		"options":  options,
+	})
+}
+
+// Verify the security key assertion of a login, the sign count of the key is updated
+func verifyWebAuthnLogin(db *gorm.DB, user models.User, input models.LoginInput) bool {
+	sessionData, err := takeCeremony(db, user.Id, models.CeremonyLogin, input.WebAuthnCeremony)
+	if err != nil {
+		return false
+	}
+	webAuthn, waUser, err := loadWebAuthnUser(db, user)
+	if err != nil {
+		log.Println("error loading the security keys: " + err.Error())
+		return false
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(input.WebAuthn))
+	if err != nil {
+		return false
+	}
+	credential, err := webAuthn.ValidateLogin(waUser, sessionData, parsed)
+	if err != nil {
+		return false
+	}
+
+	// A sign count lower than the stored one means that the key may have been cloned
+	if credential.Authenticator.CloneWarning {
+		log.Println("the security key " + base64.RawURLEncoding.EncodeToString(credential.ID) + " may have been cloned")
+		return false
+	}
+	if err := db.Model(&models.WebAuthnCredential{}).
+		Where("credential_id = ?", base64.RawURLEncoding.EncodeToString(credential.ID)).
+		Updates(map[string]interface{}{"sign_count": credential.Authenticator.SignCount, "last_used_at": utilities.GetCurrentTime()}).Error; err != nil {
+		log.Println("error updating the security key: " + err.Error())
+	}
+	return true
+}
+
+// Create the webauthn relying party, set with AUTH_WEBAUTHN_RP_ID and AUTH_WEBAUTHN_ORIGINS (separated by commas)
+func newWebAuthn() (*webauthn.WebAuthn, error) {
+	return webauthn.New(&webauthn.Config{
+		RPID:          utilities.GetEnv("AUTH_WEBAUTHN_RP_ID", "localhost"),
+		RPDisplayName: "secim2023.org",
+		RPOrigins:     strings.Split(utilities.GetEnv("AUTH_WEBAUTHN_ORIGINS", frontendURL("")), ","),
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: ceremonyLifetime},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: ceremonyLifetime},
+		},
+	})
+}
+
+// Create the relying party and load the credentials of a user
+func loadWebAuthnUser(db *gorm.DB, user models.User) (*webauthn.WebAuthn, webAuthnUser, error) {
+	waUser := webAuthnUser{user: user}
+	webAuthn, err := newWebAuthn()
+	if err != nil {
+		return nil, waUser, err
+	}
+
+	var storedCredentials []models.WebAuthnCredential
+	if err := db.Where("user_id = ?", user.Id).Find(&storedCredentials).Error; err != nil {
+		return nil, waUser, err
+	}
+	for _, stored := range storedCredentials {
+		id, err := base64.RawURLEncoding.DecodeString(stored.CredentialId)
+		if err != nil {
+			continue
+		}
+		publicKey, err := base64.RawURLEncoding.DecodeString(stored.PublicKey)
+		if err != nil {
+			continue
+		}
+		aaguid, _ := hex.DecodeString(stored.AAGUID)
+		var transports []protocol.AuthenticatorTransport
+		for _, transport := range strings.Split(stored.Transports, ",") {
+			if transport != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(transport))
+			}
+		}
+		waUser.credentials = append(waUser.credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       publicKey,
+			AttestationType: stored.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: stored.BackupEligible,
+				BackupState:    stored.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    aaguid,
+				SignCount: stored.SignCount,
+			},
+		})
+	}
+	return webAuthn, waUser, nil
+}
+
+// Store the session data of a ceremony and return the token to finish it
+func saveCeremony(db *gorm.DB, userId int64, kind string, sessionData *webauthn.SessionData) (string, error) {
+	tokenBytes, err := utilities.GenerateRandomBytes(32)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(sessionData)
+	if err != nil {
+		return "", err
+	}
+	token := utilities.ToBase64(tokenBytes)
+	now := utilities.GetCurrentTime()
+
+	// Delete the expired ceremonies on the way
+	if err := db.Where("expires_at <= ?", now).Delete(&models.WebAuthnCeremony{}).Error; err != nil {
+		log.Println("error deleting the expired ceremonies: " + err.Error())
+	}
+	ceremony := models.WebAuthnCeremony{
+		UserId:      userId,
+		Kind:        kind,
+		HashedToken: utilities.HashSHA512(token),
+		SessionData: string(data),
+		ExpiresAt:   now + ceremonyLifetime.Milliseconds(),
+	}
+	return token, db.Create(&ceremony).Error
+}
+
+// Find and delete the ceremony of a token, so that its challenge can only be answered once
+func takeCeremony(db *gorm.DB, userId int64, kind string, token string) (webauthn.SessionData, error) {
+	var sessionData webauthn.SessionData
+	var ceremony models.WebAuthnCeremony
+	if err := db.Where("hashed_token = ? AND kind = ? AND user_id = ?", utilities.HashSHA512(token), kind, userId).First(&ceremony).Error; err != nil {
+		return sessionData, errInvalidCeremony
+	}
+	result := db.Where("id = ?", ceremony.Id).Delete(&models.WebAuthnCeremony{})
+	if result.Error != nil || result.RowsAffected != 1 || utilities.GetCurrentTime() >= ceremony.ExpiresAt {
+		return sessionData, errInvalidCeremony
+	}
+	if err := json.Unmarshal([]byte(ceremony.SessionData), &sessionData); err != nil {
+		return sessionData, errInvalidCeremony
+	}
+	return sessionData, nil
+}
+
+// Create login options with a random challenge and no keys for users without keys, the ceremony is not stored
+func dummyLoginOptions() (*protocol.CredentialAssertion, string, error) {
+	challenge, err := protocol.CreateChallenge()
+	if err != nil {
+		return nil, "", err
+	}
+	tokenBytes, err := utilities.GenerateRandomBytes(32)
+	if err != nil {
+		return nil, "", err
+	}
+	options := &protocol.CredentialAssertion{Response: protocol.PublicKeyCredentialRequestOptions{
+		Challenge:        challenge,
+		Timeout:          int(ceremonyLifetime.Milliseconds()),
+		RelyingPartyID:   utilities.GetEnv("AUTH_WEBAUTHN_RP_ID", "localhost"),
+		UserVerification: protocol.VerificationPreferred,
+	}}
+	return options, utilities.ToBase64(tokenBytes), nil
+}
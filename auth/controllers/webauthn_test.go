@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/yzaimoglu/election/auth/models"
+)
+
+// Origin and relying party of the tests
+const (
+	testWebAuthnOrigin = "https://localhost"
+	testWebAuthnRPID   = "localhost"
+)
+
+// Security key in software with a P-256 key, answers the ceremonies like a browser would
+type softAuthenticator struct {
+	credentialId []byte
+	key          *ecdsa.PrivateKey
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialId := make([]byte, 32)
+	if _, err := rand.Read(credentialId); err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{credentialId: credentialId, key: key}
+}
+
+// Create the response of navigator.credentials.create with the none attestation
+func (authenticator *softAuthenticator) register(t *testing.T, options protocol.CredentialCreation) json.RawMessage {
+	t.Helper()
+	clientData := clientDataJSON(t, "webauthn.create", options.Response.Challenge.String())
+
+	// Authenticator data with the attested credential, the flags are user present, user verified and attested
+	publicKey, err := cbor.Marshal(map[int]interface{}{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: authenticator.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: authenticator.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authData := authenticator.authenticatorData(0x45)
+	authData = append(authData, make([]byte, 16)...) // aaguid
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(authenticator.credentialId)))
+	authData = append(append(authData, authenticator.credentialId...), publicKey...)
+
+	attestationObject, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authenticator.credential(t, map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"attestationObject": encode(attestationObject),
+	})
+}
+
+// Create the response of navigator.credentials.get, the sign count is increased before
+func (authenticator *softAuthenticator) login(t *testing.T, options protocol.CredentialAssertion, userHandle []byte) json.RawMessage {
+	t.Helper()
+	authenticator.signCount++
+	clientData := clientDataJSON(t, "webauthn.get", options.Response.Challenge.String())
+
+	// Sign the authenticator data and the hash of the client data, the flags are user present and user verified
+	authData := authenticator.authenticatorData(0x05)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, authenticator.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authenticator.credential(t, map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(userHandle),
+	})
+}
+
+// Create the authenticator data without the attested credential
+func (authenticator *softAuthenticator) authenticatorData(flags byte) []byte {
+	rpIdHash := sha256.Sum256([]byte(testWebAuthnRPID))
+	authData := append(rpIdHash[:], flags)
+	return binary.BigEndian.AppendUint32(authData, authenticator.signCount)
+}
+
+// Wrap a response of the authenticator in the public key credential sent by the browser
+func (authenticator *softAuthenticator) credential(t *testing.T, response map[string]string) json.RawMessage {
+	t.Helper()
+	credential, err := json.Marshal(map[string]interface{}{
+		"id":       encode(authenticator.credentialId),
+		"rawId":    encode(authenticator.credentialId),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credential
+}
+
+func clientDataJSON(t *testing.T, ceremonyType string, challenge string) []byte {
+	t.Helper()
+	clientData, err := json.Marshal(map[string]string{
+		"type":      ceremonyType,
+		"challenge": challenge,
+		"origin":    testWebAuthnOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientData
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestWebAuthnRegistrationAndLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("AUTH_WEBAUTHN_RP_ID", testWebAuthnRPID)
+	t.Setenv("AUTH_WEBAUTHN_ORIGINS", testWebAuthnOrigin)
+	db := newTestDatabase(t)
+
+	alice := models.User{Username: "alice", Email: "alice@example.com", FirstName: "Alice", LastName: "Yılmaz", Role: models.RoleUser}
+	bob := models.User{Username: "bob", Email: "bob@example.com", FirstName: "Bob", LastName: "Kaya", Role: models.RoleUser}
+	if err := db.Create(&alice).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&bob).Error; err != nil {
+		t.Fatal(err)
+	}
+	authenticator := newSoftAuthenticator(t)
+
+	// Begin the registration
+	status, response := performJSONRequest(t, BeginWebAuthnRegistration, db, &alice, nil)
+	if status != http.StatusOK {
+		t.Fatalf("begin registration: got status %d", status)
+	}
+	var ceremony string
+	var creation protocol.CredentialCreation
+	decodeField(t, response, "ceremony", &ceremony)
+	decodeField(t, response, "options", &creation)
+	registration := models.WebAuthnRegistrationInput{Ceremony: ceremony, Name: "YubiKey", Credential: authenticator.register(t, creation)}
+
+	// The ceremony of another user is not found and stays usable for its user
+	if status, _ := performJSONRequest(t, FinishWebAuthnRegistration, db, &bob, registration); status != http.StatusBadRequest {
+		t.Fatalf("finish registration as another user: got status %d, want %d", status, http.StatusBadRequest)
+	}
+	if status, response := performJSONRequest(t, FinishWebAuthnRegistration, db, &alice, registration); status != http.StatusCreated {
+		t.Fatalf("finish registration: got status %d with %s", status, response["error"])
+	}
+
+	// The ceremony can only be finished once
+	if status, _ := performJSONRequest(t, FinishWebAuthnRegistration, db, &alice, registration); status != http.StatusBadRequest {
+		t.Fatalf("finish registration again: got status %d, want %d", status, http.StatusBadRequest)
+	}
+	var credentials int64
+	db.Model(&models.WebAuthnCredential{}).Where("user_id = ?", alice.Id).Count(&credentials)
+	if credentials != 1 {
+		t.Fatalf("got %d stored credentials, want 1", credentials)
+	}
+
+	// Begin a login, the options allow the registered key
+	beginLogin := func() (string, protocol.CredentialAssertion) {
+		t.Helper()
+		status, response := performJSONRequest(t, BeginWebAuthnLogin, db, nil, models.WebAuthnLoginInput{Username: alice.Username, Email: alice.Email})
+		if status != http.StatusOK {
+			t.Fatalf("begin login: got status %d", status)
+		}
+		var ceremony string
+		var assertion protocol.CredentialAssertion
+		decodeField(t, response, "ceremony", &ceremony)
+		decodeField(t, response, "options", &assertion)
+		return ceremony, assertion
+	}
+	ceremony, assertion := beginLogin()
+	if allowed := assertion.Response.AllowedCredentials; len(allowed) != 1 || !bytes.Equal(allowed[0].CredentialID, authenticator.credentialId) {
+		t.Fatalf("got the allowed credentials %v, want the registered key", allowed)
+	}
+	userHandle := []byte(strconv.FormatInt(alice.Id, 10))
+	login := models.LoginInput{WebAuthnCeremony: ceremony, WebAuthn: authenticator.login(t, assertion, userHandle)}
+
+	// The assertion is rejected for another user and accepted for the user of the ceremony
+	if verifyWebAuthnLogin(db, bob, login) {
+		t.Fatal("the login of alice has been accepted for bob")
+	}
+	if !verifyWebAuthnLogin(db, alice, login) {
+		t.Fatal("the login has been rejected")
+	}
+	var stored models.WebAuthnCredential
+	db.Where("user_id = ?", alice.Id).First(&stored)
+	if stored.SignCount != 1 || stored.LastUsedAt == 0 {
+		t.Fatalf("got the sign count %d and last use %d, want the sign count 1 and the time of the login", stored.SignCount, stored.LastUsedAt)
+	}
+
+	// The ceremony can only be used once
+	if verifyWebAuthnLogin(db, alice, login) {
+		t.Fatal("the ceremony has been used twice")
+	}
+
+	// A key whose sign count does not increase may have been cloned
+	clone := *authenticator
+	clone.signCount = 0
+	ceremony, assertion = beginLogin()
+	if verifyWebAuthnLogin(db, alice, models.LoginInput{WebAuthnCeremony: ceremony, WebAuthn: clone.login(t, assertion, userHandle)}) {
+		t.Fatal("the login with a cloned key has been accepted")
+	}
+	db.Where("user_id = ?", alice.Id).First(&stored)
+	if stored.SignCount != 1 {
+		t.Fatalf("got the sign count %d after the cloned key, want 1", stored.SignCount)
+	}
+
+	// The original key still works
+	ceremony, assertion = beginLogin()
+	if !verifyWebAuthnLogin(db, alice, models.LoginInput{WebAuthnCeremony: ceremony, WebAuthn: authenticator.login(t, assertion, userHandle)}) {
+		t.Fatal("the login after the cloned key has been rejected")
+	}
+}
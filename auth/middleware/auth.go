@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/auth/models"
+	"gorm.io/gorm"
+)
+
+// RequireSession only lets requests with a valid session through, the session and its user are set to the context
+// as "session" and "user"
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := sessionUser(c); !ok {
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole only lets requests with a valid session of a user with one of the roles through
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := sessionUser(c)
+		if !ok {
+			return
+		}
+		for _, role := range roles {
+			if user.Role == role {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  "you are not allowed to do this",
+		})
+	}
+}
+
+// Get the user of the session of the request and set both to the context, aborts the request if there is none
+func sessionUser(c *gin.Context) (models.User, bool) {
+	// Get the database connection from the context
+	db := c.MustGet("db").(*gorm.DB)
+	sessionToken, err := c.Cookie("session-token")
+	if err != nil {
+		sessionToken = c.GetHeader("Authentication-Session-Token")
+	}
+
+	// Check the database for an active session of the token, it is extended by the request
+	var user models.User
+	session, err := models.FindActiveSession(db, sessionToken)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  "you are not logged in",
+		})
+		return user, false
+	}
+
+	// Find the user of the session, users who are not active are treated as logged out
+	if err := db.Where("id = ? AND status = ?", session.UserId, models.UserActive).First(&user).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  "you are not logged in",
+		})
+		return user, false
+	}
+
+	c.Set("session", session)
+	c.Set("user", user)
+	return user, true
+}
@@ -0,0 +1,27 @@
+package models
+
+// Model for a login attempt, kept so that admins can look into suspicious logins
+type LoginAttempt struct {
+	Id        int64  `json:"id"`
+	UserId    int64  `json:"userid"` // 0 if there is no user with the username and email
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"useragent"`
+	Success   bool   `json:"success"`
+	Reason    string `json:"reason"` // wrong password
+	CreatedAt int64  `json:"createdat"`
+}
+
+// Reasons of the login attempts
+const (
+	LoginSuccess           = "success"
+	LoginLocked            = "locked"
+	LoginUnknownUser       = "unknown user"
+	LoginWrongPassword     = "wrong password"
+	LoginWrongOTP          = "wrong otp"
+	LoginWrongRecoveryCode = "wrong recovery code"
+	LoginWrongWebAuthn     = "wrong security key"
+	LoginInactive          = "inactive"
+	LoginNoSecondFactor    = "no second factor" // the totp was reset and no security key is registered
+)
@@ -1,18 +1,28 @@
 package models
 
+import "encoding/json"
+
 // Input required to login
 type LoginInput struct {
-	Username      string `json:"username" validate:"required"`
-	Email         string `json:"email" validate:"required"`
-	PlainPassword string `json:"plainpassword" validate:"required"`
-	TOTP          string `json:"totp" validate:"required"`
+	Username         string          `json:"username" validate:"required"`
+	Email            string          `json:"email" validate:"required"`
+	PlainPassword    string          `json:"plainpassword" validate:"required"`
+	TOTP             string          `json:"totp" validate:"required_without_all=RecoveryCode WebAuthn"`
+	RecoveryCode     string          `json:"recoverycode"`                                       // used instead of the totp if the device is lost
+	WebAuthn         json.RawMessage `json:"webauthn"`                                           // assertion of a security key, used instead of the totp
+	WebAuthnCeremony string          `json:"webauthnceremony" validate:"required_with=WebAuthn"` // returned when the webauthn login was begun
 }
 
 // Model for the session object
 type Session struct {
 	Id           int64  `json:"id"`
 	UserId       int64  `json:"userid"`
-	SessionToken string `json:"sessiontoken"`
+	SessionToken string `json:"sessiontoken,omitempty"`
 	CreatedAt    int64  `json:"createdat"`
-	ExpiresAt    int64  `json:"expiresat"`
+	ExpiresAt    int64  `json:"expiresat"`    // extended by every request until MaxExpiresAt
+	MaxExpiresAt int64  `json:"maxexpiresat"` // absolute end of the session
+	LastActiveAt int64  `json:"lastactiveat"`
+	IP           string `json:"ip"`
+	UserAgent    string `json:"useragent"`
+	Device       string `json:"device"` // Firefox on Windows
 }
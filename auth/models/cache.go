@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/yzaimoglu/election/auth/utilities"
+)
+
+// RedisConnection Options struct
+type RedisConnectionOptions struct {
+	Host        string
+	Password    string
+	MaxIdle     int
+	MaxActive   int
+	IdleTimeout time.Duration
+}
+
+// RedisConnection pool
+var redisConnectionPool *redis.Pool
+
+// Setup the cache
+func SetupCache() {
+	redisConnectionPool = newRedisPool()
+}
+
+// Initialize new redis pool
+func newRedisPool() *redis.Pool {
+	// Set options
+	options := RedisConnectionOptions{
+		Host:        utilities.GetEnv("AUTH_CACHE_HOST", "localhost") + ":" + utilities.GetEnv("AUTH_CACHE_PORT", "6379"),
+		Password:    utilities.GetEnv("AUTH_CACHE_PASSWORD", ""),
+		MaxIdle:     80,
+		MaxActive:   12000,
+		IdleTimeout: 240 * time.Second,
+	}
+
+	return &redis.Pool{
+		MaxIdle:     options.MaxIdle,
+		MaxActive:   options.MaxActive,
+		IdleTimeout: options.IdleTimeout,
+		Dial: func() (redis.Conn, error) {
+			c, err := redis.Dial("tcp", options.Host)
+			if err != nil {
+				return nil, err
+			}
+			if options.Password != "" {
+				if _, err := c.Do("AUTH", options.Password); err != nil {
+					c.Close()
+					return nil, err
+				}
+			}
+			return c, err
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Model for an invite of an admin, only the hash of the token sent by email is stored
+//
+// The role, affiliation and region of the invited user are set by the admin and cannot be chosen by the user.
+type Invite struct {
+	Id          int64  `json:"id"`
+	Email       string `json:"email"`
+	Role        string `json:"role"`
+	Affiliation string `json:"affiliation"`
+	Region      string `json:"region"`
+	HashedToken string `json:"-"`
+	CreatedBy   int64  `json:"createdby"`   // 0 for the invite of the first admin
+	AutoApprove bool   `json:"autoapprove"` // the user is activated without an approval once the totp is enrolled, only for the first admin
+	CreatedAt   int64  `json:"createdat"`
+	ExpiresAt   int64  `json:"expiresat"`
+	AcceptedAt  int64  `json:"acceptedat"` // 0 until the invite is accepted
+	UserId      int64  `json:"userid"`     // user who accepted the invite
+}
+
+// Model for the create invite input
+type CreateInviteInput struct {
+	Email       string `json:"email" validate:"required,email"`
+	Role        string `json:"role" validate:"required,oneof=Kullanıcı Yönetici"`
+	Affiliation string `json:"affiliation" validate:"required"`
+	Region      string `json:"region"`
+}
+
+// Model for the accept invite input
+type AcceptInviteInput struct {
+	Token         string `json:"token" validate:"required"`
+	FirstName     string `json:"firstname" validate:"required"`
+	LastName      string `json:"lastname" validate:"required"`
+	PlainPassword string `json:"plainpassword" validate:"required"`
+}
+
+// Get the time an invite can be accepted, set with AUTH_INVITE_HOURS
+func InviteLifetime() time.Duration {
+	return time.Duration(envInt("AUTH_INVITE_HOURS", 72)) * time.Hour
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/yzaimoglu/election/auth/utilities"
+)
+
+// Limits of the failed logins, set with the environment variables
+type LoginLimits struct {
+	MaxAccountFailures int64         // failures of an account until it is locked, AUTH_LOGIN_MAX_FAILURES
+	MaxIPFailures      int64         // failures from an ip until it is locked, AUTH_LOGIN_MAX_IP_FAILURES
+	Window             time.Duration // time after which the failures are forgotten, AUTH_LOGIN_WINDOW_SECONDS
+	Lockout            time.Duration // time an account or an ip stays locked, AUTH_LOGIN_LOCKOUT_SECONDS
+	MaxDelay           time.Duration // longest delay between two failures before the lockout
+}
+
+// Get the limits of the failed logins
+func GetLoginLimits() LoginLimits {
+	return LoginLimits{
+		MaxAccountFailures: envInt("AUTH_LOGIN_MAX_FAILURES", 5),
+		MaxIPFailures:      envInt("AUTH_LOGIN_MAX_IP_FAILURES", 50),
+		Window:             time.Duration(envInt("AUTH_LOGIN_WINDOW_SECONDS", 900)) * time.Second,
+		Lockout:            time.Duration(envInt("AUTH_LOGIN_LOCKOUT_SECONDS", 900)) * time.Second,
+		MaxDelay:           30 * time.Second,
+	}
+}
+
+// Get the time until the next login of the account or from the ip is allowed, 0 if it is allowed now
+func LoginRetryAfter(account string, ip string) (time.Duration, error) {
+	client := redisConnectionPool.Get()
+	defer client.Close()
+
+	var retryAfter time.Duration
+	for _, key := range []string{loginKey("blocked", "account", account), loginKey("blocked", "ip", ip)} {
+		milliseconds, err := redis.Int64(client.Do("PTTL", key))
+		if err != nil {
+			return 0, err
+		}
+		if wait := time.Duration(milliseconds) * time.Millisecond; wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	return retryAfter, nil
+}
+
+// Count a failed login of the account from the ip
+//
+// Every failure delays the next attempt twice as long as the one before, after too many failures
+// in the window the account or the ip is locked.
+func RegisterLoginFailure(account string, ip string) error {
+	limits := GetLoginLimits()
+	client := redisConnectionPool.Get()
+	defer client.Close()
+
+	// Count the failures of the account and delay or lock it
+	failures, err := incrementFailures(client, loginKey("failures", "account", account), limits.Window)
+	if err != nil {
+		return err
+	}
+	block := limits.Lockout
+	if failures < limits.MaxAccountFailures {
+		block = time.Second << uint(failures-1)
+		if block > limits.MaxDelay {
+			block = limits.MaxDelay
+		}
+	}
+	if _, err := client.Do("SET", loginKey("blocked", "account", account), 1, "PX", block.Milliseconds()); err != nil {
+		return err
+	}
+
+	// Count the failures from the ip and lock it, the accounts of an ip are not delayed so that shared ips keep working
+	failures, err = incrementFailures(client, loginKey("failures", "ip", ip), limits.Window)
+	if err != nil {
+		return err
+	}
+	if failures >= limits.MaxIPFailures {
+		if _, err := client.Do("SET", loginKey("blocked", "ip", ip), 1, "PX", limits.Lockout.Milliseconds()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Forget the failed logins of an account after a successful login
+func ResetLoginFailures(account string) error {
+	client := redisConnectionPool.Get()
+	defer client.Close()
+
+	_, err := client.Do("DEL", loginKey("failures", "account", account), loginKey("blocked", "account", account))
+	return err
+}
+
+// Increment a failure counter, the window starts with the first failure
+func incrementFailures(client redis.Conn, key string, window time.Duration) (int64, error) {
+	failures, err := redis.Int64(client.Do("INCR", key))
+	if err != nil {
+		return 0, err
+	}
+	if failures == 1 {
+		if _, err := client.Do("PEXPIRE", key, window.Milliseconds()); err != nil {
+			return 0, err
+		}
+	}
+	return failures, nil
+}
+
+// Build the redis key of the login limits, e.g. login:failures:account:ahmet.yilmaz
+//
+// The value is folded, so that writing the username as AHMET.YILMAZ does not get around the limits of ahmet.yilmaz.
+func loginKey(kind string, scope string, value string) string {
+	return "login:" + kind + ":" + scope + ":" + utilities.Fold(value)
+}
+
+// Get a positive number from the environment or the default value
+func envInt(key string, defaultValue int64) int64 {
+	value, err := strconv.ParseInt(utilities.GetEnv(key, strconv.FormatInt(defaultValue, 10)), 10, 64)
+	if err != nil || value < 1 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// Start an in memory redis with the limits of the tests
+func newTestCache(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	cache := miniredis.RunT(t)
+	t.Setenv("AUTH_CACHE_HOST", cache.Host())
+	t.Setenv("AUTH_CACHE_PORT", cache.Port())
+	t.Setenv("AUTH_LOGIN_MAX_FAILURES", "5")
+	t.Setenv("AUTH_LOGIN_MAX_IP_FAILURES", "20")
+	t.Setenv("AUTH_LOGIN_WINDOW_SECONDS", "900")
+	t.Setenv("AUTH_LOGIN_LOCKOUT_SECONDS", "600")
+	SetupCache()
+	return cache
+}
+
+// Get the time until the next login and fail the test on an error
+func retryAfter(t *testing.T, account string, ip string) time.Duration {
+	t.Helper()
+	wait, err := LoginRetryAfter(account, ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wait
+}
+
+func registerFailure(t *testing.T, account string, ip string) {
+	t.Helper()
+	if err := RegisterLoginFailure(account, ip); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoginFailuresDelayAndLockTheAccount(t *testing.T) {
+	newTestCache(t)
+	if wait := retryAfter(t, "ahmet.yilmaz", "192.0.2.1"); wait != 0 {
+		t.Fatalf("got the wait %s before any failure, want 0", wait)
+	}
+
+	// Every failure doubles the delay until the account is locked at the maximum failures
+	for failure, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Minute} {
+		registerFailure(t, "ahmet.yilmaz", "192.0.2.1")
+		if wait := retryAfter(t, "ahmet.yilmaz", "192.0.2.1"); wait != want {
+			t.Fatalf("failure %d: got the wait %s, want %s", failure+1, wait, want)
+		}
+	}
+
+	// The lockout is per account, the case and the turkish letters of the username do not matter
+	if wait := retryAfter(t, "AHMET.YILMAZ", "198.51.100.1"); wait != 10*time.Minute {
+		t.Fatalf("got the wait %s for the upper case username, want the lockout", wait)
+	}
+	if wait := retryAfter(t, "mehmet.kaya", "192.0.2.1"); wait != 0 {
+		t.Fatalf("got the wait %s for another account from the same ip, want 0", wait)
+	}
+
+	// A successful login forgets the failures
+	if err := ResetLoginFailures("ahmet.yilmaz"); err != nil {
+		t.Fatal(err)
+	}
+	registerFailure(t, "ahmet.yilmaz", "192.0.2.1")
+	if wait := retryAfter(t, "ahmet.yilmaz", "192.0.2.1"); wait != time.Second {
+		t.Fatalf("got the wait %s after the reset, want 1s", wait)
+	}
+}
+
+func TestLoginDelayIsLimited(t *testing.T) {
+	newTestCache(t)
+	t.Setenv("AUTH_LOGIN_MAX_FAILURES", "10")
+
+	// The delay stops at the maximum delay until the lockout
+	for failure := 1; failure <= 9; failure++ {
+		registerFailure(t, "ahmet.yilmaz", "192.0.2.1")
+	}
+	if wait := retryAfter(t, "ahmet.yilmaz", "192.0.2.1"); wait != GetLoginLimits().MaxDelay {
+		t.Fatalf("got the wait %s after 9 failures, want %s", wait, GetLoginLimits().MaxDelay)
+	}
+	registerFailure(t, "ahmet.yilmaz", "192.0.2.1")
+	if wait := retryAfter(t, "ahmet.yilmaz", "192.0.2.1"); wait != 10*time.Minute {
+		t.Fatalf("got the wait %s after 10 failures, want the lockout", wait)
+	}
+}
+
+func TestLoginFailuresAreForgottenAfterTheWindow(t *testing.T) {
+	cache := newTestCache(t)
+	for failure := 1; failure <= 4; failure++ {
+		registerFailure(t, "ahmet.yilmaz", "192.0.2.1")
+	}
+
+	// The window starts with the first failure, so the next failure is the first of a new window
+	cache.FastForward(900 * time.Second)
+	registerFailure(t, "ahmet.yilmaz", "192.0.2.1")
+	if wait := retryAfter(t, "ahmet.yilmaz", "192.0.2.1"); wait != time.Second {
+		t.Fatalf("got the wait %s after the window, want 1s", wait)
+	}
+
+	// The lockout ends after its time
+	for failure := 2; failure <= 5; failure++ {
+		registerFailure(t, "ahmet.yilmaz", "192.0.2.1")
+	}
+	cache.FastForward(10 * time.Minute)
+	if wait := retryAfter(t, "ahmet.yilmaz", "192.0.2.1"); wait != 0 {
+		t.Fatalf("got the wait %s after the lockout, want 0", wait)
+	}
+}
+
+func TestLoginFailuresLockTheIP(t *testing.T) {
+	newTestCache(t)
+
+	// Failures of many accounts from one ip lock the ip for all accounts, other ips are not affected
+	for failure := 1; failure < 20; failure++ {
+		registerFailure(t, "user"+strconv.Itoa(failure), "192.0.2.1")
+	}
+	if wait := retryAfter(t, "ahmet.yilmaz", "192.0.2.1"); wait != 0 {
+		t.Fatalf("got the wait %s below the ip limit, want 0", wait)
+	}
+	registerFailure(t, "user20", "192.0.2.1")
+	if wait := retryAfter(t, "ahmet.yilmaz", "192.0.2.1"); wait != 10*time.Minute {
+		t.Fatalf("got the wait %s at the ip limit, want the lockout", wait)
+	}
+	if wait := retryAfter(t, "ahmet.yilmaz", "198.51.100.1"); wait != 0 {
+		t.Fatalf("got the wait %s from another ip, want 0", wait)
+	}
+
+	// A successful login of an account does not unlock the ip
+	if err := ResetLoginFailures("ahmet.yilmaz"); err != nil {
+		t.Fatal(err)
+	}
+	if wait := retryAfter(t, "ahmet.yilmaz", "192.0.2.1"); wait != 10*time.Minute {
+		t.Fatalf("got the wait %s after a reset of an account, want the lockout of the ip", wait)
+	}
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yzaimoglu/election/auth/utilities"
+)
+
+// Model for a tool which logs its users in with the election accounts over OpenID Connect, e.g. grafana or the wiki
+type OIDCClient struct {
+	Id           int64  `json:"id"`
+	ClientId     string `json:"clientid" gorm:"uniqueIndex;size:64"`
+	Name         string `json:"name"`
+	HashedSecret string `json:"-"`            // empty for public clients
+	RedirectURIs string `json:"redirecturis"` // separated by spaces, compared exactly
+	Public       bool   `json:"public"`       // clients without a secret like single page apps, they must use pkce
+	CreatedAt    int64  `json:"createdat"`
+}
+
+// Model for an authorization code, only its hash is stored and it can be exchanged once
+type AuthorizationCode struct {
+	Id            int64  `json:"id"`
+	HashedCode    string `json:"-"`
+	ClientId      string `json:"clientid"`
+	UserId        int64  `json:"userid"`
+	RedirectURI   string `json:"redirecturi"`
+	Scope         string `json:"scope"`
+	Nonce         string `json:"nonce"`
+	CodeChallenge string `json:"-"`        // S256 of the code verifier
+	AuthTime      int64  `json:"authtime"` // creation of the session, unix seconds
+	CreatedAt     int64  `json:"createdat"`
+	ExpiresAt     int64  `json:"expiresat"`
+	UsedAt        int64  `json:"usedat"` // 0 until the code is exchanged
+}
+
+// Claims of the user returned by the userinfo endpoint, the role and affiliation are added for the tools of the election
+type UserInfo struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	GivenName         string `json:"given_name,omitempty"`
+	FamilyName        string `json:"family_name,omitempty"`
+	Email             string `json:"email,omitempty"`
+	Role              string `json:"role"`
+	Affiliation       string `json:"affiliation"`
+	Region            string `json:"region,omitempty"`
+}
+
+// Claims of an ID token, they contain the claims of the user
+type IDTokenClaims struct {
+	Issuer          string `json:"iss"`
+	Audience        string `json:"aud"`
+	ExpiresAt       int64  `json:"exp"`
+	IssuedAt        int64  `json:"iat"`
+	AuthTime        int64  `json:"auth_time"`
+	Nonce           string `json:"nonce,omitempty"`
+	AuthorizedParty string `json:"azp"`
+	UserInfo
+}
+
+// Model for the OpenID Connect client creation input
+type CreateOIDCClientInput struct {
+	Name         string   `json:"name" validate:"required"`
+	RedirectURIs []string `json:"redirecturis" validate:"required,min=1,dive,url"`
+	Public       bool     `json:"public"`
+}
+
+// Model for the OpenID Connect token input, sent as a form
+type OIDCTokenInput struct {
+	GrantType    string `form:"grant_type" validate:"required,eq=authorization_code"`
+	Code         string `form:"code" validate:"required"`
+	RedirectURI  string `form:"redirect_uri" validate:"required"`
+	CodeVerifier string `form:"code_verifier" validate:"required,min=43,max=128"`
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// Time an authorization code can be exchanged
+const AuthorizationCodeLifetime = time.Minute
+
+// Get the issuer of the ID tokens, which is the url of the OpenID Connect routes, set with AUTH_OIDC_ISSUER
+func OIDCIssuer() string {
+	return strings.TrimSuffix(utilities.GetEnv("AUTH_OIDC_ISSUER", "http://localhost/v1/oidc"), "/")
+}
+
+// Check if a redirect uri is registered for the client
+func (client OIDCClient) AllowsRedirect(redirectURI string) bool {
+	for _, allowed := range strings.Fields(client.RedirectURIs) {
+		if allowed == redirectURI {
+			return true
+		}
+	}
+	return false
+}
+
+// Create the claims of a user for the granted scopes, the role and affiliation are always set
+func NewUserInfo(user User, scope string) UserInfo {
+	info := UserInfo{
+		Subject:     strconv.FormatInt(user.Id, 10),
+		Role:        user.Role,
+		Affiliation: user.Affiliation,
+		Region:      user.Region,
+	}
+	for _, granted := range strings.Fields(scope) {
+		switch granted {
+		case "profile":
+			info.PreferredUsername = user.Username
+			info.Name = user.FirstName + " " + user.LastName
+			info.GivenName = user.FirstName
+			info.FamilyName = user.LastName
+		case "email":
+			// The email can be changed without a confirmation, so it is not claimed to be verified
+			info.Email = user.Email
+		}
+	}
+	return info
+}
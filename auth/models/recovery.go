@@ -0,0 +1,41 @@
+package models
+
+import "encoding/json"
+
+// Model for a password reset, only the hash of the token sent by email is stored
+type PasswordReset struct {
+	Id          int64  `json:"id"`
+	UserId      int64  `json:"userid"`
+	HashedToken string `json:"-"`
+	CreatedAt   int64  `json:"createdat"`
+	ExpiresAt   int64  `json:"expiresat"`
+	UsedAt      int64  `json:"usedat"` // 0 until the token is used
+}
+
+// Model for a single-use totp recovery code, only its hash is stored
+type RecoveryCode struct {
+	Id         int64  `json:"id"`
+	UserId     int64  `json:"userid"`
+	HashedCode string `json:"-"`
+	CreatedAt  int64  `json:"createdat"`
+	UsedAt     int64  `json:"usedat"` // 0 until the code is used
+}
+
+// Model for the forgot password input
+type ForgotPasswordInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Model for the reset password input
+type ResetPasswordInput struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newpassword" validate:"required"`
+}
+
+// Model for the regenerate recovery codes input
+type RegenerateRecoveryCodesInput struct {
+	TOTP             string          `json:"totp" validate:"required_without_all=RecoveryCode WebAuthn"`
+	RecoveryCode     string          `json:"recoverycode"`                                       // a current recovery code, used instead of the totp
+	WebAuthn         json.RawMessage `json:"webauthn"`                                           // assertion of a security key, used instead of the totp
+	WebAuthnCeremony string          `json:"webauthnceremony" validate:"required_with=WebAuthn"` // returned when the webauthn login was begun
+}
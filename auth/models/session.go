@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yzaimoglu/election/auth/utilities"
+	"gorm.io/gorm"
+)
+
+// Returned if the session of a token has expired, the session is deleted
+var ErrSessionExpired = errors.New("the session has expired")
+
+// Get the time a session stays valid without requests, set with AUTH_SESSION_IDLE_MINUTES
+func SessionIdleTimeout() time.Duration {
+	return time.Duration(envInt("AUTH_SESSION_IDLE_MINUTES", 60*24)) * time.Minute
+}
+
+// Get the time after which a session ends regardless of its requests, set with AUTH_SESSION_MAX_HOURS
+func SessionMaxLifetime() time.Duration {
+	return time.Duration(envInt("AUTH_SESSION_MAX_HOURS", 24*7)) * time.Hour
+}
+
+// Get the absolute end of the session, sessions created before it was stored end after the maximum lifetime
+func (session Session) AbsoluteExpiry() int64 {
+	if session.MaxExpiresAt > 0 {
+		return session.MaxExpiresAt
+	}
+	return session.CreatedAt + SessionMaxLifetime().Milliseconds()
+}
+
+// Find the session of a token and extend it, expired sessions are deleted and return ErrSessionExpired
+//
+// The expiry is only saved if it moved by more than a minute, so that not every request writes to the database.
+func FindActiveSession(db *gorm.DB, sessionToken string) (Session, error) {
+	var session Session
+	if sessionToken == "" {
+		return session, gorm.ErrRecordNotFound
+	}
+	if err := db.Where("session_token = ?", sessionToken).First(&session).Error; err != nil {
+		return session, err
+	}
+
+	// Check if session has expired if so delete session
+	now := utilities.GetCurrentTime()
+	if now >= session.ExpiresAt || now >= session.AbsoluteExpiry() {
+		if err := db.Delete(&session).Error; err != nil {
+			return session, err
+		}
+		return session, ErrSessionExpired
+	}
+
+	// Slide the expiry up to the absolute end of the session
+	expiresAt := now + SessionIdleTimeout().Milliseconds()
+	if expiresAt > session.AbsoluteExpiry() {
+		expiresAt = session.AbsoluteExpiry()
+	}
+	if expiresAt-session.ExpiresAt > time.Minute.Milliseconds() {
+		session.ExpiresAt, session.LastActiveAt = expiresAt, now
+		if err := db.Model(&session).Updates(map[string]interface{}{"expires_at": expiresAt, "last_active_at": now}).Error; err != nil {
+			return session, err
+		}
+	}
+	return session, nil
+}
+
+// Delete the expired sessions in the given interval until the program ends, set with AUTH_SESSION_SWEEP_MINUTES
+func StartSessionSweeper(db *gorm.DB) {
+	interval := time.Duration(envInt("AUTH_SESSION_SWEEP_MINUTES", 10)) * time.Minute
+	go func() {
+		for range time.Tick(interval) {
+			now := utilities.GetCurrentTime()
+			result := db.Where("expires_at <= ? OR (max_expires_at > 0 AND max_expires_at <= ?)", now, now).Delete(&Session{})
+			if result.Error != nil {
+				log.Println("error deleting the expired sessions: " + result.Error.Error())
+			}
+		}
+	}()
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yzaimoglu/election/auth/tokens"
+	"github.com/yzaimoglu/election/auth/utilities"
+	"gorm.io/gorm"
+)
+
+// Model for a key the access tokens are signed with, the public key is published until the key retires
+type SigningKey struct {
+	Id         int64  `json:"id"`
+	KeyId      string `json:"kid" gorm:"uniqueIndex;size:64"`
+	Algorithm  string `json:"alg" gorm:"default:EdDSA"` // EdDSA for access tokens, RS256 for ID tokens
+	PrivateKey string `json:"-"`                        // ed25519 key or pkcs8 of the rsa key
+	PublicKey  string `json:"publickey"`                // ed25519 key or pkix of the rsa key
+	CreatedAt  int64  `json:"createdat"`
+	RetiresAt  int64  `json:"retiresat"` // the key is not published anymore after this
+}
+
+// Model for a machine client like the updater, which gets access tokens with its id and secret
+type ServiceClient struct {
+	Id           int64  `json:"id"`
+	ClientId     string `json:"clientid" gorm:"uniqueIndex;size:64"`
+	Name         string `json:"name"`
+	HashedSecret string `json:"-"`
+	Scope        string `json:"scope"` // scopes the client may request, separated by spaces
+	CreatedAt    int64  `json:"createdat"`
+	LastUsedAt   int64  `json:"lastusedat"`
+}
+
+// Model for the token input, either with the credentials of a client or the session of a user
+type TokenInput struct {
+	GrantType    string `json:"grant_type" form:"grant_type" validate:"required,oneof=client_credentials session"`
+	ClientId     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+	Scope        string `json:"scope" form:"scope"`
+}
+
+// Model for the service client creation input
+type CreateServiceClientInput struct {
+	Name  string `json:"name" validate:"required"`
+	Scope string `json:"scope" validate:"required"`
+}
+
+// Get the time an access token is valid, set with AUTH_TOKEN_MINUTES
+func TokenLifetime() time.Duration {
+	return time.Duration(envInt("AUTH_TOKEN_MINUTES", 10)) * time.Minute
+}
+
+// Get the time after which a new signing key is created, set with AUTH_TOKEN_KEY_ROTATION_HOURS
+func KeyRotationInterval() time.Duration {
+	return time.Duration(envInt("AUTH_TOKEN_KEY_ROTATION_HOURS", 24*7)) * time.Hour
+}
+
+// Get the issuer of the access tokens, set with AUTH_TOKEN_ISSUER
+func TokenIssuer() string {
+	return utilities.GetEnv("AUTH_TOKEN_ISSUER", "election-auth")
+}
+
+// Get the audience of the access tokens, set with AUTH_TOKEN_AUDIENCE
+func TokenAudience() string {
+	return utilities.GetEnv("AUTH_TOKEN_AUDIENCE", "election")
+}
+
+// Sign an access token with the current key, the issuer, times and id of the claims are set and the audience if it is empty
+func IssueToken(db *gorm.DB, claims tokens.Claims) (string, tokens.Claims, error) {
+	now := time.Now()
+	claims.Issuer = TokenIssuer()
+	if claims.Audience == "" {
+		claims.Audience = TokenAudience()
+	}
+	claims.IssuedAt = now.Unix()
+	claims.NotBefore = now.Unix()
+	claims.ExpiresAt = now.Add(TokenLifetime()).Unix()
+	claims.Id = uuid.New().String()
+	token, err := SignWithCurrentKey(db, tokens.Algorithm, claims)
+	return token, claims, err
+}
+
+// Sign claims with the current key of the algorithm
+func SignWithCurrentKey(db *gorm.DB, algorithm string, claims interface{}) (string, error) {
+	key, err := CurrentSigningKey(db, algorithm)
+	if err != nil {
+		return "", err
+	}
+	signer, err := key.Signer()
+	if err != nil {
+		return "", err
+	}
+	return tokens.Sign(claims, key.KeyId, signer)
+}
+
+// Get the private key to sign with
+func (key SigningKey) Signer() (crypto.Signer, error) {
+	privateKey, err := base64.RawURLEncoding.DecodeString(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if key.Algorithm != tokens.AlgorithmRSA {
+		return ed25519.PrivateKey(privateKey), nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the signing key " + key.KeyId + " is not an rsa key")
+	}
+	return rsaKey, nil
+}
+
+// Get the ed25519 public key to verify access tokens with, nil for rsa keys
+func (key SigningKey) Ed25519PublicKey() ed25519.PublicKey {
+	publicKey, err := base64.RawURLEncoding.DecodeString(key.PublicKey)
+	if err != nil || key.Algorithm == tokens.AlgorithmRSA || len(publicKey) != ed25519.PublicKeySize {
+		return nil
+	}
+	return ed25519.PublicKey(publicKey)
+}
+
+// Get the newest signing key of the algorithm, a new one is created if there is none or it is older than the rotation interval
+//
+// The old keys stay published until every token signed with them has expired.
+func CurrentSigningKey(db *gorm.DB, algorithm string) (SigningKey, error) {
+	var key SigningKey
+	err := db.Where("algorithm = ?", algorithm).Order("created_at DESC").First(&key).Error
+	if err == nil && utilities.GetCurrentTime()-key.CreatedAt < KeyRotationInterval().Milliseconds() {
+		return key, nil
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return key, err
+	}
+	return RotateSigningKey(db, algorithm)
+}
+
+// Create a new signing key of the algorithm, it is used for all tokens from now on
+func RotateSigningKey(db *gorm.DB, algorithm string) (SigningKey, error) {
+	var key SigningKey
+	var privateKey, publicKey []byte
+	switch algorithm {
+	case tokens.Algorithm:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return key, err
+		}
+		privateKey, publicKey = private, public
+	case tokens.AlgorithmRSA:
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return key, err
+		}
+		if privateKey, err = x509.MarshalPKCS8PrivateKey(private); err != nil {
+			return key, err
+		}
+		if publicKey, err = x509.MarshalPKIXPublicKey(&private.PublicKey); err != nil {
+			return key, err
+		}
+	default:
+		return key, errors.New("the algorithm " + algorithm + " is not supported")
+	}
+	keyIdBytes, err := utilities.GenerateRandomBytes(8)
+	if err != nil {
+		return key, err
+	}
+
+	key.KeyId = hex.EncodeToString(keyIdBytes)
+	key.Algorithm = algorithm
+	key.PrivateKey = base64.RawURLEncoding.EncodeToString(privateKey)
+	key.PublicKey = base64.RawURLEncoding.EncodeToString(publicKey)
+	key.CreatedAt = utilities.GetCurrentTime()
+	key.RetiresAt = key.CreatedAt + (KeyRotationInterval() + 2*TokenLifetime()).Milliseconds()
+	err = db.Create(&key).Error
+	return key, err
+}
+
+// Get the published keys, which are the keys that have not retired
+func PublishedSigningKeys(db *gorm.DB) (tokens.JWKS, error) {
+	keySet := tokens.JWKS{Keys: []tokens.JWK{}}
+	var keys []SigningKey
+	if err := db.Where("retires_at > ?", utilities.GetCurrentTime()).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return keySet, err
+	}
+	for _, key := range keys {
+		publicKey, err := base64.RawURLEncoding.DecodeString(key.PublicKey)
+		if err != nil {
+			continue
+		}
+		if key.Algorithm != tokens.AlgorithmRSA {
+			keySet.Keys = append(keySet.Keys, tokens.NewJWK(key.KeyId, ed25519.PublicKey(publicKey)))
+			continue
+		}
+		parsed, err := x509.ParsePKIXPublicKey(publicKey)
+		if rsaKey, ok := parsed.(*rsa.PublicKey); err == nil && ok {
+			keySet.Keys = append(keySet.Keys, tokens.NewRSAJWK(key.KeyId, rsaKey))
+		}
+	}
+	return keySet, nil
+}
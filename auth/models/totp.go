@@ -1,12 +1,18 @@
 package models
 
-// Model for the totp verification
+import "time"
+
+// Model for the totp verification, the secret is moved to the user once a code of it is verified
 type TOTPVerification struct {
-	Id       int64  `json:"id"`
-	Username string `json:"username" validate:"required"`
-	Code     string `json:"code" validate:"required"`
-	Secret   string `json:"secret" validate:"required"`
-	Image    string `json:"image" validate:"required"`
+	Id          int64  `json:"id"`
+	Username    string `json:"username" validate:"required"`
+	Code        string `json:"code" validate:"required"`
+	Secret      string `json:"secret" validate:"required"`
+	Image       string `json:"image" validate:"required"`
+	CreatedAt   int64  `json:"createdat"`
+	ExpiresAt   int64  `json:"expiresat"`   // verifications from before the expiry was stored (0) have expired
+	CompletedAt int64  `json:"completedat"` // 0 until the totp is verified, the verification cannot be used afterwards
+	Attempts    int64  `json:"attempts"`    // wrong totp codes, the verification is deleted after too many
 }
 
 // Model for the totp verification input
@@ -14,3 +20,29 @@ type TOTPVerificationInput struct {
 	Code string `json:"code" validate:"required"`
 	TOTP string `json:"totp" validate:"required"`
 }
+
+// Statuses of a totp enrolment
+const (
+	EnrolmentPending   = "pending"
+	EnrolmentCompleted = "completed"
+	EnrolmentExpired   = "expired"
+)
+
+// Wrong totp codes after which the verification is deleted
+const MaxEnrolmentAttempts = 5
+
+// Get the time a totp enrolment link is valid, set with AUTH_TOTP_ENROLMENT_HOURS
+func TOTPEnrolmentLifetime() time.Duration {
+	return time.Duration(envInt("AUTH_TOTP_ENROLMENT_HOURS", 72)) * time.Hour
+}
+
+// Get the status of the enrolment
+func (verification TOTPVerification) Status(now int64) string {
+	if verification.CompletedAt > 0 {
+		return EnrolmentCompleted
+	}
+	if now >= verification.ExpiresAt {
+		return EnrolmentExpired
+	}
+	return EnrolmentPending
+}
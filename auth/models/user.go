@@ -1,5 +1,18 @@
 package models
 
+// Roles of the users
+const (
+	RoleUser  = "Kullanıcı"
+	RoleAdmin = "Yönetici"
+)
+
+// Statuses of the users, only active users can log in
+const (
+	UserPending     = "pending" // registered with an invite and waiting for the approval of an admin
+	UserActive      = "active"
+	UserDeactivated = "deactivated" // deactivated by an admin instead of being deleted
+)
+
 // Model for the user object
 type User struct {
 	Id             int64  `json:"id"`
@@ -13,6 +26,13 @@ type User struct {
 	Role           string `json:"role"`
 	Affiliation    string `json:"affiliation"`
 	TOTP           string `json:"totp"`
+	Status         string `json:"status" gorm:"default:active"`
+	Region         string `json:"region"`
+	InvitedBy      int64  `json:"invitedby"`  // 0 for users who were not invited
+	ApprovedBy     int64  `json:"approvedby"` // 0 until an admin approves the user
+	ApprovedAt     int64  `json:"approvedat"`
+	DeactivatedBy  int64  `json:"deactivatedby"` // 0 unless an admin deactivated the user
+	DeactivatedAt  int64  `json:"deactivatedat"`
 }
 
 // Model for user information (User object without sensitive information)
@@ -26,15 +46,8 @@ type UserInformation struct {
 	LastSeen    int64  `json:"lastseen"`
 	Role        string `json:"role"`
 	Affiliation string `json:"affiliation"`
-}
-
-// Model to use for user creation
-type CreateUser struct {
-	FirstName     string `json:"firstname" validate:"required"`
-	LastName      string `json:"lastname" validate:"required"`
-	Email         string `json:"email" validate:"required,email"`
-	PlainPassword string `json:"plainpassword" validate:"required"`
-	Affiliation   string `json:"affiliation" validate:"required"`
+	Status      string `json:"status"`
+	Region      string `json:"region"`
 }
 
 // Model for the update email input
@@ -55,7 +68,7 @@ type UpdateAffiliationInput struct {
 
 // Model for the update role input
 type UpdateRoleInput struct {
-	Role string `json:"role" validate:"required"`
+	Role string `json:"role" validate:"required,oneof=Kullanıcı Yönetici"`
 }
 
 // Model for the update lastseen input
@@ -67,3 +80,9 @@ type UpdateLastseenInput struct {
 type UpdateTOTPInput struct {
 	TOTP string `json:"totp" validate:"required"`
 }
+
+// Model for the bulk role input, assigns the role to all users of the ids
+type BulkRoleInput struct {
+	UserIds []int64 `json:"userids" validate:"required,min=1,max=1000,dive,gt=0"`
+	Role    string  `json:"role" validate:"required,oneof=Kullanıcı Yönetici"`
+}
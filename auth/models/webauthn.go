@@ -0,0 +1,49 @@
+package models
+
+import "encoding/json"
+
+// Model for a webauthn credential (security key or passkey) of a user
+type WebAuthnCredential struct {
+	Id              int64  `json:"id"`
+	UserId          int64  `json:"userid"`
+	Name            string `json:"name"`
+	CredentialId    string `json:"credentialid" gorm:"uniqueIndex;size:255"` // base64url
+	PublicKey       string `json:"-"`                                        // base64url of the cose key
+	AttestationType string `json:"attestationtype"`
+	AAGUID          string `json:"aaguid"`
+	SignCount       uint32 `json:"signcount"`
+	Transports      string `json:"transports"` // separated by commas, e.g. usb,nfc
+	BackupEligible  bool   `json:"backupeligible"`
+	BackupState     bool   `json:"backupstate"`
+	CreatedAt       int64  `json:"createdat"`
+	LastUsedAt      int64  `json:"lastusedat"`
+}
+
+// Model for the state of a webauthn ceremony between its begin and finish request
+type WebAuthnCeremony struct {
+	Id          int64  `json:"id"`
+	UserId      int64  `json:"userid"`
+	Kind        string `json:"kind"` // registration or login
+	HashedToken string `json:"-"`
+	SessionData string `json:"-"` // json of the webauthn session data
+	ExpiresAt   int64  `json:"expiresat"`
+}
+
+// Kinds of the webauthn ceremonies
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+)
+
+// Model for the input to begin a webauthn login, the assertion is sent to the login afterwards
+type WebAuthnLoginInput struct {
+	Username string `json:"username" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+}
+
+// Model for the input to finish a webauthn registration
+type WebAuthnRegistrationInput struct {
+	Ceremony   string          `json:"ceremony" validate:"required"`
+	Name       string          `json:"name" validate:"required,max=64"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
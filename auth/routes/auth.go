@@ -3,6 +3,8 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/yzaimoglu/election/auth/controllers"
+	"github.com/yzaimoglu/election/auth/middleware"
+	"github.com/yzaimoglu/election/auth/models"
 )
 
 // Setup the auth routes for the API
@@ -12,5 +14,6 @@ func GetAuthRoutes(router *gin.RouterGroup) {
 		// Routes for authentication procedures
 		authRoutes.POST("/login/", controllers.LoginHandler)
 		authRoutes.DELETE("/logout/", controllers.LogoutHandler)
+		authRoutes.GET("/login/attempts/", middleware.RequireRole(models.RoleAdmin), controllers.GetLoginAttempts)
 	}
 }
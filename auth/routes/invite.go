@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/auth/controllers"
+	"github.com/yzaimoglu/election/auth/middleware"
+	"github.com/yzaimoglu/election/auth/models"
+)
+
+// Setup the invite routes for the API
+func GetInviteRoutes(router *gin.RouterGroup) {
+	inviteRoutes := router.Group("/invites")
+	{
+		// Routes for the registration with an invite
+		inviteRoutes.GET("/token/:token/", controllers.GetInviteByToken)
+		inviteRoutes.POST("/accept/", controllers.AcceptInvite)
+
+		// Routes for managing the invites, only for admins
+		inviteRoutes.GET("/", middleware.RequireRole(models.RoleAdmin), controllers.GetInvites)
+		inviteRoutes.POST("/", middleware.RequireRole(models.RoleAdmin), controllers.CreateInvite)
+		inviteRoutes.DELETE("/:id/", middleware.RequireRole(models.RoleAdmin), controllers.DeleteInvite)
+	}
+
+	approvalRoutes := router.Group("/approvals", middleware.RequireRole(models.RoleAdmin))
+	{
+		// Routes for approving the users who accepted an invite, only for admins
+		approvalRoutes.GET("/", controllers.GetPendingUsers)
+		approvalRoutes.POST("/:id/", controllers.ApproveUser)
+		approvalRoutes.DELETE("/:id/", controllers.RejectUser)
+	}
+}
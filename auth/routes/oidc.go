@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/auth/controllers"
+	"github.com/yzaimoglu/election/auth/middleware"
+	"github.com/yzaimoglu/election/auth/models"
+)
+
+// Setup the OpenID Connect routes for the API, the urls of the protocol have no trailing slash as in the discovery document
+func GetOIDCRoutes(router *gin.RouterGroup) {
+	oidcRoutes := router.Group("/oidc")
+	{
+		// Routes of the OpenID Connect provider
+		oidcRoutes.GET("/.well-known/openid-configuration", controllers.GetOIDCConfiguration)
+		oidcRoutes.GET("/authorize", controllers.Authorize)
+		oidcRoutes.POST("/token", controllers.OIDCToken)
+		oidcRoutes.GET("/userinfo", controllers.GetUserInfo)
+		oidcRoutes.POST("/userinfo", controllers.GetUserInfo)
+		oidcRoutes.GET("/jwks", controllers.GetJWKS)
+	}
+
+	clientRoutes := router.Group("/oidc/clients", middleware.RequireRole(models.RoleAdmin))
+	{
+		// Routes for managing the OpenID Connect clients, only for admins
+		clientRoutes.GET("/", controllers.GetOIDCClients)
+		clientRoutes.POST("/", controllers.CreateOIDCClient)
+		clientRoutes.DELETE("/:id/", controllers.DeleteOIDCClient)
+	}
+}
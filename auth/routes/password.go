@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/auth/controllers"
+)
+
+// Setup the password routes for the API
+func GetPasswordRoutes(router *gin.RouterGroup) {
+	passwordRoutes := router.Group("/password")
+	{
+		// Routes for resetting forgotten passwords
+		passwordRoutes.POST("/forgot/", controllers.ForgotPassword)
+		passwordRoutes.POST("/reset/", controllers.ResetPassword)
+	}
+}
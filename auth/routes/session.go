@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/yzaimoglu/election/auth/controllers"
+	"github.com/yzaimoglu/election/auth/middleware"
 )
 
 // Setup the session routes for the API
@@ -10,8 +11,9 @@ func GetSessionRoutes(router *gin.RouterGroup) {
 	sessionRoutes := router.Group("/")
 	{
 		// Routes for interacting with the sessions in the database
-		sessionRoutes.GET("/session/:session/", controllers.GetSessionById)
 		sessionRoutes.GET("/session/", controllers.GetSessionByCookie)
-		sessionRoutes.GET("/sessions/:id/", controllers.GetSessionsOfUser)
+		sessionRoutes.DELETE("/session/:id/", middleware.RequireSession(), controllers.RevokeSession)
+		sessionRoutes.GET("/sessions/:id/", middleware.RequireSession(), controllers.GetSessionsOfUser)
+		sessionRoutes.DELETE("/sessions/:id/", middleware.RequireSession(), controllers.RevokeSessionsOfUser)
 	}
 }
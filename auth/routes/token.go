@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/auth/controllers"
+	"github.com/yzaimoglu/election/auth/middleware"
+	"github.com/yzaimoglu/election/auth/models"
+)
+
+// Setup the token routes for the API
+func GetTokenRoutes(router *gin.RouterGroup) {
+	tokenRoutes := router.Group("/")
+	{
+		// Routes for the access tokens and the keys they are signed with
+		tokenRoutes.GET("/.well-known/jwks.json", controllers.GetJWKS)
+		tokenRoutes.POST("/token/", controllers.IssueToken)
+		tokenRoutes.POST("/token/keys/", middleware.RequireRole(models.RoleAdmin), controllers.RotateSigningKey)
+	}
+
+	clientRoutes := router.Group("/clients", middleware.RequireRole(models.RoleAdmin))
+	{
+		// Routes for managing the service clients, only for admins
+		clientRoutes.GET("/", controllers.GetServiceClients)
+		clientRoutes.POST("/", controllers.CreateServiceClient)
+		clientRoutes.DELETE("/:id/", controllers.DeleteServiceClient)
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/yzaimoglu/election/auth/controllers"
+	"github.com/yzaimoglu/election/auth/middleware"
 )
 
 // Setup the totp routes for the API
@@ -12,5 +13,9 @@ func GetTOTPRoutes(router *gin.RouterGroup) {
 		// Routes for interacting with the totp verification codes in the database
 		totpRoutes.POST("/", controllers.VerifyTOTP)
 		totpRoutes.GET("/:verification/", controllers.GetImage)
+		totpRoutes.GET("/:verification/status/", controllers.GetEnrolmentStatus)
+		totpRoutes.GET("/status/", middleware.RequireSession(), controllers.GetTOTPStatus)
+		totpRoutes.GET("/recovery/", middleware.RequireSession(), controllers.GetRecoveryCodes)
+		totpRoutes.POST("/recovery/", middleware.RequireSession(), controllers.RegenerateRecoveryCodes)
 	}
 }
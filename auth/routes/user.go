@@ -3,6 +3,8 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/yzaimoglu/election/auth/controllers"
+	"github.com/yzaimoglu/election/auth/middleware"
+	"github.com/yzaimoglu/election/auth/models"
 )
 
 // Setup the user routes for the API
@@ -11,12 +13,22 @@ func GetUserRoutes(router *gin.RouterGroup) {
 	{
 		// Routes for interacting with users in the database
 		userRoutes.GET("/:id/", controllers.GetUser)
-		userRoutes.PUT("/:id/email/", controllers.UpdateUserEmail)
-		userRoutes.PUT("/:id/password/", controllers.UpdateUserPassword)
-		userRoutes.PUT("/:id/affiliation/", controllers.UpdateUserAffiliation)
-		userRoutes.PUT("/:id/role/", controllers.UpdateUserRole)
-		userRoutes.PUT("/:id/lastseen/", controllers.UpdateUserLastseen)
-		userRoutes.POST("/", controllers.CreateUser)
-		userRoutes.DELETE("/:id/", controllers.DeleteUser)
+		userRoutes.PUT("/:id/email/", middleware.RequireSession(), controllers.UpdateUserEmail)
+		userRoutes.PUT("/:id/password/", middleware.RequireSession(), controllers.UpdateUserPassword)
+		userRoutes.PUT("/:id/affiliation/", middleware.RequireSession(), controllers.UpdateUserAffiliation)
+		userRoutes.PUT("/:id/role/", middleware.RequireRole(models.RoleAdmin), controllers.UpdateUserRole)
+		userRoutes.PUT("/:id/lastseen/", middleware.RequireSession(), controllers.UpdateUserLastseen)
+		userRoutes.POST("/:id/totp/reset/", middleware.RequireRole(models.RoleAdmin), controllers.ResetUserTOTP)
+		userRoutes.DELETE("/:id/", middleware.RequireRole(models.RoleAdmin), controllers.DeactivateUser)
+	}
+
+	usersRoutes := router.Group("/users", middleware.RequireRole(models.RoleAdmin))
+	{
+		// Routes for managing the users, only for admins
+		usersRoutes.GET("/", controllers.GetUsers)
+		usersRoutes.GET("/export/", controllers.ExportUsers)
+		usersRoutes.PUT("/role/", controllers.BulkAssignRole)
+		usersRoutes.POST("/:id/deactivate/", controllers.DeactivateUser)
+		usersRoutes.POST("/:id/reactivate/", controllers.ReactivateUser)
 	}
 }
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/auth/controllers"
+	"github.com/yzaimoglu/election/auth/middleware"
+)
+
+// Setup the webauthn routes for the API
+func GetWebAuthnRoutes(router *gin.RouterGroup) {
+	webAuthnRoutes := router.Group("/webauthn")
+	{
+		// Routes for the security keys of the logged in user
+		webAuthnRoutes.POST("/register/begin/", middleware.RequireSession(), controllers.BeginWebAuthnRegistration)
+		webAuthnRoutes.POST("/register/finish/", middleware.RequireSession(), controllers.FinishWebAuthnRegistration)
+		webAuthnRoutes.GET("/credentials/", middleware.RequireSession(), controllers.GetWebAuthnCredentials)
+		webAuthnRoutes.DELETE("/credentials/:id/", middleware.RequireSession(), controllers.DeleteWebAuthnCredential)
+
+		// Route to begin a login with a security key, the assertion is sent to /login/
+		webAuthnRoutes.POST("/login/begin/", controllers.BeginWebAuthnLogin)
+	}
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/auth/controllers"
 	"github.com/yzaimoglu/election/auth/middleware"
 	"github.com/yzaimoglu/election/auth/models"
 	"github.com/yzaimoglu/election/auth/routes"
@@ -17,11 +20,42 @@ func main() {
 	db.AutoMigrate(&models.User{})
 	db.AutoMigrate(&models.Session{})
 	db.AutoMigrate(&models.TOTPVerification{})
+	db.AutoMigrate(&models.PasswordReset{})
+	db.AutoMigrate(&models.RecoveryCode{})
+	db.AutoMigrate(&models.LoginAttempt{})
+	db.AutoMigrate(&models.SigningKey{})
+	db.AutoMigrate(&models.ServiceClient{})
+	db.AutoMigrate(&models.Invite{})
+	db.AutoMigrate(&models.WebAuthnCredential{})
+	db.AutoMigrate(&models.WebAuthnCeremony{})
+	db.AutoMigrate(&models.OIDCClient{})
+	db.AutoMigrate(&models.AuthorizationCode{})
+
+	// Invite the first admin if there is none yet
+	controllers.BootstrapAdminInvite(db)
+
+	// Setup the cache for the login limits
+	models.SetupCache()
+
+	// Delete the expired sessions in the background
+	models.StartSessionSweeper(db)
 
 	// Initialize the main router
 	gin.SetMode(gin.ReleaseMode)
 	mainRouter := gin.New()
 
+	// Only trust the forwarded ip headers of the proxies in AUTH_TRUSTED_PROXIES, e.g. 10.0.0.0/8,172.16.0.1, so that
+	// clients cannot choose the ip of the login limits and attempts
+	var trustedProxies []string
+	for _, proxy := range strings.Split(utilities.GetEnv("AUTH_TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := mainRouter.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("invalid AUTH_TRUSTED_PROXIES: " + err.Error())
+	}
+
 	// Add the database to the context
 	mainRouter.Use(func(c *gin.Context) {
 		c.Set("db", db)
@@ -57,6 +91,11 @@ func main() {
 		routes.GetAuthRoutes(v1)
 		routes.GetSessionRoutes(v1)
 		routes.GetTOTPRoutes(v1)
+		routes.GetPasswordRoutes(v1)
+		routes.GetTokenRoutes(v1)
+		routes.GetInviteRoutes(v1)
+		routes.GetWebAuthnRoutes(v1)
+		routes.GetOIDCRoutes(v1)
 	}
 
 	// Run server
@@ -0,0 +1,80 @@
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// Algorithm of the signatures of the OpenID Connect ID tokens
+const AlgorithmRSA = "RS256"
+
+// Sign the claims with the private key of the key id, the algorithm is EdDSA for ed25519 keys and RS256 for rsa keys
+func Sign(claims interface{}, keyId string, privateKey crypto.Signer) (string, error) {
+	var algorithm string
+	switch privateKey.(type) {
+	case ed25519.PrivateKey:
+		algorithm = Algorithm
+	case *rsa.PrivateKey:
+		algorithm = AlgorithmRSA
+	default:
+		return "", errors.New("the key type is not supported")
+	}
+	headerJSON, err := json.Marshal(header{Algorithm: algorithm, Type: "JWT", KeyId: keyId})
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodeSegment(headerJSON) + "." + encodeSegment(claimsJSON)
+
+	// Ed25519 signs the message itself, RS256 the sha256 digest of it
+	var signature []byte
+	if algorithm == Algorithm {
+		signature, err = privateKey.Sign(rand.Reader, []byte(signingInput), crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256([]byte(signingInput))
+		signature, err = privateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encodeSegment(signature), nil
+}
+
+// Create the JSON web key of a public key
+func NewJWK(keyId string, publicKey ed25519.PublicKey) JWK {
+	return JWK{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(publicKey),
+		KeyId:     keyId,
+		Use:       "sig",
+		Algorithm: Algorithm,
+	}
+}
+
+// Create the JSON web key of an rsa public key
+func NewRSAJWK(keyId string, publicKey *rsa.PublicKey) JWK {
+	return JWK{
+		KeyType:   "RSA",
+		N:         base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+		E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		KeyId:     keyId,
+		Use:       "sig",
+		Algorithm: AlgorithmRSA,
+	}
+}
+
+// Encode a segment of a token with unpadded base64url
+func encodeSegment(input []byte) string {
+	return base64.RawURLEncoding.EncodeToString(input)
+}
@@ -0,0 +1,130 @@
+// Package tokens signs and verifies the access tokens of the authentication service.
+//
+// The access tokens are JWTs signed with Ed25519 (alg EdDSA), the public keys are published as a JWKS
+// on /v1/.well-known/jwks.json, so that the other services can verify the tokens without asking
+// the authentication service for every request. The OpenID Connect ID tokens are signed with RS256,
+// which every OpenID Connect client supports. Only the authentication service signs tokens, the
+// other services get copies of token.go, verifier.go and jwks.go without sign.go.
+package tokens
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Algorithm of the signatures of the access tokens
+const Algorithm = "EdDSA"
+
+// Time the clocks of the services may differ when checking the expiry of a token
+const Leeway = 30 * time.Second
+
+var (
+	// Returned if a token is malformed or its signature, issuer or audience is wrong
+	ErrInvalidToken = errors.New("the token is invalid")
+	// Returned if a token has expired or is not valid yet
+	ErrExpiredToken = errors.New("the token has expired")
+	// Returned if a token was signed with a key that is not published
+	ErrUnknownKey = errors.New("the token was signed with an unknown key")
+)
+
+// Claims of an access token
+type Claims struct {
+	Issuer      string `json:"iss"`
+	Subject     string `json:"sub"` // id of the user or of the client
+	Audience    string `json:"aud"`
+	ExpiresAt   int64  `json:"exp"` // unix seconds
+	NotBefore   int64  `json:"nbf"` // unix seconds
+	IssuedAt    int64  `json:"iat"` // unix seconds
+	Id          string `json:"jti"`
+	Scope       string `json:"scope,omitempty"`     // scopes separated by spaces, e.g. "parliament:write presidency:write"
+	ClientId    string `json:"client_id,omitempty"` // only set for tokens of clients
+	Role        string `json:"role,omitempty"`      // only set for tokens of users
+	Affiliation string `json:"affiliation,omitempty"`
+}
+
+// Header of a token
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyId     string `json:"kid"`
+}
+
+// Check if the token grants a scope
+func (claims Claims) HasScope(scope string) bool {
+	for _, granted := range strings.Fields(claims.Scope) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify the signature, the times, the issuer and the audience of an access token and return its claims
+//
+// The key is looked up by the key id of the token, ErrUnknownKey is returned if it returns nil.
+func Verify(token string, issuer string, audience string, key func(keyId string) ed25519.PublicKey) (Claims, error) {
+	var claims Claims
+	keyId, err := KeyId(token)
+	if err != nil {
+		return claims, err
+	}
+	publicKey := key(keyId)
+	if publicKey == nil {
+		return claims, ErrUnknownKey
+	}
+
+	// Check the signature before looking at the claims
+	segments := strings.Split(token, ".")
+	signature, err := decodeSegment(segments[2])
+	if err != nil || !ed25519.Verify(publicKey, []byte(segments[0]+"."+segments[1]), signature) {
+		return claims, ErrInvalidToken
+	}
+	claimsJSON, err := decodeSegment(segments[1])
+	if err != nil || json.Unmarshal(claimsJSON, &claims) != nil {
+		return claims, ErrInvalidToken
+	}
+
+	// Check the claims
+	if claims.Issuer != issuer || claims.Audience != audience {
+		return claims, ErrInvalidToken
+	}
+	now := time.Now()
+	if now.Add(-Leeway).Unix() >= claims.ExpiresAt || now.Add(Leeway).Unix() < claims.NotBefore {
+		return claims, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+// Get the key id of an access token without verifying it
+func KeyId(token string) (string, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return "", ErrInvalidToken
+	}
+	headerJSON, err := decodeSegment(segments[0])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	var tokenHeader header
+	if err := json.Unmarshal(headerJSON, &tokenHeader); err != nil || tokenHeader.Algorithm != Algorithm || tokenHeader.KeyId == "" {
+		return "", ErrInvalidToken
+	}
+	return tokenHeader.KeyId, nil
+}
+
+// Get the token of an "Authorization: Bearer <token>" header, empty if there is none
+func BearerToken(authorization string) string {
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return ""
+}
+
+// Decode a segment of a token from unpadded base64url
+func decodeSegment(input string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(input)
+}
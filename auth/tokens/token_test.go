@@ -0,0 +1,233 @@
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Issuer, audience and key id of the tests
+const (
+	testIssuer   = "election-auth"
+	testAudience = "election"
+	testKeyId    = "key-1"
+)
+
+func newTestKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publicKey, privateKey
+}
+
+// Create claims which are valid for the next minutes
+func newTestClaims() Claims {
+	now := time.Now()
+	return Claims{
+		Issuer:    testIssuer,
+		Subject:   "client:updater",
+		Audience:  testAudience,
+		ExpiresAt: now.Add(5 * time.Minute).Unix(),
+		NotBefore: now.Unix(),
+		IssuedAt:  now.Unix(),
+		Id:        "token-1",
+		Scope:     "parliament:write presidency:write",
+		ClientId:  "updater",
+	}
+}
+
+// Create a token with any header and signature, used to forge tokens
+func forgeToken(t *testing.T, tokenHeader header, claims Claims, sign func(signingInput string) []byte) string {
+	t.Helper()
+	headerJSON, err := json.Marshal(tokenHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingInput := encodeSegment(headerJSON) + "." + encodeSegment(claimsJSON)
+	return signingInput + "." + encodeSegment(sign(signingInput))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+	_, otherPrivateKey := newTestKey(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := func(keyId string) ed25519.PublicKey {
+		if keyId == testKeyId {
+			return publicKey
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name  string
+		token func(t *testing.T) string
+		err   error
+	}{
+		{
+			name: "valid token",
+			token: func(t *testing.T) string {
+				return mustSign(t, newTestClaims(), testKeyId, privateKey)
+			},
+		},
+		{
+			name: "expired token",
+			token: func(t *testing.T) string {
+				claims := newTestClaims()
+				claims.ExpiresAt = time.Now().Add(-Leeway - time.Minute).Unix()
+				return mustSign(t, claims, testKeyId, privateKey)
+			},
+			err: ErrExpiredToken,
+		},
+		{
+			name: "expired within the leeway",
+			token: func(t *testing.T) string {
+				claims := newTestClaims()
+				claims.ExpiresAt = time.Now().Add(-Leeway / 2).Unix()
+				return mustSign(t, claims, testKeyId, privateKey)
+			},
+		},
+		{
+			name: "token which is not valid yet",
+			token: func(t *testing.T) string {
+				claims := newTestClaims()
+				claims.NotBefore = time.Now().Add(Leeway + time.Minute).Unix()
+				return mustSign(t, claims, testKeyId, privateKey)
+			},
+			err: ErrExpiredToken,
+		},
+		{
+			name: "wrong issuer",
+			token: func(t *testing.T) string {
+				claims := newTestClaims()
+				claims.Issuer = "someone-else"
+				return mustSign(t, claims, testKeyId, privateKey)
+			},
+			err: ErrInvalidToken,
+		},
+		{
+			name: "wrong audience",
+			token: func(t *testing.T) string {
+				claims := newTestClaims()
+				claims.Audience = "another-service"
+				return mustSign(t, claims, testKeyId, privateKey)
+			},
+			err: ErrInvalidToken,
+		},
+		{
+			name: "unknown key id",
+			token: func(t *testing.T) string {
+				return mustSign(t, newTestClaims(), "key-2", otherPrivateKey)
+			},
+			err: ErrUnknownKey,
+		},
+		{
+			name: "known key id signed with another key",
+			token: func(t *testing.T) string {
+				return mustSign(t, newTestClaims(), testKeyId, otherPrivateKey)
+			},
+			err: ErrInvalidToken,
+		},
+		{
+			name: "changed claims",
+			token: func(t *testing.T) string {
+				token := mustSign(t, newTestClaims(), testKeyId, privateKey)
+				claims := newTestClaims()
+				claims.Scope = "info:write"
+				forged := forgeToken(t, header{Algorithm: Algorithm, Type: "JWT", KeyId: testKeyId}, claims, func(string) []byte { return nil })
+				segments, forgedSegments := strings.Split(token, "."), strings.Split(forged, ".")
+				return segments[0] + "." + forgedSegments[1] + "." + segments[2]
+			},
+			err: ErrInvalidToken,
+		},
+		{
+			name: "rs256 token of the openid connect keys",
+			token: func(t *testing.T) string {
+				return mustSign(t, newTestClaims(), testKeyId, rsaKey)
+			},
+			err: ErrInvalidToken,
+		},
+		{
+			name: "alg none",
+			token: func(t *testing.T) string {
+				return forgeToken(t, header{Algorithm: "none", Type: "JWT", KeyId: testKeyId}, newTestClaims(), func(string) []byte { return nil })
+			},
+			err: ErrInvalidToken,
+		},
+		{
+			name: "hs256 with the public key as secret",
+			token: func(t *testing.T) string {
+				return forgeToken(t, header{Algorithm: "HS256", Type: "JWT", KeyId: testKeyId}, newTestClaims(), func(signingInput string) []byte {
+					mac := hmac.New(sha256.New, publicKey)
+					mac.Write([]byte(signingInput))
+					return mac.Sum(nil)
+				})
+			},
+			err: ErrInvalidToken,
+		},
+		{
+			name: "missing key id",
+			token: func(t *testing.T) string {
+				return mustSign(t, newTestClaims(), "", privateKey)
+			},
+			err: ErrInvalidToken,
+		},
+		{
+			name:  "malformed token",
+			token: func(t *testing.T) string { return "not.a-token" },
+			err:   ErrInvalidToken,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := Verify(test.token(t), testIssuer, testAudience, keys)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got the error %v, want %v", err, test.err)
+			}
+			if test.err == nil && (claims.Subject != "client:updater" || !claims.HasScope("parliament:write") || claims.HasScope("info:write")) {
+				t.Fatalf("got the claims %+v", claims)
+			}
+		})
+	}
+}
+
+// Sign the claims and fail the test on an error
+func mustSign(t *testing.T, claims Claims, keyId string, privateKey crypto.Signer) string {
+	t.Helper()
+	token, err := Sign(claims, keyId, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := map[string]string{
+		"Bearer abc.def.ghi":   "abc.def.ghi",
+		"bearer  abc.def.ghi ": "abc.def.ghi",
+		"Basic dXNlcjpwYXNz":   "",
+		"Bearer":               "",
+		"":                     "",
+	}
+	for authorization, want := range tests {
+		if got := BearerToken(authorization); got != want {
+			t.Errorf("BearerToken(%q) = %q, want %q", authorization, got, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package tokens
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Verifier checks tokens against the keys published by the authentication service
+//
+// The keys are cached and only fetched again after an hour or when a token names a key that is
+// not cached yet, which happens after a key rotation. Tokens are verified offline in between.
+type Verifier struct {
+	url      string
+	issuer   string
+	audience string
+	client   *http.Client
+
+	mutex     sync.Mutex
+	keys      map[string]ed25519.PublicKey
+	fetchedAt time.Time
+}
+
+// Time after which the cached keys are fetched again
+const keyRefreshInterval = time.Hour
+
+// Shortest time between two fetches, so that tokens with made up key ids cannot flood the authentication service
+const minimumRefreshInterval = time.Minute
+
+// Create a verifier for the tokens of an issuer and audience, the url is the JWKS of the authentication service
+func NewVerifier(url string, issuer string, audience string) *Verifier {
+	return &Verifier{
+		url:      url,
+		issuer:   issuer,
+		audience: audience,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		keys:     map[string]ed25519.PublicKey{},
+	}
+}
+
+// Verify a token and return its claims
+func (verifier *Verifier) Verify(token string) (Claims, error) {
+	keyId, err := KeyId(token)
+	if err != nil {
+		return Claims{}, err
+	}
+	publicKey, err := verifier.key(keyId)
+	if err != nil {
+		return Claims{}, err
+	}
+	return Verify(token, verifier.issuer, verifier.audience, func(string) ed25519.PublicKey {
+		return publicKey
+	})
+}
+
+// Get the cached public key of a key id, the keys are fetched if they are old or the key is unknown
+func (verifier *Verifier) key(keyId string) (ed25519.PublicKey, error) {
+	verifier.mutex.Lock()
+	defer verifier.mutex.Unlock()
+
+	publicKey, ok := verifier.keys[keyId]
+	age := time.Since(verifier.fetchedAt)
+	if (ok && age < keyRefreshInterval) || (!ok && age < minimumRefreshInterval) {
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		return publicKey, nil
+	}
+
+	// Keep the cached keys if the authentication service is unreachable
+	if err := verifier.fetch(); err != nil {
+		if ok {
+			return publicKey, nil
+		}
+		return nil, err
+	}
+	publicKey, ok = verifier.keys[keyId]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return publicKey, nil
+}
+
+// Fetch the published keys and replace the cached ones
+func (verifier *Verifier) fetch() error {
+	verifier.fetchedAt = time.Now()
+	response, err := verifier.client.Get(verifier.url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return errors.New("fetching the keys returned " + response.Status)
+	}
+
+	var keySet JWKS
+	if err := json.NewDecoder(response.Body).Decode(&keySet); err != nil {
+		return err
+	}
+	keys := map[string]ed25519.PublicKey{}
+	for _, key := range keySet.Keys {
+		publicKey, err := key.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[key.KeyId] = publicKey
+	}
+	verifier.keys = keys
+	return nil
+}
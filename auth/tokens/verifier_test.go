@@ -0,0 +1,144 @@
+package tokens
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// JWKS endpoint of the tests, the published keys can be replaced to rotate them
+type testJWKSServer struct {
+	*httptest.Server
+	mutex   sync.Mutex
+	keySet  JWKS
+	fetches int
+	down    bool
+}
+
+func newTestJWKSServer(t *testing.T, keys ...JWK) *testJWKSServer {
+	t.Helper()
+	server := &testJWKSServer{keySet: JWKS{Keys: keys}}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		server.fetches++
+		if server.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(server.keySet)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (server *testJWKSServer) publish(keys ...JWK) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.keySet = JWKS{Keys: keys}
+}
+
+func (server *testJWKSServer) setDown(down bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.down = down
+}
+
+func (server *testJWKSServer) fetchCount() int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.fetches
+}
+
+func TestVerifierFetchesAndCachesTheKeys(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+	server := newTestJWKSServer(t, NewJWK(testKeyId, publicKey))
+	verifier := NewVerifier(server.URL, testIssuer, testAudience)
+
+	// The keys are fetched once and the tokens are verified offline afterwards
+	for i := 0; i < 3; i++ {
+		if _, err := verifier.Verify(mustSign(t, newTestClaims(), testKeyId, privateKey)); err != nil {
+			t.Fatalf("verify %d: %v", i, err)
+		}
+	}
+	if fetches := server.fetchCount(); fetches != 1 {
+		t.Fatalf("got %d fetches, want 1", fetches)
+	}
+
+	// The checks of Verify apply to the verifier
+	claims := newTestClaims()
+	claims.Audience = "another-service"
+	if _, err := verifier.Verify(mustSign(t, claims, testKeyId, privateKey)); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("got the error %v for the wrong audience, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestVerifierFetchesRotatedKeys(t *testing.T) {
+	oldPublicKey, oldPrivateKey := newTestKey(t)
+	newPublicKey, newPrivateKey := newTestKey(t)
+	server := newTestJWKSServer(t, NewJWK("key-1", oldPublicKey))
+	verifier := NewVerifier(server.URL, testIssuer, testAudience)
+	if _, err := verifier.Verify(mustSign(t, newTestClaims(), "key-1", oldPrivateKey)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new key is not fetched again right away, so that made up key ids cannot flood the authentication service
+	server.publish(NewJWK("key-1", oldPublicKey), NewJWK("key-2", newPublicKey))
+	if _, err := verifier.Verify(mustSign(t, newTestClaims(), "key-2", newPrivateKey)); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("got the error %v, want %v", err, ErrUnknownKey)
+	}
+
+	// The new key is fetched once the minimum refresh interval has passed
+	verifier.fetchedAt = time.Now().Add(-minimumRefreshInterval)
+	if _, err := verifier.Verify(mustSign(t, newTestClaims(), "key-2", newPrivateKey)); err != nil {
+		t.Fatalf("the rotated key has not been fetched: %v", err)
+	}
+	if fetches := server.fetchCount(); fetches != 2 {
+		t.Fatalf("got %d fetches, want 2", fetches)
+	}
+
+	// Removed keys are no longer accepted after the next refresh
+	server.publish(NewJWK("key-2", newPublicKey))
+	verifier.fetchedAt = time.Now().Add(-keyRefreshInterval)
+	if _, err := verifier.Verify(mustSign(t, newTestClaims(), "key-1", oldPrivateKey)); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("got the error %v for the removed key, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestVerifierKeepsTheKeysWhenTheServiceIsDown(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+	server := newTestJWKSServer(t, NewJWK(testKeyId, publicKey))
+	verifier := NewVerifier(server.URL, testIssuer, testAudience)
+	if _, err := verifier.Verify(mustSign(t, newTestClaims(), testKeyId, privateKey)); err != nil {
+		t.Fatal(err)
+	}
+
+	server.setDown(true)
+	verifier.fetchedAt = time.Now().Add(-keyRefreshInterval)
+	if _, err := verifier.Verify(mustSign(t, newTestClaims(), testKeyId, privateKey)); err != nil {
+		t.Fatalf("the cached key has not been used: %v", err)
+	}
+}
+
+func TestJWKPublicKey(t *testing.T) {
+	publicKey, _ := newTestKey(t)
+	parsed, err := NewJWK(testKeyId, publicKey).PublicKey()
+	if err != nil || !parsed.Equal(publicKey) {
+		t.Fatalf("got %v and %v, want the published key", parsed, err)
+	}
+
+	// Keys which are no ed25519 keys are rejected
+	for _, key := range []JWK{
+		{KeyType: "RSA", KeyId: "rsa", N: "AQAB", E: "AQAB"},
+		{KeyType: "OKP", Curve: "X25519", KeyId: "x25519", X: NewJWK("", publicKey).X},
+		{KeyType: "OKP", Curve: "Ed25519", KeyId: "short", X: "AQAB"},
+	} {
+		if _, err := key.PublicKey(); err == nil {
+			t.Errorf("the key %s has been accepted", key.KeyId)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package utilities
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Files of the package which are copied into the services, with the services having a copy
+var copiedFiles = map[string][]string{
+	"normalize.go":   {"auth", "info", "parliament", "presidency"},
+	"search.go":      {"info", "parliament", "presidency"},
+	"copies_test.go": {"auth", "info", "parliament", "presidency"},
+}
+
+// The copies have to stay the same, a change is made in all services at once
+func TestCopiesAreTheSame(t *testing.T) {
+	for file, services := range copiedFiles {
+		var reference []byte
+		for _, service := range services {
+			// Services which are not checked out are skipped
+			if _, err := os.Stat(filepath.Join("..", "..", service)); err != nil {
+				continue
+			}
+			path := filepath.Join("..", "..", service, "utilities", file)
+			copied, err := os.ReadFile(path)
+			if err != nil {
+				t.Errorf("%s: %v", path, err)
+				continue
+			}
+			if reference == nil {
+				reference = copied
+			} else if !bytes.Equal(reference, copied) {
+				t.Errorf("%s differs from the copy of %s, keep the copies the same", path, services[0])
+			}
+		}
+	}
+}
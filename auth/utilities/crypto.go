@@ -2,35 +2,108 @@ package utilities
 
 import (
 	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
-	"math/rand"
+	"strconv"
+	"strings"
 
-	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Hash a plain password with the bcrypt library
-func HashPassword(password string) string {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+// Parameters of the argon2id password hashes
+type argon2Params struct {
+	memory      uint32 // KiB, AUTH_ARGON2_MEMORY_KIB
+	iterations  uint32 // AUTH_ARGON2_ITERATIONS
+	parallelism uint8  // AUTH_ARGON2_PARALLELISM
+}
+
+// Length of the salts and the keys of the password hashes
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
 
+// Hash a plain password with argon2id, the hash is in the PHC format
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key> so that the parameters can be changed later
+func HashPassword(password string) string {
+	params := currentArgon2Params()
+	salt, err := GenerateRandomBytes(argon2SaltLength)
 	if err != nil {
 		log.Println(err)
+		return ""
 	}
-
-	return string(hash)
+	key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.memory, params.iterations, params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
 }
 
-// Check a hashed password with a plain password
+// Check a hashed password with a plain password, the bcrypt hashes from before argon2id are still accepted
 func CheckPassword(hashedPassword string, plainPassword string) bool {
-	byteHash := []byte(hashedPassword)
-	err := bcrypt.CompareHashAndPassword(byteHash, []byte(plainPassword))
-
+	if strings.HasPrefix(hashedPassword, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(plainPassword)) == nil
+	}
+	params, salt, key, err := parseArgon2Hash(hashedPassword)
 	if err != nil {
 		log.Println(err)
 		return false
 	}
-	return true
+	plainKey := argon2.IDKey([]byte(plainPassword), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(plainKey, key) == 1
+}
+
+// Check if a password hash should be replaced after a successful login, because it is a bcrypt hash or its
+// parameters are not the current ones
+func PasswordNeedsRehash(hashedPassword string) bool {
+	params, _, _, err := parseArgon2Hash(hashedPassword)
+	return err != nil || params != currentArgon2Params()
+}
+
+// Get the parameters of new password hashes from the environment
+func currentArgon2Params() argon2Params {
+	return argon2Params{
+		memory:      uint32(envUint("AUTH_ARGON2_MEMORY_KIB", 64*1024, 1<<32-1)),
+		iterations:  uint32(envUint("AUTH_ARGON2_ITERATIONS", 3, 1<<32-1)),
+		parallelism: uint8(envUint("AUTH_ARGON2_PARALLELISM", 2, 255)),
+	}
+}
+
+// Parse the parameters, the salt and the key of an argon2id hash
+func parseArgon2Hash(hashedPassword string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	var version int
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errors.New("the password hash is not an argon2id hash")
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("the argon2 version of the password hash is not supported")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, err
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	return params, salt, key, nil
+}
+
+// Get a positive number up to the maximum from the environment or the default value
+func envUint(key string, defaultValue uint64, maximum uint64) uint64 {
+	value, err := strconv.ParseUint(GetEnv(key, strconv.FormatUint(defaultValue, 10)), 10, 64)
+	if err != nil || value < 1 || value > maximum {
+		return defaultValue
+	}
+	return value
 }
 
 // Hash with SHA512
@@ -52,18 +125,24 @@ func HashSHA512(input string) string {
 	return hashedInputHex
 }
 
-// Check if SHA512 hashes match
-func CheckSHA512(hashedInput string, input string) bool {
-	hashedNewInput := HashPassword(input)
-	return hashedNewInput == hashedInput
+// Create a random recovery code like 7kq2m-x9d4t from the crockford base32 letters, which are easy to read and type
+func GenerateRecoveryCode() (string, error) {
+	letters := "0123456789abcdefghjkmnpqrstvwxyz"
+	bytes, err := GenerateRandomBytes(10)
+	if err != nil {
+		return "", err
+	}
+	code := make([]byte, 0, 11)
+	for index, b := range bytes {
+		if index == 5 {
+			code = append(code, '-')
+		}
+		code = append(code, letters[int(b)%len(letters)])
+	}
+	return string(code), nil
 }
 
-// Create a random string
-func RandomStringWithUUID(n int) string {
-	letterRunes := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
-	b := make([]rune, n)
-	for i := range b {
-		b[i] = letterRunes[rand.Intn(len(letterRunes))]
-	}
-	return string(b) + (uuid.New().String())
+// Normalize a recovery code before hashing it, the case, spaces and dashes are ignored
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
 }
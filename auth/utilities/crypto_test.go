@@ -0,0 +1,77 @@
+package utilities
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Use cheap argon2 parameters, the defaults make the tests slow
+func setTestArgon2Params(t *testing.T, memory string) {
+	t.Helper()
+	t.Setenv("AUTH_ARGON2_MEMORY_KIB", memory)
+	t.Setenv("AUTH_ARGON2_ITERATIONS", "1")
+	t.Setenv("AUTH_ARGON2_PARALLELISM", "1")
+}
+
+func TestHashAndCheckPassword(t *testing.T) {
+	setTestArgon2Params(t, "1024")
+	hashedPassword := HashPassword("doğru at pili zımba")
+	if !strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("got the hash %q, want an argon2id hash with the current parameters", hashedPassword)
+	}
+	if hashedPassword == HashPassword("doğru at pili zımba") {
+		t.Fatal("two hashes of the same password are the same, the salt is missing")
+	}
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("doğru at pili zımba"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		hashedPassword string
+		plainPassword  string
+		valid          bool
+	}{
+		{"argon2id hash", hashedPassword, "doğru at pili zımba", true},
+		{"argon2id hash with the wrong password", hashedPassword, "dogru at pili zimba", false},
+		{"bcrypt hash from before argon2id", string(bcryptHash), "doğru at pili zımba", true},
+		{"bcrypt hash with the wrong password", string(bcryptHash), "yanlış", false},
+		{"malformed hash", "$argon2id$v=19$m=1024$abc$def", "doğru at pili zımba", false},
+		{"argon2i hash", strings.Replace(hashedPassword, "argon2id", "argon2i", 1), "doğru at pili zımba", false},
+		{"empty hash", "", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if valid := CheckPassword(test.hashedPassword, test.plainPassword); valid != test.valid {
+				t.Fatalf("got %t, want %t", valid, test.valid)
+			}
+		})
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	setTestArgon2Params(t, "1024")
+	hashedPassword := HashPassword("doğru at pili zımba")
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("doğru at pili zımba"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if PasswordNeedsRehash(hashedPassword) {
+		t.Fatal("a hash with the current parameters needs a rehash")
+	}
+	if !PasswordNeedsRehash(string(bcryptHash)) {
+		t.Fatal("a bcrypt hash does not need a rehash")
+	}
+
+	// Hashes with old parameters are replaced, but still accepted until then
+	setTestArgon2Params(t, "2048")
+	if !PasswordNeedsRehash(hashedPassword) {
+		t.Fatal("a hash with old parameters does not need a rehash")
+	}
+	if !CheckPassword(hashedPassword, "doğru at pili zımba") {
+		t.Fatal("a hash with old parameters is not accepted")
+	}
+}
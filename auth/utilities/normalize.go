@@ -0,0 +1,165 @@
+// Turkish folding, slugs and collation shared by all services. The file is copied into every service
+// and copies_test.go checks that the copies stay the same, change them all at once.
+
+package utilities
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Replacements of the turkish letters and circumflexed vowels by their ascii letters
+var asciiFolding = map[rune]rune{
+	'ç': 'c',
+	'ğ': 'g',
+	'ı': 'i',
+	'ö': 'o',
+	'ş': 's',
+	'ü': 'u',
+	'â': 'a',
+	'î': 'i',
+	'û': 'u',
+}
+
+// Order of the letters of the turkish alphabet, q, w and x are placed like in the latin alphabet
+const turkishAlphabet = "abcçdefgğhıijklmnoöpqrsştuüvwxyz"
+
+// Weights of the letters of the turkish alphabet for sorting
+var turkishWeights = func() map[rune]int {
+	weights := map[rune]int{}
+	for index, letter := range []rune(turkishAlphabet) {
+		weights[letter] = unicode.MaxRune + index + 1
+	}
+	weights['â'], weights['î'], weights['û'] = weights['a'], weights['i'], weights['u']
+	return weights
+}()
+
+// Lowercase a string with the turkish rules, I becomes ı and İ becomes i
+func TurkishLower(input string) string {
+	return strings.ToLowerSpecial(unicode.TurkishCase, input)
+}
+
+// Uppercase a string with the turkish rules, i becomes İ and ı becomes I
+func TurkishUpper(input string) string {
+	return strings.ToUpperSpecial(unicode.TurkishCase, input)
+}
+
+// Fold a string for comparisons, the case, the turkish letters and repeated whitespace are ignored
+//
+//	"  ÇANKAYA " -> "cankaya"
+func Fold(input string) string {
+	var builder strings.Builder
+	for _, r := range TurkishLower(strings.Join(strings.Fields(input), " ")) {
+		if folded, ok := asciiFolding[r]; ok {
+			r = folded
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// Create a slug of a string which can be used in urls and as a name
+//
+//	"Çankaya" -> "cankaya", "Ankara 1. Bölge" -> "ankara-1-bolge"
+func Slug(input string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range Fold(input) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && builder.Len() > 0 {
+				builder.WriteRune('-')
+			}
+			builder.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return builder.String()
+}
+
+// Check if two strings are equal ignoring the case and the turkish letters
+//
+//	EqualFold("Cankaya", "ÇANKAYA") == true
+func EqualFold(a string, b string) bool {
+	return Fold(a) == Fold(b)
+}
+
+// Variants of the ascii letters which are equal to them in a search
+var searchVariants = map[rune]string{
+	'a': "aâAÂ",
+	'c': "cçCÇ",
+	'g': "gğGĞ",
+	'i': "iıîIİÎ",
+	'o': "oöOÖ",
+	's': "sşSŞ",
+	'u': "uüûUÜÛ",
+}
+
+// Create a regular expression matching the string ignoring the case and the turkish letters
+//
+//	SearchPattern("cankaya") == "[cçCÇ][aâAÂ][nN][kK][aâAÂ][yY][aâAÂ]"
+func SearchPattern(input string) string {
+	var builder strings.Builder
+	for _, r := range Fold(input) {
+		if variants, ok := searchVariants[r]; ok {
+			builder.WriteString("[" + variants + "]")
+		} else if upper := unicode.ToUpper(r); upper != r {
+			builder.WriteString("[" + string(r) + string(upper) + "]")
+		} else {
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return builder.String()
+}
+
+// Compare two strings in the order of the turkish alphabet, returns -1, 0 or 1
+//
+// The case is only used to order otherwise equal strings, so that "Çankaya" < "Ilgaz" < "İnegöl"
+// instead of the byte order of strings.Compare which puts the turkish letters after z.
+func Compare(a string, b string) int {
+	if result := compareRunes([]rune(TurkishLower(a)), []rune(TurkishLower(b))); result != 0 {
+		return result
+	}
+	return strings.Compare(a, b)
+}
+
+// Sort the strings in the order of the turkish alphabet
+func SortTurkish(values []string) {
+	sort.SliceStable(values, func(i, j int) bool {
+		return Compare(values[i], values[j]) < 0
+	})
+}
+
+// Compare the lowercased runes by their weights in the turkish alphabet
+func compareRunes(a []rune, b []rune) int {
+	for index := 0; index < len(a) && index < len(b); index++ {
+		weightA, weightB := runeWeight(a[index]), runeWeight(b[index])
+		if weightA < weightB {
+			return -1
+		}
+		if weightA > weightB {
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// Get the weight of a rune, digits and punctuation are sorted before and other letters after the turkish alphabet
+func runeWeight(r rune) int {
+	if weight, ok := turkishWeights[r]; ok {
+		return weight
+	}
+	if unicode.IsLetter(r) {
+		return unicode.MaxRune + len(turkishWeights) + 1 + int(r)
+	}
+	return int(r)
+}
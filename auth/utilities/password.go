@@ -0,0 +1,95 @@
+package utilities
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Hashes of the breached passwords, loaded once from AUTH_PASSWORD_BREACHED_FILE
+var (
+	breachedPasswords     map[string]bool
+	breachedPasswordsOnce sync.Once
+)
+
+// Returned if a password is on the list of breached passwords
+var ErrBreachedPassword = errors.New("the password has appeared in a data breach, choose another one")
+
+// Check a new password against the password policy, the personal values like the username and the email must not
+// be used as the password
+//
+// The length is set with AUTH_PASSWORD_MIN_LENGTH and AUTH_PASSWORD_MAX_LENGTH. The breached passwords are read from
+// the file in AUTH_PASSWORD_BREACHED_FILE, with one password or upper case sha1 hash per line, so that lists like the
+// pwned passwords (HASH:COUNT) can be used as they are.
+func CheckPasswordPolicy(password string, personal ...string) error {
+	minLength := envUint("AUTH_PASSWORD_MIN_LENGTH", 12, 1024)
+	maxLength := envUint("AUTH_PASSWORD_MAX_LENGTH", 256, 4096)
+	length := uint64(utf8.RuneCountInString(password))
+	if length < minLength {
+		return fmt.Errorf("the password must be at least %d characters long", minLength)
+	}
+	if length > maxLength {
+		return fmt.Errorf("the password must be at most %d characters long", maxLength)
+	}
+	for _, value := range personal {
+		if value != "" && EqualFold(password, value) {
+			return errors.New("the password must not be your name, username or email")
+		}
+	}
+
+	breachedPasswordsOnce.Do(loadBreachedPasswords)
+	hash := sha1.Sum([]byte(password))
+	if breachedPasswords[strings.ToUpper(hex.EncodeToString(hash[:]))] {
+		return ErrBreachedPassword
+	}
+	return nil
+}
+
+// Load the breached passwords, the policy only checks the length if the file is not set or cannot be read
+func loadBreachedPasswords() {
+	breachedPasswords = map[string]bool{}
+	fileName := GetEnv("AUTH_PASSWORD_BREACHED_FILE", "")
+	if fileName == "" {
+		return
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		log.Println("error reading the breached passwords: " + err.Error())
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if hash, _, _ := strings.Cut(line, ":"); isSHA1(hash) {
+			breachedPasswords[strings.ToUpper(hash)] = true
+			continue
+		}
+		hash := sha1.Sum([]byte(line))
+		breachedPasswords[strings.ToUpper(hex.EncodeToString(hash[:]))] = true
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println("error reading the breached passwords: " + err.Error())
+	}
+	log.Printf("loaded %d breached passwords", len(breachedPasswords))
+}
+
+// Check if a value is a hex sha1 hash
+func isSHA1(value string) bool {
+	if len(value) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
@@ -0,0 +1,76 @@
+package utilities
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Load the breached passwords again with the file of the test
+func setBreachedPasswords(t *testing.T, lines ...string) {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(fileName, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AUTH_PASSWORD_BREACHED_FILE", fileName)
+	breachedPasswordsOnce = sync.Once{}
+	t.Cleanup(func() { breachedPasswordsOnce = sync.Once{} })
+}
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	t.Setenv("AUTH_PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("AUTH_PASSWORD_MAX_LENGTH", "64")
+	breachedHash := sha1.Sum([]byte("galatasaray1905"))
+	setBreachedPasswords(t,
+		"# passwords of the tests",
+		"password1234",
+		"",
+		// Lines of the pwned passwords list are upper case sha1 hashes with a count
+		strings.ToUpper(hex.EncodeToString(breachedHash[:]))+":1905",
+	)
+	personal := []string{"ismail.yilmaz", "ismail@example.com", "İsmail", "Yılmaz"}
+
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+		err      error
+	}{
+		{"long enough", "doğru at pili zımba", true, nil},
+		{"too short", "kısa şifre", false, nil},
+		{"letters and not bytes are counted", "ğüşıöçğüşıöç", true, nil},
+		{"too long", strings.Repeat("a", 65), false, nil},
+		{"username", "ISMAIL.YILMAZ", false, nil},
+		{"email", "ismail@example.com", false, nil},
+		{"username with turkish letters", "İSMAİL.YILMAZ", false, nil},
+		{"breached password", "password1234", false, ErrBreachedPassword},
+		{"breached password from the sha1 list", "galatasaray1905", false, ErrBreachedPassword},
+		{"personal value as part of a password", "ismail.yilmaz-doğru-at", true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckPasswordPolicy(test.password, personal...)
+			if (err == nil) != test.valid {
+				t.Fatalf("got the error %v, want valid %t", err, test.valid)
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Fatalf("got the error %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestCheckPasswordPolicyWithoutBreachedFile(t *testing.T) {
+	// Only the length and the personal values are checked without the file
+	setBreachedPasswords(t)
+	t.Setenv("AUTH_PASSWORD_BREACHED_FILE", "")
+	if err := CheckPasswordPolicy("password1234"); err != nil {
+		t.Fatalf("got the error %v without the breached passwords", err)
+	}
+}
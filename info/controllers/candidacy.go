@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/yzaimoglu/election/info/models"
+	"github.com/yzaimoglu/election/info/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Returns a single candidacy with a specific id, ?embed=profile embeds the individual and the party
+//
+// With ?at=2018-06-24 the individual and the party are embedded as they were on that date.
+func GetCandidacy(c *gin.Context) {
+	id := c.Param("id")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	var candidacy models.Candidacy
+
+	// Object ID from id param
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "bad request id should be in hex",
+		})
+		return
+	}
+
+	// Find the candidacy in the database
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	result := database.Collection("candidacies").FindOne(ctx, bson.M{"_id": objId})
+
+	// Check if there is a candidacy with that id
+	if result.Err() == mongo.ErrNoDocuments {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status":  http.StatusNotFound,
+			"message": "no candidacy with that id found",
+		})
+		return
+	}
+
+	// Decode candidacy into object
+	if err := result.Decode(&candidacy); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Embed the individual and the party
+	if c.Query("embed") == "profile" {
+		at, versioned, ok := parseVersionAt(c)
+		if !ok {
+			return
+		}
+		candidacies := []models.Candidacy{candidacy}
+		if err := embedCandidacyProfiles(ctx, database, candidacies, at, versioned); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+			return
+		}
+		candidacy = candidacies[0]
+	}
+
+	// Return candidacy
+	c.JSON(http.StatusOK, candidacy)
+}
+
+// Creates a new candidacy
+func CreateCandidacy(c *gin.Context) {
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Initialize the candidacy
+	var candidacy models.Candidacy
+
+	// Bind the input from the request body to the candidacy object
+	if err := c.ShouldBindJSON(&candidacy); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Create a new objectId for the candidacy
+	candidacy.Id = primitive.NewObjectID()
+
+	// Validate the input and the references
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	if !validateCandidacy(c, ctx, database, &candidacy) {
+		return
+	}
+
+	// Insert candidacy
+	if _, err := database.Collection("candidacies").InsertOne(ctx, candidacy); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"status": http.StatusConflict,
+				"error":  "this candidacy already exists in the election",
+			})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the recently created candidacy
+	c.JSON(http.StatusOK, candidacy)
+}
+
+// Changes all fields of a candidacy
+func ChangeCandidacy(c *gin.Context) {
+	id := c.Param("id")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Initialize the candidacy
+	var candidacy models.Candidacy
+
+	// Object ID from id param
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "bad request id should be in hex",
+		})
+		return
+	}
+
+	// Bind the input from the request body to the candidacy object
+	if err := c.ShouldBindJSON(&candidacy); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+	// Set the objectId
+	candidacy.Id = objId
+
+	// Validate the input and the references
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	if !validateCandidacy(c, ctx, database, &candidacy) {
+		return
+	}
+
+	// Replace the existing document with the new one
+	result, err := database.Collection("candidacies").ReplaceOne(ctx, bson.M{"_id": objId}, candidacy)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"status": http.StatusConflict,
+				"error":  "this candidacy already exists in the election",
+			})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the id of the updated object
+	c.JSON(http.StatusOK, gin.H{
+		"modifiedCount": result.ModifiedCount,
+		"updatedId":     objId,
+		"updatedObject": candidacy,
+	})
+}
+
+// Deletes a candidacy
+func DeleteCandidacy(c *gin.Context) {
+	id := c.Param("id")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Object ID from id param
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "bad request id should be in hex",
+		})
+		return
+	}
+
+	// Delete the object from the database
+	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("candidacies").DeleteOne(ctx, bson.M{"_id": objId})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Return deleted count and deleted Id
+	c.JSON(http.StatusOK, gin.H{
+		"deletedCount": result.DeletedCount,
+		"deletedId":    objId,
+	})
+}
+
+// Returns the candidacies in the collection, ?embed=profile embeds the individuals and parties
+//
+//	GET /v1/candidacies/?election=parliament-2023&constituency=ankara-1&embed=profile&at=2023-05-14
+func GetCandidacies(c *gin.Context) {
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, candidacyListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Find the page of the elements in the candidacies collection
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	page, err := findPage[models.Candidacy](ctx, database.Collection("candidacies"), nil, query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Embed the individuals and the parties, projected pages are returned as they are
+	if candidacies, ok := page.Data.([]models.Candidacy); ok && c.Query("embed") == "profile" {
+		at, versioned, ok := parseVersionAt(c)
+		if !ok {
+			return
+		}
+		if err := embedCandidacyProfiles(ctx, database, candidacies, at, versioned); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+			return
+		}
+	}
+
+	// Return the page of candidacies
+	c.JSON(http.StatusOK, page)
+}
+
+// Validate a candidacy and check if its individual and party exist, aborts the request if not
+func validateCandidacy(c *gin.Context, ctx context.Context, database *mongo.Database, candidacy *models.Candidacy) bool {
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(*candidacy); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return false
+	}
+	if candidacy.IndividualId == nil && candidacy.PartyId == nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "a candidacy needs an individualid or a partyid",
+		})
+		return false
+	}
+	candidacy.Individual, candidacy.Party = nil, nil
+
+	// Check if the referenced individual and party exist
+	references := []struct {
+		id         *primitive.ObjectID
+		collection string
+		message    string
+	}{
+		{candidacy.IndividualId, "individuals", "no individual with that individualid found"},
+		{candidacy.PartyId, "parties", "no party with that partyid found"},
+	}
+	for _, reference := range references {
+		if reference.id == nil {
+			continue
+		}
+		count, err := database.Collection(reference.collection).CountDocuments(ctx, bson.M{"_id": *reference.id})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+			return false
+		}
+		if count == 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  reference.message,
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// Embed the individuals and parties of the candidacies, as they were at the given time if versioned is set
+func embedCandidacyProfiles(ctx context.Context, database *mongo.Database, candidacies []models.Candidacy, at time.Time, versioned bool) error {
+	// Collect the referenced ids
+	var individualIds, partyIds []primitive.ObjectID
+	for _, candidacy := range candidacies {
+		if candidacy.IndividualId != nil {
+			individualIds = append(individualIds, *candidacy.IndividualId)
+		}
+		if candidacy.PartyId != nil {
+			partyIds = append(partyIds, *candidacy.PartyId)
+		}
+	}
+
+	// Get the individuals and parties at once
+	if !versioned {
+		at = time.Now().UTC()
+	}
+	individuals, err := models.FindDocumentsAt[models.Individual](ctx, database, "individuals", individualIds, at)
+	if err != nil {
+		return err
+	}
+	parties, err := models.FindDocumentsAt[models.Party](ctx, database, "parties", partyIds, at)
+	if err != nil {
+		return err
+	}
+
+	// Set the profiles
+	for index, candidacy := range candidacies {
+		if candidacy.IndividualId != nil {
+			if individual, ok := individuals[*candidacy.IndividualId]; ok {
+				candidacies[index].Individual = &individual
+			}
+		}
+		if candidacy.PartyId != nil {
+			if party, ok := parties[*candidacy.PartyId]; ok {
+				candidacies[index].Party = &party
+			}
+		}
+	}
+	return nil
+}
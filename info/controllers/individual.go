@@ -13,12 +13,11 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// Returns a single individual with a specific id
+// Returns a single individual with a specific id, ?at=2018-06-24 returns it as it was on that date
 func GetIndividual(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	var individual models.Individual
 
@@ -32,6 +31,16 @@ func GetIndividual(c *gin.Context) {
 		return
 	}
 
+	// Return the individual as it was at the date of ?at=
+	at, versioned, ok := parseVersionAt(c)
+	if !ok {
+		return
+	}
+	if versioned {
+		getDocumentAt[models.Individual](c, ctx, client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")), "individuals", objId, at, "no version of the individual valid at that date found")
+		return
+	}
+
 	// Find the individual in the database
 	result := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("individuals").FindOne(ctx, &bson.M{"_id": objId})
 
@@ -53,9 +62,8 @@ func GetIndividual(c *gin.Context) {
 
 // Creates a new individual
 func CreateIndividual(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the individual
 	var individual models.Individual
@@ -82,8 +90,15 @@ func CreateIndividual(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the individual
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Individual](c, ctx, database, "individuals", individual.Id)
+	if !ok {
+		return
+	}
+
 	// Insert individual
-	if _, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("individuals").InsertOne(ctx, individual); err != nil {
+	if _, err := database.Collection("individuals").InsertOne(ctx, individual); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
 			"error":  err.Error(),
@@ -91,6 +106,11 @@ func CreateIndividual(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the individual
+	if !recordVersion[models.Individual](c, ctx, database, "individuals", individual.Id, from) {
+		return
+	}
+
 	// Return the recently created individual
 	c.JSON(http.StatusOK, individual)
 }
@@ -98,9 +118,8 @@ func CreateIndividual(c *gin.Context) {
 // Changes all fields of an indiviudal
 func ChangeIndividual(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the individual
 	var individual models.Individual
@@ -136,8 +155,15 @@ func ChangeIndividual(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the individual
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Individual](c, ctx, database, "individuals", objId)
+	if !ok {
+		return
+	}
+
 	// Replace the existing document with the new one
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("individuals").ReplaceOne(ctx, bson.M{"_id": objId}, individual)
+	result, err := database.Collection("individuals").ReplaceOne(ctx, bson.M{"_id": objId}, individual)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -146,6 +172,11 @@ func ChangeIndividual(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the individual
+	if !recordVersion[models.Individual](c, ctx, database, "individuals", objId, from) {
+		return
+	}
+
 	// Return the id of the updated object
 	c.JSON(http.StatusOK, gin.H{
 		"modifiedCount": result.ModifiedCount,
@@ -157,9 +188,8 @@ func ChangeIndividual(c *gin.Context) {
 // Changes an individuals first name
 func ChangeIndividualFirstName(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the input object
 	var input models.UpdateIndividualFirstNameInput
@@ -193,8 +223,15 @@ func ChangeIndividualFirstName(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the individual
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Individual](c, ctx, database, "individuals", objId)
+	if !ok {
+		return
+	}
+
 	// Change the first name of the individual
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("individuals").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"firstname": input.FirstName}})
+	result, err := database.Collection("individuals").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"firstname": input.FirstName}})
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -203,6 +240,11 @@ func ChangeIndividualFirstName(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the individual
+	if !recordVersion[models.Individual](c, ctx, database, "individuals", objId, from) {
+		return
+	}
+
 	// Return the id and the first name of the updated object
 	c.JSON(http.StatusOK, gin.H{
 		"modifiedCount":    result.ModifiedCount,
@@ -214,9 +256,8 @@ func ChangeIndividualFirstName(c *gin.Context) {
 // Changes an individuals last name
 func ChangeIndividualLastName(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the input object
 	var input models.UpdateIndividualLastNameInput
@@ -250,8 +291,15 @@ func ChangeIndividualLastName(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the individual
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Individual](c, ctx, database, "individuals", objId)
+	if !ok {
+		return
+	}
+
 	// Change the last name of the individual
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("individuals").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"lastname": input.LastName}})
+	result, err := database.Collection("individuals").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"lastname": input.LastName}})
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -260,6 +308,11 @@ func ChangeIndividualLastName(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the individual
+	if !recordVersion[models.Individual](c, ctx, database, "individuals", objId, from) {
+		return
+	}
+
 	// Return the id and the last name of the updated object
 	c.JSON(http.StatusOK, gin.H{
 		"modifiedCount":   result.ModifiedCount,
@@ -271,9 +324,8 @@ func ChangeIndividualLastName(c *gin.Context) {
 // Changes an individuals birthdate
 func ChangeIndividualBirthdate(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the input object
 	var input models.UpdateIndividualBirthDateInput
@@ -307,8 +359,15 @@ func ChangeIndividualBirthdate(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the individual
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Individual](c, ctx, database, "individuals", objId)
+	if !ok {
+		return
+	}
+
 	// Change the birth date of the individual
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("individuals").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"birthdate": input.BirthDate}})
+	result, err := database.Collection("individuals").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"birthdate": input.BirthDate}})
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -317,6 +376,11 @@ func ChangeIndividualBirthdate(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the individual
+	if !recordVersion[models.Individual](c, ctx, database, "individuals", objId, from) {
+		return
+	}
+
 	// Return the id and the first name of the updated object
 	c.JSON(http.StatusOK, gin.H{
 		"modifiedCount":    result.ModifiedCount,
@@ -328,9 +392,8 @@ func ChangeIndividualBirthdate(c *gin.Context) {
 // Deletes an individual
 func DeleteIndividual(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Object ID from id param
 	objId, err := primitive.ObjectIDFromHex(id)
@@ -342,8 +405,15 @@ func DeleteIndividual(c *gin.Context) {
 		return
 	}
 
+	// Keep the current state of the individual as a version, deleted individuals can still be looked up with ?at=
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Individual](c, ctx, database, "individuals", objId)
+	if !ok {
+		return
+	}
+
 	// Delete the object from the database
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("individuals").DeleteOne(ctx, bson.M{"_id": objId})
+	result, err := database.Collection("individuals").DeleteOne(ctx, bson.M{"_id": objId})
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
@@ -352,6 +422,15 @@ func DeleteIndividual(c *gin.Context) {
 		return
 	}
 
+	// Close the current version of the individual
+	if err := models.CloseVersion(ctx, database, "individuals", objId, from); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
 	// Return deleted count and deleted Id
 	c.JSON(http.StatusOK, gin.H{
 		"deletedCount": result.DeletedCount,
@@ -361,48 +440,38 @@ func DeleteIndividual(c *gin.Context) {
 
 // Returns all individuals in the collection
 func GetIndividuals(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
-
-	var individuals []models.Individual
 
-	// Find all elements in teh individuals collection
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("individuals").Find(ctx, bson.M{})
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, individualListOptions)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
-		})
-		return
-	}
-
-	// Return that no individual has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no individuals",
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Decode all elements in the database into the individual slice
-	if err = result.All(ctx, &individuals); err != nil {
+	// Find the page of the elements in the individuals collection
+	page, err := findPage[models.Individual](ctx, client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("individuals"), nil, query)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
 		})
 		return
 	}
-	c.JSON(http.StatusOK, individuals)
+
+	// Return the page of individuals
+	c.JSON(http.StatusOK, page)
 }
 
 // Returns all specified individuals in the collection
 func GetIndividualsBySlice(c *gin.Context) {
 	sliceString := c.Param("slice")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the individuals slice
 	var individuals []models.Individual
@@ -0,0 +1,298 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/info/models"
+	"github.com/yzaimoglu/election/info/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Limits of the page size
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 1000
+)
+
+// Allowed characters of a field name in the sort and fields parameters
+var fieldNamePattern = regexp.MustCompile(`^[a-z]+$`)
+
+// Model for the options of a list endpoint
+type listOptions struct {
+	sortFields     []string          // fields which can be used for sorting, the first one is the default
+	exactFilters   map[string]string // query parameter -> field which has to be equal to the value
+	idFilters      map[string]string // query parameter -> field which has to be equal to the object id in hex
+	textSortFields []string          // sort fields which are sorted in the order of the turkish alphabet
+	searchFields   []string          // fields which contain the search parameter ignoring the case and the turkish letters
+}
+
+// Model for the parsed list query of a request
+type listQuery struct {
+	key        string
+	limit      int64
+	sortField  string
+	descending bool
+	cursor     *listCursor
+	fields     []string
+	filter     []bson.M
+	collation  *options.Collation
+}
+
+// Collation sorting the strings in the order of the turkish alphabet
+var turkishCollation = &options.Collation{Locale: "tr"}
+
+// Model for the position of the last document of a page
+type listCursor struct {
+	Value bson.RawValue `bson:"v"`
+	Id    bson.RawValue `bson:"id"`
+}
+
+// List options of the parties and individuals
+var (
+	partyListOptions = listOptions{
+		sortFields:     []string{"name", "abbreviation", "leader"},
+		exactFilters:   map[string]string{"abbreviation": "abbreviation", "leader": "leader"},
+		textSortFields: []string{"name", "leader"},
+		searchFields:   []string{"name", "abbreviation", "leader"},
+	}
+	individualListOptions = listOptions{
+		sortFields:     []string{"lastname", "firstname", "birthdate"},
+		exactFilters:   map[string]string{"affiliation": "affiliation", "lastname": "lastname"},
+		textSortFields: []string{"lastname", "firstname"},
+		searchFields:   []string{"firstname", "lastname"},
+	}
+	candidacyListOptions = listOptions{
+		sortFields:   []string{"listposition", "election", "constituency"},
+		exactFilters: map[string]string{"election": "election", "constituency": "constituency"},
+		idFilters:    map[string]string{"individual": "individualid", "party": "partyid"},
+	}
+)
+
+// Parse the pagination, filter, sort and projection parameters of the request
+//
+//	?limit=50&cursor=...&sort=-name&fields=name,leader&abbreviation=CHP&search=erdogan
+func parseListQuery(c *gin.Context, listOptions listOptions) (listQuery, error) {
+	query := listQuery{
+		key:       c.Request.URL.Query().Encode(),
+		limit:     defaultPageLimit,
+		sortField: listOptions.sortFields[0],
+	}
+
+	// Page size
+	if limit := c.Query("limit"); limit != "" {
+		limitInt, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || limitInt < 1 || limitInt > maxPageLimit {
+			return query, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageLimit))
+		}
+		query.limit = limitInt
+	}
+
+	// Sort field, a leading - sorts descending
+	if sort := c.Query("sort"); sort != "" {
+		query.descending = strings.HasPrefix(sort, "-")
+		query.sortField = strings.TrimPrefix(sort, "-")
+		if !containsString(listOptions.sortFields, query.sortField) {
+			return query, errors.New("sort must be one of " + strings.Join(listOptions.sortFields, ", "))
+		}
+	}
+	if containsString(listOptions.textSortFields, query.sortField) {
+		query.collation = turkishCollation
+	}
+
+	// Cursor of the previous page
+	if cursor := c.Query("cursor"); cursor != "" {
+		var err error
+		if query.cursor, err = decodeListCursor(cursor); err != nil {
+			return query, err
+		}
+	}
+
+	// Projected fields
+	if fields := c.Query("fields"); fields != "" {
+		for _, field := range strings.Split(fields, ",") {
+			if !fieldNamePattern.MatchString(field) {
+				return query, errors.New("invalid field " + field)
+			}
+			query.fields = append(query.fields, field)
+		}
+	}
+
+	// Filters on fields which have to be equal to the value
+	for parameter, field := range listOptions.exactFilters {
+		if value := c.Query(parameter); value != "" {
+			query.filter = append(query.filter, bson.M{field: value})
+		}
+	}
+
+	// Filters on references to other documents
+	for parameter, field := range listOptions.idFilters {
+		if value := c.Query(parameter); value != "" {
+			objId, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				return query, errors.New(parameter + " must be an id in hex")
+			}
+			query.filter = append(query.filter, bson.M{field: objId})
+		}
+	}
+
+	// Search in the text fields, "cankaya" finds Çankaya
+	if search := c.Query("search"); search != "" && len(listOptions.searchFields) > 0 {
+		var searchFilter bson.A
+		for _, field := range listOptions.searchFields {
+			searchFilter = append(searchFilter, bson.M{field: primitive.Regex{Pattern: utilities.SearchPattern(search)}})
+		}
+		query.filter = append(query.filter, bson.M{"$or": searchFilter})
+	}
+
+	return query, nil
+}
+
+// Find a page of documents of the collection matching the filter and the list query
+func findPage[T any](ctx context.Context, collection *mongo.Collection, filter []bson.M, query listQuery) (models.Page, error) {
+	page := models.Page{
+		Limit: query.limit,
+	}
+
+	// Count all documents matching the filters
+	filter = append(filter, query.filter...)
+	total, err := collection.CountDocuments(ctx, andFilter(filter), options.Count().SetCollation(query.collation))
+	if err != nil {
+		return page, err
+	}
+	page.Total = total
+
+	// Continue after the cursor of the previous page
+	operator := "$gt"
+	direction := 1
+	if query.descending {
+		operator = "$lt"
+		direction = -1
+	}
+	if query.cursor != nil {
+		filter = append(filter, bson.M{"$or": bson.A{
+			bson.M{query.sortField: bson.M{operator: query.cursor.Value}},
+			bson.M{query.sortField: query.cursor.Value, "_id": bson.M{operator: query.cursor.Id}},
+		}})
+	}
+
+	// Sort by the sort field and the id so that the order is stable, fetch one more document to know if there is a next page
+	opts := options.Find().
+		SetSort(bson.D{{Key: query.sortField, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(query.limit + 1).
+		SetCollation(query.collation)
+	if len(query.fields) > 0 {
+		projection := bson.M{"_id": 1, query.sortField: 1}
+		for _, field := range query.fields {
+			projection[field] = 1
+		}
+		opts.SetProjection(projection)
+	}
+
+	// Get the documents
+	result, err := collection.Find(ctx, andFilter(filter), opts)
+	if err != nil {
+		return page, err
+	}
+	var documents []bson.Raw
+	if err := result.All(ctx, &documents); err != nil {
+		return page, err
+	}
+
+	// Create the cursor of the next page from the last document
+	if int64(len(documents)) > query.limit {
+		documents = documents[:query.limit]
+		nextCursor, err := encodeListCursor(documents[len(documents)-1], query.sortField)
+		if err != nil {
+			return page, err
+		}
+		page.NextCursor = nextCursor
+	}
+
+	// Decode the documents
+	items := make([]T, 0, len(documents))
+	for _, document := range documents {
+		var item T
+		if err := bson.Unmarshal(document, &item); err != nil {
+			return page, err
+		}
+		items = append(items, item)
+	}
+	page.Data = items
+
+	// Only return the projected fields
+	if len(query.fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(items))
+		for _, item := range items {
+			itemJSON, err := json.Marshal(item)
+			if err != nil {
+				return page, err
+			}
+			var fields map[string]interface{}
+			if err := json.Unmarshal(itemJSON, &fields); err != nil {
+				return page, err
+			}
+			for field := range fields {
+				if field != "_id" && !containsString(query.fields, field) {
+					delete(fields, field)
+				}
+			}
+			projected = append(projected, fields)
+		}
+		page.Data = projected
+	}
+
+	return page, nil
+}
+
+// Encode the position of the last document of a page, the cursor contains its sort value and id
+func encodeListCursor(last bson.Raw, sortField string) (string, error) {
+	cursorBytes, err := bson.Marshal(listCursor{
+		Value: last.Lookup(sortField),
+		Id:    last.Lookup("_id"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(cursorBytes), nil
+}
+
+// Decode the cursor of the previous page
+func decodeListCursor(cursor string) (*listCursor, error) {
+	cursorBytes, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var decoded listCursor
+	if err := bson.Unmarshal(cursorBytes, &decoded); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &decoded, nil
+}
+
+// Combine the filters with $and
+func andFilter(filter []bson.M) bson.M {
+	if len(filter) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"$and": filter}
+}
+
+// Check if a slice contains a string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/info/models"
+	"github.com/yzaimoglu/election/info/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Uploads the logo of a party from the image field of a multipart form and saves its urls on the party
+//
+//	curl -F image=@logo.png http://localhost:82/v1/party/63f1c2.../logo/
+func UploadPartyLogo(c *gin.Context) {
+	uploadImage[models.Party](c, "parties", "party", func(sizes models.ImageSizes) bson.M {
+		return bson.M{"logo": sizes.Full, "logosizes": sizes}
+	})
+}
+
+// Uploads the portrait of an individual from the image field of a multipart form and saves its urls on the individual
+func UploadIndividualImage(c *gin.Context) {
+	uploadImage[models.Individual](c, "individuals", "individual", func(sizes models.ImageSizes) bson.M {
+		return bson.M{"image": sizes.Full, "imagesizes": sizes}
+	})
+}
+
+// Store an uploaded image of a document of the collection and set the fields with its urls
+func uploadImage[T any](c *gin.Context, collection string, noun string, fields func(models.ImageSizes) bson.M) {
+	id := c.Param("id")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Object ID from id param
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "bad request id should be in hex",
+		})
+		return
+	}
+
+	// Check if the document exists before storing any file
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	count, err := database.Collection(collection).CountDocuments(ctx, bson.M{"_id": objId})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+	if count == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status":  http.StatusNotFound,
+			"message": "no " + noun + " with that id found",
+		})
+		return
+	}
+
+	// Read the uploaded image, larger uploads are cut off
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxImageBytes+1<<20)
+	header, err := c.FormFile("image")
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the image field of the multipart form is missing or too large",
+		})
+		return
+	}
+	if header.Size > models.MaxImageBytes {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+			"status": http.StatusRequestEntityTooLarge,
+			"error":  "images cannot be larger than 10 MiB",
+		})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Validate the image and store its sizes
+	sizes, err := models.StoreImage(collection, id, data)
+	if err != nil {
+		if errors.Is(err, models.ErrImageType) || errors.Is(err, models.ErrImageDimensions) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  err.Error(),
+			})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Prepare the new version of the document
+	from, ok := prepareVersion[T](c, ctx, database, collection, objId)
+	if !ok {
+		return
+	}
+
+	// Save the urls on the document
+	result, err := database.Collection(collection).UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": fields(sizes)})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Record the new version of the document
+	if !recordVersion[T](c, ctx, database, collection, objId, from) {
+		return
+	}
+
+	// Return the id and the urls of the updated object
+	c.JSON(http.StatusOK, gin.H{
+		"modifiedCount": result.ModifiedCount,
+		"updatedId":     objId,
+		"updatedSizes":  sizes,
+	})
+}
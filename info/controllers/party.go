@@ -13,12 +13,11 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// Returns a single party with a specific id
+// Returns a single party with a specific id, ?at=2018-06-24 returns it as it was on that date
 func GetParty(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	var party models.Party
 
@@ -32,6 +31,16 @@ func GetParty(c *gin.Context) {
 		return
 	}
 
+	// Return the party as it was at the date of ?at=
+	at, versioned, ok := parseVersionAt(c)
+	if !ok {
+		return
+	}
+	if versioned {
+		getDocumentAt[models.Party](c, ctx, client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")), "parties", objId, at, "no version of the party valid at that date found")
+		return
+	}
+
 	// Find the party in the database
 	result := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties").FindOne(ctx, &bson.M{"_id": objId})
 
@@ -53,9 +62,8 @@ func GetParty(c *gin.Context) {
 
 // Creates a new party
 func CreateParty(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the party
 	var party models.Party
@@ -82,8 +90,15 @@ func CreateParty(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the party
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Party](c, ctx, database, "parties", party.Id)
+	if !ok {
+		return
+	}
+
 	// Insert party
-	if _, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties").InsertOne(ctx, party); err != nil {
+	if _, err := database.Collection("parties").InsertOne(ctx, party); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
 			"error":  err.Error(),
@@ -91,6 +106,11 @@ func CreateParty(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the party
+	if !recordVersion[models.Party](c, ctx, database, "parties", party.Id, from) {
+		return
+	}
+
 	// Return the recently created party
 	c.JSON(http.StatusOK, party)
 }
@@ -98,9 +118,8 @@ func CreateParty(c *gin.Context) {
 // Changes all fields of a party
 func ChangeParty(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the party
 	var party models.Party
@@ -136,8 +155,15 @@ func ChangeParty(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the party
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Party](c, ctx, database, "parties", objId)
+	if !ok {
+		return
+	}
+
 	// Replace the existing document with the new one
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties").ReplaceOne(ctx, bson.M{"_id": objId}, party)
+	result, err := database.Collection("parties").ReplaceOne(ctx, bson.M{"_id": objId}, party)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -146,6 +172,11 @@ func ChangeParty(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the party
+	if !recordVersion[models.Party](c, ctx, database, "parties", objId, from) {
+		return
+	}
+
 	// Return the id of the updated object
 	c.JSON(http.StatusOK, gin.H{
 		"modifiedCount": result.ModifiedCount,
@@ -157,9 +188,8 @@ func ChangeParty(c *gin.Context) {
 // Changes a partys name
 func ChangePartyName(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the input object
 	var input models.UpdatePartyNameInput
@@ -193,8 +223,15 @@ func ChangePartyName(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the party
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Party](c, ctx, database, "parties", objId)
+	if !ok {
+		return
+	}
+
 	// Change the name of the party
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"name": input.Name}})
+	result, err := database.Collection("parties").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"name": input.Name}})
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -203,6 +240,11 @@ func ChangePartyName(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the party
+	if !recordVersion[models.Party](c, ctx, database, "parties", objId, from) {
+		return
+	}
+
 	// Return the id and the name of the updated object
 	c.JSON(http.StatusOK, gin.H{
 		"modifiedCount": result.ModifiedCount,
@@ -214,9 +256,8 @@ func ChangePartyName(c *gin.Context) {
 // Changes a partys abbreviation
 func ChangePartyAbbreviation(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the input object
 	var input models.UpdatePartyAbbreviationInput
@@ -250,8 +291,15 @@ func ChangePartyAbbreviation(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the party
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Party](c, ctx, database, "parties", objId)
+	if !ok {
+		return
+	}
+
 	// Change the abbreviation of the party
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"abbreviation": input.Abbreviation}})
+	result, err := database.Collection("parties").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"abbreviation": input.Abbreviation}})
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -260,6 +308,11 @@ func ChangePartyAbbreviation(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the party
+	if !recordVersion[models.Party](c, ctx, database, "parties", objId, from) {
+		return
+	}
+
 	// Return the id and the abbreviation of the updated object
 	c.JSON(http.StatusOK, gin.H{
 		"modifiedCount":       result.ModifiedCount,
@@ -271,9 +324,8 @@ func ChangePartyAbbreviation(c *gin.Context) {
 // Changes a partys leader
 func ChangePartyLeader(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the input object
 	var input models.UpdatePartyLeaderInput
@@ -307,8 +359,15 @@ func ChangePartyLeader(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the party
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Party](c, ctx, database, "parties", objId)
+	if !ok {
+		return
+	}
+
 	// Change the leader of the party
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"leader": input.Leader}})
+	result, err := database.Collection("parties").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"leader": input.Leader}})
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -317,6 +376,11 @@ func ChangePartyLeader(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the party
+	if !recordVersion[models.Party](c, ctx, database, "parties", objId, from) {
+		return
+	}
+
 	// Return the id and the leader of the updated object
 	c.JSON(http.StatusOK, gin.H{
 		"modifiedCount": result.ModifiedCount,
@@ -328,9 +392,8 @@ func ChangePartyLeader(c *gin.Context) {
 // Changes a partys logo
 func ChangePartyLogo(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the input object
 	var input models.UpdatePartyLogoInput
@@ -364,8 +427,15 @@ func ChangePartyLogo(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the party
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Party](c, ctx, database, "parties", objId)
+	if !ok {
+		return
+	}
+
 	// Change the logo of the party
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"logo": input.Logo}})
+	result, err := database.Collection("parties").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"logo": input.Logo}, "$unset": bson.M{"logosizes": ""}})
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -374,6 +444,11 @@ func ChangePartyLogo(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the party
+	if !recordVersion[models.Party](c, ctx, database, "parties", objId, from) {
+		return
+	}
+
 	// Return the id and the logo of the updated object
 	c.JSON(http.StatusOK, gin.H{
 		"modifiedCount": result.ModifiedCount,
@@ -385,9 +460,8 @@ func ChangePartyLogo(c *gin.Context) {
 // Changes a partys color
 func ChangePartyColor(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the input object
 	var input models.UpdatePartyColorInput
@@ -421,8 +495,15 @@ func ChangePartyColor(c *gin.Context) {
 		return
 	}
 
+	// Prepare the new version of the party
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Party](c, ctx, database, "parties", objId)
+	if !ok {
+		return
+	}
+
 	// Change the color of the party
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"color": input.Color}})
+	result, err := database.Collection("parties").UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": bson.M{"color": input.Color}})
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -431,6 +512,11 @@ func ChangePartyColor(c *gin.Context) {
 		return
 	}
 
+	// Record the new version of the party
+	if !recordVersion[models.Party](c, ctx, database, "parties", objId, from) {
+		return
+	}
+
 	// Return the id and the color of the updated object
 	c.JSON(http.StatusOK, gin.H{
 		"modifiedCount": result.ModifiedCount,
@@ -442,9 +528,8 @@ func ChangePartyColor(c *gin.Context) {
 // Deletes a party
 func DeleteParty(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Object ID from id param
 	objId, err := primitive.ObjectIDFromHex(id)
@@ -456,8 +541,15 @@ func DeleteParty(c *gin.Context) {
 		return
 	}
 
+	// Keep the current state of the party as a version, deleted parties can still be looked up with ?at=
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	from, ok := prepareVersion[models.Party](c, ctx, database, "parties", objId)
+	if !ok {
+		return
+	}
+
 	// Delete the object from the database
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties").DeleteOne(ctx, bson.M{"_id": objId})
+	result, err := database.Collection("parties").DeleteOne(ctx, bson.M{"_id": objId})
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
@@ -466,6 +558,15 @@ func DeleteParty(c *gin.Context) {
 		return
 	}
 
+	// Close the current version of the party
+	if err := models.CloseVersion(ctx, database, "parties", objId, from); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
 	// Return deleted count and deleted Id
 	c.JSON(http.StatusOK, gin.H{
 		"deletedCount": result.DeletedCount,
@@ -475,34 +576,22 @@ func DeleteParty(c *gin.Context) {
 
 // Returns all parties in the collection
 func GetParties(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
-	// Initialize the parties slice
-	var parties []models.Party
-
-	// Find all elements in the parties collection
-	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties").Find(ctx, bson.M{})
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, partyListOptions)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
-		})
-		return
-	}
-
-	// Return that no party has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no parties",
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Decode all elements in the database into the party slice
-	if err = result.All(ctx, &parties); err != nil {
+	// Find the page of the elements in the parties collection
+	page, err := findPage[models.Party](ctx, client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties"), nil, query)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -510,15 +599,15 @@ func GetParties(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, parties)
+	// Return the page of parties
+	c.JSON(http.StatusOK, page)
 }
 
-// Returns all specified parties in the collection
+// Returns all specified parties in the collection, ?at=2018-06-24 returns them as they were on that date
 func GetPartiesBySlice(c *gin.Context) {
 	sliceString := c.Param("slice")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the parties slice
 	var parties []models.Party
@@ -554,6 +643,36 @@ func GetPartiesBySlice(c *gin.Context) {
 		return
 	}
 
+	// Return the parties as they were at the date of ?at=, in the order of the slice
+	at, versioned, ok := parseVersionAt(c)
+	if !ok {
+		return
+	}
+	if versioned {
+		found, err := models.FindDocumentsAt[models.Party](ctx, client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")), "parties", partiesIds, at)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+			return
+		}
+		for _, partyId := range partiesIds {
+			if party, ok := found[partyId]; ok {
+				parties = append(parties, party)
+			}
+		}
+		if len(parties) == 0 {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"status":  http.StatusNotFound,
+				"message": "there are no parties",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, parties)
+		return
+	}
+
 	// Find all elements in the parties collection
 	result, err := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")).Collection("parties").Find(ctx, bson.M{"_id": bson.M{"$in": partiesIds}})
 	if err != nil {
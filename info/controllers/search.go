@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/info/models"
+	"github.com/yzaimoglu/election/info/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Limits of a search
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// documents of a collection which are scored for a search
+	searchCandidateLimit = 500
+)
+
+// Search the parties and individuals by their names, the results are ranked by relevance
+//
+// Prefixes, words and small typos match, the case and the turkish letters are ignored.
+//
+//	GET /v1/search/?q=kilicdar&types=individual&limit=10
+func Search(c *gin.Context) {
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Parse the query, the types and the limit
+	query, types, limit, err := parseSearchQuery(c, []string{models.SearchParty, models.SearchIndividual})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+	database := client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	results := []models.SearchResult{}
+
+	// Search the parties by their name, abbreviation and leader
+	if containsString(types, models.SearchParty) {
+		parties, err := findSearchCandidates[models.Party](ctx, database.Collection("parties"), query, "name", "abbreviation", "leader")
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+			return
+		}
+		for _, party := range parties {
+			result := models.SearchResult{Type: models.SearchParty, Id: party.Id.Hex(), Name: party.Name, Color: party.Color.Hex}
+			if result.MatchAny(query, party.Name, party.Abbreviation, party.Leader) {
+				results = append(results, result)
+			}
+		}
+	}
+
+	// Search the individuals by their full name, first name and last name
+	if containsString(types, models.SearchIndividual) {
+		individuals, err := findSearchCandidates[models.Individual](ctx, database.Collection("individuals"), query, "firstname", "lastname")
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+			return
+		}
+		for _, individual := range individuals {
+			name := individual.FirstName + " " + individual.LastName
+			result := models.SearchResult{Type: models.SearchIndividual, Id: individual.Id.Hex(), Name: name, Color: individual.Color.Hex}
+			if result.MatchAny(query, name, individual.FirstName, individual.LastName) {
+				results = append(results, result)
+			}
+		}
+	}
+
+	// Rank the results and return the best ones
+	models.SortSearchResults(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"query": query,
+		"data":  results,
+	})
+}
+
+// Parse the q, types and limit parameters of a search
+func parseSearchQuery(c *gin.Context, allTypes []string) (string, []string, int, error) {
+	query := strings.TrimSpace(c.Query("q"))
+	if utilities.Fold(query) == "" {
+		return query, nil, 0, errors.New("q must not be empty")
+	}
+
+	// Types of the results, all types by default
+	types := allTypes
+	if typesQuery := c.Query("types"); typesQuery != "" {
+		types = strings.Split(typesQuery, ",")
+		for _, searchType := range types {
+			if !containsString(allTypes, searchType) {
+				return query, nil, 0, errors.New("types must be a list of " + strings.Join(allTypes, ", "))
+			}
+		}
+	}
+
+	// Number of results
+	limit := defaultSearchLimit
+	if limitQuery := c.Query("limit"); limitQuery != "" {
+		var err error
+		limit, err = strconv.Atoi(limitQuery)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return query, nil, 0, errors.New("limit must be between 1 and " + strconv.Itoa(maxSearchLimit))
+		}
+	}
+	return query, types, limit, nil
+}
+
+// Find the documents with a word in one of the fields starting like the query, they are scored afterwards
+//
+// The documents matching the query exactly or by their beginning are found first, so that they are not
+// left out by the limit of the candidates if many documents only contain the query.
+func findSearchCandidates[T any](ctx context.Context, collection *mongo.Collection, query string, fields ...string) ([]T, error) {
+	var documents []T
+	found := bson.A{}
+	for _, pattern := range utilities.SearchCandidatePatterns(query) {
+		if len(found) >= searchCandidateLimit {
+			break
+		}
+
+		// Find the documents matching the pattern which have not been found yet
+		var filter bson.A
+		for _, field := range fields {
+			filter = append(filter, bson.M{field: primitive.Regex{Pattern: pattern}})
+		}
+		result, err := collection.Find(ctx, bson.M{"$or": filter, "_id": bson.M{"$nin": found}}, options.Find().SetLimit(int64(searchCandidateLimit-len(found))))
+		if err != nil {
+			return nil, err
+		}
+		for result.Next(ctx) {
+			var document T
+			if err := result.Decode(&document); err != nil {
+				result.Close(ctx)
+				return nil, err
+			}
+			documents = append(documents, document)
+			found = append(found, result.Current.Lookup("_id"))
+		}
+		err = result.Err()
+		result.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return documents, nil
+}
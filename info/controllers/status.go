@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/info/models"
+)
+
+// Get the build status of the indexes created on startup
+func GetIndexStatus(c *gin.Context) {
+	// Check if all indexes have been built
+	status := models.GetIndexStatus()
+	ready := true
+	for _, index := range status {
+		if !index.Ready {
+			ready = false
+		}
+	}
+
+	// Return the index status
+	c.JSON(http.StatusOK, gin.H{
+		"ready":   ready,
+		"indexes": status,
+	})
+}
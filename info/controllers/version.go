@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/info/models"
+	"github.com/yzaimoglu/election/info/utilities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Returns all versions of a party, the oldest first
+func GetPartyVersions(c *gin.Context) {
+	getVersions[models.Party](c, "parties")
+}
+
+// Returns all versions of an individual, the oldest first
+func GetIndividualVersions(c *gin.Context) {
+	getVersions[models.Individual](c, "individuals")
+}
+
+// Return the versions of a document of the collection
+func getVersions[T any](c *gin.Context, collection string) {
+	id := c.Param("id")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Object ID from id param
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "bad request id should be in hex",
+		})
+		return
+	}
+
+	// Find the versions in the database
+	versions, err := models.GetVersions[T](ctx, client.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi")), collection, objId)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Return the versions
+	c.JSON(http.StatusOK, gin.H{
+		"documentId": objId,
+		"data":       versions,
+	})
+}
+
+// Parse the ?from= date at which a change becomes valid and prepare the new version, aborts the request on errors
+//
+// Without ?from= the change is valid from now on, changes cannot be valid from a future date.
+func prepareVersion[T any](c *gin.Context, ctx context.Context, database *mongo.Database, collection string, objId primitive.ObjectID) (time.Time, bool) {
+	from := time.Now().UTC()
+	if value := c.Query("from"); value != "" {
+		date, err := models.ParseVersionDate(value)
+		if err != nil || date.After(from) {
+			message := "from cannot be in the future"
+			if err != nil {
+				message = err.Error()
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  message,
+			})
+			return from, false
+		}
+		from = date
+	}
+
+	if err := models.PrepareVersion[T](ctx, database, collection, objId, from); err != nil {
+		if err == models.ErrVersionOrder {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  err.Error(),
+			})
+			return from, false
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return from, false
+	}
+	return from, true
+}
+
+// Record the changed document as a new version, aborts the request on errors
+func recordVersion[T any](c *gin.Context, ctx context.Context, database *mongo.Database, collection string, objId primitive.ObjectID, from time.Time) bool {
+	if err := models.RecordVersion[T](ctx, database, collection, objId, from); err != nil {
+		if err == models.ErrVersionConflict {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"status": http.StatusConflict,
+				"error":  "the change has been saved but its version could not be recorded: " + err.Error(),
+			})
+			return false
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  "the change has been saved but its version could not be recorded: " + err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// Parse the ?at= date of the lookups, the second value is false if it is not set, aborts the request if it is invalid
+func parseVersionAt(c *gin.Context) (time.Time, bool, bool) {
+	value := c.Query("at")
+	if value == "" {
+		return time.Time{}, false, true
+	}
+	at, err := models.ParseVersionDate(value)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return at, false, false
+	}
+	return at, true, true
+}
+
+// Return a document of the collection as it was at the given time
+func getDocumentAt[T any](c *gin.Context, ctx context.Context, database *mongo.Database, collection string, objId primitive.ObjectID, at time.Time, message string) {
+	// Find the version valid at that time
+	documents, err := models.FindDocumentsAt[T](ctx, database, collection, []primitive.ObjectID{objId}, at)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Check if there is a version valid at that time
+	document, ok := documents[objId]
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status":  http.StatusNotFound,
+			"message": message,
+		})
+		return
+	}
+
+	// Return the document
+	c.JSON(http.StatusOK, document)
+}
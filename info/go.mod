@@ -7,6 +7,7 @@ require (
 	github.com/go-playground/validator v9.31.0+incompatible
 	github.com/joho/godotenv v1.4.0
 	go.mongodb.org/mongo-driver v1.10.2
+	golang.org/x/image v0.0.0-20220413100746-70e8d0d3baa9
 )
 
 require (
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/info/tokens"
+)
+
+// Scope required by the routes of the tests
+const testScope = "info:write"
+
+// Sign a token like the authentication service, the services have no signing code of their own
+func signTestToken(t *testing.T, claims tokens.Claims, keyId string, privateKey ed25519.PrivateKey) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": tokens.Algorithm, "typ": "JWT", "kid": keyId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(signingInput)))
+}
+
+// Create a router with the middleware in front of a handler that answers with 204
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequireServiceToken(testScope))
+	router.Any("/", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func performTokenRequest(router *gin.Engine, method string, token string) int {
+	request := httptest.NewRequest(method, "/", nil)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func TestRequireServiceToken(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Publish the key like the authentication service
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(tokens.JWKS{Keys: []tokens.JWK{{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(publicKey),
+			KeyId:     "key-1",
+			Use:       "sig",
+			Algorithm: tokens.Algorithm,
+		}}})
+	}))
+	defer server.Close()
+	t.Setenv("BILGI_AUTH_JWKS_URL", server.URL)
+	t.Setenv("BILGI_AUTH_ISSUER", "election-auth")
+	t.Setenv("BILGI_AUTH_AUDIENCE", "election")
+	router := newTestRouter()
+
+	claims := func(change func(claims *tokens.Claims)) tokens.Claims {
+		now := time.Now()
+		claims := tokens.Claims{
+			Issuer:    "election-auth",
+			Subject:   "client:updater",
+			Audience:  "election",
+			ExpiresAt: now.Add(5 * time.Minute).Unix(),
+			NotBefore: now.Unix(),
+			IssuedAt:  now.Unix(),
+			Scope:     testScope,
+			ClientId:  "updater",
+		}
+		if change != nil {
+			change(&claims)
+		}
+		return claims
+	}
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		status int
+	}{
+		{"read without a token", http.MethodGet, "", http.StatusNoContent},
+		{"write without a token", http.MethodPost, "", http.StatusUnauthorized},
+		{"write with the scope", http.MethodPost, signTestToken(t, claims(nil), "key-1", privateKey), http.StatusNoContent},
+		{"write without the scope", http.MethodPut, signTestToken(t, claims(func(claims *tokens.Claims) { claims.Scope = "parliament:write" }), "key-1", privateKey), http.StatusForbidden},
+		{"write of an admin", http.MethodDelete, signTestToken(t, claims(func(claims *tokens.Claims) { claims.Scope, claims.Role = "", adminRole }), "key-1", privateKey), http.StatusNoContent},
+		{"expired token", http.MethodPost, signTestToken(t, claims(func(claims *tokens.Claims) { claims.ExpiresAt = time.Now().Add(-time.Hour).Unix() }), "key-1", privateKey), http.StatusUnauthorized},
+		{"wrong audience", http.MethodPost, signTestToken(t, claims(func(claims *tokens.Claims) { claims.Audience = "another-service" }), "key-1", privateKey), http.StatusUnauthorized},
+		{"unknown key id", http.MethodPost, signTestToken(t, claims(nil), "key-2", otherPrivateKey), http.StatusUnauthorized},
+		{"wrong signature", http.MethodPost, signTestToken(t, claims(nil), "key-1", otherPrivateKey), http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if status := performTokenRequest(router, test.method, test.token); status != test.status {
+				t.Fatalf("got the status %d, want %d", status, test.status)
+			}
+		})
+	}
+}
+
+func TestRequireServiceTokenWithoutJWKS(t *testing.T) {
+	// Writes are rejected without the authentication service
+	t.Setenv("BILGI_AUTH_JWKS_URL", "")
+	router := newTestRouter()
+	if status := performTokenRequest(router, http.MethodGet, ""); status != http.StatusNoContent {
+		t.Fatalf("got the status %d for a read, want %d", status, http.StatusNoContent)
+	}
+	if status := performTokenRequest(router, http.MethodPost, ""); status != http.StatusServiceUnavailable {
+		t.Fatalf("got the status %d for a write, want %d", status, http.StatusServiceUnavailable)
+	}
+
+	// Only disabling the authentication explicitly lets writes through
+	t.Setenv("BILGI_AUTH_DISABLED", "true")
+	router = newTestRouter()
+	if status := performTokenRequest(router, http.MethodPost, ""); status != http.StatusNoContent {
+		t.Fatalf("got the status %d for a write with disabled authentication, want %d", status, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Model for the candidacy of an individual or a party in an election, the election services reference it in their results
+type Candidacy struct {
+	Id           primitive.ObjectID  `json:"_id" bson:"_id"`
+	Election     string              `json:"election" bson:"election" validate:"required"`      // parliament-2023
+	IndividualId *primitive.ObjectID `json:"individualid" bson:"individualid"`                  // 63f1c2... (empty for party lists)
+	PartyId      *primitive.ObjectID `json:"partyid" bson:"partyid"`                            // 63f1c2... (list of the party or party of the individual)
+	Constituency string              `json:"constituency" bson:"constituency"`                  // ankara-1 (empty for nationwide elections)
+	ListPosition int64               `json:"listposition" bson:"listposition" validate:"gte=0"` // 1
+	Individual   *Individual         `json:"individual,omitempty" bson:"-"`                     // embedded with ?embed=profile
+	Party        *Party              `json:"party,omitempty" bson:"-"`                          // embedded with ?embed=profile
+}
@@ -3,7 +3,7 @@ package models
 import (
 	"context"
 	"fmt"
-	"log"
+	"strconv"
 	"time"
 
 	"github.com/yzaimoglu/election/info/utilities"
@@ -17,37 +17,68 @@ const (
 	connectionStringTemplate = "mongodb://%s:%s@%s"
 )
 
-// Get a Mongo instance (Client, Context, Cancel)
-func GetMongoInstance() (*mongo.Client, context.Context, context.CancelFunc) {
+// Process wide mongo client which is shared by all requests
+var mongoClient *mongo.Client
+
+// Connect to MongoDB once at startup, returns an error if the database is unreachable
+func ConnectMongo() error {
 	// MongoDB Credentials from .env
 	username := utilities.GetEnv("BILGI_DB_USER", "admin")
 	password := utilities.GetEnv("BILGI_DB_PASSWORD", "admin")
 	hostname := utilities.GetEnv("BILGI_HOSTNAME", "localhost")
 
-	// Connection URI for the database
-	connectionURI := fmt.Sprintf(connectionStringTemplate, username, password, hostname)
-
-	// Create the mongo client
-	client, err := mongo.NewClient(options.Client().ApplyURI(connectionURI))
+	// Connection pool settings from .env, invalid values stop the startup instead of silently becoming 0
+	maxPoolSize, err := strconv.ParseUint(utilities.GetEnv("BILGI_DB_MAX_POOL_SIZE", "100"), 10, 64)
 	if err != nil {
-		log.Printf("Failed to create client: %v", err)
+		return fmt.Errorf("invalid BILGI_DB_MAX_POOL_SIZE: %w", err)
+	}
+	minPoolSize, err := strconv.ParseUint(utilities.GetEnv("BILGI_DB_MIN_POOL_SIZE", "0"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid BILGI_DB_MIN_POOL_SIZE: %w", err)
+	}
+	maxIdleTime, err := strconv.ParseInt(utilities.GetEnv("BILGI_DB_MAX_IDLE_TIME", "60"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid BILGI_DB_MAX_IDLE_TIME: %w", err)
 	}
 
+	// Connection URI for the database
+	connectionURI := fmt.Sprintf(connectionStringTemplate, username, password, hostname)
+	clientOptions := options.Client().
+		ApplyURI(connectionURI).
+		SetMaxPoolSize(maxPoolSize).
+		SetMinPoolSize(minPoolSize).
+		SetMaxConnIdleTime(time.Duration(maxIdleTime) * time.Second)
+
 	// Create the context and the cancel function
 	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout*time.Second)
+	defer cancel()
 
 	// Connect to MongoDB and check for connection error
-	err = client.Connect(ctx)
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		log.Printf("Failed to connect to the database: %v", err)
+		return fmt.Errorf("failed to connect to the database: %w", err)
 	}
 
 	// Force a connection to verify our connection string
-	err = client.Ping(ctx, nil)
-	if err != nil {
-		log.Printf("Failed to ping the database: %v", err)
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return fmt.Errorf("failed to ping the database: %w", err)
+	}
+
+	mongoClient = client
+	return nil
+}
+
+// Disconnect the shared mongo client, used on shutdown
+func DisconnectMongo(ctx context.Context) error {
+	if mongoClient == nil {
+		return nil
 	}
+	return mongoClient.Disconnect(ctx)
+}
 
-	// Return mongo instance
-	return client, ctx, cancel
+// Get a Mongo instance (Client, Context, Cancel), the context is derived from the given parent context
+func GetMongoInstance(parent context.Context) (*mongo.Client, context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, connectTimeout*time.Second)
+	return mongoClient, ctx, cancel
 }
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yzaimoglu/election/info/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Model for the build status of an index
+type IndexStatus struct {
+	Collection string `json:"collection"`      // boxes
+	Name       string `json:"name"`            // city_1_district_1_number_1
+	Unique     bool   `json:"unique"`          // true
+	Ready      bool   `json:"ready"`           // true if the index exists in the database
+	Error      string `json:"error,omitempty"` // E11000 duplicate key error ...
+}
+
+// Model for the definition of a collection with its indexes and validator
+type collectionSchema struct {
+	Name      string
+	Indexes   []mongo.IndexModel
+	Validator bson.M
+}
+
+// Status of the last bootstrap, reported by the status route
+var (
+	indexStatusMutex sync.RWMutex
+	indexStatus      []IndexStatus
+)
+
+// Create a compound index on the given fields
+func compoundIndex(unique bool, fields ...string) mongo.IndexModel {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: 1})
+	}
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetUnique(unique)}
+}
+
+// Create the index allowing only one current version per document, so that two concurrent changes cannot both
+// leave a version open. Partial indexes cannot filter on null with $eq, the current versions have a null validto.
+func currentVersionIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{{Key: "collection", Value: 1}, {Key: "documentid", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"validto": bson.M{"$type": "null"}}),
+	}
+}
+
+// Create a json schema validator requiring the given string and number fields
+func documentValidator(stringFields []string, numberFields []string) bson.M {
+	properties := bson.M{}
+	for _, field := range stringFields {
+		properties[field] = bson.M{"bsonType": "string"}
+	}
+	for _, field := range numberFields {
+		properties[field] = bson.M{"bsonType": bson.A{"int", "long"}}
+	}
+	return bson.M{"$jsonSchema": bson.M{
+		"bsonType":   "object",
+		"required":   append(append([]string{}, stringFields...), numberFields...),
+		"properties": properties,
+	}}
+}
+
+// Collections of the service with their indexes and validators
+var collectionSchemas = []collectionSchema{
+	{
+		Name: "parties",
+		Indexes: []mongo.IndexModel{
+			compoundIndex(true, "name"),
+			compoundIndex(true, "abbreviation"),
+		},
+		Validator: documentValidator([]string{"name", "abbreviation"}, []string{}),
+	},
+	{
+		Name: "individuals",
+		Indexes: []mongo.IndexModel{
+			compoundIndex(true, "firstname", "lastname", "birthdate"),
+			compoundIndex(false, "affiliation"),
+		},
+		Validator: documentValidator([]string{"firstname", "lastname"}, []string{}),
+	},
+	{
+		Name: "candidacies",
+		Indexes: []mongo.IndexModel{
+			compoundIndex(true, "election", "constituency", "individualid", "partyid"),
+			compoundIndex(false, "individualid"),
+			compoundIndex(false, "partyid"),
+		},
+		Validator: documentValidator([]string{"election", "constituency"}, []string{"listposition"}),
+	},
+	{
+		Name: "versions",
+		Indexes: []mongo.IndexModel{
+			compoundIndex(true, "collection", "documentid", "validfrom"),
+			compoundIndex(false, "collection", "documentid", "validto"),
+			currentVersionIndex(),
+		},
+		Validator: documentValidator([]string{"collection"}, []string{}),
+	},
+}
+
+// Create the collections with their validators and indexes, failures are logged and reported in the status
+func BootstrapDatabase() []IndexStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	database := mongoClient.Database(utilities.GetEnv("BILGI_DB_DATABASE", "bilgi"))
+	var status []IndexStatus
+
+	// Get the existing collections
+	existing, err := database.ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		log.Println("error listing the collections: " + err.Error())
+	}
+
+	for _, schema := range collectionSchemas {
+		// Create the collection with the validator or apply the validator to the existing collection
+		if contains(existing, schema.Name) {
+			err = database.RunCommand(ctx, bson.D{
+				{Key: "collMod", Value: schema.Name},
+				{Key: "validator", Value: schema.Validator},
+				{Key: "validationLevel", Value: "moderate"},
+			}).Err()
+		} else {
+			err = database.CreateCollection(ctx, schema.Name, options.CreateCollection().
+				SetValidator(schema.Validator).
+				SetValidationLevel("moderate"))
+		}
+		if err != nil {
+			log.Println("error applying the validator to " + schema.Name + ": " + err.Error())
+		}
+
+		// Create the indexes one by one so that a single failing index does not hide the others
+		for _, index := range schema.Indexes {
+			built := IndexStatus{
+				Collection: schema.Name,
+				Unique:     index.Options.Unique != nil && *index.Options.Unique,
+			}
+			name, err := database.Collection(schema.Name).Indexes().CreateOne(ctx, index)
+			if err != nil {
+				log.Println("error creating an index on " + schema.Name + ": " + err.Error())
+				built.Name = indexName(index)
+				built.Error = err.Error()
+			} else {
+				built.Name = name
+				built.Ready = true
+			}
+			status = append(status, built)
+		}
+	}
+
+	// Save the status for the status route
+	indexStatusMutex.Lock()
+	indexStatus = status
+	indexStatusMutex.Unlock()
+
+	return status
+}
+
+// Get the index status of the last bootstrap
+func GetIndexStatus() []IndexStatus {
+	indexStatusMutex.RLock()
+	defer indexStatusMutex.RUnlock()
+	return indexStatus
+}
+
+// Get the default name mongo gives to an index
+func indexName(index mongo.IndexModel) string {
+	name := ""
+	for _, key := range index.Keys.(bson.D) {
+		if name != "" {
+			name += "_"
+		}
+		name += key.Key + "_1"
+	}
+	return name
+}
+
+// Check if a slice contains a string
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
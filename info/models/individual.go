@@ -9,6 +9,7 @@ type Individual struct {
 	LastName    string             `json:"lastname" bson:"lastname,omitempty" validate:"required"`
 	BirthDate   string             `json:"birthdate" bson:"birthdate,omitempty" validate:"required"`
 	Image       string             `json:"image" bson:"image" validate:"required"`
+	ImageSizes  *ImageSizes        `json:"imagesizes,omitempty" bson:"imagesizes,omitempty"` // set by uploading the portrait
 	Affiliation string             `json:"affiliation" bson:"affiliation"`
 	Color       Color              `json:"color" bson:"color" validate:"required"`
 }
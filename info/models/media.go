@@ -0,0 +1,133 @@
+package models
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yzaimoglu/election/info/utilities"
+	"golang.org/x/image/draw"
+)
+
+// Limits of the uploaded images
+const (
+	MaxImageBytes     = 10 << 20 // 10 MiB
+	minImageDimension = 64
+	maxImageDimension = 6000
+)
+
+// Errors of invalid uploads, returned as bad requests
+var (
+	ErrImageType       = errors.New("images must be png or jpeg files")
+	ErrImageDimensions = errors.New("images must be between " + strconv.Itoa(minImageDimension) + " and " + strconv.Itoa(maxImageDimension) + " pixels wide and high")
+)
+
+// Model for the urls of the standard sizes of an uploaded logo or portrait
+type ImageSizes struct {
+	Thumbnail string `json:"thumbnail" bson:"thumbnail"` // http://localhost:82/media/parties/63f1c2.../4f2a...-thumbnail.png (128px)
+	Card      string `json:"card" bson:"card"`           // 512px
+	Full      string `json:"full" bson:"full"`           // 1600px, smaller images keep their size
+}
+
+// Standard sizes of the images, the longer side is scaled down to the size
+var imageSizes = []struct {
+	name string
+	size int
+}{
+	{"thumbnail", 128},
+	{"card", 512},
+	{"full", 1600},
+}
+
+// Validate an uploaded image, generate its standard sizes and store them in the media directory
+//
+// The files are named after the hash of the upload, so the urls are stable and the same image
+// is stored only once. The directory is set with BILGI_MEDIA_DIR and served under BILGI_MEDIA_URL.
+func StoreImage(collection string, id string, data []byte) (ImageSizes, error) {
+	var sizes ImageSizes
+
+	// Check the type and the dimensions before decoding the whole image
+	contentType := http.DetectContentType(data)
+	if contentType != "image/png" && contentType != "image/jpeg" {
+		return sizes, ErrImageType
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return sizes, ErrImageType
+	}
+	if config.Width < minImageDimension || config.Height < minImageDimension || config.Width > maxImageDimension || config.Height > maxImageDimension {
+		return sizes, ErrImageDimensions
+	}
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return sizes, ErrImageType
+	}
+
+	// Create the directory of the document
+	directory := filepath.Join(utilities.GetEnv("BILGI_MEDIA_DIR", "./media"), collection, id)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return sizes, err
+	}
+	hash := sha256.Sum256(data)
+	prefix := hex.EncodeToString(hash[:8])
+	extension := ".png"
+	if contentType == "image/jpeg" {
+		extension = ".jpg"
+	}
+	baseURL := strings.TrimSuffix(utilities.GetEnv("BILGI_MEDIA_URL", "http://localhost:82/media"), "/")
+
+	// Scale and encode every size, png keeps the transparency of logos
+	urls := map[string]string{}
+	for _, standard := range imageSizes {
+		var encoded bytes.Buffer
+		scaled := scaleImage(source, standard.size)
+		if extension == ".png" {
+			err = png.Encode(&encoded, scaled)
+		} else {
+			err = jpeg.Encode(&encoded, scaled, &jpeg.Options{Quality: 90})
+		}
+		if err != nil {
+			return sizes, err
+		}
+		name := prefix + "-" + standard.name + extension
+		if err := os.WriteFile(filepath.Join(directory, name), encoded.Bytes(), 0644); err != nil {
+			return sizes, err
+		}
+		urls[standard.name] = baseURL + "/" + path.Join(collection, id, name)
+	}
+
+	sizes.Thumbnail, sizes.Card, sizes.Full = urls["thumbnail"], urls["card"], urls["full"]
+	return sizes, nil
+}
+
+// Scale an image down so that its longer side fits the size, smaller images are only copied
+func scaleImage(source image.Image, size int) image.Image {
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, height*size/width
+		} else {
+			width, height = width*size/height, size
+		}
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	scaled := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), source, bounds, draw.Src, nil)
+	return scaled
+}
@@ -9,6 +9,7 @@ type Party struct {
 	Abbreviation string             `json:"abbreviation" bson:"abbreviation,omitempty" validate:"required"`
 	Leader       string             `json:"leader" bson:"leader,omitempty" validate:"required"`
 	Logo         string             `json:"logo" bson:"logo,omitempty" validate:"required"`
+	LogoSizes    *ImageSizes        `json:"logosizes,omitempty" bson:"logosizes,omitempty"` // set by uploading the logo
 	Color        Color              `json:"color" bson:"color,omitempty" validate:"required"`
 }
 
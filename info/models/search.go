@@ -0,0 +1,46 @@
+package models
+
+import (
+	"sort"
+
+	"github.com/yzaimoglu/election/info/utilities"
+)
+
+// Types of the results of a search
+const (
+	SearchParty      = "party"
+	SearchIndividual = "individual"
+)
+
+// Model for a result of a search
+type SearchResult struct {
+	Type  string `json:"type"`            // individual
+	Id    string `json:"_id"`             // 63f1c2...
+	Name  string `json:"name"`            // Kemal Kılıçdaroğlu
+	Match string `json:"match"`           // Kılıçdaroğlu (the text which matched the query)
+	Score int    `json:"score"`           // 80
+	Color string `json:"color,omitempty"` // #ed1c24
+}
+
+// Score the result by the best matching text, returns false if none of the texts match the query
+func (result *SearchResult) MatchAny(query string, texts ...string) bool {
+	for _, text := range texts {
+		if score := utilities.MatchScore(query, text); score > result.Score {
+			result.Score, result.Match = score, text
+		}
+	}
+	return result.Score > 0
+}
+
+// Rank the results by their score, results with the same score are ordered by the length and the name
+func SortSearchResults(results []SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if len(results[i].Name) != len(results[j].Name) {
+			return len(results[i].Name) < len(results[j].Name)
+		}
+		return utilities.Compare(results[i].Name, results[j].Name) < 0
+	})
+}
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Returned if a new version would start before the current version of the document
+var ErrVersionOrder = errors.New("a new version cannot start before the current version")
+
+// Returned if another change of the document has recorded its version at the same time
+var ErrVersionConflict = errors.New("the document has been changed at the same time, try again")
+
+// Model for a version of a party or an individual, valid from ValidFrom until right before ValidTo
+type Version[T any] struct {
+	Id         primitive.ObjectID `json:"_id" bson:"_id"`
+	Collection string             `json:"collection" bson:"collection"` // parties
+	DocumentId primitive.ObjectID `json:"documentid" bson:"documentid"` // 63f1c2...
+	ValidFrom  time.Time          `json:"validfrom" bson:"validfrom"`   // 2018-05-20T00:00:00Z (zero for versions since always)
+	ValidTo    *time.Time         `json:"validto" bson:"validto"`       // 2023-03-01T00:00:00Z (null for the current version)
+	Document   T                  `json:"document" bson:"document"`     // the party or individual as it was
+}
+
+// Parse the date of a version, either a day like 2018-06-24 or a RFC3339 timestamp
+func ParseVersionDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return date, errors.New("dates must be formatted like 2018-06-24 or 2018-06-24T08:00:00Z")
+	}
+	return date.UTC(), nil
+}
+
+// Prepare a new version of a document starting at from, must be called before the document is changed
+//
+// The state of a document without versions, created before the versioning, becomes its first version
+// which is valid since always.
+func PrepareVersion[T any](ctx context.Context, database *mongo.Database, collection string, documentId primitive.ObjectID, from time.Time) error {
+	versions := database.Collection("versions")
+
+	// Check if the new version starts after the current one
+	var current Version[T]
+	err := versions.FindOne(ctx, bson.M{"collection": collection, "documentid": documentId, "validto": nil}).Decode(&current)
+	if err == nil {
+		if from.Before(current.ValidFrom) {
+			return ErrVersionOrder
+		}
+		return nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	// Keep the unversioned state of the document, closed versions mean it has been deleted
+	count, err := versions.CountDocuments(ctx, bson.M{"collection": collection, "documentid": documentId})
+	if err != nil || count > 0 {
+		return err
+	}
+	var document T
+	if err := database.Collection(collection).FindOne(ctx, bson.M{"_id": documentId}).Decode(&document); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+	_, err = versions.InsertOne(ctx, Version[T]{
+		Id:         primitive.NewObjectID(),
+		Collection: collection,
+		DocumentId: documentId,
+		Document:   document,
+	})
+	// The unversioned state has been kept by a concurrent change
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// Record the changed document as its new version starting at from and close the current version
+//
+// A new version starting at the same time as the current one replaces it, so that corrections do not create empty versions.
+// Returns ErrVersionConflict if a concurrent change has recorded its version in between.
+func RecordVersion[T any](ctx context.Context, database *mongo.Database, collection string, documentId primitive.ObjectID, from time.Time) error {
+	versions := database.Collection("versions")
+
+	// Get the changed document, nothing is recorded if it does not exist
+	var document T
+	if err := database.Collection(collection).FindOne(ctx, bson.M{"_id": documentId}).Decode(&document); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	// Replace the current version if it starts at the same time
+	result, err := versions.UpdateOne(ctx,
+		bson.M{"collection": collection, "documentid": documentId, "validto": nil, "validfrom": from},
+		bson.M{"$set": bson.M{"document": document}})
+	if err != nil || result.MatchedCount > 0 {
+		return err
+	}
+
+	// Close the current version and insert the new one
+	if err := CloseVersion(ctx, database, collection, documentId, from); err != nil {
+		return err
+	}
+	_, err = versions.InsertOne(ctx, Version[T]{
+		Id:         primitive.NewObjectID(),
+		Collection: collection,
+		DocumentId: documentId,
+		ValidFrom:  from,
+		Document:   document,
+	})
+	// Only one version can be current, see the unique index of the versions
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrVersionConflict
+	}
+	return err
+}
+
+// Close the current version of a document at the given time, used when it is changed or deleted
+func CloseVersion(ctx context.Context, database *mongo.Database, collection string, documentId primitive.ObjectID, to time.Time) error {
+	_, err := database.Collection("versions").UpdateOne(ctx,
+		bson.M{"collection": collection, "documentid": documentId, "validto": nil},
+		bson.M{"$set": bson.M{"validto": to}})
+	return err
+}
+
+// Get all versions of a document, the oldest first
+func GetVersions[T any](ctx context.Context, database *mongo.Database, collection string, documentId primitive.ObjectID) ([]Version[T], error) {
+	result, err := database.Collection("versions").Find(ctx,
+		bson.M{"collection": collection, "documentid": documentId},
+		options.Find().SetSort(bson.D{{Key: "validfrom", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	versions := []Version[T]{}
+	if err := result.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// Get the documents as they were at the given time by their id
+//
+// Documents without versions are returned as they are, documents with versions are missing
+// if none of their versions is valid at that time.
+func FindDocumentsAt[T any](ctx context.Context, database *mongo.Database, collection string, documentIds []primitive.ObjectID, at time.Time) (map[primitive.ObjectID]T, error) {
+	documents := map[primitive.ObjectID]T{}
+	if len(documentIds) == 0 {
+		return documents, nil
+	}
+	versions := database.Collection("versions")
+
+	// Get the versions valid at that time
+	result, err := versions.Find(ctx, bson.M{"$and": []bson.M{
+		{"collection": collection},
+		{"documentid": bson.M{"$in": documentIds}},
+		{"validfrom": bson.M{"$lte": at}},
+		{"$or": []bson.M{{"validto": nil}, {"validto": bson.M{"$gt": at}}}},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	var valid []Version[T]
+	if err := result.All(ctx, &valid); err != nil {
+		return nil, err
+	}
+	for _, version := range valid {
+		documents[version.DocumentId] = version.Document
+	}
+
+	// Get the documents without any version as they are
+	versioned, err := versions.Distinct(ctx, "documentid", bson.M{"collection": collection, "documentid": bson.M{"$in": documentIds}})
+	if err != nil {
+		return nil, err
+	}
+	var unversioned []primitive.ObjectID
+	for _, documentId := range documentIds {
+		if !containsObjectId(versioned, documentId) {
+			unversioned = append(unversioned, documentId)
+		}
+	}
+	if len(unversioned) == 0 {
+		return documents, nil
+	}
+	cursor, err := database.Collection(collection).Find(ctx, bson.M{"_id": bson.M{"$in": unversioned}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var document T
+		if err := cursor.Decode(&document); err != nil {
+			return nil, err
+		}
+		documentId, ok := cursor.Current.Lookup("_id").ObjectIDOK()
+		if !ok {
+			continue
+		}
+		documents[documentId] = document
+	}
+	return documents, cursor.Err()
+}
+
+// Check if the distinct values contain an object id
+func containsObjectId(values []interface{}, value primitive.ObjectID) bool {
+	for _, v := range values {
+		if id, ok := v.(primitive.ObjectID); ok && id == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/info/controllers"
+)
+
+// Returns all routes for the candidacy model
+func GetCandidacyRoutes(router *gin.RouterGroup) {
+	candidacyRoutes := router.Group("/candidacy")
+	{
+		// Routes for interacting with candidacies in the database
+		candidacyRoutes.GET("/:id/", controllers.GetCandidacy)
+		candidacyRoutes.POST("/", controllers.CreateCandidacy)
+		candidacyRoutes.PUT("/:id/", controllers.ChangeCandidacy)
+		candidacyRoutes.DELETE("/:id/", controllers.DeleteCandidacy)
+	}
+}
+
+// Returns all routes for the candidacies model
+func GetCandidaciesRoutes(router *gin.RouterGroup) {
+	candidaciesRoutes := router.Group("/candidacies")
+	{
+		// Routes for interacting with candidacies in the database
+		candidaciesRoutes.GET("/", controllers.GetCandidacies)
+	}
+}
@@ -11,11 +11,13 @@ func GetIndividualRoutes(router *gin.RouterGroup) {
 	{
 		// Routes for interacting with parties in the database
 		individualRoutes.GET("/:id", controllers.GetIndividual)
+		individualRoutes.GET("/:id/versions/", controllers.GetIndividualVersions)
 		individualRoutes.POST("/", controllers.CreateIndividual)
 		individualRoutes.PUT("/:id/", controllers.ChangeIndividual)
 		individualRoutes.PUT("/:id/firstname/", controllers.ChangeIndividualFirstName)
 		individualRoutes.PUT("/:id/lastname/", controllers.ChangeIndividualLastName)
 		individualRoutes.PUT("/:id/birthdate/", controllers.ChangeIndividualBirthdate)
+		individualRoutes.POST("/:id/image/", controllers.UploadIndividualImage)
 		individualRoutes.DELETE("/:id/", controllers.DeleteIndividual)
 	}
 }
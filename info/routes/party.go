@@ -11,12 +11,14 @@ func GetPartyRoutes(router *gin.RouterGroup) {
 	{
 		// Routes for interacting with parties in the database
 		partyRoutes.GET("/:id", controllers.GetParty)
+		partyRoutes.GET("/:id/versions/", controllers.GetPartyVersions)
 		partyRoutes.POST("/", controllers.CreateParty)
 		partyRoutes.PUT("/:id/", controllers.ChangeParty)
 		partyRoutes.PUT("/:id/name/", controllers.ChangePartyName)
 		partyRoutes.PUT("/:id/abbreviation/", controllers.ChangePartyAbbreviation)
 		partyRoutes.PUT("/:id/leader/", controllers.ChangePartyLeader)
 		partyRoutes.PUT("/:id/logo/", controllers.ChangePartyLogo)
+		partyRoutes.POST("/:id/logo/", controllers.UploadPartyLogo)
 		partyRoutes.PUT("/:id/color/", controllers.ChangePartyColor)
 		partyRoutes.DELETE("/:id/", controllers.DeleteParty)
 	}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yzaimoglu/election/info/middleware"
@@ -15,6 +21,18 @@ func main() {
 	// Setup environment variables and some other things
 	models.Setup()
 
+	// Connect to the database and stop if it is unreachable
+	if err := models.ConnectMongo(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Create the collections, validators and indexes
+	for _, index := range models.BootstrapDatabase() {
+		if !index.Ready {
+			fmt.Println("index " + index.Collection + "." + index.Name + " could not be built: " + index.Error)
+		}
+	}
+
 	// Initialize the main router
 	gin.SetMode(gin.ReleaseMode)
 	mainRouter := gin.New()
@@ -42,17 +60,46 @@ func main() {
 	// Set the Favicon
 	mainRouter.StaticFile("/favicon.ico", "./assets/favicon.ico")
 
-	// Create the main Route group for the API
-	v1 := mainRouter.Group("/v1")
+	// Serve the uploaded logos and portraits
+	mainRouter.Static("/media", utilities.GetEnv("BILGI_MEDIA_DIR", "./media"))
+
+	// Create the main Route group for the API, writes need an access token
+	v1 := mainRouter.Group("/v1", middleware.RequireServiceToken("info:write"))
 	{
 		routes.GetPartyRoutes(v1)
 		routes.GetPartiesRoutes(v1)
 		routes.GetIndividualRoutes(v1)
 		routes.GetIndividualsRoutes(v1)
+		routes.GetCandidacyRoutes(v1)
+		routes.GetCandidaciesRoutes(v1)
+		routes.GetSearchRoutes(v1)
+		routes.GetStatusRoutes(v1)
 	}
 
 	// Run server
 	serverPort := fmt.Sprint(utilities.GetEnv("BILGI_PORT", fmt.Sprint(80)))
 	fmt.Println("Bilgi server started running on port " + serverPort)
-	mainRouter.Run(":" + serverPort)
+	server := &http.Server{
+		Addr:    ":" + serverPort,
+		Handler: mainRouter,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// Wait for an interrupt and shut the server down gracefully
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	fmt.Println("Shutting down the server")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("error shutting down the server: " + err.Error())
+	}
+	if err := models.DisconnectMongo(ctx); err != nil {
+		log.Println("error disconnecting from the database: " + err.Error())
+	}
 }
@@ -0,0 +1,36 @@
+package tokens
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+)
+
+// Public key in the JSON web key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv,omitempty"` // ed25519 keys
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"` // rsa keys
+	E         string `json:"e,omitempty"`
+	KeyId     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+// Set of the published public keys
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Get the ed25519 public key of a JSON web key, rsa keys are only used for ID tokens and return an error
+func (key JWK) PublicKey() (ed25519.PublicKey, error) {
+	if key.KeyType != "OKP" || key.Curve != "Ed25519" {
+		return nil, errors.New("the key " + key.KeyId + " is not an ed25519 key")
+	}
+	publicKey, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, errors.New("the key " + key.KeyId + " is malformed")
+	}
+	return ed25519.PublicKey(publicKey), nil
+}
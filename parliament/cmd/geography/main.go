@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/yzaimoglu/election/parliament/models"
+	"github.com/yzaimoglu/election/parliament/utilities"
+)
+
+// Load the geography registry from a csv or xlsx file into the database
+//
+//	go run ./cmd/geography -file geography.csv
+func main() {
+	// Parse the command line flags
+	fileName := flag.String("file", "", "csv or xlsx file with the columns code, level, type, name, readablename, parentcode and constituency")
+	flag.Parse()
+	if *fileName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Setup environment variables and the database
+	models.Setup()
+	if err := models.ConnectMongo(); err != nil {
+		log.Fatal(err)
+	}
+	defer models.DisconnectMongo(context.Background())
+
+	// Read the rows of the file
+	file, err := os.Open(*fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := utilities.ReadSpreadsheet(file, *fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Load the units and print the report
+	report, err := models.SeedGeography(context.Background(), rows)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
@@ -17,9 +16,8 @@ import (
 
 // Create a ballot box
 func CreateBox(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the box
 	var box models.Box
@@ -46,6 +44,12 @@ func CreateBox(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveBox(&box); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Insert box
 	if _, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes").InsertOne(ctx, box); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
@@ -55,6 +59,11 @@ func CreateBox(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the box
+	if err := models.CacheInvalidate(models.BoxCacheTags(box)...); err != nil {
+		log.Println("error invalidating the cache of the box: " + err.Error())
+	}
+
 	// Return the recently created box
 	c.JSON(http.StatusOK, box)
 }
@@ -62,44 +71,32 @@ func CreateBox(c *gin.Context) {
 // Get all the boxes by city
 func GetBoxesByCity(c *gin.Context) {
 	city := c.Param("city")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
-
-	// Initialize the boxes
-	var boxes []models.Box
-	boxName := "boxes-" + city
-
-	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(boxName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &boxes); err == nil {
-			c.JSON(http.StatusOK, boxes)
-			return
-		}
-	}*/
 
-	// Get box
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes").Find(ctx, bson.M{"city": city})
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, boxListOptions)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Return that no box has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no boxes",
-		})
+	// Initialize the page of the boxes
+	var page models.Page
+	cacheKey := models.CacheKey("boxes", "city", city, query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
 		return
 	}
 
-	// Decode all elements in the database into the box slice
-	if err = result.All(ctx, &boxes); err != nil {
+	// Get the page of boxes
+	page, err = findPage[models.Box](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes"), []bson.M{{"city": city}}, query)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -108,49 +105,48 @@ func GetBoxesByCity(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	boxJSON, err := json.Marshal(boxes)
-	if err != nil {
-		log.Printf("error marshalling " + boxName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(boxName, boxJSON); err != nil {
-		log.Printf("error setting " + boxName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(boxName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + boxName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("boxes", "city", city)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the box
-	c.JSON(http.StatusOK, boxes)
+	c.JSON(http.StatusOK, page)
 }
 
 // Get all the boxes by constituency
 func GetBoxesByConstituency(c *gin.Context) {
 	city := c.Param("city")
 	constituency := c.Param("constituency")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
-	// Initialize the boxes
-	var boxes []models.Box
-	boxName := "boxes-" + city + "-" + constituency
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, boxListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Initialize the page of the boxes
+	var page models.Page
+	cacheKey := models.CacheKey("boxes", "constituency", city, constituency, query.key)
 
 	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(boxName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &boxes); err == nil {
-			c.JSON(http.StatusOK, boxes)
-			return
-		}
-	}*/
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
+		return
+	}
 
 	// Initialize $and filter
 	var filter []bson.M
 	filter = append(filter, bson.M{"city": city})
 	filter = append(filter, bson.M{"constituency": constituency})
 
-	// Get box
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes").Find(ctx, bson.M{"$and": filter})
+	// Get the page of boxes
+	page, err = findPage[models.Box](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes"), filter, query)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
@@ -159,60 +155,41 @@ func GetBoxesByConstituency(c *gin.Context) {
 		return
 	}
 
-	// Return that no box has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no boxes",
-		})
-		return
-	}
-
-	// Decode all elements in the database into the box slice
-	if err = result.All(ctx, &boxes); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
-		})
-		return
-	}
-
 	// Set the result to the cache
-	boxJSON, err := json.Marshal(boxes)
-	if err != nil {
-		log.Printf("error marshalling " + boxName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(boxName, boxJSON); err != nil {
-		log.Printf("error setting " + boxName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(boxName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + boxName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("boxes", "constituency", city, constituency)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the box
-	c.JSON(http.StatusOK, boxes)
+	c.JSON(http.StatusOK, page)
 }
 
 // Get all the boxes by district
 func GetBoxesByDistrict(c *gin.Context) {
 	city := c.Param("city")
 	district := c.Param("district")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
-	// Initialize the boxes
-	var boxes []models.Box
-	boxName := "boxes-" + city + "-" + district
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, boxListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Initialize the page of the boxes
+	var page models.Page
+	cacheKey := models.CacheKey("boxes", "district", city, district, query.key)
 
 	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(boxName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &boxes); err == nil {
-			c.JSON(http.StatusOK, boxes)
-			return
-		}
-	}*/
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
+		return
+	}
 
 	// Initialize $and input
 	var filter []bson.M
@@ -220,8 +197,8 @@ func GetBoxesByDistrict(c *gin.Context) {
 	filter = append(filter, bson.M{"city": city})
 	filter = append(filter, bson.M{"district": district})
 
-	// Get box
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes").Find(ctx, bson.M{"$and": filter})
+	// Get the page of boxes
+	page, err = findPage[models.Box](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes"), filter, query)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
@@ -230,38 +207,13 @@ func GetBoxesByDistrict(c *gin.Context) {
 		return
 	}
 
-	// Return that no box has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no boxes",
-		})
-		return
-	}
-
-	// Decode all elements in the database into the box slice
-	if err = result.All(ctx, &boxes); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
-		})
-		return
-	}
-
 	// Set the result to the cache
-	boxJSON, err := json.Marshal(boxes)
-	if err != nil {
-		log.Printf("error marshalling " + boxName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(boxName, boxJSON); err != nil {
-		log.Printf("error setting " + boxName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(boxName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + boxName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("boxes", "district", city, district)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the box
-	c.JSON(http.StatusOK, boxes)
+	c.JSON(http.StatusOK, page)
 }
 
 // Get all the boxes by quarter
@@ -269,22 +221,28 @@ func GetBoxesByQuarter(c *gin.Context) {
 	city := c.Param("city")
 	district := c.Param("district")
 	quarter := c.Param("quarter")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
-	// Initialize the boxes
-	var boxes []models.Box
-	boxName := "boxes-" + city + "-" + district
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, boxListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Initialize the page of the boxes
+	var page models.Page
+	cacheKey := models.CacheKey("boxes", "quarter", city, district, quarter, query.key)
 
 	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(boxName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &boxes); err == nil {
-			c.JSON(http.StatusOK, boxes)
-			return
-		}
-	}*/
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
+		return
+	}
 
 	// Initialize $and input
 	var filter []bson.M
@@ -293,8 +251,8 @@ func GetBoxesByQuarter(c *gin.Context) {
 	filter = append(filter, bson.M{"district": district})
 	filter = append(filter, bson.M{"quarter": quarter})
 
-	// Get box
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes").Find(ctx, bson.M{"$and": filter})
+	// Get the page of boxes
+	page, err = findPage[models.Box](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes"), filter, query)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
@@ -303,57 +261,29 @@ func GetBoxesByQuarter(c *gin.Context) {
 		return
 	}
 
-	// Return that no box has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no boxes",
-		})
-		return
-	}
-
-	// Decode all elements in the database into the box slice
-	if err = result.All(ctx, &boxes); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
-		})
-		return
-	}
-
 	// Set the result to the cache
-	boxJSON, err := json.Marshal(boxes)
-	if err != nil {
-		log.Printf("error marshalling " + boxName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(boxName, boxJSON); err != nil {
-		log.Printf("error setting " + boxName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(boxName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + boxName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("boxes", "quarter", city, district, quarter)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the box
-	c.JSON(http.StatusOK, boxes)
+	c.JSON(http.StatusOK, page)
 }
 
 // Get a box by its id
 func GetBoxById(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the box
 	var box models.Box
+	cacheKey := models.CacheKey("box", "id", id)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet("boxwithid-" + id)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &box); err == nil {
-			c.JSON(http.StatusOK, box)
-			return
-		}
+	if models.CacheGet(cacheKey, &box) {
+		c.JSON(http.StatusOK, box)
+		return
 	}
 
 	// ObjectID from id
@@ -389,15 +319,8 @@ func GetBoxById(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	boxJSON, err := json.Marshal(box)
-	if err != nil {
-		log.Printf("error marshalling boxwithid-" + id + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet("boxwithid-"+id, boxJSON); err != nil {
-		log.Printf("error setting boxwithid-" + id + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL("boxwithid-"+id, 60*5); err != nil {
-		log.Printf("error setting ttl for the boxwithid-" + id + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, box, models.DocumentTag("boxes", box.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the box
@@ -409,21 +332,17 @@ func GetBoxByNumber(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	number := c.Param("number")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the Box
 	var box models.Box
-	boxName := "box-" + city + "-" + district + "-" + number
+	cacheKey := models.CacheKey("box", "number", city, district, number)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet(boxName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &box); err == nil {
-			c.JSON(http.StatusOK, box)
-			return
-		}
+	if models.CacheGet(cacheKey, &box) {
+		c.JSON(http.StatusOK, box)
+		return
 	}
 
 	// Initialize $and input
@@ -459,15 +378,8 @@ func GetBoxByNumber(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	boxJSON, err := json.Marshal(box)
-	if err != nil {
-		log.Printf("error marshalling " + boxName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(boxName, boxJSON); err != nil {
-		log.Printf("error setting " + boxName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(boxName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + boxName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, box, models.DocumentTag("boxes", box.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the box
@@ -479,9 +391,8 @@ func ChangeBox(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	number := c.Param("number")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the box
 	var box models.Box
@@ -506,6 +417,12 @@ func ChangeBox(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveBox(&box); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Initialize $and input
 	var filter []bson.M
 	var numberInt int64
@@ -540,7 +457,7 @@ func ChangeBox(c *gin.Context) {
 	box.Id = oldBox.Id
 
 	// Replace object
-	if _, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("cities").ReplaceOne(ctx, bson.M{"$and": filter}, box); err != nil {
+	if _, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes").ReplaceOne(ctx, bson.M{"$and": filter}, box); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -548,6 +465,11 @@ func ChangeBox(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the old or the updated box
+	if err := models.CacheInvalidate(append(models.BoxCacheTags(oldBox), models.BoxCacheTags(box)...)...); err != nil {
+		log.Println("error invalidating the cache of the box: " + err.Error())
+	}
+
 	// Return the recently updated box
 	c.JSON(http.StatusOK, box)
 }
@@ -557,9 +479,8 @@ func DeleteBox(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	number := c.Param("number")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize $and input
 	var filter []bson.M
@@ -572,11 +493,20 @@ func DeleteBox(c *gin.Context) {
 	filter = append(filter, bson.M{"district": district})
 	filter = append(filter, bson.M{"number": numberInt})
 
-	// Delete box
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes").DeleteOne(ctx, bson.M{"$and": filter})
+	// Delete the box
+	result := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes").FindOneAndDelete(ctx, bson.M{"$and": filter})
 
-	// Check if there is a box with the filter
-	if err != nil {
+	// Return that nothing has been deleted if there is no box with the filter
+	if result.Err() == mongo.ErrNoDocuments {
+		c.JSON(http.StatusOK, gin.H{
+			"deletedCount": 0,
+		})
+		return
+	}
+
+	// Decode the deleted box
+	var deletedBox models.Box
+	if err := result.Decode(&deletedBox); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -584,8 +514,13 @@ func DeleteBox(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the box
+	if err := models.CacheInvalidate(models.BoxCacheTags(deletedBox)...); err != nil {
+		log.Println("error invalidating the cache of the box: " + err.Error())
+	}
+
 	// Return the deleted count
 	c.JSON(http.StatusOK, gin.H{
-		"deletedCount": result.DeletedCount,
+		"deletedCount": 1,
 	})
 }
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
@@ -13,52 +12,36 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Get all cities, important for the updater
 func GetCities(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
-	// Initialize the cities
-	var cities []models.City
-	cityName := "cities"
-
-	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(cityName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &cities); err == nil {
-			c.JSON(http.StatusOK, cities)
-			return
-		}
-	}*/
-
-	// Sorting by number ascending
-	opts := options.Find().SetSort(bson.M{"number": 1})
-
-	// Get cities
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("cities").Find(ctx, bson.M{}, opts)
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, cityListOptions)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Return that no city has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no cities",
-		})
+	// Initialize the page of the cities
+	var page models.Page
+	cacheKey := models.CacheKey("cities", "all", query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
 		return
 	}
 
-	// Decode all elements in the database into the cities slice
-	if err = result.All(ctx, &cities); err != nil {
+	// Get the page of cities
+	page, err = findPage[models.City](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("cities"), nil, query)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -67,26 +50,18 @@ func GetCities(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	cityJSON, err := json.Marshal(cities)
-	if err != nil {
-		log.Printf("error marshalling " + cityName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(cityName, cityJSON); err != nil {
-		log.Printf("error setting " + cityName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(cityName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + cityName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("cities", "all")); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the cities
-	c.JSON(http.StatusOK, cities)
+	c.JSON(http.StatusOK, page)
 }
 
 // Create a city
 func CreateCity(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the City
 	var city models.City
@@ -113,6 +88,12 @@ func CreateCity(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveCity(&city); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Insert city
 	if _, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("cities").InsertOne(ctx, city); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
@@ -122,6 +103,11 @@ func CreateCity(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the city
+	if err := models.CacheInvalidate(models.CityCacheTags(city)...); err != nil {
+		log.Println("error invalidating the cache of the city: " + err.Error())
+	}
+
 	// Return the recently created city
 	c.JSON(http.StatusOK, city)
 }
@@ -129,20 +115,17 @@ func CreateCity(c *gin.Context) {
 // Get a city by its id/name/number
 func GetCity(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the City
 	var city models.City
+	cacheKey := models.CacheKey("city", id)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet("city-" + id)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &city); err == nil {
-			c.JSON(http.StatusOK, city)
-			return
-		}
+	if models.CacheGet(cacheKey, &city) {
+		c.JSON(http.StatusOK, city)
+		return
 	}
 
 	// Initialize $or input
@@ -181,15 +164,8 @@ func GetCity(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	cityJSON, err := json.Marshal(city)
-	if err != nil {
-		log.Printf("error marshalling city-" + id + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet("city-"+id, cityJSON); err != nil {
-		log.Printf("error setting city-" + id + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL("city-"+id, 60*5); err != nil {
-		log.Printf("error setting ttl for the city-" + id + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, city, models.DocumentTag("cities", city.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the recently created city
@@ -199,9 +175,8 @@ func GetCity(c *gin.Context) {
 // Change a city
 func ChangeCity(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the City
 	var city models.City
@@ -226,6 +201,12 @@ func ChangeCity(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveCity(&city); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Initialize $or input
 	var filter []bson.M
 	var objId primitive.ObjectID
@@ -272,6 +253,11 @@ func ChangeCity(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the old or the updated city
+	if err := models.CacheInvalidate(append(models.CityCacheTags(oldCity), models.CityCacheTags(city)...)...); err != nil {
+		log.Println("error invalidating the cache of the city: " + err.Error())
+	}
+
 	// Return the recently updated city
 	c.JSON(http.StatusOK, city)
 }
@@ -279,9 +265,8 @@ func ChangeCity(c *gin.Context) {
 // Delete a city
 func DeleteCity(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize $or input
 	var filter []bson.M
@@ -298,8 +283,19 @@ func DeleteCity(c *gin.Context) {
 	filter = append(filter, bson.M{"number": numberInt})
 
 	// Delete the city
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("cities").DeleteOne(ctx, bson.M{"$or": filter})
-	if err != nil {
+	result := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("cities").FindOneAndDelete(ctx, bson.M{"$or": filter})
+
+	// Return that nothing has been deleted if there is no city with the filter
+	if result.Err() == mongo.ErrNoDocuments {
+		c.JSON(http.StatusOK, gin.H{
+			"deletedCount": 0,
+		})
+		return
+	}
+
+	// Decode the deleted city
+	var deletedCity models.City
+	if err := result.Decode(&deletedCity); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
 			"error":  err.Error(),
@@ -307,8 +303,13 @@ func DeleteCity(c *gin.Context) {
 		return
 	}
 
-	// Return deleted count
+	// Invalidate all cached entries containing the city
+	if err := models.CacheInvalidate(models.CityCacheTags(deletedCity)...); err != nil {
+		log.Println("error invalidating the cache of the city: " + err.Error())
+	}
+
+	// Return the deleted count
 	c.JSON(http.StatusOK, gin.H{
-		"deletedCount": result.DeletedCount,
+		"deletedCount": 1,
 	})
 }
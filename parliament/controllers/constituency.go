@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 
@@ -12,52 +11,36 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Get all constituencies, important for the updater
 func GetConstituencies(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
-	// Initialize the constituencies
-	var constituencies []models.District
-	constituencyName := "constituencies"
-
-	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(constituencyName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &constituencies); err == nil {
-			c.JSON(http.StatusOK, constituencies)
-			return
-		}
-	}*/
-
-	// Sorting by number ascending
-	opts := options.Find().SetSort(bson.M{"citynumber": 1})
-
-	// Get constituencies
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("constituencies").Find(ctx, bson.M{}, opts)
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, regionListOptions)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Return that no constituency has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no constituencies",
-		})
+	// Initialize the page of the constituencies
+	var page models.Page
+	cacheKey := models.CacheKey("constituencies", "all", query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
 		return
 	}
 
-	// Decode all elements in the database into the constituencies slice
-	if err = result.All(ctx, &constituencies); err != nil {
+	// Get the page of constituencies
+	page, err = findPage[models.District](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("constituencies"), nil, query)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -66,65 +49,43 @@ func GetConstituencies(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	constituencyJSON, err := json.Marshal(constituencies)
-	if err != nil {
-		log.Printf("error marshalling " + constituencyName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(constituencyName, constituencyJSON); err != nil {
-		log.Printf("error setting " + constituencyName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(constituencyName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + constituencyName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("constituencies", "all")); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the constituencies
-	c.JSON(http.StatusOK, constituencies)
+	c.JSON(http.StatusOK, page)
 }
 
 // Get all constituencies by city, important for the updater
 func GetConstituenciesByCity(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	city := c.Param("city")
 	defer cancel()
-	defer client.Disconnect(ctx)
 
-	// Initialize the constituencies
-	var constituencies []models.District
-	constituencyName := "constituencies" + "-" + city
-
-	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(constituencyName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &constituencies); err == nil {
-			c.JSON(http.StatusOK, constituencies)
-			return
-		}
-	}*/
-
-	// Sorting by number ascending
-	opts := options.Find().SetSort(bson.M{"citynumber": 1})
-
-	// Get constituencies
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("constituencies").Find(ctx, bson.M{"city": city}, opts)
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, regionListOptions)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Return that no constituency has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no constituencies",
-		})
+	// Initialize the page of the constituencies
+	var page models.Page
+	cacheKey := models.CacheKey("constituencies", "city", city, query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
 		return
 	}
 
-	// Decode all elements in the database into the constituencies slice
-	if err = result.All(ctx, &constituencies); err != nil {
+	// Get the page of constituencies
+	page, err = findPage[models.District](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("constituencies"), []bson.M{{"city": city}}, query)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -133,26 +94,18 @@ func GetConstituenciesByCity(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	constituencyJSON, err := json.Marshal(constituencies)
-	if err != nil {
-		log.Printf("error marshalling " + constituencyName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(constituencyName, constituencyJSON); err != nil {
-		log.Printf("error setting " + constituencyName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(constituencyName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + constituencyName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("constituencies", "city", city)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the constituencies
-	c.JSON(http.StatusOK, constituencies)
+	c.JSON(http.StatusOK, page)
 }
 
 // Create a constituency
 func CreateConstituency(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the Constituency
 	var constituency models.Constituency
@@ -179,6 +132,12 @@ func CreateConstituency(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveConstituency(&constituency); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Insert constituency
 	if _, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("constituencies").InsertOne(ctx, constituency); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
@@ -188,6 +147,11 @@ func CreateConstituency(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the constituency
+	if err := models.CacheInvalidate(models.ConstituencyCacheTags(constituency)...); err != nil {
+		log.Println("error invalidating the cache of the constituency: " + err.Error())
+	}
+
 	// Return the recently created constituency
 	c.JSON(http.StatusOK, constituency)
 }
@@ -195,20 +159,17 @@ func CreateConstituency(c *gin.Context) {
 // Get a constituency by its id/name
 func GetConstituency(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the constituency
 	var constituency models.Constituency
+	cacheKey := models.CacheKey("constituency", id)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet("constituency-" + id)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &constituency); err == nil {
-			c.JSON(http.StatusOK, constituency)
-			return
-		}
+	if models.CacheGet(cacheKey, &constituency) {
+		c.JSON(http.StatusOK, constituency)
+		return
 	}
 
 	// Initialize $or input
@@ -241,15 +202,8 @@ func GetConstituency(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	constituencyJSON, err := json.Marshal(constituency)
-	if err != nil {
-		log.Printf("error marshalling constituency-" + id + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet("constituency-"+id, constituencyJSON); err != nil {
-		log.Printf("error setting constituency-" + id + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL("constituency-"+id, 60*5); err != nil {
-		log.Printf("error setting ttl for the constituency-" + id + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, constituency, models.DocumentTag("constituencies", constituency.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the recently created constituency
@@ -259,9 +213,8 @@ func GetConstituency(c *gin.Context) {
 // Change a constituency
 func ChangeConstituency(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the constituency
 	var constituency models.Constituency
@@ -286,6 +239,12 @@ func ChangeConstituency(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveConstituency(&constituency); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Initialize $or input
 	var filter []bson.M
 	var objId primitive.ObjectID
@@ -326,6 +285,11 @@ func ChangeConstituency(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the old or the updated constituency
+	if err := models.CacheInvalidate(append(models.ConstituencyCacheTags(oldConstituency), models.ConstituencyCacheTags(constituency)...)...); err != nil {
+		log.Println("error invalidating the cache of the constituency: " + err.Error())
+	}
+
 	// Return the recently updated constituency
 	c.JSON(http.StatusOK, constituency)
 }
@@ -333,9 +297,8 @@ func ChangeConstituency(c *gin.Context) {
 // Delete a constituency
 func DeleteConstituency(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize $or input
 	var filter []bson.M
@@ -347,8 +310,19 @@ func DeleteConstituency(c *gin.Context) {
 	filter = append(filter, bson.M{"_id": objId})
 
 	// Delete the constituency
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("constituencies").DeleteOne(ctx, bson.M{"$or": filter})
-	if err != nil {
+	result := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("constituencies").FindOneAndDelete(ctx, bson.M{"$or": filter})
+
+	// Return that nothing has been deleted if there is no constituency with the filter
+	if result.Err() == mongo.ErrNoDocuments {
+		c.JSON(http.StatusOK, gin.H{
+			"deletedCount": 0,
+		})
+		return
+	}
+
+	// Decode the deleted constituency
+	var deletedConstituency models.Constituency
+	if err := result.Decode(&deletedConstituency); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
 			"error":  err.Error(),
@@ -356,8 +330,13 @@ func DeleteConstituency(c *gin.Context) {
 		return
 	}
 
-	// Return deleted count
+	// Invalidate all cached entries containing the constituency
+	if err := models.CacheInvalidate(models.ConstituencyCacheTags(deletedConstituency)...); err != nil {
+		log.Println("error invalidating the cache of the constituency: " + err.Error())
+	}
+
+	// Return the deleted count
 	c.JSON(http.StatusOK, gin.H{
-		"deletedCount": result.DeletedCount,
+		"deletedCount": 1,
 	})
 }
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 
@@ -12,52 +11,36 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Get all districts, important for the updater
 func GetDistricts(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
-	// Initialize the districts
-	var districts []models.District
-	districtName := "districts"
-
-	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(districtName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &districts); err == nil {
-			c.JSON(http.StatusOK, districts)
-			return
-		}
-	}*/
-
-	// Sorting by number ascending
-	opts := options.Find().SetSort(bson.M{"citynumber": 1})
-
-	// Get districts
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("districts").Find(ctx, bson.M{}, opts)
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, regionListOptions)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Return that no district has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no districts",
-		})
+	// Initialize the page of the districts
+	var page models.Page
+	cacheKey := models.CacheKey("districts", "all", query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
 		return
 	}
 
-	// Decode all elements in the database into the districts slice
-	if err = result.All(ctx, &districts); err != nil {
+	// Get the page of districts
+	page, err = findPage[models.District](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("districts"), nil, query)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -66,65 +49,43 @@ func GetDistricts(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	districtJSON, err := json.Marshal(districts)
-	if err != nil {
-		log.Printf("error marshalling " + districtName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(districtName, districtJSON); err != nil {
-		log.Printf("error setting " + districtName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(districtName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + districtName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("districts", "all")); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the districts
-	c.JSON(http.StatusOK, districts)
+	c.JSON(http.StatusOK, page)
 }
 
 // Get all districts by city, important for the updater
 func GetDistrictsByCity(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	city := c.Param("city")
 	defer cancel()
-	defer client.Disconnect(ctx)
 
-	// Initialize the districts
-	var districts []models.District
-	districtName := "districts" + "-" + city
-
-	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(districtName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &districts); err == nil {
-			c.JSON(http.StatusOK, districts)
-			return
-		}
-	}*/
-
-	// Sorting by number ascending
-	opts := options.Find().SetSort(bson.M{"citynumber": 1})
-
-	// Get districts
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("districts").Find(ctx, bson.M{"city": city}, opts)
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, regionListOptions)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Return that no district has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no districts",
-		})
+	// Initialize the page of the districts
+	var page models.Page
+	cacheKey := models.CacheKey("districts", "city", city, query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
 		return
 	}
 
-	// Decode all elements in the database into the districts slice
-	if err = result.All(ctx, &districts); err != nil {
+	// Get the page of districts
+	page, err = findPage[models.District](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("districts"), []bson.M{{"city": city}}, query)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -133,69 +94,48 @@ func GetDistrictsByCity(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	districtJSON, err := json.Marshal(districts)
-	if err != nil {
-		log.Printf("error marshalling " + districtName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(districtName, districtJSON); err != nil {
-		log.Printf("error setting " + districtName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(districtName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + districtName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("districts", "city", city)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the districts
-	c.JSON(http.StatusOK, districts)
+	c.JSON(http.StatusOK, page)
 }
 
 // Get all districts by constituency, important for the updater
 func GetDistrictsByConstituency(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	city := c.Param("city")
 	constituency := c.Param("constituency")
 	defer cancel()
-	defer client.Disconnect(ctx)
-
-	// Initialize the districts
-	var districts []models.District
-	districtName := "districts" + "-" + city + "-" + constituency
-
-	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(districtName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &districts); err == nil {
-			c.JSON(http.StatusOK, districts)
-			return
-		}
-	}*/
-
-	// Sorting by number ascending
-	opts := options.Find().SetSort(bson.M{"citynumber": 1})
-	var filter []bson.M
-	filter = append(filter, bson.M{"city": city})
-	filter = append(filter, bson.M{"constituency": constituency})
 
-	// Get districts
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("districts").Find(ctx, bson.M{"$and": filter}, opts)
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, regionListOptions)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Return that no district has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no districts",
-		})
+	// Initialize the page of the districts
+	var page models.Page
+	cacheKey := models.CacheKey("districts", "constituency", city, constituency, query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
 		return
 	}
 
-	// Decode all elements in the database into the districts slice
-	if err = result.All(ctx, &districts); err != nil {
+	var filter []bson.M
+	filter = append(filter, bson.M{"city": city})
+	filter = append(filter, bson.M{"constituency": constituency})
+
+	// Get the page of districts
+	page, err = findPage[models.District](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("districts"), filter, query)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -204,26 +144,18 @@ func GetDistrictsByConstituency(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	districtJSON, err := json.Marshal(districts)
-	if err != nil {
-		log.Printf("error marshalling " + districtName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(districtName, districtJSON); err != nil {
-		log.Printf("error setting " + districtName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(districtName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + districtName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("districts", "constituency", city, constituency)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the districts
-	c.JSON(http.StatusOK, districts)
+	c.JSON(http.StatusOK, page)
 }
 
 // Create a district
 func CreateDistrict(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the district
 	var district models.District
@@ -250,6 +182,12 @@ func CreateDistrict(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveDistrict(&district); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Insert district
 	if _, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("districts").InsertOne(ctx, district); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
@@ -259,6 +197,11 @@ func CreateDistrict(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the district
+	if err := models.CacheInvalidate(models.DistrictCacheTags(district)...); err != nil {
+		log.Println("error invalidating the cache of the district: " + err.Error())
+	}
+
 	// Return the recently created district
 	c.JSON(http.StatusOK, district)
 }
@@ -266,20 +209,17 @@ func CreateDistrict(c *gin.Context) {
 // Get a district by its id
 func GetDistrictById(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the district
 	var district models.District
+	cacheKey := models.CacheKey("district", "id", id)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet("districtwithid-" + id)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &district); err == nil {
-			c.JSON(http.StatusOK, district)
-			return
-		}
+	if models.CacheGet(cacheKey, &district) {
+		c.JSON(http.StatusOK, district)
+		return
 	}
 
 	// ObjectID from id
@@ -315,15 +255,8 @@ func GetDistrictById(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	districtJSON, err := json.Marshal(district)
-	if err != nil {
-		log.Printf("error marshalling districtwithid-" + id + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet("districtwithid-"+id, districtJSON); err != nil {
-		log.Printf("error setting districtwithid-" + id + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL("districtwithid-"+id, 60*5); err != nil {
-		log.Printf("error setting ttl for the districtwithid-" + id + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, district, models.DocumentTag("districts", district.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the district
@@ -334,21 +267,17 @@ func GetDistrictById(c *gin.Context) {
 func GetDistrictByName(c *gin.Context) {
 	city := c.Param("id")
 	districtParam := c.Param("district")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the district
 	var district models.District
-	districtName := "district-" + city + "-" + districtParam
+	cacheKey := models.CacheKey("district", "name", city, districtParam)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet(districtName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &district); err == nil {
-			c.JSON(http.StatusOK, district)
-			return
-		}
+	if models.CacheGet(cacheKey, &district) {
+		c.JSON(http.StatusOK, district)
+		return
 	}
 
 	// Initialize $and input
@@ -379,15 +308,8 @@ func GetDistrictByName(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	districtJSON, err := json.Marshal(district)
-	if err != nil {
-		log.Printf("error marshalling " + districtName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(districtName, districtJSON); err != nil {
-		log.Printf("error setting " + districtName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(districtName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + districtName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, district, models.DocumentTag("districts", district.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the district
@@ -398,9 +320,8 @@ func GetDistrictByName(c *gin.Context) {
 func ChangeDistrict(c *gin.Context) {
 	city := c.Param("id")
 	districtParam := c.Param("district")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the district
 	var district models.District
@@ -425,6 +346,12 @@ func ChangeDistrict(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveDistrict(&district); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Initialize $and input
 	var filter []bson.M
 
@@ -463,6 +390,11 @@ func ChangeDistrict(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the old or the updated district
+	if err := models.CacheInvalidate(append(models.DistrictCacheTags(oldDistrict), models.DistrictCacheTags(district)...)...); err != nil {
+		log.Println("error invalidating the cache of the district: " + err.Error())
+	}
+
 	// Return the recently updated district
 	c.JSON(http.StatusOK, district)
 }
@@ -471,20 +403,28 @@ func ChangeDistrict(c *gin.Context) {
 func DeleteDistrict(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize $and input
 	var filter []bson.M
 	filter = append(filter, bson.M{"city": city})
 	filter = append(filter, bson.M{"district": district})
 
-	// Delete district
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("districts").DeleteOne(ctx, bson.M{"$and": filter})
+	// Delete the district
+	result := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("districts").FindOneAndDelete(ctx, bson.M{"$and": filter})
 
-	// Check if there is a district with the filter
-	if err != nil {
+	// Return that nothing has been deleted if there is no district with the filter
+	if result.Err() == mongo.ErrNoDocuments {
+		c.JSON(http.StatusOK, gin.H{
+			"deletedCount": 0,
+		})
+		return
+	}
+
+	// Decode the deleted district
+	var deletedDistrict models.District
+	if err := result.Decode(&deletedDistrict); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -492,8 +432,13 @@ func DeleteDistrict(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the district
+	if err := models.CacheInvalidate(models.DistrictCacheTags(deletedDistrict)...); err != nil {
+		log.Println("error invalidating the cache of the district: " + err.Error())
+	}
+
 	// Return the deleted count
 	c.JSON(http.StatusOK, gin.H{
-		"deletedCount": result.DeletedCount,
+		"deletedCount": 1,
 	})
 }
@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"github.com/yzaimoglu/election/parliament/models"
+	"github.com/yzaimoglu/election/parliament/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Timeout of an export, exporting all boxes of Turkey takes a while
+const exportTimeout = 30 * time.Minute
+
+// Content types of the export formats
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"ndjson": "application/x-ndjson; charset=utf-8",
+}
+
+// Writer for the rows of an export
+type exportWriter interface {
+	header(columns []string) error
+	row(columns []string, document models.ExportDocument) error
+	close() error
+}
+
+// Export all boxes or aggregates of a level, optionally limited to a region
+//
+//	GET /v1/export/boxes/?format=csv&city=ankara&district=cankaya&columns=district,number,validvotes,candidates
+func ExportLevel(c *gin.Context) {
+	// Get the exported level and the format
+	level, ok := models.ExportLevels[c.Param("level")]
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  "unknown level, must be one of boxes, cities, constituencies, districts or quarters",
+		})
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "format must be csv, xlsx or ndjson",
+		})
+		return
+	}
+
+	client, ctx, cancel := models.GetMongoInstanceWithTimeout(c.Request.Context(), exportTimeout)
+	defer cancel()
+	collection := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection(level.Collection)
+
+	// Initialize $and filter for the region
+	var filter []bson.M
+	fileName := "milletvekili-" + c.Param("level")
+	for _, field := range []string{"city", "constituency", "district", "quarter"} {
+		if value := utilities.Slug(c.Query(field)); value != "" {
+			filter = append(filter, bson.M{field: value})
+			fileName += "-" + value
+		}
+	}
+
+	// Get all candidates of the region for the candidate columns
+	var candidateColumns []string
+	var candidates []struct {
+		Candidate models.CandidateInBox `bson:"_id"`
+	}
+	if err := distinctVotes(ctx, collection, filter, "candidates", bson.M{"firstname": "$candidates.firstname", "lastname": "$candidates.lastname"}, &candidates); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+	for _, candidate := range candidates {
+		candidateColumns = append(candidateColumns, models.CandidateColumn(candidate.Candidate))
+	}
+
+	// Select the columns, candidates selects the columns of all candidates
+	columns := append(append([]string{}, level.Columns...), candidateColumns...)
+	if selected := c.Query("columns"); selected != "" {
+		columns = nil
+		for _, column := range strings.Split(selected, ",") {
+			switch {
+			case column == "candidates":
+				columns = append(columns, candidateColumns...)
+			case column == "_id" || containsString(level.Columns, column) || containsString(candidateColumns, column):
+				columns = append(columns, column)
+			default:
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"status": http.StatusBadRequest,
+					"error":  "unknown column " + column,
+				})
+				return
+			}
+		}
+	}
+
+	// Get the documents in batches, they are written one by one so that they are never all in memory
+	var sort bson.D
+	for _, field := range level.Sort {
+		sort = append(sort, bson.E{Key: field, Value: 1})
+	}
+	cursor, err := collection.Find(ctx, andFilter(filter), options.Find().SetSort(sort).SetBatchSize(500))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	// Set the headers of the download, the file name may contain turkish characters
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName + "." + format}))
+	c.Status(http.StatusOK)
+
+	// Write the rows
+	writer := newExportWriter(format, c.Writer)
+	if err := writer.header(columns); err != nil {
+		log.Println("error writing the export header: " + err.Error())
+		return
+	}
+	for cursor.Next(ctx) {
+		var document models.ExportDocument
+		if err := cursor.Decode(&document); err != nil {
+			log.Println("error decoding an exported document: " + err.Error())
+			return
+		}
+		if err := writer.row(columns, document); err != nil {
+			log.Println("error writing an export row: " + err.Error())
+			return
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		log.Println("error reading the exported documents: " + err.Error())
+	}
+	if err := writer.close(); err != nil {
+		log.Println("error closing the export: " + err.Error())
+	}
+}
+
+// Get the distinct candidates of the documents matching the filter, sorted by the group fields
+func distinctVotes(ctx context.Context, collection *mongo.Collection, filter []bson.M, field string, group bson.M, results interface{}) error {
+	sort := bson.D{}
+	for _, key := range []string{"name", "lastname", "firstname"} {
+		if _, ok := group[key]; ok {
+			sort = append(sort, bson.E{Key: "_id." + key, Value: 1})
+		}
+	}
+	result, err := collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": andFilter(filter)},
+		bson.M{"$unwind": "$" + field},
+		bson.M{"$group": bson.M{"_id": group}},
+		bson.M{"$sort": sort},
+	})
+	if err != nil {
+		return err
+	}
+	return result.All(ctx, results)
+}
+
+// Create the writer for the format
+func newExportWriter(format string, w io.Writer) exportWriter {
+	switch format {
+	case "xlsx":
+		return &xlsxExportWriter{w: w}
+	case "ndjson":
+		return &ndjsonExportWriter{w: w}
+	default:
+		return &csvExportWriter{w: w, writer: csv.NewWriter(w)}
+	}
+}
+
+// Writer for csv exports, starts with a byte order mark so that Excel reads turkish characters as UTF-8
+type csvExportWriter struct {
+	w      io.Writer
+	writer *csv.Writer
+}
+
+func (writer *csvExportWriter) header(columns []string) error {
+	if _, err := writer.w.Write([]byte("\xef\xbb\xbf")); err != nil {
+		return err
+	}
+	values := make([]string, len(columns))
+	for index, column := range columns {
+		values[index] = csvField(column)
+	}
+	return writer.writer.Write(values)
+}
+
+func (writer *csvExportWriter) row(columns []string, document models.ExportDocument) error {
+	values := make([]string, len(columns))
+	for index, column := range columns {
+		values[index] = csvField(fmt.Sprint(document.Value(column)))
+	}
+	return writer.writer.Write(values)
+}
+
+func (writer *csvExportWriter) close() error {
+	writer.writer.Flush()
+	return writer.writer.Error()
+}
+
+// Escape a csv field which a spreadsheet would run as a formula, e.g. a candidate column named =1+1
+func csvField(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Writer for newline delimited json exports, the keys keep the order of the columns
+type ndjsonExportWriter struct {
+	w io.Writer
+}
+
+func (writer *ndjsonExportWriter) header(columns []string) error {
+	return nil
+}
+
+func (writer *ndjsonExportWriter) row(columns []string, document models.ExportDocument) error {
+	line := []byte{'{'}
+	for index, column := range columns {
+		if index > 0 {
+			line = append(line, ',')
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(document.Value(column))
+		if err != nil {
+			return err
+		}
+		line = append(append(append(line, key...), ':'), value...)
+	}
+	line = append(line, '}', '\n')
+	_, err := writer.w.Write(line)
+	return err
+}
+
+func (writer *ndjsonExportWriter) close() error {
+	return nil
+}
+
+// Writer for xlsx exports, the stream writer keeps only a small part of the rows in memory
+type xlsxExportWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	rows   int
+}
+
+func (writer *xlsxExportWriter) header(columns []string) error {
+	writer.file = excelize.NewFile()
+	stream, err := writer.file.NewStreamWriter("Sheet1")
+	if err != nil {
+		return err
+	}
+	writer.stream = stream
+	values := make([]interface{}, len(columns))
+	for index, column := range columns {
+		values[index] = column
+	}
+	return writer.setRow(values)
+}
+
+func (writer *xlsxExportWriter) row(columns []string, document models.ExportDocument) error {
+	values := make([]interface{}, len(columns))
+	for index, column := range columns {
+		values[index] = document.Value(column)
+	}
+	return writer.setRow(values)
+}
+
+func (writer *xlsxExportWriter) setRow(values []interface{}) error {
+	writer.rows++
+	cell, err := excelize.CoordinatesToCellName(1, writer.rows)
+	if err != nil {
+		return err
+	}
+	return writer.stream.SetRow(cell, values)
+}
+
+func (writer *xlsxExportWriter) close() error {
+	defer writer.file.Close()
+	if err := writer.stream.Flush(); err != nil {
+		return err
+	}
+	return writer.file.Write(writer.w)
+}
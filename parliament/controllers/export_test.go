@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/yzaimoglu/election/parliament/models"
+)
+
+func TestCSVField(t *testing.T) {
+	tests := map[string]string{
+		"=1+1":                     "'=1+1",
+		"+90 312 000 00 00":        "'+90 312 000 00 00",
+		"-2+3":                     "'-2+3",
+		"@SUM(A1:A2)":              "'@SUM(A1:A2)",
+		"\t=1+1":                   "'\t=1+1",
+		"\r=1+1":                   "'\r=1+1",
+		"=HYPERLINK(\"http://x\")": "'=HYPERLINK(\"http://x\")",
+		"Çankaya":                  "Çankaya",
+		"1+1=2":                    "1+1=2",
+		"1200":                     "1200",
+		"":                         "",
+	}
+	for value, want := range tests {
+		if got := csvField(value); got != want {
+			t.Errorf("csvField(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestCSVExportEscapesFormulas(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := newExportWriter("csv", &buffer)
+	columns := []string{"city", "number", "sst", "sdc"}
+	if err := writer.header(columns); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.row(columns, models.ExportDocument{City: "ankara", Number: 1001, SST: "=cmd|' /C calc'!A0", SDC: "@SUM(1+1)"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file starts with the byte order mark for Excel and the cells are quoted by the csv writer where needed
+	want := "\xef\xbb\xbfcity,number,sst,sdc\nankara,1001,'=cmd|' /C calc'!A0,'@SUM(1+1)\n"
+	if got := buffer.String(); got != want {
+		t.Fatalf("got the export %q, want %q", got, want)
+	}
+}
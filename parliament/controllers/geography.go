@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/yzaimoglu/election/parliament/models"
+	"github.com/yzaimoglu/election/parliament/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// List options of the geography registry
+var geographyListOptions = listOptions{
+	sortFields:     []string{"code", "name", "readablename"},
+	exactFilters:   map[string]string{"parent": "parentcode", "type": "type", "constituency": "constituency"},
+	textSortFields: []string{"name", "readablename"},
+	searchFields:   []string{"name", "readablename"},
+}
+
+// Abort the request with the error of resolving the regions of the request
+func abortGeographyError(c *gin.Context, err error) {
+	if errors.Is(err, models.ErrUnknownGeography) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+		"status":  http.StatusInternalServerError,
+		"message": "internal server error",
+	})
+}
+
+// Check if the level of the request is a level of the geography registry
+func isGeographyLevel(level string) bool {
+	return level == models.GeographyProvince || level == models.GeographyDistrict || level == models.GeographyQuarter
+}
+
+// Create a unit of the geography registry
+func CreateGeographyUnit(c *gin.Context) {
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Initialize the unit
+	var unit models.GeographyUnit
+
+	// Bind the input from the request body to the unit object
+	if err := c.ShouldBindJSON(&unit); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Create a new objectId for the unit
+	unit.Id = primitive.NewObjectID()
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(unit); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Check if the parent is registered
+	if unit.Level != models.GeographyProvince {
+		parentLevel := models.GeographyProvince
+		if unit.Level == models.GeographyQuarter {
+			parentLevel = models.GeographyDistrict
+		}
+		if unit.ParentCode == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  "parentcode must not be empty for districts and quarters",
+			})
+			return
+		}
+		if _, err := models.NewGeographyResolver(ctx).Resolve(parentLevel, unit.ParentCode, "", ""); err != nil {
+			abortGeographyError(c, err)
+			return
+		}
+	}
+
+	// Insert the unit
+	if _, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("geography").InsertOne(ctx, unit); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"status": http.StatusConflict,
+				"error":  "a unit with this level and code already exists",
+			})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Return the recently created unit
+	c.JSON(http.StatusOK, unit)
+}
+
+// Get all units of a level of the geography registry
+//
+//	GET /v1/geography/district/?parent=6&sort=name
+func GetGeographyUnits(c *gin.Context) {
+	level := c.Param("level")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Check the level
+	if !isGeographyLevel(level) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  "unknown level, must be one of province, district or quarter",
+		})
+		return
+	}
+
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, geographyListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Get the units
+	collection := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("geography")
+	page, err := findPage[models.GeographyUnit](ctx, collection, []bson.M{{"level": level}}, query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Return the units
+	c.JSON(http.StatusOK, page)
+}
+
+// Get a unit of the geography registry by its level and code
+func GetGeographyUnit(c *gin.Context) {
+	level := c.Param("level")
+	code := c.Param("code")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Initialize the unit
+	var unit models.GeographyUnit
+
+	// Get the unit
+	result := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("geography").FindOne(ctx, bson.M{"level": level, "code": code})
+
+	// Check if there is a unit with the level and code
+	if result.Err() == mongo.ErrNoDocuments {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status":  http.StatusNotFound,
+			"message": "no unit with this level and code found",
+		})
+		return
+	}
+
+	// Decode result to object
+	if err := result.Decode(&unit); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Return the unit
+	c.JSON(http.StatusOK, unit)
+}
+
+// Change a unit of the geography registry, the new names are applied to all regions and boxes referencing it
+func ChangeGeographyUnit(c *gin.Context) {
+	level := c.Param("level")
+	code := c.Param("code")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Initialize the unit
+	var unit models.GeographyUnit
+	var oldUnit models.GeographyUnit
+
+	// Bind the input from the request body to the unit object, level and code cannot be changed
+	if err := c.ShouldBindJSON(&unit); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+	unit.Level = level
+	unit.Code = code
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(unit); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Get the unit
+	collection := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("geography")
+	if err := collection.FindOne(ctx, bson.M{"level": level, "code": code}).Decode(&oldUnit); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"status":  http.StatusNotFound,
+				"message": "no unit with this level and code found",
+			})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+	unit.Id = oldUnit.Id
+
+	// Replace the unit
+	if _, err := collection.ReplaceOne(ctx, bson.M{"_id": oldUnit.Id}, unit); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Apply the names to the regions and boxes referencing the unit
+	if err := models.RenameGeography(ctx, unit); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Return the changed unit
+	c.JSON(http.StatusOK, unit)
+}
+
+// Delete a unit of the geography registry, units which still have children cannot be deleted
+func DeleteGeographyUnit(c *gin.Context) {
+	level := c.Param("level")
+	code := c.Param("code")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+	collection := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("geography")
+
+	// Check if the unit has children
+	if level != models.GeographyQuarter {
+		childLevel := models.GeographyDistrict
+		if level == models.GeographyDistrict {
+			childLevel = models.GeographyQuarter
+		}
+		children, err := collection.CountDocuments(ctx, bson.M{"level": childLevel, "parentcode": code})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+			return
+		}
+		if children > 0 {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"status": http.StatusConflict,
+				"error":  "the unit still has children",
+			})
+			return
+		}
+	}
+
+	// Delete the unit
+	result, err := collection.DeleteOne(ctx, bson.M{"level": level, "code": code})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Return the deleted count
+	c.JSON(http.StatusOK, gin.H{
+		"deletedCount": result.DeletedCount,
+	})
+}
+
+// Load the geography registry from an uploaded csv or xlsx file
+func SeedGeography(c *gin.Context) {
+	// Get the uploaded file
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the csv or xlsx file must be uploaded as the form field file",
+		})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+	defer file.Close()
+
+	// Read the rows of the file
+	rows, err := utilities.ReadSpreadsheet(file, fileHeader.Filename)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Load the units
+	report, err := models.SeedGeography(c.Request.Context(), rows)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+			"report": report,
+		})
+		return
+	}
+
+	// Return the report
+	c.JSON(http.StatusOK, report)
+}
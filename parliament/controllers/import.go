@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/parliament/models"
+	"github.com/yzaimoglu/election/parliament/utilities"
+)
+
+// Import boxes, regions or vote counts from an uploaded csv or xlsx file
+func ImportFile(c *gin.Context) {
+	kind := c.Param("kind")
+	atomic := c.Query("atomic") == "true"
+
+	// Get the uploaded file
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the csv or xlsx file must be uploaded as the form field file",
+		})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+	defer file.Close()
+
+	// Read the rows of the file
+	rows, err := utilities.ReadSpreadsheet(file, fileHeader.Filename)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Import the rows
+	report, err := models.Import(c.Request.Context(), kind, rows, atomic)
+	if err == models.ErrUnknownImportKind {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+			"report": report,
+		})
+		return
+	}
+
+	// Return the report, nothing has been imported if an atomic import has errors
+	if atomic && len(report.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Parse the list query of a request with the query string
+func parseTestListQuery(t *testing.T, rawQuery string, listOptions listOptions) (listQuery, error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?"+rawQuery, nil)
+	return parseListQuery(c, listOptions)
+}
+
+func TestParseListQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		options    listOptions
+		limit      int64
+		sortField  string
+		descending bool
+		collation  bool
+		fields     []string
+		filters    int
+		err        string
+	}{
+		{name: "defaults", options: cityListOptions, limit: defaultPageLimit, sortField: "number"},
+		{name: "limit and descending sort", query: "limit=10&sort=-eligiblevoters", options: cityListOptions, limit: 10, sortField: "eligiblevoters", descending: true},
+		{name: "text sort with the turkish collation", query: "sort=name", options: regionListOptions, limit: defaultPageLimit, sortField: "name", collation: true},
+		{name: "projection", query: "fields=name,number", options: cityListOptions, limit: defaultPageLimit, sortField: "number", fields: []string{"name", "number"}},
+		{name: "filters", query: "status=reported&minTurnout=80&maxTurnout=95.5&search=cankaya", options: regionListOptions, limit: defaultPageLimit, sortField: "citynumber", filters: 4},
+		{name: "exact filters of the boxes", query: "quarter=kizilay&constituency=ankara-1-bolge", options: boxListOptions, limit: defaultPageLimit, sortField: "number", filters: 2},
+		{name: "search without search fields", query: "search=cankaya", options: boxListOptions, limit: defaultPageLimit, sortField: "number"},
+		{name: "limit too small", query: "limit=0", options: cityListOptions, err: "limit must be between 1 and 1000"},
+		{name: "limit too large", query: "limit=1001", options: cityListOptions, err: "limit must be between 1 and 1000"},
+		{name: "unknown sort field", query: "sort=-name", options: boxListOptions, err: "sort must be one of number, eligiblevoters, actualvoters, validvotes"},
+		{name: "field with an operator", query: "fields=name,$where", options: cityListOptions, err: "invalid field $where"},
+		{name: "unknown status", query: "status=done", options: cityListOptions, err: "status must be reported or pending"},
+		{name: "turnout which is not a number", query: "minTurnout=high", options: cityListOptions, err: "minTurnout must be a number"},
+		{name: "cursor which is not base64", query: "cursor=not*base64", options: cityListOptions, err: "invalid cursor"},
+		{name: "cursor which is not bson", query: "cursor=AAAA", options: cityListOptions, err: "invalid cursor"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := parseTestListQuery(t, test.query, test.options)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("got the error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query.limit != test.limit || query.sortField != test.sortField || query.descending != test.descending {
+				t.Fatalf("got the limit %d and the sort %q descending %t", query.limit, query.sortField, query.descending)
+			}
+			if (query.collation != nil) != test.collation {
+				t.Fatalf("got the collation %v, want one %t", query.collation, test.collation)
+			}
+			if !reflect.DeepEqual(query.fields, test.fields) || len(query.filter) != test.filters {
+				t.Fatalf("got the fields %v and the filters %v", query.fields, query.filter)
+			}
+		})
+	}
+}
+
+func TestListCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	tests := []struct {
+		sortField string
+		value     interface{}
+	}{
+		{"number", int64(6)},
+		{"name", "çankaya"},
+		{"turnout", 87.25},
+	}
+	for _, test := range tests {
+		t.Run(test.sortField, func(t *testing.T) {
+			last, err := bson.Marshal(bson.D{{Key: "_id", Value: id}, {Key: test.sortField, Value: test.value}, {Key: "other", Value: "ignored"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cursor, err := encodeListCursor(last, test.sortField)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The cursor is passed to the next request in the url
+			query, err := parseTestListQuery(t, "cursor="+cursor, cityListOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query.cursor == nil || !query.cursor.Value.Equal(bson.Raw(last).Lookup(test.sortField)) || query.cursor.Id.ObjectID() != id {
+				t.Fatalf("got the cursor %+v, want the value %v and the id %s", query.cursor, test.value, id.Hex())
+			}
+		})
+	}
+}
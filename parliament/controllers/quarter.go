@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 
@@ -12,52 +11,36 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Get all quarters, important for the updater
 func GetQuarters(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
-	// Initialize the cities
-	var quarters []models.Quarter
-	quarterName := "quarters"
-
-	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(quarterName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &quarters); err == nil {
-			c.JSON(http.StatusOK, quarters)
-			return
-		}
-	}*/
-
-	// Sorting by number ascending
-	opts := options.Find().SetSort(bson.M{"citynumber": 1})
-
-	// Get quarters
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("quarters").Find(ctx, bson.M{}, opts)
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, regionListOptions)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Return that no quarter has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no quarters",
-		})
+	// Initialize the page of the cities
+	var page models.Page
+	cacheKey := models.CacheKey("quarters", "all", query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
 		return
 	}
 
-	// Decode all elements in the database into the quarters slice
-	if err = result.All(ctx, &quarters); err != nil {
+	// Get the page of cities
+	page, err = findPage[models.Quarter](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("quarters"), nil, query)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -66,69 +49,48 @@ func GetQuarters(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	quarterJSON, err := json.Marshal(quarters)
-	if err != nil {
-		log.Printf("error marshalling " + quarterName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(quarterName, quarterJSON); err != nil {
-		log.Printf("error setting " + quarterName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(quarterName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + quarterName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("quarters", "all")); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the quarters
-	c.JSON(http.StatusOK, quarters)
+	c.JSON(http.StatusOK, page)
 }
 
 // Get all quarters, important for the updater
 func GetQuartersOfDistrict(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	city := c.Param("city")
 	district := c.Param("district")
 	defer cancel()
-	defer client.Disconnect(ctx)
-
-	// Initialize the cities
-	var quarters []models.Quarter
-	quarterName := "quarters-" + city + district
 
-	// Check if the result has been cached if so return
-	/*redisResult, redisErr := models.RedisGet(quarterName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &quarters); err == nil {
-			c.JSON(http.StatusOK, quarters)
-			return
-		}
-	}*/
-
-	// Sorting by number ascending
-	opts := options.Find().SetSort(bson.M{"citynumber": 1})
-	var filter []bson.M
-	filter = append(filter, bson.M{"city": city})
-	filter = append(filter, bson.M{"district": district})
-
-	// Get quarters
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("quarters").Find(ctx, bson.M{"$and": filter}, opts)
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, regionListOptions)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"status":  http.StatusInternalServerError,
-			"message": "internal server error",
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
 		})
 		return
 	}
 
-	// Return that no quarter has been found
-	if !result.TryNext(ctx) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"status":  http.StatusNotFound,
-			"message": "there are no quarters",
-		})
+	// Initialize the page of the cities
+	var page models.Page
+	cacheKey := models.CacheKey("quarters", "district", city, district, query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
 		return
 	}
 
-	// Decode all elements in the database into the quarters slice
-	if err = result.All(ctx, &quarters); err != nil {
+	var filter []bson.M
+	filter = append(filter, bson.M{"city": city})
+	filter = append(filter, bson.M{"district": district})
+
+	// Get the page of cities
+	page, err = findPage[models.Quarter](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("quarters"), filter, query)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -137,26 +99,18 @@ func GetQuartersOfDistrict(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	quarterJSON, err := json.Marshal(quarters)
-	if err != nil {
-		log.Printf("error marshalling " + quarterName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(quarterName, quarterJSON); err != nil {
-		log.Printf("error setting " + quarterName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(quarterName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + quarterName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, page, models.ListTag("quarters", "district", city, district)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the quarters
-	c.JSON(http.StatusOK, quarters)
+	c.JSON(http.StatusOK, page)
 }
 
 // Create a quarter
 func CreateQuarter(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the quarter
 	var quarter models.Quarter
@@ -183,6 +137,12 @@ func CreateQuarter(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveQuarter(&quarter); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Insert quarter
 	if _, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("quarters").InsertOne(ctx, quarter); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
@@ -192,6 +152,11 @@ func CreateQuarter(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the quarter
+	if err := models.CacheInvalidate(models.QuarterCacheTags(quarter)...); err != nil {
+		log.Println("error invalidating the cache of the quarter: " + err.Error())
+	}
+
 	// Return the recently created quarter
 	c.JSON(http.StatusOK, quarter)
 }
@@ -199,20 +164,17 @@ func CreateQuarter(c *gin.Context) {
 // Get a quarter by its id
 func GetQuarterById(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the quarter
 	var quarter models.Quarter
+	cacheKey := models.CacheKey("quarter", "id", id)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet("quarterwithid-" + id)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &quarter); err == nil {
-			c.JSON(http.StatusOK, quarter)
-			return
-		}
+	if models.CacheGet(cacheKey, &quarter) {
+		c.JSON(http.StatusOK, quarter)
+		return
 	}
 
 	// ObjectID from id
@@ -248,15 +210,8 @@ func GetQuarterById(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	quarterJSON, err := json.Marshal(quarter)
-	if err != nil {
-		log.Printf("error marshalling quarterwithid-" + id + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet("quarterwithid-"+id, quarterJSON); err != nil {
-		log.Printf("error setting quarterwithid-" + id + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL("quarterwithid-"+id, 60*5); err != nil {
-		log.Printf("error setting ttl for the quarterwithid-" + id + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, quarter, models.DocumentTag("quarters", quarter.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the quarter
@@ -268,21 +223,17 @@ func GetQuarterByName(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	name := c.Param("quarter")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the quarter
 	var quarter models.Quarter
-	quarterName := "quarter-" + city + "-" + district + "-" + name
+	cacheKey := models.CacheKey("quarter", "name", city, district, name)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet(quarterName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &quarter); err == nil {
-			c.JSON(http.StatusOK, quarter)
-			return
-		}
+	if models.CacheGet(cacheKey, &quarter) {
+		c.JSON(http.StatusOK, quarter)
+		return
 	}
 
 	// Initialize $and input
@@ -314,15 +265,8 @@ func GetQuarterByName(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	quarterJSON, err := json.Marshal(quarter)
-	if err != nil {
-		log.Printf("error marshalling " + quarterName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(quarterName, quarterJSON); err != nil {
-		log.Printf("error setting " + quarterName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(quarterName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + quarterName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, quarter, models.DocumentTag("quarters", quarter.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the quarter
@@ -334,9 +278,8 @@ func ChangeQuarter(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	name := c.Param("quarter")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the quarter
 	var quarter models.Quarter
@@ -361,6 +304,12 @@ func ChangeQuarter(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveQuarter(&quarter); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Initialize $and input
 	var filter []bson.M
 	filter = append(filter, bson.M{"city": city})
@@ -399,6 +348,11 @@ func ChangeQuarter(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the old or the updated quarter
+	if err := models.CacheInvalidate(append(models.QuarterCacheTags(oldQuarter), models.QuarterCacheTags(quarter)...)...); err != nil {
+		log.Println("error invalidating the cache of the quarter: " + err.Error())
+	}
+
 	// Return the recently updated quarter
 	c.JSON(http.StatusOK, quarter)
 }
@@ -408,9 +362,8 @@ func DeleteQuarter(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	name := c.Param("quarter")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize $and input
 	var filter []bson.M
@@ -418,11 +371,20 @@ func DeleteQuarter(c *gin.Context) {
 	filter = append(filter, bson.M{"district": district})
 	filter = append(filter, bson.M{"name": name})
 
-	// Delete quarter
-	result, err := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("quarters").DeleteOne(ctx, bson.M{"$and": filter})
+	// Delete the quarter
+	result := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("quarters").FindOneAndDelete(ctx, bson.M{"$and": filter})
 
-	// Check if there is a quarter with the filter
-	if err != nil {
+	// Return that nothing has been deleted if there is no quarter with the filter
+	if result.Err() == mongo.ErrNoDocuments {
+		c.JSON(http.StatusOK, gin.H{
+			"deletedCount": 0,
+		})
+		return
+	}
+
+	// Decode the deleted quarter
+	var deletedQuarter models.Quarter
+	if err := result.Decode(&deletedQuarter); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -430,8 +392,13 @@ func DeleteQuarter(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the quarter
+	if err := models.CacheInvalidate(models.QuarterCacheTags(deletedQuarter)...); err != nil {
+		log.Println("error invalidating the cache of the quarter: " + err.Error())
+	}
+
 	// Return the deleted count
 	c.JSON(http.StatusOK, gin.H{
-		"deletedCount": result.DeletedCount,
+		"deletedCount": 1,
 	})
 }
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
@@ -16,23 +15,20 @@ import (
 // Get the results by city
 func GetResultsByCity(c *gin.Context) {
 	city := c.Param("city")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the result
 	var resultObj models.Result
 	var cityObj models.City
 	resultName := "results-" + city
 	resultObj.Location = resultName
+	cacheKey := models.CacheKey("results", "city", city)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet(resultName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &resultObj); err == nil {
-			c.JSON(http.StatusOK, resultObj)
-			return
-		}
+	if models.CacheGet(cacheKey, &resultObj) {
+		returnResult(c, resultObj)
+		return
 	}
 
 	// Get results
@@ -63,51 +59,42 @@ func GetResultsByCity(c *gin.Context) {
 	for _, candidate := range candidates {
 		percentage := float32(candidate.Votes) / float32(total) * 100
 		newCandidate := models.CandidateInResult{
-			FirstName:  candidate.FirstName,
-			LastName:   candidate.LastName,
-			Percentage: percentage,
+			CandidacyId: candidate.CandidacyId,
+			FirstName:   candidate.FirstName,
+			LastName:    candidate.LastName,
+			Percentage:  percentage,
 		}
 		candidatesOfResult = append(candidatesOfResult, newCandidate)
 	}
 	resultObj.Candidates = candidatesOfResult
 
 	// Set the result to the cache
-	resultJSON, err := json.Marshal(resultObj)
-	if err != nil {
-		log.Printf("error marshalling " + resultName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(resultName, resultJSON); err != nil {
-		log.Printf("error setting " + resultName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(resultName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + resultName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, resultObj, models.DocumentTag("cities", cityObj.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the quarter
-	c.JSON(http.StatusOK, resultObj)
+	returnResult(c, resultObj)
 }
 
 // Get the results by city
 func GetResultsByConstituency(c *gin.Context) {
 	city := c.Param("city")
 	constituency := c.Param("constituency")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the result
 	var resultObj models.Result
 	var constituencyObj models.Constituency
 	resultName := "results-" + city + "-" + constituency
 	resultObj.Location = resultName
+	cacheKey := models.CacheKey("results", "constituency", city, constituency)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet(resultName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &resultObj); err == nil {
-			c.JSON(http.StatusOK, resultObj)
-			return
-		}
+	if models.CacheGet(cacheKey, &resultObj) {
+		returnResult(c, resultObj)
+		return
 	}
 
 	// Initialize the $and filter
@@ -143,28 +130,22 @@ func GetResultsByConstituency(c *gin.Context) {
 	for _, candidate := range candidates {
 		percentage := float32(candidate.Votes) / float32(total) * 100
 		newCandidate := models.CandidateInResult{
-			FirstName:  candidate.FirstName,
-			LastName:   candidate.LastName,
-			Percentage: percentage,
+			CandidacyId: candidate.CandidacyId,
+			FirstName:   candidate.FirstName,
+			LastName:    candidate.LastName,
+			Percentage:  percentage,
 		}
 		candidatesOfResult = append(candidatesOfResult, newCandidate)
 	}
 	resultObj.Candidates = candidatesOfResult
 
 	// Set the result to the cache
-	resultJSON, err := json.Marshal(resultObj)
-	if err != nil {
-		log.Printf("error marshalling " + resultName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(resultName, resultJSON); err != nil {
-		log.Printf("error setting " + resultName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(resultName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + resultName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, resultObj, models.DocumentTag("constituencies", constituencyObj.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the quarter
-	c.JSON(http.StatusOK, resultObj)
+	returnResult(c, resultObj)
 }
 
 // Get the results by city
@@ -172,23 +153,20 @@ func GetResultsByDistrict(c *gin.Context) {
 	city := c.Param("city")
 	constituency := c.Param("constituency")
 	district := c.Param("district")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the result
 	var resultObj models.Result
 	var districtObj models.District
 	resultName := "results-" + city + "-" + constituency + "-" + district
 	resultObj.Location = resultName
+	cacheKey := models.CacheKey("results", "district", city, constituency, district)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet(resultName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &resultObj); err == nil {
-			c.JSON(http.StatusOK, resultObj)
-			return
-		}
+	if models.CacheGet(cacheKey, &resultObj) {
+		returnResult(c, resultObj)
+		return
 	}
 
 	// Initialize the $and filter
@@ -225,28 +203,22 @@ func GetResultsByDistrict(c *gin.Context) {
 	for _, candidate := range candidates {
 		percentage := float32(candidate.Votes) / float32(total) * 100
 		newCandidate := models.CandidateInResult{
-			FirstName:  candidate.FirstName,
-			LastName:   candidate.LastName,
-			Percentage: percentage,
+			CandidacyId: candidate.CandidacyId,
+			FirstName:   candidate.FirstName,
+			LastName:    candidate.LastName,
+			Percentage:  percentage,
 		}
 		candidatesOfResult = append(candidatesOfResult, newCandidate)
 	}
 	resultObj.Candidates = candidatesOfResult
 
 	// Set the result to the cache
-	resultJSON, err := json.Marshal(resultObj)
-	if err != nil {
-		log.Printf("error marshalling " + resultName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(resultName, resultJSON); err != nil {
-		log.Printf("error setting " + resultName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(resultName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + resultName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, resultObj, models.DocumentTag("districts", districtObj.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the quarter
-	c.JSON(http.StatusOK, resultObj)
+	returnResult(c, resultObj)
 }
 
 // Get the results by city
@@ -255,23 +227,20 @@ func GetResultsByQuarter(c *gin.Context) {
 	constituency := c.Param("constituency")
 	district := c.Param("district")
 	quarter := c.Param("quarter")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the result
 	var resultObj models.Result
 	var quarterObj models.Quarter
 	resultName := "results-" + city + "-" + constituency + "-" + district + "-" + quarter
 	resultObj.Location = resultName
+	cacheKey := models.CacheKey("results", "quarter", city, constituency, district, quarter)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet(resultName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &resultObj); err == nil {
-			c.JSON(http.StatusOK, resultObj)
-			return
-		}
+	if models.CacheGet(cacheKey, &resultObj) {
+		returnResult(c, resultObj)
+		return
 	}
 
 	// Initialize the $and filter
@@ -309,28 +278,22 @@ func GetResultsByQuarter(c *gin.Context) {
 	for _, candidate := range candidates {
 		percentage := float32(candidate.Votes) / float32(total) * 100
 		newCandidate := models.CandidateInResult{
-			FirstName:  candidate.FirstName,
-			LastName:   candidate.LastName,
-			Percentage: percentage,
+			CandidacyId: candidate.CandidacyId,
+			FirstName:   candidate.FirstName,
+			LastName:    candidate.LastName,
+			Percentage:  percentage,
 		}
 		candidatesOfResult = append(candidatesOfResult, newCandidate)
 	}
 	resultObj.Candidates = candidatesOfResult
 
 	// Set the result to the cache
-	resultJSON, err := json.Marshal(resultObj)
-	if err != nil {
-		log.Printf("error marshalling " + resultName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(resultName, resultJSON); err != nil {
-		log.Printf("error setting " + resultName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(resultName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + resultName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, resultObj, models.DocumentTag("quarters", quarterObj.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the quarter
-	c.JSON(http.StatusOK, resultObj)
+	returnResult(c, resultObj)
 }
 
 // Get the results by city
@@ -340,23 +303,20 @@ func GetResultsByBox(c *gin.Context) {
 	district := c.Param("district")
 	quarter := c.Param("quarter")
 	box := c.Param("box")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the result
 	var resultObj models.Result
 	var boxObj models.Box
 	resultName := "results-" + city + "-" + constituency + "-" + district + "-" + quarter + "-" + box
 	resultObj.Location = resultName
+	cacheKey := models.CacheKey("results", "box", city, constituency, district, quarter, box)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet(resultName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &resultObj); err == nil {
-			c.JSON(http.StatusOK, resultObj)
-			return
-		}
+	if models.CacheGet(cacheKey, &resultObj) {
+		returnResult(c, resultObj)
+		return
 	}
 
 	// Parse the box into a boxnumber
@@ -405,26 +365,38 @@ func GetResultsByBox(c *gin.Context) {
 	for _, candidate := range candidates {
 		percentage := float32(candidate.Votes) / float32(total) * 100
 		newCandidate := models.CandidateInResult{
-			FirstName:  candidate.FirstName,
-			LastName:   candidate.LastName,
-			Percentage: percentage,
+			CandidacyId: candidate.CandidacyId,
+			FirstName:   candidate.FirstName,
+			LastName:    candidate.LastName,
+			Percentage:  percentage,
 		}
 		candidatesOfResult = append(candidatesOfResult, newCandidate)
 	}
 	resultObj.Candidates = candidatesOfResult
 
 	// Set the result to the cache
-	resultJSON, err := json.Marshal(resultObj)
-	if err != nil {
-		log.Printf("error marshalling " + resultName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(resultName, resultJSON); err != nil {
-		log.Printf("error setting " + resultName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(resultName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + resultName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, resultObj, models.DocumentTag("boxes", boxObj.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the quarter
+	returnResult(c, resultObj)
+}
+
+// Return the result, ?embed=candidate embeds the profiles of the candidates from the info service
+//
+// The result is returned without the profiles if the info service is unreachable.
+func returnResult(c *gin.Context, resultObj models.Result) {
+	if c.Query("embed") == "candidate" {
+		candidacies, err := models.GetInfoCandidacies()
+		if err != nil {
+			log.Println("error getting the candidacies from the info service: " + err.Error())
+		}
+		for index, candidate := range resultObj.Candidates {
+			if profile, ok := candidacies.Profile(candidate.CandidacyId, candidate.FirstName+" "+candidate.LastName); ok {
+				resultObj.Candidates[index].Candidate = &profile
+			}
+		}
+	}
 	c.JSON(http.StatusOK, resultObj)
 }
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/parliament/models"
+	"github.com/yzaimoglu/election/parliament/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Limits of a search
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// documents of a collection which are scored for a search
+	searchCandidateLimit = 500
+)
+
+// Types of the results of a search and the collections of the regions
+var (
+	searchTypes       = []string{models.SearchParty, models.SearchIndividual, models.SearchCity, models.SearchDistrict, models.SearchQuarter, models.SearchBox}
+	searchCollections = map[string]string{models.SearchCity: "cities", models.SearchDistrict: "districts", models.SearchQuarter: "quarters"}
+)
+
+// Search the parties, individuals, cities, districts, quarters and box numbers, the results are ranked by relevance
+//
+// Prefixes, words and small typos match, the case and the turkish letters are ignored. The parties and
+// individuals are searched by the info service, they are left out if it is unreachable.
+//
+//	GET /v1/search/?q=cankaya&types=district,quarter&limit=10
+func Search(c *gin.Context) {
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Parse the query, the types and the limit
+	query, types, limit, err := parseSearchQuery(c, searchTypes)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+	database := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili"))
+	results := []models.SearchResult{}
+
+	// Search the cities, districts and quarters by their names
+	for _, searchType := range []string{models.SearchCity, models.SearchDistrict, models.SearchQuarter} {
+		if !containsString(types, searchType) {
+			continue
+		}
+		regions, err := searchRegions(ctx, database.Collection(searchCollections[searchType]), searchType, query)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+			return
+		}
+		results = append(results, regions...)
+	}
+
+	// Search the boxes by the beginning of their number
+	if containsString(types, models.SearchBox) && isNumber(query) {
+		boxes, err := searchBoxes(ctx, database.Collection("boxes"), query, limit)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+			return
+		}
+		results = append(results, boxes...)
+	}
+
+	// Search the parties and individuals with the info service
+	var infoTypes []string
+	for _, searchType := range []string{models.SearchParty, models.SearchIndividual} {
+		if containsString(types, searchType) {
+			infoTypes = append(infoTypes, searchType)
+		}
+	}
+	if len(infoTypes) > 0 {
+		info, err := models.SearchInfo(query, infoTypes, limit)
+		if err != nil {
+			log.Println("error searching the info service: " + err.Error())
+		}
+		results = append(results, info...)
+	}
+
+	// Rank the results and return the best ones
+	models.SortSearchResults(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"query": query,
+		"data":  results,
+	})
+}
+
+// Parse the q, types and limit parameters of a search
+func parseSearchQuery(c *gin.Context, allTypes []string) (string, []string, int, error) {
+	query := strings.TrimSpace(c.Query("q"))
+	if utilities.Fold(query) == "" {
+		return query, nil, 0, errors.New("q must not be empty")
+	}
+
+	// Types of the results, all types by default
+	types := allTypes
+	if typesQuery := c.Query("types"); typesQuery != "" {
+		types = strings.Split(typesQuery, ",")
+		for _, searchType := range types {
+			if !containsString(allTypes, searchType) {
+				return query, nil, 0, errors.New("types must be a list of " + strings.Join(allTypes, ", "))
+			}
+		}
+	}
+
+	// Number of results
+	limit := defaultSearchLimit
+	if limitQuery := c.Query("limit"); limitQuery != "" {
+		var err error
+		limit, err = strconv.Atoi(limitQuery)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return query, nil, 0, errors.New("limit must be between 1 and " + strconv.Itoa(maxSearchLimit))
+		}
+	}
+	return query, types, limit, nil
+}
+
+// Search the regions of a collection by their name and readable name
+func searchRegions(ctx context.Context, collection *mongo.Collection, searchType string, query string) ([]models.SearchResult, error) {
+	// Find the regions with a word starting like the query, they are scored afterwards
+	regions, err := findSearchCandidates[models.SearchRegion](ctx, collection, query, "name", "readablename")
+	if err != nil {
+		return nil, err
+	}
+
+	// Score the regions
+	var results []models.SearchResult
+	for _, region := range regions {
+		searchResult := models.SearchResult{Type: searchType, Id: region.Id.Hex(), Name: region.ReadableName, City: region.City, District: region.District}
+		if searchResult.Name == "" {
+			searchResult.Name = region.Name
+		}
+		if searchType == models.SearchCity {
+			searchResult.City = region.Name
+		}
+		if searchResult.MatchAny(query, region.ReadableName, region.Name) {
+			results = append(results, searchResult)
+		}
+	}
+	return results, nil
+}
+
+// Find the documents with a word in one of the fields starting like the query, they are scored afterwards
+//
+// The documents matching the query exactly or by their beginning are found first, so that they are not
+// left out by the limit of the candidates if many documents only contain the query.
+func findSearchCandidates[T any](ctx context.Context, collection *mongo.Collection, query string, fields ...string) ([]T, error) {
+	var documents []T
+	found := bson.A{}
+	for _, pattern := range utilities.SearchCandidatePatterns(query) {
+		if len(found) >= searchCandidateLimit {
+			break
+		}
+
+		// Find the documents matching the pattern which have not been found yet
+		var filter bson.A
+		for _, field := range fields {
+			filter = append(filter, bson.M{field: primitive.Regex{Pattern: pattern}})
+		}
+		result, err := collection.Find(ctx, bson.M{"$or": filter, "_id": bson.M{"$nin": found}}, options.Find().SetLimit(int64(searchCandidateLimit-len(found))))
+		if err != nil {
+			return nil, err
+		}
+		for result.Next(ctx) {
+			var document T
+			if err := result.Decode(&document); err != nil {
+				result.Close(ctx)
+				return nil, err
+			}
+			documents = append(documents, document)
+			found = append(found, result.Current.Lookup("_id"))
+		}
+		err = result.Err()
+		result.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return documents, nil
+}
+
+// Search the boxes with a number starting with the query, the lowest numbers first
+func searchBoxes(ctx context.Context, collection *mongo.Collection, query string, limit int) ([]models.SearchResult, error) {
+	filter := bson.M{"$expr": bson.M{"$regexMatch": bson.M{"input": bson.M{"$toString": "$number"}, "regex": "^" + query}}}
+	result, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "number", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	var boxes []models.Box
+	if err := result.All(ctx, &boxes); err != nil {
+		return nil, err
+	}
+
+	// Score the boxes, an exact number is ranked before the longer numbers
+	var results []models.SearchResult
+	for _, box := range boxes {
+		number := strconv.FormatInt(box.Number, 10)
+		searchResult := models.SearchResult{Type: models.SearchBox, Id: box.Id.Hex(), Name: number, City: box.City, District: box.District, Quarter: box.Quarter, Number: box.Number}
+		if searchResult.MatchAny(query, number) {
+			results = append(results, searchResult)
+		}
+	}
+	return results, nil
+}
+
+// Check if a string only contains digits
+func isNumber(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
@@ -0,0 +1,557 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/yzaimoglu/election/parliament/models"
+	"github.com/yzaimoglu/election/parliament/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Limits of the nearby searches
+const (
+	defaultNearbyKilometers = 1
+	maxNearbyKilometers     = 50
+)
+
+// List options of the polling stations
+var stationListOptions = listOptions{
+	sortFields:     []string{"name"},
+	exactFilters:   map[string]string{"quarter": "quarter"},
+	textSortFields: []string{"name"},
+	searchFields:   []string{"name", "address"},
+}
+
+// Create a polling station
+func CreatePollingStation(c *gin.Context) {
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Initialize the station
+	var station models.PollingStation
+
+	// Bind the input from the request body to the station object
+	if err := c.ShouldBindJSON(&station); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Create a new objectId for the station
+	station.Id = primitive.NewObjectID()
+
+	// Validate the input
+	if !validatePollingStation(c, &station) {
+		return
+	}
+
+	// Check if the boxes exist and are not placed in another station
+	database := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili"))
+	if !checkStationBoxes(c, ctx, database, station) {
+		return
+	}
+
+	// Insert the station
+	if _, err := database.Collection("stations").InsertOne(ctx, station); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"status": http.StatusConflict,
+				"error":  "a polling station with this name already exists in the quarter",
+			})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Invalidate all cached entries containing the station
+	if err := models.CacheInvalidate(models.PollingStationCacheTags(station)...); err != nil {
+		log.Println("error invalidating the cache of the polling station: " + err.Error())
+	}
+
+	// Return the recently created station
+	c.JSON(http.StatusOK, station)
+}
+
+// Get a polling station by its id
+func GetPollingStation(c *gin.Context) {
+	id := c.Param("id")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Initialize the station
+	var station models.PollingStation
+	cacheKey := models.CacheKey("station", "id", id)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &station) {
+		c.JSON(http.StatusOK, station)
+		return
+	}
+
+	// ObjectID from id
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "bad request id must be in hex",
+		})
+		return
+	}
+
+	// Find the station
+	result := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("stations").FindOne(ctx, bson.M{"_id": objId})
+
+	// Check if there is a station with the id
+	if result.Err() == mongo.ErrNoDocuments {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status":  http.StatusNotFound,
+			"message": "no polling station with this id found",
+		})
+		return
+	}
+
+	// Decode result to object
+	if err := result.Decode(&station); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Set the result to the cache
+	if err := models.CacheSet(cacheKey, station, models.DocumentTag("stations", station.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
+	}
+
+	// Return the station
+	c.JSON(http.StatusOK, station)
+}
+
+// Get all polling stations of a district, ?format=geojson returns a feature collection
+func GetPollingStationsByDistrict(c *gin.Context) {
+	city := c.Param("city")
+	district := c.Param("district")
+	getPollingStations(c, []bson.M{{"city": city}, {"district": district}}, []string{"district", city, district})
+}
+
+// Get all polling stations of a quarter, ?format=geojson returns a feature collection
+func GetPollingStationsByQuarter(c *gin.Context) {
+	city := c.Param("city")
+	district := c.Param("district")
+	quarter := c.Param("quarter")
+	getPollingStations(c, []bson.M{{"city": city}, {"district": district}, {"quarter": quarter}}, []string{"quarter", city, district, quarter})
+}
+
+// Return a page of the polling stations matching the filter as json or GeoJSON
+//
+// The scope of the list, e.g. quarter, ankara, cankaya, cukurambar, is used for its cache key and tag.
+func getPollingStations(c *gin.Context, filter []bson.M, scope []string) {
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, stationListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+	geoJSON := c.Query("format") == "geojson"
+	if geoJSON && len(query.fields) > 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "fields can not be used with the geojson format",
+		})
+		return
+	}
+
+	// Initialize the page of the stations
+	var page struct {
+		models.Page
+		Data []models.PollingStation `json:"data"`
+	}
+	cacheKey := models.CacheKey("stations", append(scope, query.key)...)
+
+	// Check if the result has been cached if so use the cached page, projected pages are not cached
+	if len(query.fields) > 0 || !models.CacheGet(cacheKey, &page) {
+		// Get the page of stations
+		found, err := findPage[models.PollingStation](ctx, client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("stations"), filter, query)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+			return
+		}
+
+		// Projected pages are returned as they are
+		if len(query.fields) > 0 {
+			c.JSON(http.StatusOK, found)
+			return
+		}
+		page.Page, page.Data = found, found.Data.([]models.PollingStation)
+
+		// Set the result to the cache
+		if err := models.CacheSet(cacheKey, page, models.ListTag("stations", scope...)); err != nil {
+			log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
+		}
+	}
+
+	// Return the stations as GeoJSON features
+	if geoJSON {
+		featureCollection := models.PollingStationFeatures(page.Data)
+		featureCollection.NextCursor = page.NextCursor
+		c.JSON(http.StatusOK, featureCollection)
+		return
+	}
+
+	// Return the stations
+	c.JSON(http.StatusOK, page)
+}
+
+// Change a polling station
+func ChangePollingStation(c *gin.Context) {
+	id := c.Param("id")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Initialize the station
+	var station models.PollingStation
+	var oldStation models.PollingStation
+
+	// Bind the input from the request body to the station object
+	if err := c.ShouldBindJSON(&station); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// ObjectID from id
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "bad request id must be in hex",
+		})
+		return
+	}
+	station.Id = objId
+
+	// Validate the input
+	if !validatePollingStation(c, &station) {
+		return
+	}
+
+	// Find the old station
+	database := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili"))
+	result := database.Collection("stations").FindOne(ctx, bson.M{"_id": objId})
+
+	// Check if there is a station with the id
+	if result.Err() == mongo.ErrNoDocuments {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status":  http.StatusNotFound,
+			"message": "no polling station with this id found",
+		})
+		return
+	}
+
+	// Decode result to object
+	if err := result.Decode(&oldStation); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Check if the boxes exist and are not placed in another station
+	if !checkStationBoxes(c, ctx, database, station) {
+		return
+	}
+
+	// Replace object
+	if _, err := database.Collection("stations").ReplaceOne(ctx, bson.M{"_id": objId}, station); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"status": http.StatusConflict,
+				"error":  "a polling station with this name already exists in the quarter",
+			})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Invalidate all cached entries containing the old or the updated station
+	if err := models.CacheInvalidate(append(models.PollingStationCacheTags(oldStation), models.PollingStationCacheTags(station)...)...); err != nil {
+		log.Println("error invalidating the cache of the polling station: " + err.Error())
+	}
+
+	// Return the recently updated station
+	c.JSON(http.StatusOK, station)
+}
+
+// Delete a polling station, the boxes of the station are kept
+func DeletePollingStation(c *gin.Context) {
+	id := c.Param("id")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// ObjectID from id
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "bad request id must be in hex",
+		})
+		return
+	}
+
+	// Delete the station
+	result := client.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("stations").FindOneAndDelete(ctx, bson.M{"_id": objId})
+
+	// Return that nothing has been deleted if there is no station with the id
+	if result.Err() == mongo.ErrNoDocuments {
+		c.JSON(http.StatusOK, gin.H{
+			"deletedCount": 0,
+		})
+		return
+	}
+
+	// Decode the deleted station
+	var deletedStation models.PollingStation
+	if err := result.Decode(&deletedStation); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Invalidate all cached entries containing the station
+	if err := models.CacheInvalidate(models.PollingStationCacheTags(deletedStation)...); err != nil {
+		log.Println("error invalidating the cache of the polling station: " + err.Error())
+	}
+
+	// Return the deleted count
+	c.JSON(http.StatusOK, gin.H{
+		"deletedCount": 1,
+	})
+}
+
+// Get the polling stations near a point, ?format=geojson returns a feature collection
+//
+//	GET /v1/nearby/stations/?lat=39.9051&lng=32.8113&km=2&limit=20
+func GetNearbyStations(c *gin.Context) {
+	// Parse the point, the distance and the limit
+	point, kilometers, limit, err := parseNearbyQuery(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Get the stations, the nearest first
+	stations, err := models.FindNearbyStations(c.Request.Context(), point, kilometers, limit)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Return the stations as GeoJSON features
+	if c.Query("format") == "geojson" {
+		c.JSON(http.StatusOK, models.PollingStationFeatures(stations))
+		return
+	}
+
+	// Return the stations
+	c.JSON(http.StatusOK, gin.H{
+		"data": stations,
+	})
+}
+
+// Get the boxes of the polling stations near a point, ?format=geojson returns a feature collection
+//
+//	GET /v1/nearby/boxes/?lat=39.9051&lng=32.8113&km=2&limit=100
+func GetNearbyBoxes(c *gin.Context) {
+	// Parse the point, the distance and the limit
+	point, kilometers, limit, err := parseNearbyQuery(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Get the boxes, the nearest first
+	boxes, err := models.FindNearbyBoxes(c.Request.Context(), point, kilometers, limit)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Return the boxes as GeoJSON features
+	if c.Query("format") == "geojson" {
+		c.JSON(http.StatusOK, models.NearbyBoxFeatures(boxes))
+		return
+	}
+
+	// Return the boxes
+	c.JSON(http.StatusOK, gin.H{
+		"data": boxes,
+	})
+}
+
+// Validate a polling station and resolve its regions, aborts the request if it is invalid
+func validatePollingStation(c *gin.Context, station *models.PollingStation) bool {
+	// The distance is only set by nearby searches
+	station.Distance = 0
+
+	// Validate the input
+	validator := validator.New()
+	if err := validator.Struct(*station); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return false
+	}
+	if !station.Location.Valid() {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "location must be a GeoJSON point with the longitude and the latitude",
+		})
+		return false
+	}
+
+	// Remove duplicate box numbers
+	boxes := []int64{}
+	for _, number := range station.Boxes {
+		if !containsInt64(boxes, number) {
+			boxes = append(boxes, number)
+		}
+	}
+	station.Boxes = boxes
+
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(c.Request.Context()).ResolveStation(station); err != nil {
+		abortGeographyError(c, err)
+		return false
+	}
+	return true
+}
+
+// Check if the boxes of the station exist in its district and are not placed in another station, aborts the request if not
+func checkStationBoxes(c *gin.Context, ctx context.Context, database *mongo.Database, station models.PollingStation) bool {
+	if len(station.Boxes) == 0 {
+		return true
+	}
+
+	// Count the existing boxes
+	count, err := database.Collection("boxes").CountDocuments(ctx, bson.M{"city": station.City, "district": station.District, "number": bson.M{"$in": station.Boxes}})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return false
+	}
+	if count != int64(len(station.Boxes)) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "some boxes of the polling station do not exist in its district",
+		})
+		return false
+	}
+
+	// Check if another station contains one of the boxes
+	var other models.PollingStation
+	err = database.Collection("stations").FindOne(ctx, bson.M{"city": station.City, "district": station.District, "boxes": bson.M{"$in": station.Boxes}, "_id": bson.M{"$ne": station.Id}}).Decode(&other)
+	if err == nil {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"status": http.StatusConflict,
+			"error":  "some boxes are already placed in the polling station " + other.Name,
+		})
+		return false
+	}
+	if err != mongo.ErrNoDocuments {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return false
+	}
+	return true
+}
+
+// Parse the point, the distance in kilometers and the limit of a nearby search
+func parseNearbyQuery(c *gin.Context) (models.GeoPoint, float64, int64, error) {
+	latitude, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+	longitude, lngErr := strconv.ParseFloat(c.Query("lng"), 64)
+	point := models.NewGeoPoint(longitude, latitude)
+	if latErr != nil || lngErr != nil || !point.Valid() {
+		return point, 0, 0, errors.New("lat and lng must be valid coordinates")
+	}
+
+	// Distance in kilometers
+	kilometers := float64(defaultNearbyKilometers)
+	if km := c.Query("km"); km != "" {
+		var err error
+		kilometers, err = strconv.ParseFloat(km, 64)
+		if err != nil || kilometers <= 0 || kilometers > maxNearbyKilometers {
+			return point, 0, 0, errors.New("km must be greater than 0 and at most " + strconv.Itoa(maxNearbyKilometers))
+		}
+	}
+
+	// Number of results
+	limit := int64(defaultPageLimit)
+	if limitQuery := c.Query("limit"); limitQuery != "" {
+		var err error
+		limit, err = strconv.ParseInt(limitQuery, 10, 64)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return point, 0, 0, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageLimit))
+		}
+	}
+	return point, kilometers, limit, nil
+}
+
+// Check if a slice contains a number
+func containsInt64(values []int64, value int64) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
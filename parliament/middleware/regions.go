@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/parliament/utilities"
+)
+
+// Route parameters which contain the name of a region
+var regionParams = map[string]bool{
+	"id":           true,
+	"city":         true,
+	"constituency": true,
+	"district":     true,
+	"quarter":      true,
+	"name":         true,
+}
+
+// NormalizeRegionParams replaces the region names in the route parameters by their slugs, the
+// regions are stored with slugs as names so that /cities/Çankaya/ and /cities/cankaya/ are equal
+func NormalizeRegionParams() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for index, param := range c.Params {
+			if regionParams[param.Key] {
+				c.Params[index].Value = utilities.Slug(param.Value)
+			}
+		}
+	}
+}
@@ -6,11 +6,14 @@ import "go.mongodb.org/mongo-driver/bson/primitive"
 type Box struct {
 	Id             primitive.ObjectID `json:"_id" bson:"_id"`
 	Number         int64              `json:"number" bson:"number"`             // 1001
-	City           string             `json:"city" bson:"city"`                 // Ankara
+	City           string             `json:"city" bson:"city"`                 // ankara
 	CityNumber     int64              `json:"citynumber" bson:"citynumber"`     // 6
-	Constituency   string             `json:"constituency" bson:"constituency"` // Ankara-1
-	District       string             `json:"district" bson:"district"`         // Çankaya
-	Quarter        string             `json:"quarter" bson:"quarter"`           // Çukurambar
+	CityCode       string             `json:"citycode" bson:"citycode"`         // 6 (geography registry)
+	Constituency   string             `json:"constituency" bson:"constituency"` // ankara-1
+	District       string             `json:"district" bson:"district"`         // cankaya
+	DistrictCode   string             `json:"districtcode" bson:"districtcode"` // 1231 (geography registry)
+	Quarter        string             `json:"quarter" bson:"quarter"`           // cukurambar
+	QuarterCode    string             `json:"quartercode" bson:"quartercode"`   // 40123 (geography registry)
 	Candidates     []CandidateInBox   `json:"candidates" bson:"candidates"`
 	EligibleVoters int64              `json:"eligiblevoters" bson:"eligiblevoters"` // 12621
 	ActualVoters   int64              `json:"actualvoters" bson:"actualvoters"`     // 10262
@@ -22,7 +25,19 @@ type Box struct {
 
 // Model for a Party in a Box
 type CandidateInBox struct {
-	FirstName string `json:"firstname" bson:"firstname"` // Recep Tayyip
-	LastName  string `json:"lastname" bson:"lastname"`   // Erdogan
-	Votes     int64  `json:"votes" bson:"votes"`         // 121
+	CandidacyId string `json:"candidacyid,omitempty" bson:"candidacyid,omitempty"` // 63f1c2... (candidacy of the info service)
+	FirstName   string `json:"firstname" bson:"firstname"`                         // Recep Tayyip
+	LastName    string `json:"lastname" bson:"lastname"`                           // Erdogan
+	Votes       int64  `json:"votes" bson:"votes"`                                 // 121
+}
+
+// Get the cache tags of all entries which contain the box
+func BoxCacheTags(box Box) []string {
+	return []string{
+		DocumentTag("boxes", box.Id.Hex()),
+		ListTag("boxes", "city", box.City),
+		ListTag("boxes", "constituency", box.City, box.Constituency),
+		ListTag("boxes", "district", box.City, box.District),
+		ListTag("boxes", "quarter", box.City, box.District, box.Quarter),
+	}
 }
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
@@ -112,3 +114,110 @@ func RedisExists(key string) (bool, error) {
 	}
 	return value, nil
 }
+
+// Time to live of the cached entries in seconds
+const CacheTTL = 60 * 5
+
+// Prefix of all cache keys of the service, so that the services and the login limits of auth can share a redis database
+const cachePrefix = "mv"
+
+// Build a cache key from its parts, e.g. CacheKey("boxes", "district", "ankara", "cankaya") is "mv:boxes:district:ankara:cankaya"
+func CacheKey(kind string, parts ...string) string {
+	return strings.Join(append([]string{cachePrefix, kind}, parts...), ":")
+}
+
+// Tag for all cached entries which contain a single document of a collection
+func DocumentTag(collection string, id string) string {
+	return CacheKey("tag", collection, id)
+}
+
+// Tag for all cached entries which list the documents of a collection in a scope, e.g. ListTag("boxes", "city", "ankara")
+func ListTag(collection string, scope ...string) string {
+	return CacheKey("tag", append([]string{collection, "list"}, scope...)...)
+}
+
+// Set a value as JSON in the cache and add the key to the given tags
+func CacheSet(key string, value interface{}, tags ...string) error {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	client := redisConnectionPool.Get()
+	defer client.Close()
+
+	// Set the value with the ttl
+	if _, err := client.Do("SET", key, valueJSON, "EX", CacheTTL); err != nil {
+		return err
+	}
+
+	// Remember the key in the tags so that it can be invalidated later on
+	for _, tag := range tags {
+		if _, err := client.Do("SADD", tag, key); err != nil {
+			return err
+		}
+		if _, err := client.Do("EXPIRE", tag, CacheTTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get a cached JSON value and decode it into the value, returns false if there is no such entry
+func CacheGet(key string, value interface{}) bool {
+	cached, err := RedisGet(key)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(cached, value) == nil
+}
+
+// Delete all cached entries of the service, used if a change affects entries of unknown keys
+//
+// The keys are found with SCAN in batches, the keys of other services in the same database are kept.
+func CacheFlush() error {
+	client := redisConnectionPool.Get()
+	defer client.Close()
+
+	cursor := 0
+	for {
+		values, err := redis.Values(client.Do("SCAN", cursor, "MATCH", cachePrefix+":*", "COUNT", 1000))
+		if err != nil {
+			return err
+		}
+		cursor, err = redis.Int(values[0], nil)
+		if err != nil {
+			return err
+		}
+		keys, err := redis.Strings(values[1], nil)
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if _, err := client.Do("DEL", redis.Args{}.AddFlat(keys)...); err != nil {
+				return err
+			}
+		}
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// Delete all cached entries of the given tags
+func CacheInvalidate(tags ...string) error {
+	client := redisConnectionPool.Get()
+	defer client.Close()
+
+	for _, tag := range tags {
+		keys, err := redis.Strings(client.Do("SMEMBERS", tag))
+		if err != nil {
+			return err
+		}
+		args := redis.Args{}.Add(tag).AddFlat(keys)
+		if _, err := client.Do("DEL", args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
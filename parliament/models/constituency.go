@@ -7,7 +7,8 @@ type Constituency struct {
 	Id             primitive.ObjectID `json:"_id" bson:"_id"`
 	Name           string             `json:"name" bson:"name" validate:"required"`                     // ankara-1
 	ReadableName   string             `json:"readablename" bson:"readablename"`                         // Ankara-01
-	City           string             `json:"city" bson:"city" validate:"required"`                     // Ankara
+	City           string             `json:"city" bson:"city" validate:"required"`                     // ankara
+	CityCode       string             `json:"citycode" bson:"citycode"`                                 // 6 (geography registry)
 	CityNumber     int64              `json:"citynumber" bson:"citynumber" validate:"required,numeric"` // 6
 	Candidates     []CandidateInBox   `json:"candidates" bson:"candidates"`
 	EligibleVoters int64              `json:"eligiblevoters" bson:"eligiblevoters"` // 12621
@@ -15,3 +16,12 @@ type Constituency struct {
 	ValidVotes     int64              `json:"validvotes" bson:"validvotes"`         // 10101
 	InvalidVotes   int64              `json:"invalidvotes" bson:"invalidvotes"`     // 161
 }
+
+// Get the cache tags of all entries which contain the constituency
+func ConstituencyCacheTags(constituency Constituency) []string {
+	return []string{
+		DocumentTag("constituencies", constituency.Id.Hex()),
+		ListTag("constituencies", "all"),
+		ListTag("constituencies", "city", constituency.City),
+	}
+}
@@ -0,0 +1,523 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yzaimoglu/election/parliament/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Timeout of an import
+const importTimeout = 5 * time.Minute
+
+// Error returned for unknown import kinds
+var ErrUnknownImportKind = errors.New("unknown import kind, must be one of boxes, cities, constituencies, districts or quarters")
+
+// Model for an error in a row of an imported file
+type ImportRowError struct {
+	Row     int    `json:"row"`     // 2 (the header is row 1)
+	Column  string `json:"column"`  // F
+	Field   string `json:"field"`   // number
+	Message string `json:"message"` // must be a number
+}
+
+// Model for the report of an import
+type ImportReport struct {
+	Kind     string           `json:"kind"`     // boxes
+	Atomic   bool             `json:"atomic"`   // true if nothing is imported when a row is invalid
+	Rows     int              `json:"rows"`     // 1200
+	Inserted int64            `json:"inserted"` // 1100
+	Updated  int64            `json:"updated"`  // 95
+	Skipped  int              `json:"skipped"`  // 5 (invalid rows which have not been imported)
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// Model for a row of an imported file
+type importRow struct {
+	line    int
+	columns []string
+	header  map[string]int
+	values  []string
+	errors  []ImportRowError
+}
+
+// Model for a validated document of an imported file
+type importDocument struct {
+	row      int
+	key      bson.M
+	fields   bson.M
+	defaults bson.M // fields which are only set on insert if they are not in the file
+	tags     func(id primitive.ObjectID) []string
+}
+
+// Definition of an import kind with its collection and the parser of its rows
+type importKind struct {
+	collection string
+	parse      func(row *importRow, resolver *GeographyResolver) importDocument
+}
+
+// All kinds which can be imported
+var importKinds = map[string]importKind{
+	"boxes":          {"boxes", parseBoxRow},
+	"cities":         {"cities", parseCityRow},
+	"constituencies": {"constituencies", parseConstituencyRow},
+	"districts":      {"districts", parseDistrictRow},
+	"quarters":       {"quarters", parseQuarterRow},
+}
+
+// Import the rows of a csv or xlsx file, the first row is the header
+//
+// Existing documents are updated so that an import can be repeated, boxes are identified by
+// (city, district, number) and regions by their name and parents. Optional columns which are not in
+// the file are left as they are and the vote counts of regions are left to the updater. Invalid rows
+// are skipped unless the import is atomic, then nothing is imported if a row is invalid and all
+// writes happen in one transaction (requires a replica set).
+func Import(ctx context.Context, kind string, rows [][]string, atomic bool) (ImportReport, error) {
+	report := ImportReport{
+		Kind:   kind,
+		Atomic: atomic,
+		Errors: []ImportRowError{},
+	}
+
+	// Get the import kind
+	definition, ok := importKinds[kind]
+	if !ok {
+		return report, ErrUnknownImportKind
+	}
+	if len(rows) == 0 {
+		report.Errors = append(report.Errors, ImportRowError{Row: 1, Message: "the file is empty"})
+		return report, nil
+	}
+
+	// Parse and validate all rows, the regions are resolved with the geography registry
+	documents := parseImport(definition, rows, NewGeographyResolver(ctx), &report)
+
+	// Import nothing in the atomic mode if there are errors
+	if atomic && len(report.Errors) > 0 {
+		report.Skipped = report.Rows
+		return report, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, importTimeout)
+	defer cancel()
+	collection := mongoClient.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection(definition.collection)
+
+	// Write the documents, in one transaction in the atomic mode
+	var tags []string
+	var err error
+	if atomic {
+		var session mongo.Session
+		session, err = mongoClient.StartSession()
+		if err != nil {
+			return report, err
+		}
+		defer session.EndSession(ctx)
+		_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+			report.Inserted, report.Updated = 0, 0
+			tags, err = writeImport(sessionContext, collection, documents, &report)
+			return nil, err
+		})
+	} else {
+		tags, err = writeImport(ctx, collection, documents, &report)
+	}
+
+	// Invalidate all cached entries containing the imported documents
+	if len(tags) > 0 && (err == nil || !atomic) {
+		if err := CacheInvalidate(tags...); err != nil {
+			log.Println("error invalidating the cache of the imported " + kind + ": " + err.Error())
+		}
+	}
+	return report, err
+}
+
+// Parse and validate the rows of a file, the invalid rows are added to the errors of the report
+func parseImport(definition importKind, rows [][]string, resolver *GeographyResolver, report *ImportReport) []importDocument {
+	// Read the header
+	header := map[string]int{}
+	for index, column := range rows[0] {
+		header[strings.ToLower(strings.TrimSpace(column))] = index
+	}
+
+	var documents []importDocument
+	keys := map[string]int{}
+	for index, values := range rows[1:] {
+		row := &importRow{line: index + 2, columns: rows[0], header: header, values: values}
+		if row.empty() {
+			continue
+		}
+		report.Rows++
+		document := definition.parse(row, resolver)
+
+		// Check for rows with the same key
+		key := fmt.Sprint(document.key)
+		if first, ok := keys[key]; ok && len(row.errors) == 0 {
+			row.fail("", "duplicate of row "+strconv.Itoa(first))
+		}
+		keys[key] = row.line
+
+		if len(row.errors) > 0 {
+			report.Errors = append(report.Errors, row.errors...)
+			report.Skipped++
+			continue
+		}
+		documents = append(documents, document)
+	}
+	return documents
+}
+
+// Insert or update the documents and return the cache tags of all written documents
+func writeImport(ctx context.Context, collection *mongo.Collection, documents []importDocument, report *ImportReport) ([]string, error) {
+	var tags []string
+	for _, document := range documents {
+		// Update the document with the key or insert it with a new id
+		id := primitive.NewObjectID()
+		opts := options.FindOneAndUpdate().SetUpsert(true).SetProjection(bson.M{"_id": 1})
+		var existing struct {
+			Id primitive.ObjectID `bson:"_id"`
+		}
+		onInsert := bson.M{"_id": id}
+		for field, value := range document.defaults {
+			onInsert[field] = value
+		}
+		err := collection.FindOneAndUpdate(ctx, document.key, bson.M{
+			"$set":         document.fields,
+			"$setOnInsert": onInsert,
+		}, opts).Decode(&existing)
+		switch err {
+		case mongo.ErrNoDocuments:
+			report.Inserted++
+		case nil:
+			id = existing.Id
+			report.Updated++
+		default:
+			return tags, fmt.Errorf("row %d: %w", document.row, err)
+		}
+		tags = append(tags, document.tags(id)...)
+	}
+	return tags, nil
+}
+
+// Add the error of resolving the regions of the row to the errors of the row
+func (row *importRow) resolve(err error) {
+	if err != nil {
+		row.fail("", err.Error())
+	}
+}
+
+// Check if all values of the row are empty
+func (row *importRow) empty() bool {
+	for _, value := range row.values {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Add an error for a field of the row
+func (row *importRow) fail(field string, message string) {
+	column := ""
+	if index, ok := row.header[field]; ok {
+		column = columnName(index)
+	}
+	row.errors = append(row.errors, ImportRowError{
+		Row:     row.line,
+		Column:  column,
+		Field:   field,
+		Message: message,
+	})
+}
+
+// Get the trimmed text of a field, adds an error if a required field is missing
+func (row *importRow) text(field string, required bool) string {
+	index, ok := row.header[field]
+	if !ok {
+		if required {
+			row.fail(field, "column is missing")
+		}
+		return ""
+	}
+	value := ""
+	if index < len(row.values) {
+		value = strings.TrimSpace(row.values[index])
+	}
+	if value == "" && required {
+		row.fail(field, "must not be empty")
+	}
+	return value
+}
+
+// Check if the file has a column for the field
+func (row *importRow) has(field string) bool {
+	_, ok := row.header[field]
+	return ok
+}
+
+// Add an optional field to the updated fields if the file has its column or the value has been resolved,
+// so that the stored value is not overwritten by an import without the column
+func (row *importRow) optional(fields bson.M, field string, value interface{}) {
+	if row.has(field) || !reflect.ValueOf(value).IsZero() {
+		fields[field] = value
+	}
+}
+
+// Get a non negative number of a field, adds an error if the value is not a number
+func (row *importRow) number(field string, required bool) int64 {
+	value := row.text(field, required)
+	if value == "" {
+		return 0
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < 0 {
+		row.fail(field, "must be a non negative number")
+		return 0
+	}
+	return number
+}
+
+// Get the fields and the original names of the columns which start with the prefix, e.g. candidate:
+func (row *importRow) prefixed(prefix string) ([]string, []string) {
+	var fields, names []string
+	for _, column := range row.columns {
+		field := strings.ToLower(strings.TrimSpace(column))
+		if strings.HasPrefix(field, prefix) {
+			fields = append(fields, field)
+			names = append(names, strings.TrimSpace(column)[len(prefix):])
+		}
+	}
+	return fields, names
+}
+
+// Check the voter totals of a box or region
+func (row *importRow) checkVotes(eligibleVoters int64, actualVoters int64, validVotes int64, invalidVotes int64) {
+	if actualVoters > eligibleVoters {
+		row.fail("actualvoters", "must not be greater than eligiblevoters")
+	}
+	if validVotes+invalidVotes != actualVoters {
+		row.fail("validvotes", "validvotes and invalidvotes must add up to actualvoters")
+	}
+}
+
+// Get the name of a spreadsheet column, 0 is A
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// Parse a row of a box import
+//
+//	city, citynumber, constituency, district, quarter, number, eligiblevoters, actualvoters,
+//	validvotes, invalidvotes, sst, sdc, candidate:<firstname> <lastname>..., citycode, districtcode, quartercode,
+//	candidacy:<firstname> <lastname>... (optional candidacy ids of the info service)
+func parseBoxRow(row *importRow, resolver *GeographyResolver) importDocument {
+	box := Box{
+		City:           row.text("city", true),
+		CityNumber:     row.number("citynumber", false),
+		CityCode:       row.text("citycode", false),
+		Constituency:   row.text("constituency", true),
+		District:       row.text("district", true),
+		DistrictCode:   row.text("districtcode", false),
+		Quarter:        row.text("quarter", true),
+		QuarterCode:    row.text("quartercode", false),
+		Number:         row.number("number", true),
+		EligibleVoters: row.number("eligiblevoters", true),
+		ActualVoters:   row.number("actualvoters", true),
+		ValidVotes:     row.number("validvotes", true),
+		InvalidVotes:   row.number("invalidvotes", true),
+		SST:            row.text("sst", false),
+		SDC:            row.text("sdc", false),
+		Candidates:     []CandidateInBox{},
+	}
+
+	row.resolve(resolver.ResolveBox(&box))
+
+	// Read the votes of the candidates, the last word of the column is the lastname
+	var candidateVotes int64
+	fields, names := row.prefixed("candidate:")
+	for index, field := range fields {
+		name := strings.Fields(names[index])
+		if len(name) < 2 {
+			row.fail(field, "candidate columns must be named candidate:<firstname> <lastname>")
+			continue
+		}
+		votes := row.number(field, false)
+		candidateVotes += votes
+		box.Candidates = append(box.Candidates, CandidateInBox{
+			CandidacyId: row.text("candidacy:"+strings.TrimPrefix(field, "candidate:"), false),
+			FirstName:   strings.Join(name[:len(name)-1], " "),
+			LastName:    name[len(name)-1],
+			Votes:       votes,
+		})
+	}
+	row.checkVotes(box.EligibleVoters, box.ActualVoters, box.ValidVotes, box.InvalidVotes)
+	if len(box.Candidates) > 0 && candidateVotes != box.ValidVotes {
+		row.fail("validvotes", "the votes of the candidates must add up to validvotes")
+	}
+
+	// Only overwrite the optional columns which are in the file
+	set := bson.M{
+		"city":           box.City,
+		"constituency":   box.Constituency,
+		"district":       box.District,
+		"quarter":        box.Quarter,
+		"number":         box.Number,
+		"eligiblevoters": box.EligibleVoters,
+		"actualvoters":   box.ActualVoters,
+		"validvotes":     box.ValidVotes,
+		"invalidvotes":   box.InvalidVotes,
+	}
+	row.optional(set, "citynumber", box.CityNumber)
+	row.optional(set, "citycode", box.CityCode)
+	row.optional(set, "districtcode", box.DistrictCode)
+	row.optional(set, "quartercode", box.QuarterCode)
+	row.optional(set, "sst", box.SST)
+	row.optional(set, "sdc", box.SDC)
+	defaults := bson.M{}
+	if len(box.Candidates) > 0 {
+		set["candidates"] = box.Candidates
+	} else {
+		defaults["candidates"] = box.Candidates
+	}
+
+	return importDocument{
+		row:      row.line,
+		key:      bson.M{"city": box.City, "district": box.District, "number": box.Number},
+		fields:   set,
+		defaults: defaults,
+		tags: func(id primitive.ObjectID) []string {
+			box.Id = id
+			return BoxCacheTags(box)
+		},
+	}
+}
+
+// Parse a row of a city import
+//
+//	name, readablename, number, code
+func parseCityRow(row *importRow, resolver *GeographyResolver) importDocument {
+	city := City{
+		Code:         row.text("code", false),
+		Name:         row.text("name", true),
+		ReadableName: row.text("readablename", false),
+		Number:       row.number("number", true),
+	}
+	row.resolve(resolver.ResolveCity(&city))
+	// Only overwrite the optional columns which are in the file
+	fields := bson.M{"name": city.Name, "number": city.Number}
+	row.optional(fields, "readablename", city.ReadableName)
+	row.optional(fields, "code", city.Code)
+	return importDocument{
+		row:    row.line,
+		key:    bson.M{"name": city.Name},
+		fields: fields,
+		tags: func(id primitive.ObjectID) []string {
+			city.Id = id
+			return CityCacheTags(city)
+		},
+	}
+}
+
+// Parse a row of a constituency import
+//
+//	name, readablename, city, citynumber, citycode
+func parseConstituencyRow(row *importRow, resolver *GeographyResolver) importDocument {
+	constituency := Constituency{
+		Name:         row.text("name", true),
+		ReadableName: row.text("readablename", false),
+		City:         row.text("city", true),
+		CityNumber:   row.number("citynumber", true),
+		CityCode:     row.text("citycode", false),
+	}
+	row.resolve(resolver.ResolveConstituency(&constituency))
+	// Only overwrite the optional columns which are in the file
+	fields := bson.M{"name": constituency.Name, "city": constituency.City, "citynumber": constituency.CityNumber}
+	row.optional(fields, "readablename", constituency.ReadableName)
+	row.optional(fields, "citycode", constituency.CityCode)
+	return importDocument{
+		row:    row.line,
+		key:    bson.M{"city": constituency.City, "name": constituency.Name},
+		fields: fields,
+		tags: func(id primitive.ObjectID) []string {
+			constituency.Id = id
+			return ConstituencyCacheTags(constituency)
+		},
+	}
+}
+
+// Parse a row of a district import
+//
+//	name, readablename, city, citynumber, constituency, code, citycode
+func parseDistrictRow(row *importRow, resolver *GeographyResolver) importDocument {
+	district := District{
+		Code:         row.text("code", false),
+		CityCode:     row.text("citycode", false),
+		Name:         row.text("name", true),
+		ReadableName: row.text("readablename", false),
+		City:         row.text("city", true),
+		CityNumber:   row.number("citynumber", false),
+		Constituency: row.text("constituency", true),
+	}
+	row.resolve(resolver.ResolveDistrict(&district))
+	// Only overwrite the optional columns which are in the file
+	fields := bson.M{"name": district.Name, "city": district.City, "constituency": district.Constituency}
+	row.optional(fields, "readablename", district.ReadableName)
+	row.optional(fields, "citynumber", district.CityNumber)
+	row.optional(fields, "code", district.Code)
+	row.optional(fields, "citycode", district.CityCode)
+	return importDocument{
+		row:    row.line,
+		key:    bson.M{"city": district.City, "name": district.Name},
+		fields: fields,
+		tags: func(id primitive.ObjectID) []string {
+			district.Id = id
+			return DistrictCacheTags(district)
+		},
+	}
+}
+
+// Parse a row of a quarter import
+//
+//	name, readablename, city, citynumber, constituency, district, code, citycode, districtcode
+func parseQuarterRow(row *importRow, resolver *GeographyResolver) importDocument {
+	quarter := Quarter{
+		Code:         row.text("code", false),
+		CityCode:     row.text("citycode", false),
+		DistrictCode: row.text("districtcode", false),
+		Name:         row.text("name", true),
+		ReadableName: row.text("readablename", false),
+		City:         row.text("city", true),
+		CityNumber:   row.number("citynumber", false),
+		Constituency: row.text("constituency", true),
+		District:     row.text("district", true),
+	}
+	row.resolve(resolver.ResolveQuarter(&quarter))
+	// Only overwrite the optional columns which are in the file
+	fields := bson.M{"name": quarter.Name, "city": quarter.City, "constituency": quarter.Constituency, "district": quarter.District}
+	row.optional(fields, "readablename", quarter.ReadableName)
+	row.optional(fields, "citynumber", quarter.CityNumber)
+	row.optional(fields, "code", quarter.Code)
+	row.optional(fields, "citycode", quarter.CityCode)
+	row.optional(fields, "districtcode", quarter.DistrictCode)
+	return importDocument{
+		row:    row.line,
+		key:    bson.M{"city": quarter.City, "district": quarter.District, "name": quarter.Name},
+		fields: fields,
+		tags: func(id primitive.ObjectID) []string {
+			quarter.Id = id
+			return QuarterCacheTags(quarter)
+		},
+	}
+}
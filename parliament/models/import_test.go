@@ -0,0 +1,121 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Resolver of a registry which has not been seeded, so that the rows are validated without a database
+func newUnseededResolver() *GeographyResolver {
+	return &GeographyResolver{
+		units:  map[string]*GeographyUnit{},
+		seeded: map[string]bool{GeographyProvince: false, GeographyDistrict: false, GeographyQuarter: false},
+	}
+}
+
+// Header of the box imports of the tests, number is column F and validvotes is column I
+var testBoxHeader = []string{"City", "Constituency", "District", "Quarter", "SST", "Number", "EligibleVoters", "ActualVoters",
+	"ValidVotes", "InvalidVotes", "Candidate:Ayşe Nur Demir", "Candidate:Mehmet Kaya"}
+
+func TestParseImportReportsRowsAndColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		rows   [][]string
+		errors []ImportRowError
+	}{
+		{
+			name: "valid rows",
+			rows: [][]string{
+				{"ANKARA", "Ankara 1. Bölge", "Çankaya", "Kızılay", "", "1001", "300", "250", "240", "10", "140", "100"},
+				{"Ankara", "Ankara 1. Bölge", "Çankaya", "Kızılay", "", "1002", "300", "0", "0", "0", "0", "0"},
+			},
+		},
+		{
+			name: "number which is not a number",
+			rows: [][]string{{"Ankara", "Ankara 1. Bölge", "Çankaya", "Kızılay", "", "bir", "300", "250", "240", "10", "140", "100"}},
+			errors: []ImportRowError{
+				{Row: 2, Column: "F", Field: "number", Message: "must be a non negative number"},
+			},
+		},
+		{
+			name: "missing and negative values",
+			rows: [][]string{{"Ankara", "", "Çankaya", "Kızılay", "", "1001", "-300", "250", "240", "10", "140", "100"}},
+			errors: []ImportRowError{
+				{Row: 2, Column: "B", Field: "constituency", Message: "must not be empty"},
+				{Row: 2, Column: "G", Field: "eligiblevoters", Message: "must be a non negative number"},
+				{Row: 2, Column: "H", Field: "actualvoters", Message: "must not be greater than eligiblevoters"},
+			},
+		},
+		{
+			name: "voter totals which do not add up",
+			rows: [][]string{{"Ankara", "Ankara 1. Bölge", "Çankaya", "Kızılay", "", "1001", "300", "250", "240", "5", "140", "90"}},
+			errors: []ImportRowError{
+				{Row: 2, Column: "I", Field: "validvotes", Message: "validvotes and invalidvotes must add up to actualvoters"},
+				{Row: 2, Column: "I", Field: "validvotes", Message: "the votes of the candidates must add up to validvotes"},
+			},
+		},
+		{
+			name: "duplicate box after an empty row",
+			rows: [][]string{
+				{"Ankara", "Ankara 1. Bölge", "Çankaya", "Kızılay", "", "1001", "300", "0", "0", "0", "0", "0"},
+				{"", " ", ""},
+				{"ANKARA", "Ankara 1. Bölge", "ÇANKAYA", "Kızılay", "", "1001", "300", "0", "0", "0", "0", "0"},
+			},
+			errors: []ImportRowError{
+				{Row: 4, Message: "duplicate of row 2"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := ImportReport{Errors: []ImportRowError{}}
+			documents := parseImport(importKinds["boxes"], append([][]string{testBoxHeader}, test.rows...), newUnseededResolver(), &report)
+			if !reflect.DeepEqual(report.Errors, append([]ImportRowError{}, test.errors...)) {
+				t.Fatalf("got the errors %+v, want %+v", report.Errors, test.errors)
+			}
+			if report.Skipped+len(documents) != report.Rows {
+				t.Fatalf("got %d skipped rows and %d documents of %d rows", report.Skipped, len(documents), report.Rows)
+			}
+		})
+	}
+}
+
+func TestParseImportOnlySetsTheColumnsOfTheFile(t *testing.T) {
+	report := ImportReport{Errors: []ImportRowError{}}
+	rows := [][]string{
+		testBoxHeader,
+		{"ANKARA", "Ankara 1. Bölge", "ÇANKAYA", "Kızılay", "", "1001", "300", "250", "240", "10", "140", "100"},
+	}
+	documents := parseImport(importKinds["boxes"], rows, newUnseededResolver(), &report)
+	if len(documents) != 1 {
+		t.Fatalf("got %d documents with the errors %+v, want 1", len(documents), report.Errors)
+	}
+	document := documents[0]
+
+	// The names are stored as slugs and the box is identified by its city, district and number
+	if want := map[string]interface{}{"city": "ankara", "district": "cankaya", "number": int64(1001)}; !reflect.DeepEqual(map[string]interface{}(document.key), want) {
+		t.Fatalf("got the key %v, want %v", document.key, want)
+	}
+
+	// The empty sst column of the file is set, the codes which are not in the file are left as they are
+	if _, ok := document.fields["sst"]; !ok {
+		t.Fatal("the sst column of the file has not been set")
+	}
+	for _, field := range []string{"citycode", "districtcode", "quartercode", "sdc", "citynumber"} {
+		if _, ok := document.fields[field]; ok {
+			t.Fatalf("the field %s which is not in the file would be overwritten", field)
+		}
+	}
+	candidates, ok := document.fields["candidates"].([]CandidateInBox)
+	if !ok || len(candidates) != 2 || candidates[0].FirstName != "Ayşe Nur" || candidates[0].LastName != "Demir" || candidates[1].Votes != 100 {
+		t.Fatalf("got the candidates %+v", document.fields["candidates"])
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 5: "F", 25: "Z", 26: "AA", 51: "AZ", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(index); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", index, got, want)
+		}
+	}
+}
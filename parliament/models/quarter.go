@@ -5,15 +5,27 @@ import "go.mongodb.org/mongo-driver/bson/primitive"
 // Model for the quarter
 type Quarter struct {
 	Id             primitive.ObjectID `json:"_id" bson:"_id"`
+	Code           string             `json:"code" bson:"code"`                 // 40123 (geography registry)
 	Name           string             `json:"name" bson:"name"`                 // mahalle
 	ReadableName   string             `json:"readablename" bson:"readablename"` // Mahalle
-	City           string             `json:"city" bson:"city"`                 // Ankara
+	City           string             `json:"city" bson:"city"`                 // ankara
+	CityCode       string             `json:"citycode" bson:"citycode"`         // 6 (geography registry)
 	CityNumber     int64              `json:"citynumber" bson:"citynumber"`     // 6
 	Constituency   string             `json:"constituency" bson:"constituency"` // ankara-1
-	District       string             `json:"district" bson:"district"`         // Cankaya
+	DistrictCode   string             `json:"districtcode" bson:"districtcode"` // 1231 (geography registry)
+	District       string             `json:"district" bson:"district"`         // cankaya
 	Candidates     []CandidateInBox   `json:"candidates" bson:"candidates"`
 	EligibleVoters int64              `json:"eligiblevoters" bson:"eligiblevoters"` // 12621
 	ActualVoters   int64              `json:"actualvoters" bson:"actualvoters"`     // 10262
 	ValidVotes     int64              `json:"validvotes" bson:"validvotes"`         // 10101
 	InvalidVotes   int64              `json:"invalidvotes" bson:"invalidvotes"`     // 161
 }
+
+// Get the cache tags of all entries which contain the quarter
+func QuarterCacheTags(quarter Quarter) []string {
+	return []string{
+		DocumentTag("quarters", quarter.Id.Hex()),
+		ListTag("quarters", "all"),
+		ListTag("quarters", "district", quarter.City, quarter.District),
+	}
+}
@@ -8,7 +8,9 @@ type Result struct {
 
 // Model for the candidate in a result
 type CandidateInResult struct {
-	FirstName  string  `json:"firstname" bson:"firstname"`
-	LastName   string  `json:"lastname" bson:"lastname"`
-	Percentage float32 `json:"percentage" bson:"percentage"`
+	CandidacyId string            `json:"candidacyid,omitempty" bson:"candidacyid,omitempty"`
+	FirstName   string            `json:"firstname" bson:"firstname"`
+	LastName    string            `json:"lastname" bson:"lastname"`
+	Percentage  float32           `json:"percentage" bson:"percentage"`
+	Candidate   *CandidateProfile `json:"candidate,omitempty" bson:"-"` // embedded with ?embed=candidate
 }
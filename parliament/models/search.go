@@ -0,0 +1,64 @@
+package models
+
+import (
+	"sort"
+
+	"github.com/yzaimoglu/election/parliament/utilities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Types of the results of a search
+const (
+	SearchParty      = "party"
+	SearchIndividual = "individual"
+	SearchCity       = "city"
+	SearchDistrict   = "district"
+	SearchQuarter    = "quarter"
+	SearchBox        = "box"
+)
+
+// Model for a result of a search
+type SearchResult struct {
+	Type     string `json:"type"`               // district
+	Id       string `json:"_id"`                // 63f1c2...
+	Name     string `json:"name"`               // Çankaya
+	Match    string `json:"match"`              // Çankaya (the text which matched the query)
+	Score    int    `json:"score"`              // 100
+	Color    string `json:"color,omitempty"`    // #ed1c24 (parties and individuals)
+	City     string `json:"city,omitempty"`     // ankara (regions and boxes)
+	District string `json:"district,omitempty"` // cankaya (quarters and boxes)
+	Quarter  string `json:"quarter,omitempty"`  // cukurambar (boxes)
+	Number   int64  `json:"number,omitempty"`   // 1001 (boxes)
+}
+
+// Model for a city, district or quarter found by a search
+type SearchRegion struct {
+	Id           primitive.ObjectID `bson:"_id"`
+	Name         string             `bson:"name"`
+	ReadableName string             `bson:"readablename"`
+	City         string             `bson:"city"`
+	District     string             `bson:"district"`
+}
+
+// Score the result by the best matching text, returns false if none of the texts match the query
+func (result *SearchResult) MatchAny(query string, texts ...string) bool {
+	for _, text := range texts {
+		if score := utilities.MatchScore(query, text); score > result.Score {
+			result.Score, result.Match = score, text
+		}
+	}
+	return result.Score > 0
+}
+
+// Rank the results by their score, results with the same score are ordered by the length and the name
+func SortSearchResults(results []SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if len(results[i].Name) != len(results[j].Name) {
+			return len(results[i].Name) < len(results[j].Name)
+		}
+		return utilities.Compare(results[i].Name, results[j].Name) < 0
+	})
+}
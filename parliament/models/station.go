@@ -0,0 +1,219 @@
+package models
+
+import (
+	"context"
+
+	"github.com/yzaimoglu/election/parliament/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Model for a polling station, usually a school, which groups the ballot boxes placed in it
+type PollingStation struct {
+	Id           primitive.ObjectID `json:"_id" bson:"_id"`
+	Name         string             `json:"name" bson:"name" validate:"required"`         // Çukurambar İlkokulu
+	Address      string             `json:"address" bson:"address"`                       // Çukurambar Mah. 1443. Cad. No:5
+	City         string             `json:"city" bson:"city" validate:"required"`         // ankara
+	CityCode     string             `json:"citycode" bson:"citycode"`                     // 6 (geography registry)
+	Constituency string             `json:"constituency" bson:"constituency"`             // ankara-1
+	District     string             `json:"district" bson:"district" validate:"required"` // cankaya
+	DistrictCode string             `json:"districtcode" bson:"districtcode"`             // 1231 (geography registry)
+	Quarter      string             `json:"quarter" bson:"quarter" validate:"required"`   // cukurambar
+	QuarterCode  string             `json:"quartercode" bson:"quartercode"`               // 40123 (geography registry)
+	Location     GeoPoint           `json:"location" bson:"location"`                     // {"type": "Point", "coordinates": [32.8113, 39.9051]}
+	Boxes        []int64            `json:"boxes" bson:"boxes"`                           // [1001, 1002] (numbers of the boxes in the district)
+	Distance     float64            `json:"distance,omitempty" bson:"distance,omitempty"` // 1.24 (kilometers, only in nearby searches)
+}
+
+// Model for a GeoJSON point, the coordinates are the longitude and the latitude
+type GeoPoint struct {
+	Type        string    `json:"type" bson:"type"`               // Point
+	Coordinates []float64 `json:"coordinates" bson:"coordinates"` // [32.8113, 39.9051]
+}
+
+// Model for a box found near a point with the polling station it is placed in
+type NearbyBox struct {
+	Box       `bson:",inline"`
+	Station   string   `json:"station"`   // Çukurambar İlkokulu
+	StationId string   `json:"stationid"` // 63f1c2...
+	Location  GeoPoint `json:"location"`  // location of the polling station
+	Distance  float64  `json:"distance"`  // 1.24 (kilometers)
+}
+
+// Create a GeoJSON point from the longitude and the latitude
+func NewGeoPoint(longitude float64, latitude float64) GeoPoint {
+	return GeoPoint{
+		Type:        "Point",
+		Coordinates: []float64{longitude, latitude},
+	}
+}
+
+// Check if the point has valid coordinates
+func (point GeoPoint) Valid() bool {
+	return point.Type == "Point" &&
+		len(point.Coordinates) == 2 &&
+		point.Coordinates[0] >= -180 && point.Coordinates[0] <= 180 &&
+		point.Coordinates[1] >= -90 && point.Coordinates[1] <= 90
+}
+
+// Get the GeoJSON geometry of the point for a feature
+func (point GeoPoint) Geometry() bson.M {
+	return bson.M{"type": point.Type, "coordinates": point.Coordinates}
+}
+
+// Get the cache tags of all entries which contain the polling station
+func PollingStationCacheTags(station PollingStation) []string {
+	return []string{
+		DocumentTag("stations", station.Id.Hex()),
+		ListTag("stations", "district", station.City, station.District),
+		ListTag("stations", "quarter", station.City, station.District, station.Quarter),
+	}
+}
+
+// Resolve the geography references of a polling station, the names are stored as slugs and taken from the registry
+func (resolver *GeographyResolver) ResolveStation(station *PollingStation) error {
+	station.City, station.Constituency, station.District, station.Quarter = utilities.Slug(station.City), utilities.Slug(station.Constituency), utilities.Slug(station.District), utilities.Slug(station.Quarter)
+	province, district, quarter, err := resolver.resolveRegion(station.CityCode, station.City, station.DistrictCode, station.District, station.QuarterCode, station.Quarter)
+	if err != nil {
+		return err
+	}
+	if province != nil {
+		station.CityCode, station.City = province.Code, province.Name
+	}
+	if district != nil {
+		station.DistrictCode, station.District = district.Code, district.Name
+		if district.Constituency != "" {
+			station.Constituency = district.Constituency
+		}
+	}
+	if quarter != nil {
+		station.QuarterCode, station.Quarter = quarter.Code, quarter.Name
+	}
+	return nil
+}
+
+// Find the polling stations within the distance in kilometers of the point, the nearest first
+func FindNearbyStations(ctx context.Context, point GeoPoint, kilometers float64, limit int64) ([]PollingStation, error) {
+	stations := mongoClient.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("stations")
+
+	// $geoNear uses the 2dsphere index on the location and returns the distance in meters
+	pipeline := mongo.Pipeline{
+		{{Key: "$geoNear", Value: bson.M{
+			"near":               point,
+			"distanceField":      "distance",
+			"maxDistance":        kilometers * 1000,
+			"distanceMultiplier": 0.001,
+			"spherical":          true,
+		}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	result, err := stations.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	nearby := []PollingStation{}
+	if err := result.All(ctx, &nearby); err != nil {
+		return nil, err
+	}
+	return nearby, nil
+}
+
+// Find the boxes of the polling stations within the distance in kilometers of the point, the nearest first
+func FindNearbyBoxes(ctx context.Context, point GeoPoint, kilometers float64, limit int64) ([]NearbyBox, error) {
+	nearbyBoxes := []NearbyBox{}
+
+	// Get the nearby stations
+	stations, err := FindNearbyStations(ctx, point, kilometers, limit)
+	if err != nil || len(stations) == 0 {
+		return nearbyBoxes, err
+	}
+
+	// Get the boxes of all stations at once
+	var filter bson.A
+	for _, station := range stations {
+		if len(station.Boxes) == 0 {
+			continue
+		}
+		filter = append(filter, bson.M{"city": station.City, "district": station.District, "number": bson.M{"$in": station.Boxes}})
+	}
+	if len(filter) == 0 {
+		return nearbyBoxes, nil
+	}
+	boxes := mongoClient.Database(utilities.GetEnv("MV_DB_DATABASE", "milletvekili")).Collection("boxes")
+	result, err := boxes.Find(ctx, bson.M{"$or": filter}, options.Find().SetSort(bson.D{{Key: "number", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var found []Box
+	if err := result.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	boxesByDistrict := map[string][]Box{}
+	for _, box := range found {
+		key := box.City + "/" + box.District
+		boxesByDistrict[key] = append(boxesByDistrict[key], box)
+	}
+
+	// Keep the order of the stations and stop at the limit
+	for _, station := range stations {
+		for _, box := range boxesByDistrict[station.City+"/"+station.District] {
+			if !containsNumber(station.Boxes, box.Number) || int64(len(nearbyBoxes)) >= limit {
+				continue
+			}
+			nearbyBoxes = append(nearbyBoxes, NearbyBox{
+				Box:       box,
+				Station:   station.Name,
+				StationId: station.Id.Hex(),
+				Location:  station.Location,
+				Distance:  station.Distance,
+			})
+		}
+	}
+	return nearbyBoxes, nil
+}
+
+// Create a GeoJSON feature collection of polling stations, the stations are the properties of the features
+func PollingStationFeatures(stations []PollingStation) FeatureCollection {
+	featureCollection := FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []Feature{},
+	}
+	for _, station := range stations {
+		featureCollection.Features = append(featureCollection.Features, Feature{
+			Type:       "Feature",
+			Id:         station.Id.Hex(),
+			Geometry:   station.Location.Geometry(),
+			Properties: station,
+		})
+	}
+	return featureCollection
+}
+
+// Create a GeoJSON feature collection of nearby boxes, every box is placed at its polling station
+func NearbyBoxFeatures(boxes []NearbyBox) FeatureCollection {
+	featureCollection := FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []Feature{},
+	}
+	for _, box := range boxes {
+		featureCollection.Features = append(featureCollection.Features, Feature{
+			Type:       "Feature",
+			Id:         box.Id.Hex(),
+			Geometry:   box.Location.Geometry(),
+			Properties: box,
+		})
+	}
+	return featureCollection
+}
+
+// Check if a slice contains a number
+func containsNumber(values []int64, value int64) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -13,7 +13,7 @@ func GetBoxRoutes(router *gin.RouterGroup) {
 		boxRoutes.POST("/", controllers.CreateBox)
 		boxRoutes.GET("/:id/", controllers.GetBoxById)
 		boxRoutes.GET("/:id/:district/:number/", controllers.GetBoxByNumber)
-		boxRoutes.PUT("/:id/", controllers.ChangeBox)
+		boxRoutes.PUT("/:id/:district/:number/", controllers.ChangeBox)
 		boxRoutes.DELETE("/:id/:district/:number/", controllers.DeleteBox)
 	}
 }
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/parliament/controllers"
+)
+
+// Returns all routes for the export of results
+func GetExportRoutes(router *gin.RouterGroup) {
+	exportRoutes := router.Group("/export")
+	{
+		// Routes for exporting boxes and aggregates as csv, xlsx or ndjson
+		exportRoutes.GET("/:level/", controllers.ExportLevel)
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/parliament/controllers"
+)
+
+// Returns all routes for the maps of the results
+func GetMapRoutes(router *gin.RouterGroup) {
+	mapRoutes := router.Group("/map")
+	{
+		// Routes for the GeoJSON maps of the regions
+		mapRoutes.GET("/", controllers.GetMap)
+		mapRoutes.GET("/:city/", controllers.GetMapOfCity)
+		mapRoutes.GET("/:city/:district/", controllers.GetMapOfDistrict)
+	}
+	geometriesRoutes := router.Group("/geometries")
+	{
+		// Routes for loading the geometries of the regions
+		geometriesRoutes.POST("/:level/", controllers.LoadGeometry)
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/parliament/controllers"
+)
+
+// Returns all routes for the status of the service
+func GetStatusRoutes(router *gin.RouterGroup) {
+	statusRoutes := router.Group("/status")
+	{
+		// Routes for the status of the database
+		statusRoutes.GET("/indexes/", controllers.GetIndexStatus)
+	}
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yzaimoglu/election/parliament/middleware"
@@ -14,6 +20,24 @@ import (
 func main() {
 	// Setup environment variables and some other things
 	models.Setup()
+
+	// Connect to the database and stop if it is unreachable
+	if err := models.ConnectMongo(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Store the region names of older documents as slugs before the unique indexes are built
+	if err := models.MigrateRegionNames(); err != nil {
+		fmt.Println(err.Error())
+	}
+
+	// Create the collections, validators and indexes
+	for _, index := range models.BootstrapDatabase() {
+		if !index.Ready {
+			fmt.Println("index " + index.Collection + "." + index.Name + " could not be built: " + index.Error)
+		}
+	}
+
 	models.SetupCache()
 
 	// Initialize the main router
@@ -43,8 +67,8 @@ func main() {
 	// Set the Favicon
 	mainRouter.StaticFile("/favicon.ico", "./assets/favicon.ico")
 
-	// Create the main Route group for the API
-	v1 := mainRouter.Group("/v1")
+	// Create the main Route group for the API, the region names in the route parameters are normalized to slugs and writes need an access token
+	v1 := mainRouter.Group("/v1", middleware.NormalizeRegionParams(), middleware.RequireServiceToken("parliament:write"))
 	{
 		routes.GetCityRoutes(v1)
 		routes.GetConstituencyRoutes(v1)
@@ -57,13 +81,43 @@ func main() {
 		routes.GetQuartersRoutes(v1)
 		routes.GetBoxesRoutes(v1)
 		routes.GetResultsRoutes(v1)
+		routes.GetImportRoutes(v1)
+		routes.GetExportRoutes(v1)
+		routes.GetGeographyRoutes(v1)
+		routes.GetMapRoutes(v1)
+		routes.GetStationRoutes(v1)
+		routes.GetStationsRoutes(v1)
+		routes.GetSearchRoutes(v1)
+		routes.GetStatusRoutes(v1)
 	}
 
 	// Run server
 	serverPort := fmt.Sprint(utilities.GetEnv("MV_PORT", fmt.Sprint(80)))
-	if err := models.RedisSet("initialized_at", utilities.GetCurrentTime()); err != nil {
+	if err := models.RedisSet(models.CacheKey("initialized_at"), utilities.GetCurrentTime()); err != nil {
 		fmt.Println("error initializing redis: " + err.Error())
 	}
 	fmt.Println("Milletvekili server started running on port " + serverPort)
-	mainRouter.Run(":" + serverPort)
+	server := &http.Server{
+		Addr:    ":" + serverPort,
+		Handler: mainRouter,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// Wait for an interrupt and shut the server down gracefully
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	fmt.Println("Shutting down the server")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("error shutting down the server: " + err.Error())
+	}
+	if err := models.DisconnectMongo(ctx); err != nil {
+		log.Println("error disconnecting from the database: " + err.Error())
+	}
 }
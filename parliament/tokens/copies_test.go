@@ -0,0 +1,41 @@
+package tokens
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Files of the package which are copied into the services that verify tokens, sign.go is only in the authentication service
+var copiedFiles = map[string][]string{
+	"token.go":       {"auth", "info", "parliament", "presidency"},
+	"verifier.go":    {"auth", "info", "parliament", "presidency"},
+	"jwks.go":        {"auth", "info", "parliament", "presidency"},
+	"copies_test.go": {"auth", "info", "parliament", "presidency"},
+}
+
+// The copies have to stay the same, a change is made in all services at once
+func TestCopiesAreTheSame(t *testing.T) {
+	for file, services := range copiedFiles {
+		own, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, service := range services {
+			// Services which are not checked out are skipped
+			if _, err := os.Stat(filepath.Join("..", "..", service)); err != nil {
+				continue
+			}
+			path := filepath.Join("..", "..", service, "tokens", file)
+			copied, err := os.ReadFile(path)
+			if err != nil {
+				t.Errorf("%s: %v", path, err)
+				continue
+			}
+			if !bytes.Equal(own, copied) {
+				t.Errorf("%s differs from tokens/%s, keep the copies the same", path, file)
+			}
+		}
+	}
+}
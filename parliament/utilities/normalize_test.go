@@ -0,0 +1,117 @@
+package utilities
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestTurkishCase(t *testing.T) {
+	tests := []struct {
+		input string
+		lower string
+		upper string
+	}{
+		{"İSTANBUL", "istanbul", "İSTANBUL"},
+		{"ISPARTA", "ısparta", "ISPARTA"},
+		{"ığdır", "ığdır", "IĞDIR"},
+		{"izmir", "izmir", "İZMİR"},
+		{"ŞIRNAK", "şırnak", "ŞIRNAK"},
+	}
+	for _, test := range tests {
+		if lower := TurkishLower(test.input); lower != test.lower {
+			t.Errorf("TurkishLower(%q) = %q, want %q", test.input, lower, test.lower)
+		}
+		if upper := TurkishUpper(test.input); upper != test.upper {
+			t.Errorf("TurkishUpper(%q) = %q, want %q", test.input, upper, test.upper)
+		}
+	}
+}
+
+func TestFoldAndSlug(t *testing.T) {
+	tests := []struct {
+		input string
+		fold  string
+		slug  string
+	}{
+		{"  ÇANKAYA ", "cankaya", "cankaya"},
+		{"İstanbul", "istanbul", "istanbul"},
+		{"IĞDIR", "igdir", "igdir"},
+		{"ığdır", "igdir", "igdir"},
+		{"Şişli", "sisli", "sisli"},
+		{"Gümüşhane", "gumushane", "gumushane"},
+		{"Ankara 1. Bölge", "ankara 1. bolge", "ankara-1-bolge"},
+		{"Kâhta", "kahta", "kahta"},
+		{"Afyonkarahisar  (Merkez)", "afyonkarahisar (merkez)", "afyonkarahisar-merkez"},
+		{"--Ağrı--", "--agri--", "agri"},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		if fold := Fold(test.input); fold != test.fold {
+			t.Errorf("Fold(%q) = %q, want %q", test.input, fold, test.fold)
+		}
+		if slug := Slug(test.input); slug != test.slug {
+			t.Errorf("Slug(%q) = %q, want %q", test.input, slug, test.slug)
+		}
+	}
+
+	// A slug does not change when it is made again
+	for _, test := range tests {
+		if slug := Slug(test.slug); slug != test.slug {
+			t.Errorf("Slug(%q) = %q, want the same slug", test.slug, slug)
+		}
+	}
+}
+
+func TestEqualFold(t *testing.T) {
+	for _, pair := range [][2]string{{"Cankaya", "ÇANKAYA"}, {"igdir", "IĞDIR"}, {"İzmir", "IZMIR"}, {"sisli", "ŞİŞLİ"}} {
+		if !EqualFold(pair[0], pair[1]) {
+			t.Errorf("EqualFold(%q, %q) = false, want true", pair[0], pair[1])
+		}
+	}
+	if EqualFold("Ankara", "Antalya") {
+		t.Error(`EqualFold("Ankara", "Antalya") = true, want false`)
+	}
+}
+
+func TestSearchPattern(t *testing.T) {
+	if pattern := SearchPattern("cankaya"); pattern != "[cçCÇ][aâAÂ][nN][kK][aâAÂ][yY][aâAÂ]" {
+		t.Fatalf("got the pattern %q", pattern)
+	}
+
+	// The pattern matches every spelling of the name and the special characters are escaped
+	tests := []struct {
+		query   string
+		matches []string
+		misses  []string
+	}{
+		{"igdir", []string{"Iğdır", "IĞDIR", "ığdır", "igdir"}, []string{"igdi"}},
+		{"Şişli", []string{"ŞİŞLİ", "sisli", "Şişli"}, []string{"Sisle"}},
+		{"1. bölge", []string{"1. Bölge", "1. BOLGE"}, []string{"1x bolge"}},
+	}
+	for _, test := range tests {
+		pattern := regexp.MustCompile("^" + SearchPattern(test.query) + "$")
+		for _, text := range test.matches {
+			if !pattern.MatchString(text) {
+				t.Errorf("the pattern of %q does not match %q", test.query, text)
+			}
+		}
+		for _, text := range test.misses {
+			if pattern.MatchString(text) {
+				t.Errorf("the pattern of %q matches %q", test.query, text)
+			}
+		}
+	}
+}
+
+func TestSortTurkish(t *testing.T) {
+	values := []string{"Zonguldak", "İzmir", "Ordu", "Çorum", "Iğdır", "Ankara", "Şanlıurfa", "Uşak", "Ceyhan", "Osmaniye", "Gümüşhane", "Giresun", "Sakarya", "Isparta", "Ünye", "Ilgaz"}
+	SortTurkish(values)
+	want := []string{"Ankara", "Ceyhan", "Çorum", "Giresun", "Gümüşhane", "Iğdır", "Ilgaz", "Isparta", "İzmir", "Ordu", "Osmaniye", "Sakarya", "Şanlıurfa", "Uşak", "Ünye", "Zonguldak"}
+	if !reflect.DeepEqual(values, want) {
+		t.Fatalf("got %v, want %v", values, want)
+	}
+	if Compare("ankara", "Ankara") == 0 {
+		t.Fatal("strings which only differ in the case are equal")
+	}
+}
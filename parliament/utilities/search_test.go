@@ -0,0 +1,104 @@
+package utilities
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+)
+
+func TestMatchScore(t *testing.T) {
+	tests := []struct {
+		query string
+		text  string
+		score int
+	}{
+		{"cankaya", "Çankaya", ScoreExact},
+		{"IGDIR", "Iğdır", ScoreExact},
+		{"ank", "Ankara", ScorePrefix},
+		{"bolge", "Ankara 1. Bölge", ScoreWordPrefix},
+		{"merkez", "Afyonkarahisar (Merkez)", ScoreWordPrefix},
+		{"kara", "Afyonkarahisar", ScoreContains},
+		{"ankra", "Ankara", ScoreFuzzy - 10},
+		{"istanbol", "İstanbul", ScoreFuzzy - 10},
+		{"gumushne", "Gümüşhane", ScoreFuzzy - 10},
+		{"eskisehri", "Eskişehir", ScoreFuzzy - 20},
+		{"kayseri", "Kayseri Melikgazi", ScorePrefix},
+		{"melikgazy", "Kayseri Melikgazi", ScoreFuzzy - 10},
+		{"antlya", "Antalya Muratpaşa", ScoreFuzzy - 10},
+		{"ank", "Ankra", ScorePrefix},
+		{"akn", "Ankara", 0},
+		{"ankra", "Ağrı", 0},
+		{"eskshr", "Eskişehir", 0},
+		{"", "Ankara", 0},
+		{"ankara", "", 0},
+	}
+	for _, test := range tests {
+		if score := MatchScore(test.query, test.text); score != test.score {
+			t.Errorf("MatchScore(%q, %q) = %d, want %d", test.query, test.text, score, test.score)
+		}
+	}
+}
+
+func TestMatchScoreRanksTheBestMatchesFirst(t *testing.T) {
+	texts := []string{"Karaman", "Afyonkarahisar", "Kars", "Kara Bölge", "Karabük", "Kara"}
+	sort.SliceStable(texts, func(i, j int) bool {
+		return MatchScore("kara", texts[i]) > MatchScore("kara", texts[j])
+	})
+	want := []string{"Kara", "Karaman", "Kara Bölge", "Karabük", "Afyonkarahisar", "Kars"}
+	for index := range want {
+		if texts[index] != want[index] {
+			t.Fatalf("got the order %v, want %v", texts, want)
+		}
+	}
+}
+
+func TestSearchCandidatePatterns(t *testing.T) {
+	// The candidates of a typo contain the text, so that MatchScore can score it
+	candidates := regexp.MustCompile(SearchCandidatePattern("ankra"))
+	for _, text := range []string{"Ankara", "ANKARA", "Yeni Ankara", "Ankra"} {
+		if !candidates.MatchString(text) {
+			t.Errorf("the candidates of ankra do not contain %q", text)
+		}
+	}
+	if candidates.MatchString("Kayseri") {
+		t.Error("the candidates of ankra contain Kayseri")
+	}
+
+	// The patterns go from the exact matches to all candidates
+	patterns := SearchCandidatePatterns("ank")
+	tests := []struct {
+		text    string
+		matches []bool
+	}{
+		{"Ank", []bool{true, true, true}},
+		{"Ankara", []bool{false, true, true}},
+		{"Yeni Ankara", []bool{false, false, true}},
+		{"Kayseri", []bool{false, false, false}},
+	}
+	for _, test := range tests {
+		for index, pattern := range patterns {
+			if matches := regexp.MustCompile(pattern).MatchString(test.text); matches != test.matches[index] {
+				t.Errorf("pattern %d of ank matches %q: %t, want %t", index, test.text, matches, test.matches[index])
+			}
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"ankara", "ankara", 0},
+		{"ankra", "ankara", 1},
+		{"ankara", "ankaar", 2},
+		{"ığdır", "igdir", 3},
+		{"", "abc", 3},
+	}
+	for _, test := range tests {
+		if distance := levenshtein(test.a, test.b); distance != test.distance {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", test.a, test.b, distance, test.distance)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/yzaimoglu/election/presidency/models"
+)
+
+// Load the geometries of cities, districts or quarters from a GeoJSON file into the database
+//
+//	go run ./cmd/geometry -level district -file ankara.geojson -city ankara -name ilce_adi -code ilce_kodu
+func main() {
+	// Parse the command line flags
+	level := flag.String("level", models.GeographyProvince, "level of the geometries (province, district or quarter)")
+	fileName := flag.String("file", "", "GeoJSON file with a FeatureCollection")
+	codeProperty := flag.String("code", "", "feature property with the code of the geography registry")
+	nameProperty := flag.String("name", "name", "feature property with the name of the region")
+	cityProperty := flag.String("city-property", "", "feature property with the name of the city")
+	districtProperty := flag.String("district-property", "", "feature property with the name of the district")
+	city := flag.String("city", "", "city of all features, if there is no city property")
+	district := flag.String("district", "", "district of all features, if there is no district property")
+	flag.Parse()
+	if *fileName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Setup environment variables, the database and the cache
+	models.Setup()
+	if err := models.ConnectMongo(); err != nil {
+		log.Fatal(err)
+	}
+	defer models.DisconnectMongo(context.Background())
+	models.SetupCache()
+
+	// Read the file
+	data, err := os.ReadFile(*fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Load the geometries and print the report
+	properties := models.GeometryProperties{
+		Code:     *codeProperty,
+		Name:     *nameProperty,
+		City:     *cityProperty,
+		District: *districtProperty,
+	}
+	report, err := models.LoadGeometry(context.Background(), *level, data, properties, *city, *district)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
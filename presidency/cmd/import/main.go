@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/yzaimoglu/election/presidency/models"
+	"github.com/yzaimoglu/election/presidency/utilities"
+)
+
+// Import boxes, regions or vote counts from a csv or xlsx file into the database
+//
+//	go run ./cmd/import -kind boxes -file boxes.xlsx -atomic
+func main() {
+	// Parse the command line flags
+	kind := flag.String("kind", "boxes", "kind of the imported documents (boxes, cities, constituencies, districts or quarters)")
+	fileName := flag.String("file", "", "csv or xlsx file to import")
+	atomic := flag.Bool("atomic", false, "import nothing if a row is invalid and write all rows in one transaction")
+	flag.Parse()
+	if *fileName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Setup environment variables, the database and the cache
+	models.Setup()
+	if err := models.ConnectMongo(); err != nil {
+		log.Fatal(err)
+	}
+	defer models.DisconnectMongo(context.Background())
+	models.SetupCache()
+
+	// Read the rows of the file
+	file, err := os.Open(*fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := utilities.ReadSpreadsheet(file, *fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Import the rows and print the report
+	report, err := models.Import(context.Background(), *kind, rows, *atomic)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
@@ -17,9 +16,8 @@ import (
 
 // Create a ballot box
 func CreateBox(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the box
 	var box models.Box
@@ -46,6 +44,12 @@ func CreateBox(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveBox(&box); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Insert box
 	if _, err := client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("boxes").InsertOne(ctx, box); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
@@ -55,6 +59,11 @@ func CreateBox(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the box
+	if err := models.CacheInvalidate(models.BoxCacheTags(box)...); err != nil {
+		log.Println("error invalidating the cache of the box: " + err.Error())
+	}
+
 	// Return the recently created box
 	c.JSON(http.StatusOK, box)
 }
@@ -62,20 +71,17 @@ func CreateBox(c *gin.Context) {
 // Get a box by its id
 func GetBoxById(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the box
 	var box models.Box
+	cacheKey := models.CacheKey("box", "id", id)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet("boxwithid-" + id)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &box); err == nil {
-			c.JSON(http.StatusOK, box)
-			return
-		}
+	if models.CacheGet(cacheKey, &box) {
+		c.JSON(http.StatusOK, box)
+		return
 	}
 
 	// ObjectID from id
@@ -111,15 +117,8 @@ func GetBoxById(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	boxJSON, err := json.Marshal(box)
-	if err != nil {
-		log.Printf("error marshalling boxwithid-" + id + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet("boxwithid-"+id, boxJSON); err != nil {
-		log.Printf("error setting boxwithid-" + id + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL("boxwithid-"+id, 60*5); err != nil {
-		log.Printf("error setting ttl for the boxwithid-" + id + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, box, models.DocumentTag("boxes", box.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the box
@@ -131,21 +130,17 @@ func GetBoxByNumber(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	number := c.Param("number")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the Box
 	var box models.Box
-	boxName := "box-" + city + "-" + district + "-" + number
+	cacheKey := models.CacheKey("box", "number", city, district, number)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet(boxName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &box); err == nil {
-			c.JSON(http.StatusOK, box)
-			return
-		}
+	if models.CacheGet(cacheKey, &box) {
+		c.JSON(http.StatusOK, box)
+		return
 	}
 
 	// Initialize $and input
@@ -181,15 +176,8 @@ func GetBoxByNumber(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	boxJSON, err := json.Marshal(box)
-	if err != nil {
-		log.Printf("error marshalling " + boxName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(boxName, boxJSON); err != nil {
-		log.Printf("error setting " + boxName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(boxName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + boxName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, box, models.DocumentTag("boxes", box.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the box
@@ -201,9 +189,8 @@ func ChangeBox(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	number := c.Param("number")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the box
 	var box models.Box
@@ -228,6 +215,12 @@ func ChangeBox(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveBox(&box); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Initialize $and input
 	var filter []bson.M
 	var numberInt int64
@@ -262,7 +255,7 @@ func ChangeBox(c *gin.Context) {
 	box.Id = oldBox.Id
 
 	// Replace object
-	if _, err := client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("cities").ReplaceOne(ctx, bson.M{"$and": filter}, box); err != nil {
+	if _, err := client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("boxes").ReplaceOne(ctx, bson.M{"$and": filter}, box); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -270,6 +263,11 @@ func ChangeBox(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the old or the updated box
+	if err := models.CacheInvalidate(append(models.BoxCacheTags(oldBox), models.BoxCacheTags(box)...)...); err != nil {
+		log.Println("error invalidating the cache of the box: " + err.Error())
+	}
+
 	// Return the recently updated box
 	c.JSON(http.StatusOK, box)
 }
@@ -279,9 +277,8 @@ func DeleteBox(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	number := c.Param("number")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize $and input
 	var filter []bson.M
@@ -294,11 +291,20 @@ func DeleteBox(c *gin.Context) {
 	filter = append(filter, bson.M{"district": district})
 	filter = append(filter, bson.M{"number": numberInt})
 
-	// Delete box
-	result, err := client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("boxes").DeleteOne(ctx, bson.M{"$and": filter})
+	// Delete the box
+	result := client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("boxes").FindOneAndDelete(ctx, bson.M{"$and": filter})
 
-	// Check if there is a box with the filter
-	if err != nil {
+	// Return that nothing has been deleted if there is no box with the filter
+	if result.Err() == mongo.ErrNoDocuments {
+		c.JSON(http.StatusOK, gin.H{
+			"deletedCount": 0,
+		})
+		return
+	}
+
+	// Decode the deleted box
+	var deletedBox models.Box
+	if err := result.Decode(&deletedBox); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -306,8 +312,215 @@ func DeleteBox(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the box
+	if err := models.CacheInvalidate(models.BoxCacheTags(deletedBox)...); err != nil {
+		log.Println("error invalidating the cache of the box: " + err.Error())
+	}
+
 	// Return the deleted count
 	c.JSON(http.StatusOK, gin.H{
-		"deletedCount": result.DeletedCount,
+		"deletedCount": 1,
 	})
 }
+
+// Get all the boxes by city
+func GetBoxesByCity(c *gin.Context) {
+	city := c.Param("city")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, boxListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Initialize the page of the boxes
+	var page models.Page
+	cacheKey := models.CacheKey("boxes", "city", city, query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	// Get the page of boxes
+	page, err = findPage[models.Box](ctx, client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("boxes"), []bson.M{{"city": city}}, query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Set the result to the cache
+	if err := models.CacheSet(cacheKey, page, models.ListTag("boxes", "city", city)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
+	}
+
+	// Return the box
+	c.JSON(http.StatusOK, page)
+}
+
+// Get all the boxes by constituency
+func GetBoxesByConstituency(c *gin.Context) {
+	city := c.Param("city")
+	constituency := c.Param("constituency")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, boxListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Initialize the page of the boxes
+	var page models.Page
+	cacheKey := models.CacheKey("boxes", "constituency", city, constituency, query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	// Initialize $and filter
+	var filter []bson.M
+	filter = append(filter, bson.M{"city": city})
+	filter = append(filter, bson.M{"constituency": constituency})
+
+	// Get the page of boxes
+	page, err = findPage[models.Box](ctx, client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("boxes"), filter, query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Set the result to the cache
+	if err := models.CacheSet(cacheKey, page, models.ListTag("boxes", "constituency", city, constituency)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
+	}
+
+	// Return the box
+	c.JSON(http.StatusOK, page)
+}
+
+// Get all the boxes by district
+func GetBoxesByDistrict(c *gin.Context) {
+	city := c.Param("city")
+	district := c.Param("district")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, boxListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Initialize the page of the boxes
+	var page models.Page
+	cacheKey := models.CacheKey("boxes", "district", city, district, query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	// Initialize $and input
+	var filter []bson.M
+
+	filter = append(filter, bson.M{"city": city})
+	filter = append(filter, bson.M{"district": district})
+
+	// Get the page of boxes
+	page, err = findPage[models.Box](ctx, client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("boxes"), filter, query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Set the result to the cache
+	if err := models.CacheSet(cacheKey, page, models.ListTag("boxes", "district", city, district)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
+	}
+
+	// Return the box
+	c.JSON(http.StatusOK, page)
+}
+
+// Get all the boxes by quarter
+func GetBoxesByQuarter(c *gin.Context) {
+	city := c.Param("city")
+	district := c.Param("district")
+	quarter := c.Param("quarter")
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, boxListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Initialize the page of the boxes
+	var page models.Page
+	cacheKey := models.CacheKey("boxes", "quarter", city, district, quarter, query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	// Initialize $and input
+	var filter []bson.M
+
+	filter = append(filter, bson.M{"city": city})
+	filter = append(filter, bson.M{"district": district})
+	filter = append(filter, bson.M{"quarter": quarter})
+
+	// Get the page of boxes
+	page, err = findPage[models.Box](ctx, client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("boxes"), filter, query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Set the result to the cache
+	if err := models.CacheSet(cacheKey, page, models.ListTag("boxes", "quarter", city, district, quarter)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
+	}
+
+	// Return the box
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/presidency/models"
+	"github.com/yzaimoglu/election/presidency/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Get the map of all cities
+func GetMap(c *gin.Context) {
+	getRegionMap(c, models.GeographyProvince, "cities", nil, "", "", models.ListTag("cities", "all"))
+}
+
+// Get the map of the districts of a city
+func GetMapOfCity(c *gin.Context) {
+	city := c.Param("city")
+	getRegionMap(c, models.GeographyDistrict, "districts", []bson.M{{"city": city}}, city, "", models.ListTag("districts", "city", city))
+}
+
+// Get the map of the quarters of a district
+func GetMapOfDistrict(c *gin.Context) {
+	city := c.Param("city")
+	district := c.Param("district")
+	getRegionMap(c, models.GeographyQuarter, "quarters", []bson.M{{"city": city}, {"district": district}}, city, district, models.ListTag("quarters", "district", city, district))
+}
+
+// Return the regions matching the filter as a GeoJSON feature collection with their results as properties
+//
+// Regions without a geometry are left out, the map is cached until one of the regions or the
+// geometries of the level change.
+func getRegionMap(c *gin.Context, level string, collectionName string, filter []bson.M, city string, district string, regionsTag string) {
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Initialize the feature collection
+	featureCollection := models.FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []models.Feature{},
+	}
+	cacheKey := models.CacheKey("map", level, city, district)
+
+	// Check if the map has been cached if so return
+	if models.CacheGet(cacheKey, &featureCollection) {
+		c.JSON(http.StatusOK, featureCollection)
+		return
+	}
+	database := client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi"))
+
+	// Get the regions
+	result, err := database.Collection(collectionName).Find(ctx, andFilter(filter))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+	var regions []models.MapRegion
+	if err := result.All(ctx, &regions); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+	if len(regions) == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status":  http.StatusNotFound,
+			"message": "no regions for this map found",
+		})
+		return
+	}
+
+	// Get the geometries of the level below the parent
+	result, err = database.Collection("geometries").Find(ctx, bson.M{"level": level, "city": utilities.Slug(city), "district": utilities.Slug(district)})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+	var geometries []models.RegionGeometry
+	if err := result.All(ctx, &geometries); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+	geometriesByCode := map[string]models.RegionGeometry{}
+	geometriesByName := map[string]models.RegionGeometry{}
+	for _, geometry := range geometries {
+		if geometry.Code != "" {
+			geometriesByCode[geometry.Code] = geometry
+		}
+		geometriesByName[geometry.Name] = geometry
+	}
+
+	// Get the colors of the individuals and parties, the map is still returned without them if the info service is down
+	colors, colorsErr := models.GetInfoColors()
+	if colorsErr != nil {
+		log.Println("error getting the colors from the info service: " + colorsErr.Error())
+	}
+
+	// Create a feature for every region with a geometry
+	for _, region := range regions {
+		geometry, ok := geometriesByCode[region.Code]
+		if !ok {
+			if geometry, ok = geometriesByName[utilities.Slug(region.Name)]; !ok {
+				continue
+			}
+		}
+		featureCollection.Features = append(featureCollection.Features, models.Feature{
+			Type:       "Feature",
+			Id:         region.Id.Hex(),
+			Geometry:   geometry.Geometry,
+			Properties: models.MapRegionProperties(region, colors),
+		})
+	}
+
+	// Set the map to the cache, maps without colors are not cached
+	if colorsErr == nil {
+		if err := models.CacheSet(cacheKey, featureCollection, regionsTag, models.ListTag("geometries", level)); err != nil {
+			log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
+		}
+	}
+
+	// Return the map
+	c.JSON(http.StatusOK, featureCollection)
+}
+
+// Load the geometries of a level from an uploaded GeoJSON file
+//
+//	POST /v1/geometries/district/?name=ilce_adi&code=ilce_kodu&city=ankara
+func LoadGeometry(c *gin.Context) {
+	level := c.Param("level")
+
+	// Get the uploaded file
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  "the GeoJSON file must be uploaded as the form field file",
+		})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Load the geometries, the feature properties identifying the regions are given as query parameters
+	properties := models.GeometryProperties{
+		Code:     c.Query("code"),
+		Name:     c.DefaultQuery("name", "name"),
+		City:     c.Query("cityProperty"),
+		District: c.Query("districtProperty"),
+	}
+	report, err := models.LoadGeometry(c.Request.Context(), level, data, properties, c.Query("city"), c.Query("district"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+			"report": report,
+		})
+		return
+	}
+
+	// Return the report
+	c.JSON(http.StatusOK, report)
+}
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 
@@ -16,9 +15,8 @@ import (
 
 // Create a quarter
 func CreateQuarter(c *gin.Context) {
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the quarter
 	var quarter models.Quarter
@@ -45,6 +43,12 @@ func CreateQuarter(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveQuarter(&quarter); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Insert quarter
 	if _, err := client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("quarters").InsertOne(ctx, quarter); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
@@ -54,6 +58,11 @@ func CreateQuarter(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the quarter
+	if err := models.CacheInvalidate(models.QuarterCacheTags(quarter)...); err != nil {
+		log.Println("error invalidating the cache of the quarter: " + err.Error())
+	}
+
 	// Return the recently created quarter
 	c.JSON(http.StatusOK, quarter)
 }
@@ -61,20 +70,17 @@ func CreateQuarter(c *gin.Context) {
 // Get a quarter by its id
 func GetQuarterById(c *gin.Context) {
 	id := c.Param("id")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the quarter
 	var quarter models.Quarter
+	cacheKey := models.CacheKey("quarter", "id", id)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet("quarterwithid-" + id)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &quarter); err == nil {
-			c.JSON(http.StatusOK, quarter)
-			return
-		}
+	if models.CacheGet(cacheKey, &quarter) {
+		c.JSON(http.StatusOK, quarter)
+		return
 	}
 
 	// ObjectID from id
@@ -110,15 +116,8 @@ func GetQuarterById(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	quarterJSON, err := json.Marshal(quarter)
-	if err != nil {
-		log.Printf("error marshalling quarterwithid-" + id + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet("quarterwithid-"+id, quarterJSON); err != nil {
-		log.Printf("error setting quarterwithid-" + id + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL("quarterwithid-"+id, 60*5); err != nil {
-		log.Printf("error setting ttl for the quarterwithid-" + id + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, quarter, models.DocumentTag("quarters", quarter.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the quarter
@@ -130,21 +129,17 @@ func GetQuarterByName(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	name := c.Param("quarter")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the quarter
 	var quarter models.Quarter
-	quarterName := "quarter-" + city + "-" + district + "-" + name
+	cacheKey := models.CacheKey("quarter", "name", city, district, name)
 
 	// Check if the result has been cached if so return
-	redisResult, redisErr := models.RedisGet(quarterName)
-	if redisErr == nil {
-		if err := json.Unmarshal(redisResult, &quarter); err == nil {
-			c.JSON(http.StatusOK, quarter)
-			return
-		}
+	if models.CacheGet(cacheKey, &quarter) {
+		c.JSON(http.StatusOK, quarter)
+		return
 	}
 
 	// Initialize $and input
@@ -176,15 +171,8 @@ func GetQuarterByName(c *gin.Context) {
 	}
 
 	// Set the result to the cache
-	quarterJSON, err := json.Marshal(quarter)
-	if err != nil {
-		log.Printf("error marshalling " + quarterName + " to a json object: " + err.Error())
-	}
-	if err := models.RedisSet(quarterName, quarterJSON); err != nil {
-		log.Printf("error setting " + quarterName + " to the cache: " + err.Error())
-	}
-	if err := models.RedisTTL(quarterName, 60*5); err != nil {
-		log.Printf("error setting ttl for the " + quarterName + " in the cache: " + err.Error())
+	if err := models.CacheSet(cacheKey, quarter, models.DocumentTag("quarters", quarter.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
 	}
 
 	// Return the quarter
@@ -196,9 +184,8 @@ func ChangeQuarter(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	name := c.Param("quarter")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize the quarter
 	var quarter models.Quarter
@@ -223,6 +210,12 @@ func ChangeQuarter(c *gin.Context) {
 		return
 	}
 
+	// Resolve the regions with the geography registry, unknown regions are rejected
+	if err := models.NewGeographyResolver(ctx).ResolveQuarter(&quarter); err != nil {
+		abortGeographyError(c, err)
+		return
+	}
+
 	// Initialize $and input
 	var filter []bson.M
 	filter = append(filter, bson.M{"city": city})
@@ -261,6 +254,11 @@ func ChangeQuarter(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the old or the updated quarter
+	if err := models.CacheInvalidate(append(models.QuarterCacheTags(oldQuarter), models.QuarterCacheTags(quarter)...)...); err != nil {
+		log.Println("error invalidating the cache of the quarter: " + err.Error())
+	}
+
 	// Return the recently updated quarter
 	c.JSON(http.StatusOK, quarter)
 }
@@ -270,9 +268,8 @@ func DeleteQuarter(c *gin.Context) {
 	city := c.Param("id")
 	district := c.Param("district")
 	name := c.Param("quarter")
-	client, ctx, cancel := models.GetMongoInstance()
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
 	defer cancel()
-	defer client.Disconnect(ctx)
 
 	// Initialize $and input
 	var filter []bson.M
@@ -280,11 +277,20 @@ func DeleteQuarter(c *gin.Context) {
 	filter = append(filter, bson.M{"district": district})
 	filter = append(filter, bson.M{"name": name})
 
-	// Delete quarter
-	result, err := client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("quarters").DeleteOne(ctx, bson.M{"$and": filter})
+	// Delete the quarter
+	result := client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("quarters").FindOneAndDelete(ctx, bson.M{"$and": filter})
 
-	// Check if there is a quarter with the filter
-	if err != nil {
+	// Return that nothing has been deleted if there is no quarter with the filter
+	if result.Err() == mongo.ErrNoDocuments {
+		c.JSON(http.StatusOK, gin.H{
+			"deletedCount": 0,
+		})
+		return
+	}
+
+	// Decode the deleted quarter
+	var deletedQuarter models.Quarter
+	if err := result.Decode(&deletedQuarter); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
 			"message": "internal server error",
@@ -292,8 +298,107 @@ func DeleteQuarter(c *gin.Context) {
 		return
 	}
 
+	// Invalidate all cached entries containing the quarter
+	if err := models.CacheInvalidate(models.QuarterCacheTags(deletedQuarter)...); err != nil {
+		log.Println("error invalidating the cache of the quarter: " + err.Error())
+	}
+
 	// Return the deleted count
 	c.JSON(http.StatusOK, gin.H{
-		"deletedCount": result.DeletedCount,
+		"deletedCount": 1,
 	})
 }
+
+// Get all quarters, important for the updater
+func GetQuarters(c *gin.Context) {
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, regionListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Initialize the page of the cities
+	var page models.Page
+	cacheKey := models.CacheKey("quarters", "all", query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	// Get the page of cities
+	page, err = findPage[models.Quarter](ctx, client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("quarters"), nil, query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Set the result to the cache
+	if err := models.CacheSet(cacheKey, page, models.ListTag("quarters", "all")); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
+	}
+
+	// Return the quarters
+	c.JSON(http.StatusOK, page)
+}
+
+// Get all quarters, important for the updater
+func GetQuartersOfDistrict(c *gin.Context) {
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	city := c.Param("city")
+	district := c.Param("district")
+	defer cancel()
+
+	// Parse the pagination, filter, sort and projection parameters
+	query, err := parseListQuery(c, regionListOptions)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// Initialize the page of the cities
+	var page models.Page
+	cacheKey := models.CacheKey("quarters", "district", city, district, query.key)
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &page) {
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	var filter []bson.M
+	filter = append(filter, bson.M{"city": city})
+	filter = append(filter, bson.M{"district": district})
+
+	// Get the page of cities
+	page, err = findPage[models.Quarter](ctx, client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("quarters"), filter, query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Set the result to the cache
+	if err := models.CacheSet(cacheKey, page, models.ListTag("quarters", "district", city, district)); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
+	}
+
+	// Return the quarters
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/presidency/models"
+	"github.com/yzaimoglu/election/presidency/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Get the results by city
+func GetResultsByCity(c *gin.Context) {
+	city := c.Param("city")
+	getResult(c, "cities", []bson.M{{"name": city}}, "results-"+city, models.CacheKey("results", "city", city), "no city with these details found")
+}
+
+// Get the results by district
+func GetResultsByDistrict(c *gin.Context) {
+	city := c.Param("city")
+	district := c.Param("district")
+	getResult(c, "districts", []bson.M{{"city": city}, {"name": district}}, "results-"+city+"-"+district, models.CacheKey("results", "district", city, district), "no district with these details found")
+}
+
+// Get the results by quarter
+func GetResultsByQuarter(c *gin.Context) {
+	city := c.Param("city")
+	district := c.Param("district")
+	quarter := c.Param("quarter")
+	getResult(c, "quarters", []bson.M{{"city": city}, {"district": district}, {"name": quarter}}, "results-"+city+"-"+district+"-"+quarter, models.CacheKey("results", "quarter", city, district, quarter), "no quarter with these details found")
+}
+
+// Get the results by box
+func GetResultsByBox(c *gin.Context) {
+	city := c.Param("city")
+	district := c.Param("district")
+	quarter := c.Param("quarter")
+	box := c.Param("box")
+
+	// Parse the box into a boxnumber
+	boxNumber, err := strconv.ParseInt(box, 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "the box number must be a number",
+		})
+		return
+	}
+	getResult(c, "boxes", []bson.M{{"city": city}, {"district": district}, {"quarter": quarter}, {"number": boxNumber}}, "results-"+city+"-"+district+"-"+quarter+"-"+box, models.CacheKey("results", "box", city, district, quarter, box), "no box with these details found")
+}
+
+// Return the result of the region or box matching the filter, ?embed=candidate embeds the profiles of the candidates
+func getResult(c *gin.Context, collectionName string, filter []bson.M, location string, cacheKey string, notFoundMessage string) {
+	client, ctx, cancel := models.GetMongoInstance(c.Request.Context())
+	defer cancel()
+
+	// Initialize the result
+	var resultObj models.Result
+
+	// Check if the result has been cached if so return
+	if models.CacheGet(cacheKey, &resultObj) {
+		returnResult(c, resultObj)
+		return
+	}
+
+	// Get the votes
+	result := client.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection(collectionName).FindOne(ctx, bson.M{"$and": filter})
+
+	// Check if there is a region with the filter
+	if result.Err() == mongo.ErrNoDocuments {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"status":  http.StatusNotFound,
+			"message": notFoundMessage,
+		})
+		return
+	}
+
+	// Decode result to object
+	var votes models.ResultVotes
+	if err := result.Decode(&votes); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+		return
+	}
+
+	// Calculate the percentages
+	resultObj = models.NewResult(location, votes)
+
+	// Set the result to the cache
+	if err := models.CacheSet(cacheKey, resultObj, models.DocumentTag(collectionName, votes.Id.Hex())); err != nil {
+		log.Println("error setting " + cacheKey + " to the cache: " + err.Error())
+	}
+
+	// Return the result
+	returnResult(c, resultObj)
+}
+
+// Return the result, ?embed=candidate embeds the profiles of the parties and individuals from the info service
+//
+// The result is returned without the profiles if the info service is unreachable.
+func returnResult(c *gin.Context, resultObj models.Result) {
+	if c.Query("embed") == "candidate" {
+		candidacies, err := models.GetInfoCandidacies()
+		if err != nil {
+			log.Println("error getting the candidacies from the info service: " + err.Error())
+		}
+		for index, party := range resultObj.Parties {
+			if profile, ok := candidacies.Profile(party.CandidacyId, party.Name); ok {
+				resultObj.Parties[index].Candidate = &profile
+			}
+		}
+		for index, individual := range resultObj.Individuals {
+			if profile, ok := candidacies.Profile(individual.CandidacyId, individual.FirstName+" "+individual.LastName); ok {
+				resultObj.Individuals[index].Candidate = &profile
+			}
+		}
+	}
+	c.JSON(http.StatusOK, resultObj)
+}
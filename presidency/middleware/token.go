@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/presidency/tokens"
+	"github.com/yzaimoglu/election/presidency/utilities"
+)
+
+// Role of the admins in the access tokens of users
+const adminRole = "Yönetici"
+
+// RequireServiceToken only lets writes through with an access token of the authentication service which grants
+// the scope or belongs to an admin, reads stay public and the claims are set to the context as "token"
+//
+// The tokens are verified with the keys published on CB_AUTH_JWKS_URL, e.g. http://localhost:80/v1/.well-known/jwks.json,
+// writes are rejected if it is not set. Only CB_AUTH_DISABLED=true lets the service run without the authentication
+// service, then nothing is checked.
+func RequireServiceToken(scope string) gin.HandlerFunc {
+	if utilities.GetEnv("CB_AUTH_DISABLED", "false") == "true" {
+		log.Println("CB_AUTH_DISABLED is set, writes are not authenticated")
+		return func(c *gin.Context) {}
+	}
+	jwksURL := utilities.GetEnv("CB_AUTH_JWKS_URL", "")
+	if jwksURL == "" {
+		log.Println("CB_AUTH_JWKS_URL is not set, writes are rejected")
+		return func(c *gin.Context) {
+			if !isRead(c) {
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"status": http.StatusServiceUnavailable,
+					"error":  "writes are disabled because the authentication service is not configured",
+				})
+			}
+		}
+	}
+	verifier := tokens.NewVerifier(jwksURL, utilities.GetEnv("CB_AUTH_ISSUER", "election-auth"), utilities.GetEnv("CB_AUTH_AUDIENCE", "election"))
+
+	return func(c *gin.Context) {
+		if isRead(c) {
+			return
+		}
+
+		// Verify the bearer token of the request
+		token := tokens.BearerToken(c.GetHeader("Authorization"))
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status": http.StatusUnauthorized,
+				"error":  "an access token is required",
+			})
+			return
+		}
+		claims, err := verifier.Verify(token)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status": http.StatusUnauthorized,
+				"error":  err.Error(),
+			})
+			return
+		}
+
+		// Check the scope of clients and the role of users
+		if !claims.HasScope(scope) && claims.Role != adminRole {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"status": http.StatusForbidden,
+				"error":  "the access token does not grant " + scope,
+			})
+			return
+		}
+		c.Set("token", claims)
+	}
+}
+
+// Check if the request only reads, reads are public
+func isRead(c *gin.Context) bool {
+	return c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"net/url"
+
+	"github.com/yzaimoglu/election/presidency/utilities"
+)
+
+// Model for the profile of a candidate of the info service, embedded in the results
+type CandidateProfile struct {
+	CandidacyId  string `json:"candidacyid"`            // 63f1c2...
+	FirstName    string `json:"firstname,omitempty"`    // Recep Tayyip
+	LastName     string `json:"lastname,omitempty"`     // Erdogan
+	Party        string `json:"party,omitempty"`        // Adalet ve Kalkınma Partisi
+	Abbreviation string `json:"abbreviation,omitempty"` // AKP
+	Affiliation  string `json:"affiliation,omitempty"`  // AKP
+	Image        string `json:"image,omitempty"`        // image of the individual or logo of the party
+	Color        string `json:"color,omitempty"`        // #ffa500
+	Constituency string `json:"constituency,omitempty"` // Ankara-1
+	ListPosition int64  `json:"listposition"`           // 1
+}
+
+// Candidate profiles of the election by their candidacy id and by their folded name
+type InfoCandidacies struct {
+	Profiles map[string]CandidateProfile `json:"profiles"` // 63f1c2... -> profile
+	Names    map[string]string           `json:"names"`    // recep tayyip erdogan -> 63f1c2...
+}
+
+// Model for a candidacy of the info service with the embedded individual and party
+type infoCandidacy struct {
+	Id           string `json:"_id"`
+	Constituency string `json:"constituency"`
+	ListPosition int64  `json:"listposition"`
+	Individual   *struct {
+		FirstName   string `json:"firstname"`
+		LastName    string `json:"lastname"`
+		Image       string `json:"image"`
+		Affiliation string `json:"affiliation"`
+		Color       struct {
+			Hex string `json:"hex"`
+		} `json:"color"`
+	} `json:"individual"`
+	Party *struct {
+		Name         string `json:"name"`
+		Abbreviation string `json:"abbreviation"`
+		Logo         string `json:"logo"`
+		Color        struct {
+			Hex string `json:"hex"`
+		} `json:"color"`
+	} `json:"party"`
+}
+
+// Get the profile of a candidate by the candidacy id, candidates without one are looked up by their name
+func (candidacies InfoCandidacies) Profile(candidacyId string, name string) (CandidateProfile, bool) {
+	if candidacyId == "" {
+		candidacyId = candidacies.Names[utilities.Fold(name)]
+	}
+	profile, ok := candidacies.Profiles[candidacyId]
+	return profile, ok
+}
+
+// Get the candidacies of the election with their profiles from the info service, the candidacies are cached
+//
+// The election is set with CB_ELECTION, the profiles fall back to the color of the affiliated party. The individuals
+// and parties are shown as they were on CB_ELECTION_DATE, so that later changes of a leader or a logo do not alter the results.
+func GetInfoCandidacies() (InfoCandidacies, error) {
+	election := utilities.GetEnv("CB_ELECTION", "presidency-2023")
+	electionDate := utilities.GetEnv("CB_ELECTION_DATE", "2023-05-14")
+	candidacies := InfoCandidacies{
+		Profiles: map[string]CandidateProfile{},
+		Names:    map[string]string{},
+	}
+	cacheKey := CacheKey("candidacies", election, electionDate)
+
+	// Check if the candidacies have been cached if so return
+	if CacheGet(cacheKey, &candidacies) {
+		return candidacies, nil
+	}
+
+	// Get the candidacies with the individuals and parties
+	parameters := url.Values{}
+	parameters.Set("election", election)
+	parameters.Set("embed", "profile")
+	if electionDate != "" {
+		parameters.Set("at", electionDate)
+	}
+	found, err := getInfoPages[infoCandidacy]("/candidacies/", parameters)
+	if err != nil {
+		return candidacies, err
+	}
+	colors, err := GetInfoColors()
+	if err != nil {
+		return candidacies, err
+	}
+
+	for _, candidacy := range found {
+		profile := CandidateProfile{
+			CandidacyId:  candidacy.Id,
+			Constituency: candidacy.Constituency,
+			ListPosition: candidacy.ListPosition,
+		}
+		if candidacy.Party != nil {
+			profile.Party, profile.Abbreviation = candidacy.Party.Name, candidacy.Party.Abbreviation
+			profile.Image, profile.Color = candidacy.Party.Logo, candidacy.Party.Color.Hex
+			candidacies.Names[utilities.Fold(candidacy.Party.Name)] = candidacy.Id
+			candidacies.Names[utilities.Fold(candidacy.Party.Abbreviation)] = candidacy.Id
+		}
+		if candidacy.Individual != nil {
+			name := candidacy.Individual.FirstName + " " + candidacy.Individual.LastName
+			profile.FirstName, profile.LastName = candidacy.Individual.FirstName, candidacy.Individual.LastName
+			profile.Affiliation, profile.Image = candidacy.Individual.Affiliation, candidacy.Individual.Image
+			profile.Color = colors.Individual(candidacy.Individual.FirstName, candidacy.Individual.LastName)
+			candidacies.Names[utilities.Fold(name)] = candidacy.Id
+		}
+		candidacies.Profiles[candidacy.Id] = profile
+	}
+
+	// Set the candidacies to the cache
+	if err := CacheSet(cacheKey, candidacies); err != nil {
+		return candidacies, err
+	}
+	return candidacies, nil
+}
@@ -5,7 +5,9 @@ import "go.mongodb.org/mongo-driver/bson/primitive"
 // Model for the city
 type City struct {
 	Id             primitive.ObjectID `json:"_id" bson:"_id"`
-	Name           string             `json:"name" bson:"name" validate:"required"`             // Ankara
+	Code           string             `json:"code" bson:"code"`                                 // 6 (geography registry)
+	Name           string             `json:"name" bson:"name" validate:"required"`             // ankara
+	ReadableName   string             `json:"readablename" bson:"readablename"`                 // Ankara
 	Number         int64              `json:"number" bson:"number" validate:"required,numeric"` // 6
 	Parties        []PartyInBox       `json:"parties" bson:"parties"`
 	Individuals    []IndividualInBox  `json:"individuals" bson:"individuals"`
@@ -14,3 +16,11 @@ type City struct {
 	ValidVotes     int64              `json:"validvotes" bson:"validvotes" validate:"numeric"`         // 10101
 	InvalidVotes   int64              `json:"invalidvotes" bson:"invalidvotes" validate:"numeric"`     // 161
 }
+
+// Get the cache tags of all entries which contain the city
+func CityCacheTags(city City) []string {
+	return []string{
+		DocumentTag("cities", city.Id.Hex()),
+		ListTag("cities", "all"),
+	}
+}
@@ -5,8 +5,11 @@ import "go.mongodb.org/mongo-driver/bson/primitive"
 // Model for the district
 type District struct {
 	Id             primitive.ObjectID `json:"_id" bson:"_id"`
-	Name           string             `json:"name" bson:"name"`                 // Cankaya
-	City           string             `json:"city" bson:"city"`                 // Ankara
+	Code           string             `json:"code" bson:"code"`                 // 1231 (geography registry)
+	Name           string             `json:"name" bson:"name"`                 // cankaya
+	ReadableName   string             `json:"readablename" bson:"readablename"` // Çankaya
+	City           string             `json:"city" bson:"city"`                 // ankara
+	CityCode       string             `json:"citycode" bson:"citycode"`         // 6 (geography registry)
 	CityNumber     int64              `json:"citynumber" bson:"citynumber"`     // 6
 	Constituency   string             `json:"constituency" bson:"constituency"` // ankara-1
 	Parties        []PartyInBox       `json:"parties" bson:"parties"`
@@ -16,3 +19,13 @@ type District struct {
 	ValidVotes     int64              `json:"validvotes" bson:"validvotes"`         // 10101
 	InvalidVotes   int64              `json:"invalidvotes" bson:"invalidvotes"`     // 161
 }
+
+// Get the cache tags of all entries which contain the district
+func DistrictCacheTags(district District) []string {
+	return []string{
+		DocumentTag("districts", district.Id.Hex()),
+		ListTag("districts", "all"),
+		ListTag("districts", "city", district.City),
+		ListTag("districts", "constituency", district.City, district.Constituency),
+	}
+}
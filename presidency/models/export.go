@@ -0,0 +1,137 @@
+package models
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Model for a box or region in an export, contains the fields of all levels
+type ExportDocument struct {
+	Id             primitive.ObjectID `bson:"_id"`
+	Name           string             `bson:"name"`
+	City           string             `bson:"city"`
+	CityNumber     int64              `bson:"citynumber"`
+	Constituency   string             `bson:"constituency"`
+	District       string             `bson:"district"`
+	Quarter        string             `bson:"quarter"`
+	Number         int64              `bson:"number"`
+	Parties        []PartyInBox       `bson:"parties"`
+	Individuals    []IndividualInBox  `bson:"individuals"`
+	EligibleVoters int64              `bson:"eligiblevoters"`
+	ActualVoters   int64              `bson:"actualvoters"`
+	ValidVotes     int64              `bson:"validvotes"`
+	InvalidVotes   int64              `bson:"invalidvotes"`
+	SST            string             `bson:"sst"`
+	SDC            string             `bson:"sdc"`
+}
+
+// Model for a level which can be exported
+type ExportLevel struct {
+	Collection string   // boxes
+	Columns    []string // default columns without the parties and individuals
+	Sort       []string // fields the export is sorted by
+}
+
+// All levels which can be exported
+var ExportLevels = map[string]ExportLevel{
+	"boxes": {
+		Collection: "boxes",
+		Columns:    []string{"city", "citynumber", "constituency", "district", "quarter", "number", "eligiblevoters", "actualvoters", "validvotes", "invalidvotes", "turnout", "sst", "sdc"},
+		Sort:       []string{"citynumber", "district", "quarter", "number"},
+	},
+	"cities": {
+		Collection: "cities",
+		Columns:    []string{"name", "number", "eligiblevoters", "actualvoters", "validvotes", "invalidvotes", "turnout"},
+		Sort:       []string{"number"},
+	},
+	"constituencies": {
+		Collection: "constituencies",
+		Columns:    []string{"name", "city", "citynumber", "eligiblevoters", "actualvoters", "validvotes", "invalidvotes", "turnout"},
+		Sort:       []string{"citynumber", "name"},
+	},
+	"districts": {
+		Collection: "districts",
+		Columns:    []string{"name", "city", "citynumber", "constituency", "eligiblevoters", "actualvoters", "validvotes", "invalidvotes", "turnout"},
+		Sort:       []string{"citynumber", "name"},
+	},
+	"quarters": {
+		Collection: "quarters",
+		Columns:    []string{"name", "city", "citynumber", "constituency", "district", "eligiblevoters", "actualvoters", "validvotes", "invalidvotes", "turnout"},
+		Sort:       []string{"citynumber", "district", "name"},
+	},
+}
+
+// Prefixes of the columns containing the votes of a party or an individual
+const (
+	PartyColumnPrefix      = "party:"
+	IndividualColumnPrefix = "individual:"
+)
+
+// Get the name of the column containing the votes of a party
+func PartyColumn(party PartyInBox) string {
+	return PartyColumnPrefix + party.Name
+}
+
+// Get the name of the column containing the votes of an individual
+func IndividualColumn(individual IndividualInBox) string {
+	return IndividualColumnPrefix + individual.FirstName + " " + individual.LastName
+}
+
+// Get the value of a column of the document, unknown columns are empty
+func (document ExportDocument) Value(column string) interface{} {
+	switch column {
+	case "_id":
+		return document.Id.Hex()
+	case "name":
+		return document.Name
+	case "city":
+		return document.City
+	case "citynumber":
+		return document.CityNumber
+	case "constituency":
+		return document.Constituency
+	case "district":
+		return document.District
+	case "quarter":
+		return document.Quarter
+	case "number":
+		return document.Number
+	case "eligiblevoters":
+		return document.EligibleVoters
+	case "actualvoters":
+		return document.ActualVoters
+	case "validvotes":
+		return document.ValidVotes
+	case "invalidvotes":
+		return document.InvalidVotes
+	case "turnout":
+		if document.EligibleVoters == 0 {
+			return 0.0
+		}
+		return float64(document.ActualVoters*10000/document.EligibleVoters) / 100
+	case "sst":
+		return document.SST
+	case "sdc":
+		return document.SDC
+	}
+
+	// Votes of a party or an individual
+	if strings.HasPrefix(column, PartyColumnPrefix) {
+		for _, party := range document.Parties {
+			if PartyColumn(party) == column {
+				return party.Votes
+			}
+		}
+		return int64(0)
+	}
+	if strings.HasPrefix(column, IndividualColumnPrefix) {
+		for _, individual := range document.Individuals {
+			if IndividualColumn(individual) == column {
+				return individual.Votes
+			}
+		}
+		return int64(0)
+	}
+	return ""
+}
@@ -0,0 +1,345 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yzaimoglu/election/presidency/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Levels of the administrative geography
+const (
+	GeographyProvince = "province"
+	GeographyDistrict = "district"
+	GeographyQuarter  = "quarter"
+)
+
+// Error returned if a region references a unit which is not in the geography registry
+var ErrUnknownGeography = errors.New("unknown region")
+
+// Model for an administrative unit of the geography registry
+type GeographyUnit struct {
+	Id           primitive.ObjectID `json:"_id" bson:"_id"`
+	Code         string             `json:"code" bson:"code" validate:"required"`                                   // 1231 (official id, the plate code for provinces)
+	Level        string             `json:"level" bson:"level" validate:"required,oneof=province district quarter"` // district
+	Type         string             `json:"type" bson:"type"`                                                       // neighbourhood or village for quarters
+	Name         string             `json:"name" bson:"name" validate:"required"`                                   // cankaya
+	ReadableName string             `json:"readablename" bson:"readablename" validate:"required"`                   // Çankaya
+	ParentCode   string             `json:"parentcode" bson:"parentcode"`                                           // 6
+	Constituency string             `json:"constituency" bson:"constituency"`                                       // ankara-1 (for districts)
+}
+
+// Resolver for references to the geography registry, caches the looked up units
+type GeographyResolver struct {
+	ctx        context.Context
+	collection *mongo.Collection
+	units      map[string]*GeographyUnit
+	seeded     map[string]bool
+}
+
+// Create a new resolver for the geography registry
+func NewGeographyResolver(ctx context.Context) *GeographyResolver {
+	return &GeographyResolver{
+		ctx:        ctx,
+		collection: mongoClient.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("geography"),
+		units:      map[string]*GeographyUnit{},
+		seeded:     map[string]bool{},
+	}
+}
+
+// Resolve a unit by its code or else by its name or readable name below the parent, the name is
+// compared as a slug so that "Cankaya" and "ÇANKAYA" both resolve to Çankaya
+//
+// Returns nil if no unit of the level has been registered yet, so that the services
+// keep working before the registry has been seeded.
+func (resolver *GeographyResolver) Resolve(level string, code string, name string, parentCode string) (*GeographyUnit, error) {
+	// Check if the level has been seeded
+	seeded, ok := resolver.seeded[level]
+	if !ok {
+		count, err := resolver.collection.CountDocuments(resolver.ctx, bson.M{"level": level}, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, err
+		}
+		seeded = count > 0
+		resolver.seeded[level] = seeded
+	}
+	if !seeded {
+		return nil, nil
+	}
+
+	// Check if the unit has already been looked up
+	cacheKey := level + ":" + code + ":" + name + ":" + parentCode
+	if unit, ok := resolver.units[cacheKey]; ok {
+		return unit, nil
+	}
+
+	// Initialize $and filter
+	var filter []bson.M
+	filter = append(filter, bson.M{"level": level})
+	if code != "" {
+		filter = append(filter, bson.M{"code": code})
+	} else {
+		filter = append(filter, bson.M{"$or": []bson.M{{"name": utilities.Slug(name)}, {"readablename": name}}})
+	}
+	if parentCode != "" {
+		filter = append(filter, bson.M{"parentcode": parentCode})
+	}
+
+	// Get the unit
+	var unit GeographyUnit
+	if err := resolver.collection.FindOne(resolver.ctx, bson.M{"$and": filter}).Decode(&unit); err != nil {
+		if err == mongo.ErrNoDocuments {
+			reference := name
+			if code != "" {
+				reference = code
+			}
+			return nil, fmt.Errorf("%w: %s %s is not in the geography registry", ErrUnknownGeography, level, reference)
+		}
+		return nil, err
+	}
+	resolver.units[cacheKey] = &unit
+	return &unit, nil
+}
+
+// Resolve the province, district and quarter of a region, the parents are resolved first
+func (resolver *GeographyResolver) resolveRegion(cityCode string, city string, districtCode string, district string, quarterCode string, quarter string) (province *GeographyUnit, districtUnit *GeographyUnit, quarterUnit *GeographyUnit, err error) {
+	if city != "" || cityCode != "" {
+		if province, err = resolver.Resolve(GeographyProvince, cityCode, city, ""); err != nil {
+			return
+		}
+	}
+	if district != "" || districtCode != "" {
+		parentCode := ""
+		if province != nil {
+			parentCode = province.Code
+		}
+		if districtUnit, err = resolver.Resolve(GeographyDistrict, districtCode, district, parentCode); err != nil {
+			return
+		}
+	}
+	if quarter != "" || quarterCode != "" {
+		parentCode := ""
+		if districtUnit != nil {
+			parentCode = districtUnit.Code
+		}
+		quarterUnit, err = resolver.Resolve(GeographyQuarter, quarterCode, quarter, parentCode)
+	}
+	return
+}
+
+// Resolve the geography references of a box, the names are stored as slugs and taken from the registry
+func (resolver *GeographyResolver) ResolveBox(box *Box) error {
+	box.City, box.Constituency, box.District, box.Quarter = utilities.Slug(box.City), utilities.Slug(box.Constituency), utilities.Slug(box.District), utilities.Slug(box.Quarter)
+	province, district, quarter, err := resolver.resolveRegion(box.CityCode, box.City, box.DistrictCode, box.District, box.QuarterCode, box.Quarter)
+	if err != nil {
+		return err
+	}
+	if province != nil {
+		box.CityCode, box.City, box.CityNumber = province.Code, province.Name, provinceNumber(province)
+	}
+	if district != nil {
+		box.DistrictCode, box.District = district.Code, district.Name
+		if district.Constituency != "" {
+			box.Constituency = district.Constituency
+		}
+	}
+	if quarter != nil {
+		box.QuarterCode, box.Quarter = quarter.Code, quarter.Name
+	}
+	return nil
+}
+
+// Resolve the geography reference of a city, the names are taken from the registry
+func (resolver *GeographyResolver) ResolveCity(city *City) error {
+	city.Name = utilities.Slug(city.Name)
+	province, _, _, err := resolver.resolveRegion(city.Code, city.Name, "", "", "", "")
+	if err != nil {
+		return err
+	}
+	if province != nil {
+		city.Code, city.Name, city.ReadableName, city.Number = province.Code, province.Name, province.ReadableName, provinceNumber(province)
+	}
+	return nil
+}
+
+// Resolve the geography reference of a constituency, the city is taken from the registry
+func (resolver *GeographyResolver) ResolveConstituency(constituency *Constituency) error {
+	constituency.Name, constituency.City = utilities.Slug(constituency.Name), utilities.Slug(constituency.City)
+	province, _, _, err := resolver.resolveRegion(constituency.CityCode, constituency.City, "", "", "", "")
+	if err != nil {
+		return err
+	}
+	if province != nil {
+		constituency.CityCode, constituency.City, constituency.CityNumber = province.Code, province.Name, provinceNumber(province)
+	}
+	return nil
+}
+
+// Resolve the geography references of a district, the names are taken from the registry
+func (resolver *GeographyResolver) ResolveDistrict(district *District) error {
+	district.Name, district.City, district.Constituency = utilities.Slug(district.Name), utilities.Slug(district.City), utilities.Slug(district.Constituency)
+	province, districtUnit, _, err := resolver.resolveRegion(district.CityCode, district.City, district.Code, district.Name, "", "")
+	if err != nil {
+		return err
+	}
+	if province != nil {
+		district.CityCode, district.City, district.CityNumber = province.Code, province.Name, provinceNumber(province)
+	}
+	if districtUnit != nil {
+		district.Code, district.Name, district.ReadableName = districtUnit.Code, districtUnit.Name, districtUnit.ReadableName
+		if districtUnit.Constituency != "" {
+			district.Constituency = districtUnit.Constituency
+		}
+	}
+	return nil
+}
+
+// Resolve the geography references of a quarter, the names are taken from the registry
+func (resolver *GeographyResolver) ResolveQuarter(quarter *Quarter) error {
+	quarter.Name, quarter.City, quarter.Constituency, quarter.District = utilities.Slug(quarter.Name), utilities.Slug(quarter.City), utilities.Slug(quarter.Constituency), utilities.Slug(quarter.District)
+	province, district, quarterUnit, err := resolver.resolveRegion(quarter.CityCode, quarter.City, quarter.DistrictCode, quarter.District, quarter.Code, quarter.Name)
+	if err != nil {
+		return err
+	}
+	if province != nil {
+		quarter.CityCode, quarter.City, quarter.CityNumber = province.Code, province.Name, provinceNumber(province)
+	}
+	if district != nil {
+		quarter.DistrictCode, quarter.District = district.Code, district.Name
+		if district.Constituency != "" {
+			quarter.Constituency = district.Constituency
+		}
+	}
+	if quarterUnit != nil {
+		quarter.Code, quarter.Name, quarter.ReadableName = quarterUnit.Code, quarterUnit.Name, quarterUnit.ReadableName
+	}
+	return nil
+}
+
+// Get the plate number of a province from its code
+func provinceNumber(province *GeographyUnit) int64 {
+	number, _ := strconv.ParseInt(province.Code, 10, 64)
+	return number
+}
+
+// Rename all regions and boxes referencing the unit after it has been corrected in the registry
+func RenameGeography(ctx context.Context, unit GeographyUnit) error {
+	database := mongoClient.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi"))
+
+	// Initialize the updates of the collections referencing the unit
+	type rename struct {
+		collection string
+		filter     bson.M
+		update     bson.M
+	}
+	var renames []rename
+	switch unit.Level {
+	case GeographyProvince:
+		renames = append(renames, rename{"cities", bson.M{"code": unit.Code}, bson.M{"name": unit.Name, "readablename": unit.ReadableName}})
+		for _, collection := range []string{"constituencies", "districts", "quarters", "boxes", "stations"} {
+			renames = append(renames, rename{collection, bson.M{"citycode": unit.Code}, bson.M{"city": unit.Name}})
+		}
+	case GeographyDistrict:
+		renames = append(renames, rename{"districts", bson.M{"code": unit.Code}, bson.M{"name": unit.Name, "readablename": unit.ReadableName}})
+		for _, collection := range []string{"quarters", "boxes", "stations"} {
+			renames = append(renames, rename{collection, bson.M{"districtcode": unit.Code}, bson.M{"district": unit.Name}})
+		}
+		if unit.Constituency != "" {
+			renames = append(renames, rename{"districts", bson.M{"code": unit.Code}, bson.M{"constituency": unit.Constituency}})
+			for _, collection := range []string{"quarters", "boxes", "stations"} {
+				renames = append(renames, rename{collection, bson.M{"districtcode": unit.Code}, bson.M{"constituency": unit.Constituency}})
+			}
+		}
+	case GeographyQuarter:
+		renames = append(renames, rename{"quarters", bson.M{"code": unit.Code}, bson.M{"name": unit.Name, "readablename": unit.ReadableName}})
+		for _, collection := range []string{"boxes", "stations"} {
+			renames = append(renames, rename{collection, bson.M{"quartercode": unit.Code}, bson.M{"quarter": unit.Name}})
+		}
+	}
+
+	// Update the documents
+	for _, rename := range renames {
+		if _, err := database.Collection(rename.collection).UpdateMany(ctx, rename.filter, bson.M{"$set": rename.update}); err != nil {
+			return err
+		}
+	}
+
+	// The cached entries are keyed by the old names, so the whole cache of the service is flushed
+	return CacheFlush()
+}
+
+// Load the geography registry from the rows of a csv or xlsx file, existing units are updated
+//
+//	code, level, type, name, readablename, parentcode, constituency
+func SeedGeography(ctx context.Context, rows [][]string) (ImportReport, error) {
+	report := ImportReport{
+		Kind:   "geography",
+		Errors: []ImportRowError{},
+	}
+	if len(rows) == 0 {
+		report.Errors = append(report.Errors, ImportRowError{Row: 1, Message: "the file is empty"})
+		return report, nil
+	}
+
+	// Read the header
+	header := map[string]int{}
+	for index, column := range rows[0] {
+		header[strings.ToLower(strings.TrimSpace(column))] = index
+	}
+
+	// Parse and validate all rows
+	var documents []importDocument
+	for index, values := range rows[1:] {
+		row := &importRow{line: index + 2, columns: rows[0], header: header, values: values}
+		if row.empty() {
+			continue
+		}
+		report.Rows++
+		unit := GeographyUnit{
+			Code:         row.text("code", true),
+			Level:        row.text("level", true),
+			Type:         row.text("type", false),
+			Name:         row.text("name", true),
+			ReadableName: row.text("readablename", true),
+			ParentCode:   row.text("parentcode", false),
+			Constituency: row.text("constituency", false),
+		}
+		if unit.Level != GeographyProvince && unit.Level != GeographyDistrict && unit.Level != GeographyQuarter {
+			row.fail("level", "must be province, district or quarter")
+		}
+		if unit.Level != GeographyProvince && unit.ParentCode == "" {
+			row.fail("parentcode", "must not be empty for districts and quarters")
+		}
+		if len(row.errors) > 0 {
+			report.Errors = append(report.Errors, row.errors...)
+			report.Skipped++
+			continue
+		}
+		documents = append(documents, importDocument{
+			row: row.line,
+			key: bson.M{"level": unit.Level, "code": unit.Code},
+			fields: bson.M{
+				"code":         unit.Code,
+				"level":        unit.Level,
+				"type":         unit.Type,
+				"name":         unit.Name,
+				"readablename": unit.ReadableName,
+				"parentcode":   unit.ParentCode,
+				"constituency": unit.Constituency,
+			},
+			tags: func(id primitive.ObjectID) []string { return nil },
+		})
+	}
+
+	// Insert or update the units
+	collection := mongoClient.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("geography")
+	_, err := writeImport(ctx, collection, documents, &report)
+	return report, err
+}
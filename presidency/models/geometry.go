@@ -0,0 +1,253 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/yzaimoglu/election/presidency/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Model for the geometry of a city, district or quarter, loaded from GeoJSON files
+type RegionGeometry struct {
+	Id       primitive.ObjectID `json:"_id" bson:"_id"`
+	Level    string             `json:"level" bson:"level"`       // district
+	Code     string             `json:"code" bson:"code"`         // 1231 (geography registry)
+	City     string             `json:"city" bson:"city"`         // ankara (empty for cities)
+	District string             `json:"district" bson:"district"` // cankaya (only for quarters)
+	Name     string             `json:"name" bson:"name"`         // cankaya
+	Geometry bson.M             `json:"geometry" bson:"geometry"` // GeoJSON geometry
+}
+
+// Names of the feature properties which identify the region of a feature
+type GeometryProperties struct {
+	Code     string // property with the code of the geography registry
+	Name     string // property with the name of the region
+	City     string // property with the name of the city, if all features belong to the same city
+	District string // property with the name of the district, if all features belong to the same district
+}
+
+// Model for a GeoJSON feature collection
+type FeatureCollection struct {
+	Type       string    `json:"type"` // FeatureCollection
+	Features   []Feature `json:"features"`
+	NextCursor string    `json:"nextcursor,omitempty"` // cursor of the next page for paginated lists
+}
+
+// Model for a GeoJSON feature
+type Feature struct {
+	Type       string      `json:"type"` // Feature
+	Id         string      `json:"id,omitempty"`
+	Geometry   bson.M      `json:"geometry"`
+	Properties interface{} `json:"properties"`
+}
+
+// Model for a region on a map, contains the fields of cities, districts and quarters
+type MapRegion struct {
+	Id             primitive.ObjectID `bson:"_id"`
+	Code           string             `bson:"code"`
+	Name           string             `bson:"name"`
+	ReadableName   string             `bson:"readablename"`
+	Parties        []PartyInBox       `bson:"parties"`
+	Individuals    []IndividualInBox  `bson:"individuals"`
+	EligibleVoters int64              `bson:"eligiblevoters"`
+	ActualVoters   int64              `bson:"actualvoters"`
+	ValidVotes     int64              `bson:"validvotes"`
+}
+
+// Model for the properties of a region on a map
+type MapProperties struct {
+	Name         string         `json:"name"`         // cankaya
+	ReadableName string         `json:"readablename"` // Çankaya
+	Code         string         `json:"code"`         // 1231
+	Turnout      float64        `json:"turnout"`      // 81.31
+	Winner       string         `json:"winner"`       // Recep Tayyip Erdogan
+	WinnerVotes  int64          `json:"winnervotes"`  // 5121
+	Margin       float64        `json:"margin"`       // 12.4 (percentage points ahead of the second)
+	Color        string         `json:"color"`        // #ffa500 (color of the winner)
+	Party        string         `json:"party"`        // AKP (party with the most votes)
+	PartyColor   string         `json:"partycolor"`   // #ffa500
+	Individuals  []MapCandidate `json:"individuals"`
+	Parties      []MapCandidate `json:"parties"`
+}
+
+// Model for an individual or party of a region on a map
+type MapCandidate struct {
+	Name       string  `json:"name"`       // Recep Tayyip Erdogan
+	Votes      int64   `json:"votes"`      // 5121
+	Percentage float64 `json:"percentage"` // 52.12
+	Color      string  `json:"color"`      // #ffa500
+}
+
+// Get the map properties of a region, the individuals and parties are sorted by their votes
+func MapRegionProperties(region MapRegion, colors InfoColors) MapProperties {
+	properties := MapProperties{
+		Name:         region.Name,
+		ReadableName: region.ReadableName,
+		Code:         region.Code,
+		Turnout:      percentage(region.ActualVoters, region.EligibleVoters),
+		Individuals:  []MapCandidate{},
+		Parties:      []MapCandidate{},
+	}
+	for _, individual := range region.Individuals {
+		properties.Individuals = append(properties.Individuals, MapCandidate{
+			Name:       individual.FirstName + " " + individual.LastName,
+			Votes:      individual.Votes,
+			Percentage: percentage(individual.Votes, region.ValidVotes),
+			Color:      colors.Individual(individual.FirstName, individual.LastName),
+		})
+	}
+	for _, party := range region.Parties {
+		properties.Parties = append(properties.Parties, MapCandidate{
+			Name:       party.Name,
+			Votes:      party.Votes,
+			Percentage: percentage(party.Votes, region.ValidVotes),
+			Color:      colors.Party(party.Name),
+		})
+	}
+	sortMapCandidates(properties.Individuals)
+	sortMapCandidates(properties.Parties)
+
+	// The winners need at least one vote
+	if len(properties.Individuals) > 0 && properties.Individuals[0].Votes > 0 {
+		winner := properties.Individuals[0]
+		properties.Winner, properties.WinnerVotes, properties.Color = winner.Name, winner.Votes, winner.Color
+		properties.Margin = winner.Percentage
+		if len(properties.Individuals) > 1 {
+			properties.Margin = math.Round((winner.Percentage-properties.Individuals[1].Percentage)*100) / 100
+		}
+	}
+	if len(properties.Parties) > 0 && properties.Parties[0].Votes > 0 {
+		properties.Party, properties.PartyColor = properties.Parties[0].Name, properties.Parties[0].Color
+	}
+	return properties
+}
+
+// Sort the individuals or parties of a map by their votes
+func sortMapCandidates(candidates []MapCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Votes > candidates[j].Votes
+	})
+}
+
+// Get the percentage of a part of a total rounded to two decimals
+func percentage(part int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part*10000/total) / 100
+}
+
+// Load the geometries of a level from a GeoJSON feature collection, existing geometries are replaced
+//
+// The regions are identified by the given feature properties, their names are stored as slugs so
+// that they can be matched with the regions regardless of the spelling.
+func LoadGeometry(ctx context.Context, level string, data []byte, properties GeometryProperties, city string, district string) (ImportReport, error) {
+	report := ImportReport{
+		Kind:   "geometry",
+		Errors: []ImportRowError{},
+	}
+	if level != GeographyProvince && level != GeographyDistrict && level != GeographyQuarter {
+		return report, fmt.Errorf("level must be province, district or quarter")
+	}
+
+	// Parse the feature collection
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			Geometry   bson.M                     `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return report, err
+	}
+	if collection.Type != "FeatureCollection" {
+		return report, fmt.Errorf("the file is not a GeoJSON FeatureCollection")
+	}
+
+	// Validate the features
+	var documents []importDocument
+	for index, feature := range collection.Features {
+		report.Rows++
+		geometry := RegionGeometry{
+			Level:    level,
+			Code:     featureProperty(feature.Properties, properties.Code),
+			City:     utilities.Slug(city),
+			District: utilities.Slug(district),
+			Name:     utilities.Slug(featureProperty(feature.Properties, properties.Name)),
+			Geometry: feature.Geometry,
+		}
+		if properties.City != "" {
+			geometry.City = utilities.Slug(featureProperty(feature.Properties, properties.City))
+		}
+		if properties.District != "" {
+			geometry.District = utilities.Slug(featureProperty(feature.Properties, properties.District))
+		}
+
+		// Check if the region of the feature is known
+		var message string
+		switch {
+		case geometry.Name == "":
+			message = "the feature has no " + properties.Name + " property"
+		case level != GeographyProvince && geometry.City == "":
+			message = "the city of the feature is unknown"
+		case level == GeographyQuarter && geometry.District == "":
+			message = "the district of the feature is unknown"
+		case geometry.Geometry == nil:
+			message = "the feature has no geometry"
+		}
+		if message != "" {
+			report.Errors = append(report.Errors, ImportRowError{Row: index + 1, Message: message})
+			report.Skipped++
+			continue
+		}
+
+		documents = append(documents, importDocument{
+			row: index + 1,
+			key: bson.M{"level": geometry.Level, "city": geometry.City, "district": geometry.District, "name": geometry.Name},
+			fields: bson.M{
+				"level":    geometry.Level,
+				"code":     geometry.Code,
+				"city":     geometry.City,
+				"district": geometry.District,
+				"name":     geometry.Name,
+				"geometry": geometry.Geometry,
+			},
+			tags: func(id primitive.ObjectID) []string { return nil },
+		})
+	}
+
+	// Insert or replace the geometries
+	geometries := mongoClient.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi")).Collection("geometries")
+	if _, err := writeImport(ctx, geometries, documents, &report); err != nil {
+		return report, err
+	}
+
+	// Invalidate all cached maps of the level
+	if err := CacheInvalidate(ListTag("geometries", level)); err != nil {
+		log.Println("error invalidating the cache of the geometries: " + err.Error())
+	}
+	return report, nil
+}
+
+// Get a property of a feature as a string, numbers like codes are kept as they are written
+func featureProperty(properties map[string]json.RawMessage, name string) string {
+	value, ok := properties[name]
+	if !ok || name == "" {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(value, &text); err == nil {
+		return text
+	}
+	if string(value) == "null" {
+		return ""
+	}
+	return string(value)
+}
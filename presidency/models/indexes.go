@@ -0,0 +1,247 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yzaimoglu/election/presidency/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Model for the build status of an index
+type IndexStatus struct {
+	Collection string `json:"collection"`      // boxes
+	Name       string `json:"name"`            // city_1_district_1_number_1
+	Unique     bool   `json:"unique"`          // true
+	Ready      bool   `json:"ready"`           // true if the index exists in the database
+	Error      string `json:"error,omitempty"` // E11000 duplicate key error ...
+}
+
+// Model for the definition of a collection with its indexes and validator
+type collectionSchema struct {
+	Name      string
+	Indexes   []mongo.IndexModel
+	Validator bson.M
+	Dropped   []string // names of the indexes which have been replaced and are dropped if they exist
+}
+
+// Status of the last bootstrap, reported by the status route
+var (
+	indexStatusMutex sync.RWMutex
+	indexStatus      []IndexStatus
+)
+
+// Create a compound index on the given fields
+func compoundIndex(unique bool, fields ...string) mongo.IndexModel {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: 1})
+	}
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetUnique(unique)}
+}
+
+// Create a 2dsphere index on a field with GeoJSON points
+func geoIndex(field string) mongo.IndexModel {
+	return mongo.IndexModel{Keys: bson.D{{Key: field, Value: "2dsphere"}}, Options: options.Index()}
+}
+
+// Create a json schema validator requiring the given string and number fields
+func documentValidator(stringFields []string, numberFields []string) bson.M {
+	properties := bson.M{}
+	for _, field := range stringFields {
+		properties[field] = bson.M{"bsonType": "string"}
+	}
+	for _, field := range numberFields {
+		properties[field] = bson.M{"bsonType": bson.A{"int", "long"}}
+	}
+	return bson.M{"$jsonSchema": bson.M{
+		"bsonType":   "object",
+		"required":   append(append([]string{}, stringFields...), numberFields...),
+		"properties": properties,
+	}}
+}
+
+// Collections of the service with their indexes and validators
+var collectionSchemas = []collectionSchema{
+	{
+		Name: "cities",
+		Indexes: []mongo.IndexModel{
+			compoundIndex(true, "name"),
+			compoundIndex(true, "number"),
+			compoundIndex(false, "code"),
+		},
+		Validator: documentValidator([]string{"name"}, []string{"number"}),
+	},
+	{
+		Name: "constituencies",
+		Indexes: []mongo.IndexModel{
+			compoundIndex(true, "city", "name"),
+			compoundIndex(false, "citycode"),
+		},
+		Validator: documentValidator([]string{"name", "city"}, []string{"citynumber"}),
+	},
+	{
+		Name: "districts",
+		Indexes: []mongo.IndexModel{
+			compoundIndex(true, "city", "name"),
+			compoundIndex(false, "city", "constituency"),
+			compoundIndex(false, "code"),
+			compoundIndex(false, "citycode"),
+		},
+		Validator: documentValidator([]string{"name", "city", "constituency"}, []string{}),
+	},
+	{
+		Name: "quarters",
+		Indexes: []mongo.IndexModel{
+			compoundIndex(true, "city", "district", "name"),
+			compoundIndex(false, "code"),
+			compoundIndex(false, "citycode"),
+			compoundIndex(false, "districtcode"),
+		},
+		Validator: documentValidator([]string{"name", "city", "district"}, []string{}),
+	},
+	{
+		Name: "boxes",
+		Indexes: []mongo.IndexModel{
+			compoundIndex(true, "city", "district", "number"),
+			compoundIndex(false, "city", "constituency"),
+			compoundIndex(false, "city", "district", "quarter"),
+			compoundIndex(false, "citycode"),
+			compoundIndex(false, "districtcode"),
+			compoundIndex(false, "quartercode"),
+		},
+		Validator: documentValidator([]string{"city", "district", "quarter"}, []string{"number"}),
+	},
+	{
+		Name: "stations",
+		Indexes: []mongo.IndexModel{
+			geoIndex("location"),
+			compoundIndex(true, "city", "district", "quarter", "name"),
+			compoundIndex(false, "city", "district", "boxes"),
+		},
+		Validator: documentValidator([]string{"name", "city", "district", "quarter"}, []string{}),
+		Dropped:   []string{"city_1_district_1_name_1", "city_1_district_1_quarter_1"},
+	},
+	{
+		Name: "geography",
+		Indexes: []mongo.IndexModel{
+			compoundIndex(true, "level", "code"),
+			compoundIndex(false, "level", "parentcode", "name"),
+			compoundIndex(false, "level", "readablename"),
+		},
+		Validator: documentValidator([]string{"level", "code", "name", "readablename"}, []string{}),
+	},
+	{
+		Name: "geometries",
+		Indexes: []mongo.IndexModel{
+			compoundIndex(true, "level", "city", "district", "name"),
+		},
+		Validator: documentValidator([]string{"level", "city", "district", "name"}, []string{}),
+	},
+}
+
+// Create the collections with their validators and indexes, failures are logged and reported in the status
+func BootstrapDatabase() []IndexStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	database := mongoClient.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi"))
+	var status []IndexStatus
+
+	// Get the existing collections
+	existing, err := database.ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		log.Println("error listing the collections: " + err.Error())
+	}
+
+	for _, schema := range collectionSchemas {
+		// Create the collection with the validator or apply the validator to the existing collection
+		if contains(existing, schema.Name) {
+			err = database.RunCommand(ctx, bson.D{
+				{Key: "collMod", Value: schema.Name},
+				{Key: "validator", Value: schema.Validator},
+				{Key: "validationLevel", Value: "moderate"},
+			}).Err()
+		} else {
+			err = database.CreateCollection(ctx, schema.Name, options.CreateCollection().
+				SetValidator(schema.Validator).
+				SetValidationLevel("moderate"))
+		}
+		if err != nil {
+			log.Println("error applying the validator to " + schema.Name + ": " + err.Error())
+		}
+
+		// Drop the replaced indexes, e.g. a unique index which has been extended by a field
+		for _, name := range schema.Dropped {
+			if _, err := database.Collection(schema.Name).Indexes().DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+				log.Println("error dropping the index " + name + " on " + schema.Name + ": " + err.Error())
+			}
+		}
+
+		// Create the indexes one by one so that a single failing index does not hide the others
+		for _, index := range schema.Indexes {
+			built := IndexStatus{
+				Collection: schema.Name,
+				Unique:     index.Options.Unique != nil && *index.Options.Unique,
+			}
+			name, err := database.Collection(schema.Name).Indexes().CreateOne(ctx, index)
+			if err != nil {
+				log.Println("error creating an index on " + schema.Name + ": " + err.Error())
+				built.Name = indexName(index)
+				built.Error = err.Error()
+			} else {
+				built.Name = name
+				built.Ready = true
+			}
+			status = append(status, built)
+		}
+	}
+
+	// Save the status for the status route
+	indexStatusMutex.Lock()
+	indexStatus = status
+	indexStatusMutex.Unlock()
+
+	return status
+}
+
+// Get the index status of the last bootstrap
+func GetIndexStatus() []IndexStatus {
+	indexStatusMutex.RLock()
+	defer indexStatusMutex.RUnlock()
+	return indexStatus
+}
+
+// Get the default name mongo gives to an index
+func indexName(index mongo.IndexModel) string {
+	name := ""
+	for _, key := range index.Keys.(bson.D) {
+		if name != "" {
+			name += "_"
+		}
+		name += key.Key + "_" + fmt.Sprint(key.Value)
+	}
+	return name
+}
+
+// Check if an error is returned because an index or its collection does not exist
+func isIndexNotFound(err error) bool {
+	var commandError mongo.CommandError
+	return errors.As(err, &commandError) && (commandError.Name == "IndexNotFound" || commandError.Name == "NamespaceNotFound")
+}
+
+// Check if a slice contains a string
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yzaimoglu/election/presidency/utilities"
+)
+
+// Colors of the parties and individuals of the info service, the keys are the folded names
+type InfoColors struct {
+	Parties     map[string]string `json:"parties"`     // chp -> #ed1c24
+	Individuals map[string]string `json:"individuals"` // kemal kilicdaroglu -> #ed1c24
+}
+
+// Model for a party of the info service
+type infoParty struct {
+	Name         string `json:"name"`
+	Abbreviation string `json:"abbreviation"`
+	Color        struct {
+		Hex string `json:"hex"`
+	} `json:"color"`
+}
+
+// Model for an individual of the info service
+type infoIndividual struct {
+	FirstName   string `json:"firstname"`
+	LastName    string `json:"lastname"`
+	Affiliation string `json:"affiliation"`
+	Color       struct {
+		Hex string `json:"hex"`
+	} `json:"color"`
+}
+
+// Get the color of a party by its name or abbreviation, returns an empty string if it is unknown
+func (colors InfoColors) Party(name string) string {
+	return colors.Parties[utilities.Fold(name)]
+}
+
+// Get the color of an individual by the name, returns an empty string if it is unknown
+func (colors InfoColors) Individual(firstName string, lastName string) string {
+	return colors.Individuals[utilities.Fold(firstName+" "+lastName)]
+}
+
+// Get the colors of all parties and individuals from the info service, the colors are cached
+//
+// Individuals without an own color get the color of the party they are affiliated with.
+func GetInfoColors() (InfoColors, error) {
+	colors := InfoColors{
+		Parties:     map[string]string{},
+		Individuals: map[string]string{},
+	}
+	cacheKey := CacheKey("colors")
+
+	// Check if the colors have been cached if so return
+	if CacheGet(cacheKey, &colors) {
+		return colors, nil
+	}
+
+	// Get the parties and individuals
+	parties, err := getInfoPages[infoParty]("/parties/", url.Values{})
+	if err != nil {
+		return colors, err
+	}
+	individuals, err := getInfoPages[infoIndividual]("/individuals/", url.Values{})
+	if err != nil {
+		return colors, err
+	}
+
+	for _, party := range parties {
+		colors.Parties[utilities.Fold(party.Name)] = party.Color.Hex
+		colors.Parties[utilities.Fold(party.Abbreviation)] = party.Color.Hex
+	}
+	for _, individual := range individuals {
+		color := individual.Color.Hex
+		if color == "" {
+			color = colors.Party(individual.Affiliation)
+		}
+		colors.Individuals[utilities.Fold(individual.FirstName+" "+individual.LastName)] = color
+	}
+
+	// Set the colors to the cache
+	if err := CacheSet(cacheKey, colors); err != nil {
+		return colors, err
+	}
+	return colors, nil
+}
+
+// Search the parties and individuals of the info service, the results are ranked by the info service
+func SearchInfo(query string, types []string, limit int) ([]SearchResult, error) {
+	// Initialize the HTTP Client
+	client := http.Client{
+		Timeout: time.Second * 5,
+	}
+
+	// Get the results
+	parameters := url.Values{}
+	parameters.Set("q", query)
+	parameters.Set("types", strings.Join(types, ","))
+	parameters.Set("limit", strconv.Itoa(limit))
+	response, err := client.Get(utilities.GetEnv("CB_INFO_URL", "http://localhost:82/v1") + "/search/?" + parameters.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("info service responded with %d to /search/", response.StatusCode)
+	}
+
+	// Decode the results
+	var results struct {
+		Data []SearchResult `json:"data"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&results); err != nil {
+		return nil, err
+	}
+	return results.Data, nil
+}
+
+// Get all pages of a list endpoint of the info service, the parameters are added to every request
+func getInfoPages[T any](path string, parameters url.Values) ([]T, error) {
+	// Initialize the HTTP Client
+	client := http.Client{
+		Timeout: time.Second * 10,
+	}
+
+	var documents []T
+	cursor := ""
+	for {
+		// Get the next page
+		query := url.Values{}
+		for key, values := range parameters {
+			query[key] = values
+		}
+		query.Set("limit", "1000")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		response, err := client.Get(utilities.GetEnv("CB_INFO_URL", "http://localhost:82/v1") + path + "?" + query.Encode())
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode != http.StatusOK {
+			response.Body.Close()
+			return nil, fmt.Errorf("info service responded with %d to %s", response.StatusCode, path)
+		}
+
+		// Decode the page
+		var page struct {
+			Data       []T    `json:"data"`
+			NextCursor string `json:"nextcursor"`
+		}
+		err = json.NewDecoder(response.Body).Decode(&page)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, page.Data...)
+
+		// Stop after the last page
+		if page.NextCursor == "" {
+			return documents, nil
+		}
+		cursor = page.NextCursor
+	}
+}
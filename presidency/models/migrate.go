@@ -0,0 +1,131 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yzaimoglu/election/presidency/utilities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Name of the migration which stores the region names as slugs
+const regionSlugMigration = "region-slugs"
+
+// Model for a migration which has been applied to the database
+type appliedMigration struct {
+	Name      string `bson:"_id"`       // region-slugs
+	AppliedAt int64  `bson:"appliedat"` // 1682892000000
+}
+
+// Collections with the fields which contain region names, the readable name is kept for the levels of the registry
+var regionNameMigrations = []struct {
+	collection string
+	fields     []string
+	level      string // level of the registry of the name, empty if the collection has no readable name
+	readable   bool
+}{
+	{"cities", []string{"name"}, GeographyProvince, true},
+	{"constituencies", []string{"name", "city"}, "", false},
+	{"districts", []string{"name", "city", "constituency"}, GeographyDistrict, true},
+	{"quarters", []string{"name", "city", "constituency", "district"}, GeographyQuarter, true},
+	{"boxes", []string{"city", "constituency", "district", "quarter"}, "", false},
+	{"stations", []string{"city", "constituency", "district", "quarter"}, "", false},
+}
+
+// Store the region names of the documents written before they were stored as slugs, e.g. "Çankaya" becomes
+// "cankaya" and is kept as the readable name if there is none in the document or the geography registry
+//
+// The migration runs once before the indexes are built and is run again on the next start if it fails.
+func MigrateRegionNames() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	database := mongoClient.Database(utilities.GetEnv("CB_DB_DATABASE", "cumhurbaskanligi"))
+	migrations := database.Collection("migrations")
+
+	// Skip the migration if it has been applied already
+	if err := migrations.FindOne(ctx, bson.M{"_id": regionSlugMigration}).Err(); err == nil {
+		return nil
+	} else if err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	// Migrate the collections
+	resolver := NewGeographyResolver(ctx)
+	for _, migration := range regionNameMigrations {
+		if err := migrateRegionNames(ctx, database.Collection(migration.collection), migration.fields, migration.level, migration.readable, resolver); err != nil {
+			return fmt.Errorf("error migrating the region names of %s: %w", migration.collection, err)
+		}
+	}
+
+	// Remember the migration
+	_, err := migrations.InsertOne(ctx, appliedMigration{Name: regionSlugMigration, AppliedAt: utilities.GetCurrentTime()})
+	return err
+}
+
+// Replace the region names of the documents of a collection by their slugs, the documents are updated in batches
+func migrateRegionNames(ctx context.Context, collection *mongo.Collection, fields []string, level string, readable bool, resolver *GeographyResolver) error {
+	projection := bson.M{"code": 1, "readablename": 1}
+	for _, field := range fields {
+		projection[field] = 1
+	}
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var updates []mongo.WriteModel
+	write := func() error {
+		if len(updates) == 0 {
+			return nil
+		}
+		_, err := collection.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false))
+		updates = updates[:0]
+		return err
+	}
+	for cursor.Next(ctx) {
+		var document bson.M
+		if err := cursor.Decode(&document); err != nil {
+			return err
+		}
+
+		// Slug the names which are not slugs yet
+		set := bson.M{}
+		for _, field := range fields {
+			value, _ := document[field].(string)
+			if slug := utilities.Slug(value); slug != value {
+				set[field] = slug
+			}
+		}
+
+		// Keep the readable name, it is taken from the registry if the region has a code and else from the old name
+		if readableName, _ := document["readablename"].(string); readable && readableName == "" {
+			name, _ := document["name"].(string)
+			if code, _ := document["code"].(string); code != "" && level != "" {
+				if unit, err := resolver.Resolve(level, code, "", ""); err == nil && unit != nil {
+					name = unit.ReadableName
+				}
+			}
+			if name != "" {
+				set["readablename"] = name
+			}
+		}
+
+		if len(set) > 0 {
+			updates = append(updates, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": document["_id"]}).SetUpdate(bson.M{"$set": set}))
+		}
+		if len(updates) == 1000 {
+			if err := write(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return write()
+}
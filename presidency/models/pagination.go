@@ -0,0 +1,9 @@
+package models
+
+// Model for a page of a list endpoint
+type Page struct {
+	Data       interface{} `json:"data"`       // [{...}, {...}]
+	Total      int64       `json:"total"`      // 81 (number of documents matching the filters)
+	Limit      int64       `json:"limit"`      // 50
+	NextCursor string      `json:"nextcursor"` // empty on the last page
+}
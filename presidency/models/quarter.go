@@ -5,11 +5,15 @@ import "go.mongodb.org/mongo-driver/bson/primitive"
 // Model for the quarter
 type Quarter struct {
 	Id             primitive.ObjectID `json:"_id" bson:"_id"`
-	Name           string             `json:"name" bson:"name"`                 // Cevizlidere
-	City           string             `json:"city" bson:"city"`                 // Ankara
+	Code           string             `json:"code" bson:"code"`                 // 40123 (geography registry)
+	Name           string             `json:"name" bson:"name"`                 // cevizlidere
+	ReadableName   string             `json:"readablename" bson:"readablename"` // Cevizlidere
+	City           string             `json:"city" bson:"city"`                 // ankara
+	CityCode       string             `json:"citycode" bson:"citycode"`         // 6 (geography registry)
 	CityNumber     int64              `json:"citynumber" bson:"citynumber"`     // 6
-	Constituency   string             `json:"constituency" bson:"constituency"` // Ankara-1
-	District       string             `json:"district" bson:"district"`         // Cankaya
+	Constituency   string             `json:"constituency" bson:"constituency"` // ankara-1
+	DistrictCode   string             `json:"districtcode" bson:"districtcode"` // 1231 (geography registry)
+	District       string             `json:"district" bson:"district"`         // cankaya
 	Parties        []PartyInBox       `json:"parties" bson:"parties"`
 	Individuals    []IndividualInBox  `json:"individuals" bson:"individuals"`
 	EligibleVoters int64              `json:"eligiblevoters" bson:"eligiblevoters"` // 12621
@@ -17,3 +21,12 @@ type Quarter struct {
 	ValidVotes     int64              `json:"validvotes" bson:"validvotes"`         // 10101
 	InvalidVotes   int64              `json:"invalidvotes" bson:"invalidvotes"`     // 161
 }
+
+// Get the cache tags of all entries which contain the quarter
+func QuarterCacheTags(quarter Quarter) []string {
+	return []string{
+		DocumentTag("quarters", quarter.Id.Hex()),
+		ListTag("quarters", "all"),
+		ListTag("quarters", "district", quarter.City, quarter.District),
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Model for the results
+type Result struct {
+	Location    string               `json:"location" bson:"location"`
+	Parties     []PartyInResult      `json:"parties" bson:"parties"`
+	Individuals []IndividualInResult `json:"individuals" bson:"individuals"`
+}
+
+// Model for the party in a result
+type PartyInResult struct {
+	CandidacyId string            `json:"candidacyid,omitempty" bson:"candidacyid,omitempty"`
+	Name        string            `json:"name" bson:"name"`
+	Votes       int64             `json:"votes" bson:"votes"`
+	Percentage  float64           `json:"percentage" bson:"percentage"`
+	Candidate   *CandidateProfile `json:"candidate,omitempty" bson:"-"` // embedded with ?embed=candidate
+}
+
+// Model for the individual in a result
+type IndividualInResult struct {
+	CandidacyId string            `json:"candidacyid,omitempty" bson:"candidacyid,omitempty"`
+	FirstName   string            `json:"firstname" bson:"firstname"`
+	LastName    string            `json:"lastname" bson:"lastname"`
+	Votes       int64             `json:"votes" bson:"votes"`
+	Percentage  float64           `json:"percentage" bson:"percentage"`
+	Candidate   *CandidateProfile `json:"candidate,omitempty" bson:"-"` // embedded with ?embed=candidate
+}
+
+// Model for the votes of a region or box, contains the fields of cities, districts, quarters and boxes
+type ResultVotes struct {
+	Id          primitive.ObjectID `bson:"_id"`
+	Parties     []PartyInBox       `bson:"parties"`
+	Individuals []IndividualInBox  `bson:"individuals"`
+	ValidVotes  int64              `bson:"validvotes"`
+}
+
+// Create the result of a region or box with the percentages of the parties and individuals
+func NewResult(location string, votes ResultVotes) Result {
+	result := Result{
+		Location:    location,
+		Parties:     []PartyInResult{},
+		Individuals: []IndividualInResult{},
+	}
+	for _, party := range votes.Parties {
+		result.Parties = append(result.Parties, PartyInResult{
+			CandidacyId: party.CandidacyId,
+			Name:        party.Name,
+			Votes:       party.Votes,
+			Percentage:  percentage(party.Votes, votes.ValidVotes),
+		})
+	}
+	for _, individual := range votes.Individuals {
+		result.Individuals = append(result.Individuals, IndividualInResult{
+			CandidacyId: individual.CandidacyId,
+			FirstName:   individual.FirstName,
+			LastName:    individual.LastName,
+			Votes:       individual.Votes,
+			Percentage:  percentage(individual.Votes, votes.ValidVotes),
+		})
+	}
+	return result
+}
@@ -16,3 +16,12 @@ func GetCityRoutes(router *gin.RouterGroup) {
 		cityRoutes.DELETE("/:id/", controllers.DeleteCity)
 	}
 }
+
+// Returns all routes for the cities model
+func GetCitiesRoutes(router *gin.RouterGroup) {
+	citiesRoutes := router.Group("/cities")
+	{
+		// Routes for interacting with cities in the database
+		citiesRoutes.GET("/", controllers.GetCities)
+	}
+}
@@ -16,3 +16,13 @@ func GetConstituencyRoutes(router *gin.RouterGroup) {
 		constituencyRoutes.DELETE("/:id/", controllers.DeleteConstituency)
 	}
 }
+
+// Returns all routes for the constituency model
+func GetConstituenciesRoutes(router *gin.RouterGroup) {
+	constituenciesRoutes := router.Group("/constituencies")
+	{
+		// Routes for interacting with constituencies in the database
+		constituenciesRoutes.GET("/", controllers.GetConstituencies)
+		constituenciesRoutes.GET("/:city/", controllers.GetConstituenciesByCity)
+	}
+}
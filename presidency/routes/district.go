@@ -17,3 +17,14 @@ func GetDistrictRoutes(router *gin.RouterGroup) {
 		districtRoutes.DELETE("/:id/", controllers.DeleteDistrict)
 	}
 }
+
+// Returns all routes for the districts model
+func GetDistrictsRoutes(router *gin.RouterGroup) {
+	districtsRoutes := router.Group("/districts")
+	{
+		// Routes for interacting with districts in the database
+		districtsRoutes.GET("/", controllers.GetDistricts)
+		districtsRoutes.GET("/:city/", controllers.GetDistrictsByCity)
+		districtsRoutes.GET("/:city/:constituency/", controllers.GetDistrictsByConstituency)
+	}
+}
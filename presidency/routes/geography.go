@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/presidency/controllers"
+)
+
+// Returns all routes for the geography registry
+func GetGeographyRoutes(router *gin.RouterGroup) {
+	geographyRoutes := router.Group("/geography")
+	{
+		// Routes for the units of the registry
+		geographyRoutes.POST("/", controllers.CreateGeographyUnit)
+		geographyRoutes.POST("/seed/", controllers.SeedGeography)
+		geographyRoutes.GET("/:level/", controllers.GetGeographyUnits)
+		geographyRoutes.GET("/:level/:code/", controllers.GetGeographyUnit)
+		geographyRoutes.PUT("/:level/:code/", controllers.ChangeGeographyUnit)
+		geographyRoutes.DELETE("/:level/:code/", controllers.DeleteGeographyUnit)
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/presidency/controllers"
+)
+
+// Returns all routes for the bulk import
+func GetImportRoutes(router *gin.RouterGroup) {
+	importRoutes := router.Group("/import")
+	{
+		// Routes for importing csv and xlsx files into the database
+		importRoutes.POST("/:kind/", controllers.ImportFile)
+	}
+}
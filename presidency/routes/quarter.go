@@ -17,3 +17,13 @@ func GetQuarterRoutes(router *gin.RouterGroup) {
 		quarterRoutes.DELETE("/:id/", controllers.DeleteQuarter)
 	}
 }
+
+// Returns all routes for the quarters model
+func GetQuartersRoutes(router *gin.RouterGroup) {
+	quartersRoutes := router.Group("/quarters")
+	{
+		// Routes for interacting with quarters in the database
+		quartersRoutes.GET("/", controllers.GetQuarters)
+		quartersRoutes.GET("/:city/:district/", controllers.GetQuartersOfDistrict)
+	}
+}
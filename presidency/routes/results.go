@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/presidency/controllers"
+)
+
+// Returns all routes for the result model
+func GetResultsRoutes(router *gin.RouterGroup) {
+	resultsRoutes := router.Group("/results")
+	{
+		// Routes for interacting with results in the database
+		resultsRoutes.GET("/:city/", controllers.GetResultsByCity)
+		resultsRoutes.GET("/:city/:district/", controllers.GetResultsByDistrict)
+		resultsRoutes.GET("/:city/:district/:quarter/", controllers.GetResultsByQuarter)
+		resultsRoutes.GET("/:city/:district/:quarter/:box/", controllers.GetResultsByBox)
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/presidency/controllers"
+)
+
+// Returns all routes for the search
+func GetSearchRoutes(router *gin.RouterGroup) {
+	searchRoutes := router.Group("/search")
+	{
+		// Routes for searching the parties, individuals, regions and boxes
+		searchRoutes.GET("/", controllers.Search)
+	}
+}
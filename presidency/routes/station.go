@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yzaimoglu/election/presidency/controllers"
+)
+
+// Returns all routes for the polling station model
+func GetStationRoutes(router *gin.RouterGroup) {
+	stationRoutes := router.Group("/station")
+	{
+		// Routes for interacting with polling stations in the database
+		stationRoutes.POST("/", controllers.CreatePollingStation)
+		stationRoutes.GET("/:id/", controllers.GetPollingStation)
+		stationRoutes.PUT("/:id/", controllers.ChangePollingStation)
+		stationRoutes.DELETE("/:id/", controllers.DeletePollingStation)
+	}
+}
+
+// Returns all routes for the polling station model
+func GetStationsRoutes(router *gin.RouterGroup) {
+	stationRoutes := router.Group("/stations")
+	{
+		// Routes for listing the polling stations of a region
+		stationRoutes.GET("/:city/:district/", controllers.GetPollingStationsByDistrict)
+		stationRoutes.GET("/:city/:district/:quarter/", controllers.GetPollingStationsByQuarter)
+	}
+	nearbyRoutes := router.Group("/nearby")
+	{
+		// Routes for finding the polling stations and boxes near a point
+		nearbyRoutes.GET("/stations/", controllers.GetNearbyStations)
+		nearbyRoutes.GET("/boxes/", controllers.GetNearbyBoxes)
+	}
+}
@@ -0,0 +1,123 @@
+// Search scoring shared by the services with a search. The file is copied into these services and
+// copies_test.go checks that the copies stay the same, change them all at once.
+
+package utilities
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Scores of the kinds of matches of a search, higher scores are ranked first
+const (
+	ScoreExact      = 100 // the whole text equals the query
+	ScorePrefix     = 80  // the text starts with the query
+	ScoreWordPrefix = 60  // a word of the text starts with the query
+	ScoreContains   = 40  // the text contains the query
+	ScoreFuzzy      = 30  // a word of the text is a typo of the query, reduced by 10 per edit
+)
+
+// Create a regular expression matching the candidates of a search, the texts containing the query or
+// a word starting like it
+//
+// Only the first two letters are used for the words so that typos later in the query are still found by MatchScore.
+//
+//	SearchCandidatePattern("ankra") == "(^|[\s\-./(])[aâAÂ][nN]|[aâAÂ][nN][kK][rR][aâAÂ]"
+func SearchCandidatePattern(input string) string {
+	runes := []rune(Fold(input))
+	if len(runes) > 2 {
+		runes = runes[:2]
+	}
+	return `(^|[\s\-./(])` + SearchPattern(string(runes)) + "|" + SearchPattern(input)
+}
+
+// Create the regular expressions of the candidates of a search from the best to the worst matches, the
+// texts equal to the query, the texts starting with it and the candidates of SearchCandidatePattern
+//
+// The candidates are searched in this order so that the best matches are not left out by a limit.
+//
+//	SearchCandidatePatterns("ank") == []string{"^[aâAÂ][nN][kK]$", "^[aâAÂ][nN][kK]", SearchCandidatePattern("ank")}
+func SearchCandidatePatterns(input string) []string {
+	pattern := SearchPattern(input)
+	return []string{"^" + pattern + "$", "^" + pattern, SearchCandidatePattern(input)}
+}
+
+// Score how well a text matches the query ignoring the case and the turkish letters, 0 if it does not match
+//
+//	MatchScore("cankaya", "Çankaya") == 100, MatchScore("ankra", "Ankara") == 20
+func MatchScore(query string, text string) int {
+	query, text = Fold(query), Fold(text)
+	if query == "" || text == "" {
+		return 0
+	}
+	switch {
+	case text == query:
+		return ScoreExact
+	case strings.HasPrefix(text, query):
+		return ScorePrefix
+	}
+	words := strings.FieldsFunc(text, isWordSeparator)
+	for _, word := range words {
+		if strings.HasPrefix(word, query) {
+			return ScoreWordPrefix
+		}
+	}
+	if strings.Contains(text, query) {
+		return ScoreContains
+	}
+
+	// Compare the query with the text, its words and the beginnings of its words allowing a few typos
+	maxDistance := 2
+	switch length := len([]rune(query)); {
+	case length <= 3:
+		return 0
+	case length <= 6:
+		maxDistance = 1
+	}
+	distance := levenshtein(query, text)
+	for _, word := range words {
+		distance = minInt(distance, levenshtein(query, word))
+		if runes := []rune(word); len(runes) > len([]rune(query)) {
+			distance = minInt(distance, levenshtein(query, string(runes[:len([]rune(query))])))
+		}
+	}
+	if distance > maxDistance {
+		return 0
+	}
+	return ScoreFuzzy - 10*distance
+}
+
+// Check if a rune separates the words of a text
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Get the number of insertions, deletions and substitutions of runes needed to change a into b
+func levenshtein(a string, b string) int {
+	runesA, runesB := []rune(a), []rune(b)
+	previous := make([]int, len(runesB)+1)
+	current := make([]int, len(runesB)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(runesA); i++ {
+		current[0] = i
+		for j := 1; j <= len(runesB); j++ {
+			cost := 1
+			if runesA[i-1] == runesB[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(runesB)]
+}
+
+// Get the smaller of two numbers
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
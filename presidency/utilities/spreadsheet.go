@@ -0,0 +1,58 @@
+package utilities
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Read all rows of a csv or xlsx file, the format is detected by the extension of the file name
+func ReadSpreadsheet(reader io.Reader, fileName string) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return readCSV(reader)
+	case ".xlsx":
+		return readXLSX(reader)
+	default:
+		return nil, errors.New("unsupported file type " + filepath.Ext(fileName) + ", must be .csv or .xlsx")
+	}
+}
+
+// Read the rows of a csv file, semicolons are used as the delimiter if the header contains no commas
+func readCSV(reader io.Reader) ([][]string, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the byte order mark written by Excel
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	csvReader := csv.NewReader(bytes.NewReader(content))
+	csvReader.FieldsPerRecord = -1
+	header, _, _ := bytes.Cut(content, []byte("\n"))
+	if !bytes.Contains(header, []byte(",")) && bytes.Contains(header, []byte(";")) {
+		csvReader.Comma = ';'
+	}
+	return csvReader.ReadAll()
+}
+
+// Read the rows of the first sheet of a xlsx file
+func readXLSX(reader io.Reader) ([][]string, error) {
+	file, err := excelize.OpenReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("the file contains no sheets")
+	}
+	return file.GetRows(sheets[0])
+}
@@ -30,6 +30,21 @@ export function get(path) {
 	return send({ method: 'GET', path });
 }
 
+export async function getAll(path) {
+	let data = [];
+	let cursor = '';
+	do {
+		const separator = path.includes('?') ? '&' : '?';
+		const page = await get(`${path}${separator}limit=1000${cursor ? '&cursor=' + cursor : ''}`);
+		if (!page?.data) {
+			return data;
+		}
+		data = data.concat(page.data);
+		cursor = page.nextcursor;
+	} while (cursor);
+	return data;
+}
+
 export function del(path) {
 	return send({ method: 'DELETE', path });
 }
@@ -1,9 +1,9 @@
-import { get } from '$lib/milletvekili/api.js'
+import { getAll } from '$lib/milletvekili/api.js'
 
 export async function load() {
-    const quarters = await get("quarters/");
-    const districts = await get("districts/");
-    const constituencies = await get("constituencies/");
-    const cities = await get("cities/");
+    const quarters = await getAll("quarters/");
+    const districts = await getAll("districts/");
+    const constituencies = await getAll("constituencies/");
+    const cities = await getAll("cities/");
 	return { quarters, districts, constituencies, cities };
 }
\ No newline at end of file
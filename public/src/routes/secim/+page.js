@@ -1,5 +1,5 @@
 // @ts-nocheck
-import { get as mvget } from '$lib/milletvekili/api'
+import { getAll as mvgetAll } from '$lib/milletvekili/api'
 import { get as bilgiget } from '$lib/bilgi/api'
 import { capitalizeWord } from '$lib/utilities/stringUtilities';
 
@@ -7,7 +7,7 @@ export async function load({ params }) {
     let cities;
 
     // Get the cities from the database
-    cities = await mvget("cities/");
+    cities = await mvgetAll("cities/");
 
     // Return the cities
     return { cities }
@@ -19,8 +19,8 @@ func LoopCity() {
 
 	// Loop through all the cities
 	for _, city := range cities {
-		fmt.Println("-----------")
-		fmt.Println(city.Name)
+		fmt.Fprintln(progress, "-----------")
+		fmt.Fprintln(progress, city.Name)
 
 		// Initialize candidates of the city
 		oldCandidatesOfCity := city.Candidates
@@ -34,6 +34,7 @@ func LoopCity() {
 
 		// Get all the constituencies of the city
 		constituencies := GetConstituenciesOfCity(city)
+		constituencies = dryRunConstituencies(constituencies)
 		totalEligibleVoters := int64(0)
 		totalValidVotes := int64(0)
 		totalInvalidVotes := int64(0)
@@ -41,7 +42,7 @@ func LoopCity() {
 
 		// Loop over all the constituencies
 		for _, constituency := range constituencies {
-			fmt.Println("Name: " + constituency.Name)
+			fmt.Fprintln(progress, "Name: "+constituency.Name)
 			candidates := constituency.Candidates
 			totalEligibleVoters += constituency.EligibleVoters
 			totalValidVotes += constituency.ValidVotes
@@ -51,19 +52,30 @@ func LoopCity() {
 			// Loop over the candidates to be able to calculate
 			for candidateIndex, candidate := range candidates {
 				// Check if candidate is corrupt
-				if candidatesOfCity[candidateIndex].LastName == candidate.LastName {
+				if candidatesOfCity[candidateIndex].Same(candidate) {
 					// Calculate the new votes
 					candidatesOfCity[candidateIndex].Votes = candidatesOfCity[candidateIndex].Votes + candidate.Votes
-					fmt.Println("Candidate: "+candidate.LastName+" (", candidatesOfCity[candidateIndex].Votes, ")")
+					fmt.Fprintln(progress, "Candidate: "+candidate.LastName+" (", candidatesOfCity[candidateIndex].Votes, ")")
 				}
 			}
 		}
-		// Set the new votes with a PUT request to the rest api
-		status, statusCode := SetVotesOfCity(city, candidatesOfCity, totalEligibleVoters, totalValidVotes, totalInvalidVotes, totalActualVoters)
-		fmt.Println(statusCode, status)
-		fmt.Println("-----------")
-		fmt.Println("")
-		fmt.Println("")
+		// Record the new votes in the diff instead of writing them when in the dry run mode
+		if IsDryRun() {
+			updatedCity := city
+			updatedCity.Candidates = candidatesOfCity
+			updatedCity.EligibleVoters = totalEligibleVoters
+			updatedCity.ValidVotes = totalValidVotes
+			updatedCity.InvalidVotes = totalInvalidVotes
+			updatedCity.ActualVoters = totalActualVoters
+			recordCity(city, updatedCity)
+		} else {
+			// Set the new votes with a PUT request to the rest api
+			status, statusCode := SetVotesOfCity(city, candidatesOfCity, totalEligibleVoters, totalValidVotes, totalInvalidVotes, totalActualVoters)
+			fmt.Fprintln(progress, statusCode, status)
+		}
+		fmt.Fprintln(progress, "-----------")
+		fmt.Fprintln(progress, "")
+		fmt.Fprintln(progress, "")
 	}
 }
 
@@ -95,8 +107,8 @@ func SetVotesOfCity(city models.MVCity, candidates []models.MVCandidateInBox,
 	}
 
 	// Set the request headers
-	// TODO: AUTHENTICATION
 	req.Header.Set("User-Agent", "updater-v1")
+	authorize(req)
 
 	// Execute the recently created request
 	res, err := client.Do(req)
@@ -117,102 +129,12 @@ func SetVotesOfCity(city models.MVCity, candidates []models.MVCandidateInBox,
 
 // Get the boxes of a city
 func GetConstituenciesOfCity(city models.MVCity) []models.MVConstituency {
-	// Initialize the HTTP client
-	client := http.Client{
-		Timeout: time.Second * 15,
-	}
-
-	// Initialize the constituencies slice to be returned
-	var constituencies []models.MVConstituency
-
-	// Create the request
-	req, err := http.NewRequest(http.MethodGet, "http://localhost:84/v1/constituencies/"+city.Name, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Set the request headers
-	// TODO: AUTHENTICATION
-	req.Header.Set("User-Agent", "updater-v1")
-
-	// Execute the request
-	res, getErr := client.Do(req)
-	if getErr != nil {
-		log.Fatal(getErr)
-	}
-
-	// Return not found if the status code is not 200
-	if res.StatusCode != 200 {
-		fmt.Println("no constituencies for this city found")
-		return nil
-	}
-
-	// Close the response body
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	// Read the body
-	body, readErr := io.ReadAll(res.Body)
-	if readErr != nil {
-		log.Fatal(readErr)
-	}
-
-	// Unmarshal the response to the constituencies object and return
-	jsonErr := json.Unmarshal(body, &constituencies)
-	if jsonErr != nil {
-		log.Fatal(jsonErr)
-	}
-	return constituencies
+	// Get all pages of the constituencies of the city from the rest api
+	return getAllPages[models.MVConstituency]("http://localhost:84/v1/constituencies/"+city.Name+"/", "no constituencies for this city found")
 }
 
 // Get an object from the json
 func GetCities() []models.MVCity {
-	// Initialize the HTTP Client
-	client := http.Client{
-		Timeout: time.Second * 15,
-	}
-
-	// Initialize the cities slice
-	var cities []models.MVCity
-
-	// Creat the GET request
-	req, err := http.NewRequest(http.MethodGet, "http://localhost:84/v1/cities", nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Set the request headers
-	// TODO: AUTHENTICATION
-	req.Header.Set("User-Agent", "updater-v1")
-
-	// Execute the request
-	res, getErr := client.Do(req)
-	if getErr != nil {
-		log.Fatal(getErr)
-	}
-
-	// Return not found if the status code is not 200
-	if res.StatusCode != 200 {
-		fmt.Println("no cities found")
-		return nil
-	}
-
-	// Close the response body
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	// Read the body
-	body, readErr := io.ReadAll(res.Body)
-	if readErr != nil {
-		log.Fatal(readErr)
-	}
-
-	// Unmarshal the json into the cities slice
-	jsonErr := json.Unmarshal(body, &cities)
-	if jsonErr != nil {
-		log.Fatal(jsonErr)
-	}
-	return cities
+	// Get all pages of the cities from the rest api
+	return getAllPages[models.MVCity]("http://localhost:84/v1/cities/", "no cities found")
 }
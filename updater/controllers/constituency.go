@@ -19,8 +19,8 @@ func LoopConstituency() {
 
 	// Loop through all the constituencies
 	for _, constituency := range constituencies {
-		fmt.Println("-----------")
-		fmt.Println(constituency.City + "-" + constituency.Name)
+		fmt.Fprintln(progress, "-----------")
+		fmt.Fprintln(progress, constituency.City+"-"+constituency.Name)
 
 		// Initialize candidates of constituency
 		oldCandidatesOfConstituency := constituency.Candidates
@@ -34,6 +34,7 @@ func LoopConstituency() {
 
 		// Get all the districts of the district
 		districts := GetDistrictsOfConstituency(constituency)
+		districts = dryRunDistricts(districts)
 		totalEligibleVoters := int64(0)
 		totalValidVotes := int64(0)
 		totalInvalidVotes := int64(0)
@@ -41,7 +42,7 @@ func LoopConstituency() {
 
 		// Loop over all the quarters
 		for _, district := range districts {
-			fmt.Println("District: ", district.Name)
+			fmt.Fprintln(progress, "District: ", district.Name)
 			candidates := district.Candidates
 			totalEligibleVoters += district.EligibleVoters
 			totalValidVotes += district.ValidVotes
@@ -51,19 +52,30 @@ func LoopConstituency() {
 			// Loop over the candidates to be able to calculate
 			for candidateIndex, candidate := range candidates {
 				// Check if the candidate is corrupt
-				if candidatesOfConstituency[candidateIndex].LastName == candidate.LastName {
+				if candidatesOfConstituency[candidateIndex].Same(candidate) {
 					// Calculate the new votes
 					candidatesOfConstituency[candidateIndex].Votes = candidatesOfConstituency[candidateIndex].Votes + candidate.Votes
-					fmt.Println("Candidate: "+candidate.LastName+" (", candidatesOfConstituency[candidateIndex].Votes, ")")
+					fmt.Fprintln(progress, "Candidate: "+candidate.LastName+" (", candidatesOfConstituency[candidateIndex].Votes, ")")
 				}
 			}
 		}
-		// Set the new votes with a PUT request to the rest api
-		status, statusCode := SetVotesOfConstituency(constituency, candidatesOfConstituency, totalEligibleVoters, totalValidVotes, totalInvalidVotes, totalActualVoters)
-		fmt.Println(statusCode, status)
-		fmt.Println("-----------")
-		fmt.Println("")
-		fmt.Println("")
+		// Record the new votes in the diff instead of writing them when in the dry run mode
+		if IsDryRun() {
+			updatedConstituency := constituency
+			updatedConstituency.Candidates = candidatesOfConstituency
+			updatedConstituency.EligibleVoters = totalEligibleVoters
+			updatedConstituency.ValidVotes = totalValidVotes
+			updatedConstituency.InvalidVotes = totalInvalidVotes
+			updatedConstituency.ActualVoters = totalActualVoters
+			recordConstituency(constituency, updatedConstituency)
+		} else {
+			// Set the new votes with a PUT request to the rest api
+			status, statusCode := SetVotesOfConstituency(constituency, candidatesOfConstituency, totalEligibleVoters, totalValidVotes, totalInvalidVotes, totalActualVoters)
+			fmt.Fprintln(progress, statusCode, status)
+		}
+		fmt.Fprintln(progress, "-----------")
+		fmt.Fprintln(progress, "")
+		fmt.Fprintln(progress, "")
 	}
 }
 
@@ -95,8 +107,8 @@ func SetVotesOfConstituency(constituency models.MVConstituency, candidates []mod
 	}
 
 	// Set the request headers
-	// TODO: AUTHENTICATION
 	req.Header.Set("User-Agent", "updater-v1")
+	authorize(req)
 
 	// Execute the recently created request
 	res, err := client.Do(req)
@@ -117,102 +129,12 @@ func SetVotesOfConstituency(constituency models.MVConstituency, candidates []mod
 
 // Get the districts of constituency
 func GetDistrictsOfConstituency(constituency models.MVConstituency) []models.MVDistrict {
-	// Initialize the HTTP Client
-	client := http.Client{
-		Timeout: time.Second * 15,
-	}
-
-	// Initialize the district slice to be returned
-	var districts []models.MVDistrict
-
-	// Create the request
-	req, err := http.NewRequest(http.MethodGet, "http://localhost:84/v1/districts/"+constituency.City+"/"+constituency.Name+"/", nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Set the request Headers
-	// TODO: AUTHENTICATION
-	req.Header.Set("User-Agent", "updater-v1")
-
-	// Execute the request
-	res, getErr := client.Do(req)
-	if getErr != nil {
-		log.Fatal(getErr)
-	}
-
-	// Return not found if the status code is not 200
-	if res.StatusCode != 200 {
-		fmt.Println("no districts for this constituency found")
-		return nil
-	}
-
-	// Close the response body
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	// Read the body
-	body, readErr := io.ReadAll(res.Body)
-	if readErr != nil {
-		log.Fatal(readErr)
-	}
-
-	// Unmarshal the reponse to the districts object and return
-	jsonErr := json.Unmarshal(body, &districts)
-	if jsonErr != nil {
-		log.Fatal(jsonErr)
-	}
-	return districts
+	// Get all pages of the districts of the constituency from the rest api
+	return getAllPages[models.MVDistrict]("http://localhost:84/v1/districts/"+constituency.City+"/"+constituency.Name+"/", "no districts for this constituency found")
 }
 
 // Get the districts
 func GetConstituencies() []models.MVConstituency {
-	// Initialize the HTTP Client
-	client := http.Client{
-		Timeout: time.Second * 15,
-	}
-
-	// Initialize the constituencies slice
-	var constituencies []models.MVConstituency
-
-	// Create the GET request
-	req, err := http.NewRequest(http.MethodGet, "http://localhost:84/v1/constituencies", nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Set the request headers
-	// TODO: AUTHENTICATION
-	req.Header.Set("User-Agent", "updater-v1")
-
-	// Execute the request
-	res, getErr := client.Do(req)
-	if getErr != nil {
-		log.Fatal(getErr)
-	}
-
-	// Return not found if the status code is not 200
-	if res.StatusCode != 200 {
-		fmt.Println("no constituencies found")
-		return nil
-	}
-
-	// Close the response body
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	// Read the body
-	body, readErr := io.ReadAll(res.Body)
-	if readErr != nil {
-		log.Fatal(readErr)
-	}
-
-	// Unmarshal the json into the constituencies slice
-	jsonErr := json.Unmarshal(body, &constituencies)
-	if jsonErr != nil {
-		log.Fatal(jsonErr)
-	}
-	return constituencies
+	// Get all pages of the constituencies from the rest api
+	return getAllPages[models.MVConstituency]("http://localhost:84/v1/constituencies/", "no constituencies found")
 }
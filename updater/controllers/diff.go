@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/yzaimoglu/election/updater/models"
+	"github.com/yzaimoglu/election/updater/utilities"
+)
+
+// State of the dry run, nil if the updater writes its results to the rest api
+var dryRun *dryRunState
+
+// Output of the progress, stderr in the dry run mode so that a diff written to stdout stays machine-readable
+var progress io.Writer = os.Stdout
+
+// Model for the state of a dry run
+type dryRunState struct {
+	diff           models.Diff
+	quarters       map[string]models.MVQuarter
+	districts      map[string]models.MVDistrict
+	constituencies map[string]models.MVConstituency
+}
+
+// Model for the totals of a region which are compared in the diff
+type regionTotals struct {
+	Candidates     []models.MVCandidateInBox
+	EligibleVoters int64
+	ActualVoters   int64
+	ValidVotes     int64
+	InvalidVotes   int64
+}
+
+// Enable the dry run mode, all aggregates are computed but nothing is written and the progress goes to stderr
+func EnableDryRun(threshold float64) {
+	progress = os.Stderr
+	dryRun = &dryRunState{
+		diff: models.Diff{
+			GeneratedAt: utilities.GetCurrentTime(),
+			Threshold:   threshold,
+			Regions:     []models.RegionDiff{},
+		},
+		quarters:       map[string]models.MVQuarter{},
+		districts:      map[string]models.MVDistrict{},
+		constituencies: map[string]models.MVConstituency{},
+	}
+}
+
+// Check if the updater is in the dry run mode
+func IsDryRun() bool {
+	return dryRun != nil
+}
+
+// Write the diff of the dry run in the given format (json or csv)
+func WriteDiff(w io.Writer, format string) error {
+	if dryRun == nil {
+		return errors.New("the updater is not in the dry run mode")
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(dryRun.diff)
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"level", "_id", "location", "flagged", "field", "old", "new", "delta", "percent", "swing", "removed"}); err != nil {
+			return err
+		}
+		for _, region := range dryRun.diff.Regions {
+			for _, change := range region.Changes {
+				if err := writer.Write([]string{
+					region.Level,
+					region.Id,
+					region.Location,
+					strconv.FormatBool(region.Flagged),
+					change.Field,
+					strconv.FormatInt(change.Old, 10),
+					strconv.FormatInt(change.New, 10),
+					strconv.FormatInt(change.Delta, 10),
+					strconv.FormatFloat(change.Percent, 'f', 2, 64),
+					strconv.FormatFloat(change.Swing, 'f', 2, 64),
+					strconv.FormatBool(change.Removed),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("unknown diff format %q", format)
+	}
+}
+
+// Record the computed quarter in the dry run
+func recordQuarter(old models.MVQuarter, updated models.MVQuarter) {
+	dryRun.quarters[old.City+"-"+old.District+"-"+old.Name] = updated
+	recordRegion("quarter", old.Id.Hex(), old.City+"-"+old.District+"-"+old.Name,
+		regionTotals{old.Candidates, old.EligibleVoters, old.ActualVoters, old.ValidVotes, old.InvalidVotes},
+		regionTotals{updated.Candidates, updated.EligibleVoters, updated.ActualVoters, updated.ValidVotes, updated.InvalidVotes})
+}
+
+// Record the computed district in the dry run
+func recordDistrict(old models.MVDistrict, updated models.MVDistrict) {
+	dryRun.districts[old.City+"-"+old.Name] = updated
+	recordRegion("district", old.Id.Hex(), old.City+"-"+old.Constituency+"-"+old.Name,
+		regionTotals{old.Candidates, old.EligibleVoters, old.ActualVoters, old.ValidVotes, old.InvalidVotes},
+		regionTotals{updated.Candidates, updated.EligibleVoters, updated.ActualVoters, updated.ValidVotes, updated.InvalidVotes})
+}
+
+// Record the computed constituency in the dry run
+func recordConstituency(old models.MVConstituency, updated models.MVConstituency) {
+	dryRun.constituencies[old.City+"-"+old.Name] = updated
+	recordRegion("constituency", old.Id.Hex(), old.City+"-"+old.Name,
+		regionTotals{old.Candidates, old.EligibleVoters, old.ActualVoters, old.ValidVotes, old.InvalidVotes},
+		regionTotals{updated.Candidates, updated.EligibleVoters, updated.ActualVoters, updated.ValidVotes, updated.InvalidVotes})
+}
+
+// Record the computed city in the dry run
+func recordCity(old models.MVCity, updated models.MVCity) {
+	recordRegion("city", old.Id.Hex(), old.Name,
+		regionTotals{old.Candidates, old.EligibleVoters, old.ActualVoters, old.ValidVotes, old.InvalidVotes},
+		regionTotals{updated.Candidates, updated.EligibleVoters, updated.ActualVoters, updated.ValidVotes, updated.InvalidVotes})
+}
+
+// Replace the stored quarters with the ones computed in the dry run
+func dryRunQuarters(quarters []models.MVQuarter) []models.MVQuarter {
+	if dryRun == nil {
+		return quarters
+	}
+	for index, quarter := range quarters {
+		if computed, ok := dryRun.quarters[quarter.City+"-"+quarter.District+"-"+quarter.Name]; ok {
+			quarters[index] = computed
+		}
+	}
+	return quarters
+}
+
+// Replace the stored districts with the ones computed in the dry run
+func dryRunDistricts(districts []models.MVDistrict) []models.MVDistrict {
+	if dryRun == nil {
+		return districts
+	}
+	for index, district := range districts {
+		if computed, ok := dryRun.districts[district.City+"-"+district.Name]; ok {
+			districts[index] = computed
+		}
+	}
+	return districts
+}
+
+// Replace the stored constituencies with the ones computed in the dry run
+func dryRunConstituencies(constituencies []models.MVConstituency) []models.MVConstituency {
+	if dryRun == nil {
+		return constituencies
+	}
+	for index, constituency := range constituencies {
+		if computed, ok := dryRun.constituencies[constituency.City+"-"+constituency.Name]; ok {
+			constituencies[index] = computed
+		}
+	}
+	return constituencies
+}
+
+// Compare the stored and the computed totals of a region and add the changes to the diff
+func recordRegion(level string, id string, location string, old regionTotals, updated regionTotals) {
+	regionDiff := models.RegionDiff{
+		Level:    level,
+		Id:       id,
+		Location: location,
+	}
+
+	// Compare the voter and vote totals
+	totals := []struct {
+		field string
+		old   int64
+		new   int64
+	}{
+		{"eligiblevoters", old.EligibleVoters, updated.EligibleVoters},
+		{"actualvoters", old.ActualVoters, updated.ActualVoters},
+		{"validvotes", old.ValidVotes, updated.ValidVotes},
+		{"invalidvotes", old.InvalidVotes, updated.InvalidVotes},
+	}
+	for _, total := range totals {
+		if total.old == total.new {
+			continue
+		}
+		change := newFieldChange(total.field, total.old, total.new)
+		if math.Abs(change.Percent) >= dryRun.diff.Threshold {
+			regionDiff.Flagged = true
+		}
+		regionDiff.Changes = append(regionDiff.Changes, change)
+	}
+
+	// Key the stored candidates by their identity, the order of the candidates can differ between the two
+	oldCandidates := map[string]int{}
+	oldLastNames := map[string]int{}
+	for index, candidate := range old.Candidates {
+		oldCandidates[candidate.Key()] = index
+		oldLastNames[candidate.LastName] = index
+	}
+	matched := map[int]bool{}
+
+	// Compare the votes and the vote shares of the candidates
+	for _, candidate := range updated.Candidates {
+		var oldVotes int64
+		index, ok := oldCandidates[candidate.Key()]
+		if !ok {
+			// Entries with and without a candidacy are the same candidate if their lastnames are equal
+			index, ok = oldLastNames[candidate.LastName]
+			ok = ok && old.Candidates[index].Same(candidate)
+		}
+		if ok && !matched[index] {
+			matched[index] = true
+			oldVotes = old.Candidates[index].Votes
+		}
+		oldShare := share(oldVotes, old.ValidVotes)
+		newShare := share(candidate.Votes, updated.ValidVotes)
+		if oldVotes == candidate.Votes && oldShare == newShare {
+			continue
+		}
+		change := newFieldChange("candidate:"+candidate.FirstName+" "+candidate.LastName, oldVotes, candidate.Votes)
+		change.Swing = round(newShare - oldShare)
+		if math.Abs(change.Swing) >= dryRun.diff.Threshold {
+			regionDiff.Flagged = true
+		}
+		regionDiff.Changes = append(regionDiff.Changes, change)
+	}
+
+	// Stored candidates which are not part of the computed region anymore lose all their votes
+	for index, candidate := range old.Candidates {
+		if matched[index] {
+			continue
+		}
+		change := newFieldChange("candidate:"+candidate.FirstName+" "+candidate.LastName, candidate.Votes, 0)
+		change.Swing = round(-share(candidate.Votes, old.ValidVotes))
+		change.Removed = true
+		if math.Abs(change.Swing) >= dryRun.diff.Threshold {
+			regionDiff.Flagged = true
+		}
+		regionDiff.Changes = append(regionDiff.Changes, change)
+	}
+
+	// Only regions with changes are part of the diff
+	if len(regionDiff.Changes) > 0 {
+		dryRun.diff.Regions = append(dryRun.diff.Regions, regionDiff)
+	}
+}
+
+// Create a field change with the delta and the relative change
+func newFieldChange(field string, old int64, new int64) models.FieldChange {
+	change := models.FieldChange{
+		Field: field,
+		Old:   old,
+		New:   new,
+		Delta: new - old,
+	}
+	if old != 0 {
+		change.Percent = round(float64(new-old) / float64(old) * 100)
+	} else if new != 0 {
+		change.Percent = 100
+	}
+	return change
+}
+
+// Calculate the share of the votes in percent
+func share(votes int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(votes) / float64(total) * 100
+}
+
+// Round a float to two decimal places
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yzaimoglu/election/updater/models"
+)
+
+func TestRecordRegionMatchesTheCandidatesByIdentity(t *testing.T) {
+	EnableDryRun(5)
+	t.Cleanup(func() { dryRun = nil })
+
+	// The computed region has the candidates in another order, one of them is new and one is gone
+	old := regionTotals{
+		Candidates: []models.MVCandidateInBox{
+			{CandidacyId: "c1", FirstName: "Ayşe", LastName: "Demir", Votes: 500},
+			{FirstName: "Mehmet", LastName: "Kaya", Votes: 300},
+			{CandidacyId: "c3", FirstName: "Ali", LastName: "Çelik", Votes: 200},
+		},
+		EligibleVoters: 1200, ActualVoters: 1020, ValidVotes: 1000, InvalidVotes: 20,
+	}
+	updated := regionTotals{
+		Candidates: []models.MVCandidateInBox{
+			{CandidacyId: "c2", FirstName: "Mehmet", LastName: "Kaya", Votes: 300},
+			{CandidacyId: "c1", FirstName: "Ayşe", LastName: "Demir", Votes: 500},
+			{CandidacyId: "c4", FirstName: "Zeynep", LastName: "Arslan", Votes: 200},
+		},
+		EligibleVoters: 1200, ActualVoters: 1020, ValidVotes: 1000, InvalidVotes: 20,
+	}
+	recordRegion("quarter", "63f8c2e1a4b5d6e7f8a9b0c1", "ankara-cankaya-kizilay", old, updated)
+
+	want := []models.FieldChange{
+		{Field: "candidate:Zeynep Arslan", Old: 0, New: 200, Delta: 200, Percent: 100, Swing: 20},
+		{Field: "candidate:Ali Çelik", Old: 200, New: 0, Delta: -200, Percent: -100, Swing: -20, Removed: true},
+	}
+	if len(dryRun.diff.Regions) != 1 {
+		t.Fatalf("got %d regions in the diff, want 1", len(dryRun.diff.Regions))
+	}
+	region := dryRun.diff.Regions[0]
+	if !reflect.DeepEqual(region.Changes, want) || !region.Flagged {
+		t.Fatalf("got the changes %+v flagged %t, want %+v flagged", region.Changes, region.Flagged, want)
+	}
+
+	// A region with the same candidates in another order has no changes
+	dryRun.diff.Regions = nil
+	reordered := old
+	reordered.Candidates = []models.MVCandidateInBox{old.Candidates[2], old.Candidates[0], old.Candidates[1]}
+	recordRegion("quarter", "63f8c2e1a4b5d6e7f8a9b0c1", "ankara-cankaya-kizilay", old, reordered)
+	if len(dryRun.diff.Regions) != 0 {
+		t.Fatalf("got the changes %+v for reordered candidates, want none", dryRun.diff.Regions[0].Changes)
+	}
+}
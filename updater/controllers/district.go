@@ -19,8 +19,8 @@ func LoopDistrict() {
 
 	// Loop through all the districts
 	for _, district := range districts {
-		fmt.Println("-----------")
-		fmt.Println(district.City + "-" + district.Constituency + "-" + district.Name)
+		fmt.Fprintln(progress, "-----------")
+		fmt.Fprintln(progress, district.City+"-"+district.Constituency+"-"+district.Name)
 
 		// Initialize candidates of district
 		oldCandidatesOfDistrict := district.Candidates
@@ -34,6 +34,7 @@ func LoopDistrict() {
 
 		// Get all the quarters of the district
 		quarters := GetQuartersOfDistrict(district)
+		quarters = dryRunQuarters(quarters)
 		totalEligibleVoters := int64(0)
 		totalValidVotes := int64(0)
 		totalInvalidVotes := int64(0)
@@ -41,7 +42,7 @@ func LoopDistrict() {
 
 		// Loop over all the quarters
 		for _, quarter := range quarters {
-			fmt.Println("Quarter: ", quarter.Name)
+			fmt.Fprintln(progress, "Quarter: ", quarter.Name)
 			candidates := quarter.Candidates
 			totalEligibleVoters += quarter.EligibleVoters
 			totalValidVotes += quarter.ValidVotes
@@ -51,19 +52,30 @@ func LoopDistrict() {
 			// Loop over the candidates to be able to calculate
 			for candidateIndex, candidate := range candidates {
 				// Check if the candidate is corrupt
-				if candidatesOfDistrict[candidateIndex].LastName == candidate.LastName {
+				if candidatesOfDistrict[candidateIndex].Same(candidate) {
 					// Calculate the new votes
 					candidatesOfDistrict[candidateIndex].Votes = candidatesOfDistrict[candidateIndex].Votes + candidate.Votes
-					fmt.Println("Candidate: "+candidate.LastName+" (", candidatesOfDistrict[candidateIndex].Votes, ")")
+					fmt.Fprintln(progress, "Candidate: "+candidate.LastName+" (", candidatesOfDistrict[candidateIndex].Votes, ")")
 				}
 			}
 		}
-		// Set the new votes with a PUT request to the rest api
-		status, statusCode := SetVotesOfDistrict(district, candidatesOfDistrict, totalEligibleVoters, totalValidVotes, totalInvalidVotes, totalActualVoters)
-		fmt.Println(statusCode, status)
-		fmt.Println("-----------")
-		fmt.Println("")
-		fmt.Println("")
+		// Record the new votes in the diff instead of writing them when in the dry run mode
+		if IsDryRun() {
+			updatedDistrict := district
+			updatedDistrict.Candidates = candidatesOfDistrict
+			updatedDistrict.EligibleVoters = totalEligibleVoters
+			updatedDistrict.ValidVotes = totalValidVotes
+			updatedDistrict.InvalidVotes = totalInvalidVotes
+			updatedDistrict.ActualVoters = totalActualVoters
+			recordDistrict(district, updatedDistrict)
+		} else {
+			// Set the new votes with a PUT request to the rest api
+			status, statusCode := SetVotesOfDistrict(district, candidatesOfDistrict, totalEligibleVoters, totalValidVotes, totalInvalidVotes, totalActualVoters)
+			fmt.Fprintln(progress, statusCode, status)
+		}
+		fmt.Fprintln(progress, "-----------")
+		fmt.Fprintln(progress, "")
+		fmt.Fprintln(progress, "")
 	}
 }
 
@@ -95,8 +107,8 @@ func SetVotesOfDistrict(district models.MVDistrict, candidates []models.MVCandid
 	}
 
 	// Set the request headers
-	// TODO: AUTHENTICATION
 	req.Header.Set("User-Agent", "updater-v1")
+	authorize(req)
 
 	// Execute the recently created request
 	res, err := client.Do(req)
@@ -117,102 +129,12 @@ func SetVotesOfDistrict(district models.MVDistrict, candidates []models.MVCandid
 
 // Get the quarters of a district
 func GetQuartersOfDistrict(district models.MVDistrict) []models.MVQuarter {
-	// Initialize the HTTP Client
-	client := http.Client{
-		Timeout: time.Second * 15,
-	}
-
-	// Initialize the quarter slice to be returned
-	var quarters []models.MVQuarter
-
-	// Create the request
-	req, err := http.NewRequest(http.MethodGet, "http://localhost:84/v1/quarters/"+district.City+"/"+district.Name+"/", nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Set the request Headers
-	// TODO: AUTHENTICATION
-	req.Header.Set("User-Agent", "updater-v1")
-
-	// Execute the request
-	res, getErr := client.Do(req)
-	if getErr != nil {
-		log.Fatal(getErr)
-	}
-
-	// Return not found if the status code is not 200
-	if res.StatusCode != 200 {
-		fmt.Println("no quarters for this district found")
-		return nil
-	}
-
-	// Close the response body
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	// Read the body
-	body, readErr := io.ReadAll(res.Body)
-	if readErr != nil {
-		log.Fatal(readErr)
-	}
-
-	// Unmarshal the reponse to the boxes object and return
-	jsonErr := json.Unmarshal(body, &quarters)
-	if jsonErr != nil {
-		log.Fatal(jsonErr)
-	}
-	return quarters
+	// Get all pages of the quarters of the district from the rest api
+	return getAllPages[models.MVQuarter]("http://localhost:84/v1/quarters/"+district.City+"/"+district.Name+"/", "no quarters for this district found")
 }
 
 // Get the districts
 func GetDistricts() []models.MVDistrict {
-	// Initialize the HTTP Client
-	client := http.Client{
-		Timeout: time.Second * 15,
-	}
-
-	// Initialize the districts slice
-	var districts []models.MVDistrict
-
-	// Create the GET request
-	req, err := http.NewRequest(http.MethodGet, "http://localhost:84/v1/districts", nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Set the request headers
-	// TODO: AUTHENTICATION
-	req.Header.Set("User-Agent", "updater-v1")
-
-	// Execute the request
-	res, getErr := client.Do(req)
-	if getErr != nil {
-		log.Fatal(getErr)
-	}
-
-	// Return not found if the status code is not 200
-	if res.StatusCode != 200 {
-		fmt.Println("no districts found")
-		return nil
-	}
-
-	// Close the response body
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	// Read the body
-	body, readErr := io.ReadAll(res.Body)
-	if readErr != nil {
-		log.Fatal(readErr)
-	}
-
-	// Unmarshal the json into the districts slice
-	jsonErr := json.Unmarshal(body, &districts)
-	if jsonErr != nil {
-		log.Fatal(jsonErr)
-	}
-	return districts
+	// Get all pages of the districts from the rest api
+	return getAllPages[models.MVDistrict]("http://localhost:84/v1/districts/", "no districts found")
 }
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/yzaimoglu/election/updater/models"
+)
+
+// Number of documents requested per page
+const pageLimit = 1000
+
+// Get all pages of a list endpoint, returns nil if the endpoint does not respond with 200
+func getAllPages[T any](endpoint string, notFound string) []T {
+	// Initialize the HTTP Client
+	client := http.Client{
+		Timeout: time.Second * 15,
+	}
+
+	// Initialize the slice of all documents
+	var documents []T
+
+	cursor := ""
+	for {
+		// Create the GET request for the next page
+		query := url.Values{}
+		query.Set("limit", fmt.Sprint(pageLimit))
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		req, err := http.NewRequest(http.MethodGet, endpoint+"?"+query.Encode(), nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Set the request headers
+		req.Header.Set("User-Agent", "updater-v1")
+		authorize(req)
+
+		// Execute the request
+		res, getErr := client.Do(req)
+		if getErr != nil {
+			log.Fatal(getErr)
+		}
+
+		// Return not found if the status code is not 200
+		if res.StatusCode != 200 {
+			res.Body.Close()
+			fmt.Fprintln(progress, notFound)
+			return nil
+		}
+
+		// Read the body
+		body, readErr := io.ReadAll(res.Body)
+		res.Body.Close()
+		if readErr != nil {
+			log.Fatal(readErr)
+		}
+
+		// Unmarshal the json into the page
+		var page models.MVPage[T]
+		jsonErr := json.Unmarshal(body, &page)
+		if jsonErr != nil {
+			log.Fatal(jsonErr)
+		}
+		documents = append(documents, page.Data...)
+
+		// Stop after the last page
+		if page.NextCursor == "" {
+			return documents
+		}
+		cursor = page.NextCursor
+	}
+}
@@ -19,8 +19,8 @@ func LoopQuarter() {
 
 	// Loop through all the quarters
 	for _, quarter := range quarters {
-		fmt.Println("-----------")
-		fmt.Println(quarter.City + "-" + quarter.District + "-" + quarter.Name)
+		fmt.Fprintln(progress, "-----------")
+		fmt.Fprintln(progress, quarter.City+"-"+quarter.District+"-"+quarter.Name)
 
 		// Initialize candidates of quarter
 		oldCandidatesOfQuarter := quarter.Candidates
@@ -41,7 +41,7 @@ func LoopQuarter() {
 
 		// Loop over all the boxes
 		for _, box := range boxes {
-			fmt.Println("Box: ", box.Number)
+			fmt.Fprintln(progress, "Box: ", box.Number)
 			candidates := box.Candidates
 			totalEligibleVoters += box.EligibleVoters
 			totalValidVotes += box.ValidVotes
@@ -51,19 +51,30 @@ func LoopQuarter() {
 			// Loop over the candidates to be able to calculate
 			for candidateIndex, candidate := range candidates {
 				// Check if the candidate is corrupt
-				if candidatesOfQuarter[candidateIndex].LastName == candidate.LastName {
+				if candidatesOfQuarter[candidateIndex].Same(candidate) {
 					// Calculate the new votes
 					candidatesOfQuarter[candidateIndex].Votes = candidatesOfQuarter[candidateIndex].Votes + candidate.Votes
-					fmt.Println("Candidate: "+candidate.LastName+" (", candidatesOfQuarter[candidateIndex].Votes, ")")
+					fmt.Fprintln(progress, "Candidate: "+candidate.LastName+" (", candidatesOfQuarter[candidateIndex].Votes, ")")
 				}
 			}
 		}
-		// Set the new votes with a PUT request to the rest api
-		status, statusCode := SetVotesOfQuarter(quarter, candidatesOfQuarter, totalEligibleVoters, totalValidVotes, totalInvalidVotes, totalActualVoters)
-		fmt.Println(statusCode, status)
-		fmt.Println("-----------")
-		fmt.Println("")
-		fmt.Println("")
+		// Record the new votes in the diff instead of writing them when in the dry run mode
+		if IsDryRun() {
+			updatedQuarter := quarter
+			updatedQuarter.Candidates = candidatesOfQuarter
+			updatedQuarter.EligibleVoters = totalEligibleVoters
+			updatedQuarter.ValidVotes = totalValidVotes
+			updatedQuarter.InvalidVotes = totalInvalidVotes
+			updatedQuarter.ActualVoters = totalActualVoters
+			recordQuarter(quarter, updatedQuarter)
+		} else {
+			// Set the new votes with a PUT request to the rest api
+			status, statusCode := SetVotesOfQuarter(quarter, candidatesOfQuarter, totalEligibleVoters, totalValidVotes, totalInvalidVotes, totalActualVoters)
+			fmt.Fprintln(progress, statusCode, status)
+		}
+		fmt.Fprintln(progress, "-----------")
+		fmt.Fprintln(progress, "")
+		fmt.Fprintln(progress, "")
 	}
 }
 
@@ -95,8 +106,8 @@ func SetVotesOfQuarter(quarter models.MVQuarter, candidates []models.MVCandidate
 	}
 
 	// Set the request headers
-	// TODO: AUTHENTICATION
 	req.Header.Set("User-Agent", "updater-v1")
+	authorize(req)
 
 	// Execute the recently created request
 	res, err := client.Do(req)
@@ -117,102 +128,12 @@ func SetVotesOfQuarter(quarter models.MVQuarter, candidates []models.MVCandidate
 
 // Get the boxes of a quarter
 func GetBoxesOfQuarter(quarter models.MVQuarter) []models.MVBox {
-	// Initialize the HTTP Client
-	client := http.Client{
-		Timeout: time.Second * 15,
-	}
-
-	// Initialize the box slice to be returned
-	var boxes []models.MVBox
-
-	// Create the request
-	req, err := http.NewRequest(http.MethodGet, "http://localhost:84/v1/boxes/"+quarter.City+"/"+quarter.District+"/"+quarter.Name, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Set the request Headers
-	// TODO: AUTHENTICATION
-	req.Header.Set("User-Agent", "updater-v1")
-
-	// Execute the request
-	res, getErr := client.Do(req)
-	if getErr != nil {
-		log.Fatal(getErr)
-	}
-
-	// Return not found if the status code is not 200
-	if res.StatusCode != 200 {
-		fmt.Println("no boxes for this quarter found")
-		return nil
-	}
-
-	// Close the response body
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	// Read the body
-	body, readErr := io.ReadAll(res.Body)
-	if readErr != nil {
-		log.Fatal(readErr)
-	}
-
-	// Unmarshal the reponse to the boxes object and return
-	jsonErr := json.Unmarshal(body, &boxes)
-	if jsonErr != nil {
-		log.Fatal(jsonErr)
-	}
-	return boxes
+	// Get all pages of the boxes of the quarter from the rest api
+	return getAllPages[models.MVBox]("http://localhost:84/v1/boxes/"+quarter.City+"/"+quarter.District+"/"+quarter.Name+"/", "no boxes for this quarter found")
 }
 
 // Get the quarters
 func GetQuarters() []models.MVQuarter {
-	// Initialize the HTTP Client
-	client := http.Client{
-		Timeout: time.Second * 15,
-	}
-
-	// Initialize the quarters slice
-	var quarters []models.MVQuarter
-
-	// Create the GET request
-	req, err := http.NewRequest(http.MethodGet, "http://localhost:84/v1/quarters", nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Set the request headers
-	// TODO: AUTHENTICATION
-	req.Header.Set("User-Agent", "updater-v1")
-
-	// Execute the request
-	res, getErr := client.Do(req)
-	if getErr != nil {
-		log.Fatal(getErr)
-	}
-
-	// Return not found if the status code is not 200
-	if res.StatusCode != 200 {
-		fmt.Println("no quarters found")
-		return nil
-	}
-
-	// Close the response body
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	// Read the body
-	body, readErr := io.ReadAll(res.Body)
-	if readErr != nil {
-		log.Fatal(readErr)
-	}
-
-	// Unmarshal the json into the quarters slice
-	jsonErr := json.Unmarshal(body, &quarters)
-	if jsonErr != nil {
-		log.Fatal(jsonErr)
-	}
-	return quarters
+	// Get all pages of the quarters from the rest api
+	return getAllPages[models.MVQuarter]("http://localhost:84/v1/quarters/", "no quarters found")
 }
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yzaimoglu/election/updater/utilities"
+)
+
+// Access token of the updater, it is reused until shortly before it expires
+var (
+	accessToken        string
+	accessTokenExpires time.Time
+	accessTokenMutex   sync.Mutex
+)
+
+// Set the access token of the updater to a request to the parliament service
+//
+// The token is requested from the authentication service with the client credentials in UPDATER_CLIENT_ID and
+// UPDATER_CLIENT_SECRET, requests are sent without a token if no client is configured.
+func authorize(req *http.Request) {
+	if token := serviceToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// Get the cached access token or request a new one if it expires within the next minute
+func serviceToken() string {
+	clientId := utilities.GetEnv("UPDATER_CLIENT_ID", "")
+	if clientId == "" {
+		return ""
+	}
+
+	accessTokenMutex.Lock()
+	defer accessTokenMutex.Unlock()
+	if accessToken != "" && time.Until(accessTokenExpires) > time.Minute {
+		return accessToken
+	}
+
+	// Request a token with the client credentials
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("scope", utilities.GetEnv("UPDATER_SCOPE", "parliament:write"))
+	req, err := http.NewRequest(http.MethodPost, utilities.GetEnv("UPDATER_AUTH_URL", "http://localhost:80/v1")+"/token/", strings.NewReader(form.Encode()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.SetBasicAuth(clientId, utilities.GetEnv("UPDATER_CLIENT_SECRET", ""))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "updater-v1")
+
+	// Execute the request
+	client := http.Client{
+		Timeout: time.Second * 10,
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		log.Fatal("requesting an access token returned " + res.Status)
+	}
+
+	// Parse the token and its lifetime
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		log.Fatal(err)
+	}
+	accessToken = token.AccessToken
+	accessTokenExpires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return accessToken
+}
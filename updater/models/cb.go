@@ -81,13 +81,15 @@ type CBQuarter struct {
 
 // Model for a Party in a Box
 type CBPartyInBox struct {
-	Name  string `json:"name" bson:"name"`   // CHP
-	Votes int64  `json:"votes" bson:"votes"` // 121
+	CandidacyId string `json:"candidacyid,omitempty" bson:"candidacyid,omitempty"` // 63f1c2... (candidacy of the info service)
+	Name        string `json:"name" bson:"name"`                                   // CHP
+	Votes       int64  `json:"votes" bson:"votes"`                                 // 121
 }
 
 // Model for a Individual in a Box
 type CBIndividualInBox struct {
-	FirstName string `json:"firstname" bson:"firstname"` // Max
-	LastName  string `json:"lastname" bson:"lastname"`   // Mustermann
-	Votes     int64  `json:"votes" bson:"votes"`         // 121
+	CandidacyId string `json:"candidacyid,omitempty" bson:"candidacyid,omitempty"` // 63f1c2... (candidacy of the info service)
+	FirstName   string `json:"firstname" bson:"firstname"`                         // Max
+	LastName    string `json:"lastname" bson:"lastname"`                           // Mustermann
+	Votes       int64  `json:"votes" bson:"votes"`                                 // 121
 }
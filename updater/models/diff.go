@@ -0,0 +1,28 @@
+package models
+
+// Model for the diff created by a dry run of the updater
+type Diff struct {
+	GeneratedAt int64        `json:"generatedat"` // 1684065600000
+	Threshold   float64      `json:"threshold"`   // 5
+	Regions     []RegionDiff `json:"regions"`
+}
+
+// Model for the changes of a single region
+type RegionDiff struct {
+	Level    string        `json:"level"`    // quarter
+	Id       string        `json:"_id"`      // 63f8c2e1a4b5d6e7f8a9b0c1
+	Location string        `json:"location"` // ankara-cankaya-cukurambar
+	Flagged  bool          `json:"flagged"`  // true if a change exceeds the threshold
+	Changes  []FieldChange `json:"changes"`
+}
+
+// Model for a single changed field of a region
+type FieldChange struct {
+	Field   string  `json:"field"`   // candidate:Max Mustermann
+	Old     int64   `json:"old"`     // 1210
+	New     int64   `json:"new"`     // 1342
+	Delta   int64   `json:"delta"`   // 132
+	Percent float64 `json:"percent"` // 10.9 (relative change of the value)
+	Swing   float64 `json:"swing"`   // 1.2 (change of the share of the valid votes in percentage points)
+	Removed bool    `json:"removed"` // true if the candidate is not part of the computed region anymore
+}
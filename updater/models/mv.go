@@ -76,7 +76,32 @@ type MVQuarter struct {
 
 // Model for a Party in a Box
 type MVCandidateInBox struct {
-	FirstName string `json:"firstname" bson:"firstname"`
-	LastName  string `json:"lastname" bson:"lastname"`
-	Votes     int64  `json:"votes" bson:"votes"` // 121
+	CandidacyId string `json:"candidacyid,omitempty" bson:"candidacyid,omitempty"` // 63f1c2... (candidacy of the info service)
+	FirstName   string `json:"firstname" bson:"firstname"`
+	LastName    string `json:"lastname" bson:"lastname"`
+	Votes       int64  `json:"votes" bson:"votes"` // 121
+}
+
+// Check if two entries belong to the same candidate, by the candidacy if both have one or else by the lastname
+func (candidate MVCandidateInBox) Same(other MVCandidateInBox) bool {
+	if candidate.CandidacyId != "" && other.CandidacyId != "" {
+		return candidate.CandidacyId == other.CandidacyId
+	}
+	return candidate.LastName == other.LastName
+}
+
+// Get the identity of the candidate, the candidacy if it has one or else the lastname like in Same
+func (candidate MVCandidateInBox) Key() string {
+	if candidate.CandidacyId != "" {
+		return "candidacy:" + candidate.CandidacyId
+	}
+	return "lastname:" + candidate.LastName
+}
+
+// Model for a page of a list endpoint
+type MVPage[T any] struct {
+	Data       []T    `json:"data"`
+	Total      int64  `json:"total"`      // 81
+	Limit      int64  `json:"limit"`      // 1000
+	NextCursor string `json:"nextcursor"` // empty on the last page
 }
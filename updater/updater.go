@@ -1,12 +1,61 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/yzaimoglu/election/updater/controllers"
+	"github.com/yzaimoglu/election/updater/utilities"
 )
 
 func main() {
+	// Parse the command line flags, the environment variables are used as defaults
+	threshold, err := strconv.ParseFloat(utilities.GetEnv("UPDATER_SWING_THRESHOLD", "5"), 64)
+	if err != nil {
+		log.Fatalf("invalid UPDATER_SWING_THRESHOLD: %v", err)
+	}
+	dryRun := flag.Bool("dry-run", utilities.GetEnv("UPDATER_DRY_RUN", "false") == "true", "compute all aggregates and write a diff instead of updating the regions")
+	diffFormat := flag.String("diff-format", utilities.GetEnv("UPDATER_DIFF_FORMAT", "json"), "format of the dry run diff (json or csv)")
+	diffOutput := flag.String("diff-output", utilities.GetEnv("UPDATER_DIFF_OUTPUT", ""), "file for the dry run diff, - for stdout (default updater-diff.<format>)")
+	swingThreshold := flag.Float64("swing-threshold", threshold, "flag regions whose values change by at least this many percent (points for vote shares)")
+	flag.Parse()
+
+	// Compute all aggregates once and write the diff when in the dry run mode
+	if *dryRun {
+		if *diffFormat != "json" && *diffFormat != "csv" {
+			log.Fatalf("unknown diff format %q, must be json or csv", *diffFormat)
+		}
+		controllers.EnableDryRun(*swingThreshold)
+		controllers.LoopQuarter()
+		controllers.LoopDistrict()
+		controllers.LoopConstituency()
+		controllers.LoopCity()
+
+		// Write the diff to stdout or to the output file
+		output := os.Stdout
+		if *diffOutput != "-" {
+			fileName := *diffOutput
+			if fileName == "" {
+				fileName = "updater-diff." + *diffFormat
+			}
+			file, err := os.Create(fileName)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer file.Close()
+			output = file
+			fmt.Println("Writing the diff to " + fileName)
+		}
+		if err := controllers.WriteDiff(output, *diffFormat); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	loop := true
 	for loop {
 		controllers.LoopQuarter()